@@ -0,0 +1,24 @@
+data "xenserver_host_metrics" "hosts" {}
+
+output "host_metrics_output" {
+  value = data.xenserver_host_metrics.hosts.data_items
+}
+
+# Choose the live host with the most free memory
+locals {
+  vm_memory  = 4 * 1024 * 1024 * 1024
+  live_hosts = [for h in data.xenserver_host_metrics.hosts.data_items : h if h.live]
+  max_free   = max([for h in local.live_hosts : h.memory_free]...)
+  best_host  = [for h in local.live_hosts : h.uuid if h.memory_free == local.max_free][0]
+}
+
+check "host_capacity" {
+  assert {
+    condition     = local.max_free >= local.vm_memory
+    error_message = "No live host has enough free memory for the VM."
+  }
+}
+
+output "best_host_output" {
+  value = local.best_host
+}
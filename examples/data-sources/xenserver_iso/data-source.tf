@@ -0,0 +1,28 @@
+data "xenserver_sr" "iso_library" {
+  name_label = "ISOs"
+}
+
+data "xenserver_iso" "debian" {
+  name_label = "debian-12.iso"
+  sr_uuid    = data.xenserver_sr.iso_library.data_items[0].uuid
+}
+
+output "iso_output" {
+  value = data.xenserver_iso.debian.uuid
+}
+
+# Insert the ISO in the CD-ROM drive of a VM
+data "xenserver_network" "network" {}
+
+resource "xenserver_vm" "vm" {
+  name_label     = "Debian VM"
+  template_name  = "Debian Bookworm 12"
+  static_mem_max = 4 * 1024 * 1024 * 1024
+  vcpus          = 2
+  cdrom_vdi_uuid = data.xenserver_iso.debian.uuid
+  network_interface = [
+    {
+      network_uuid = data.xenserver_network.network.data_items[0].uuid,
+    },
+  ]
+}
@@ -4,4 +4,10 @@ data "xenserver_nic" "nic" {
 
 output "nic_output" {
   value = data.xenserver_nic.nic.data_items
-}
\ No newline at end of file
+}
+# list the SR-IOV capable NICs which are not used by any SR-IOV network yet
+data "xenserver_nic" "sriov_nic" {
+  network_type   = "sriov"
+  available_only = true
+  capabilities   = ["sriov"]
+}
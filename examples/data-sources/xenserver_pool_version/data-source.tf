@@ -0,0 +1,15 @@
+data "xenserver_pool_version" "version" {}
+
+output "pool_version_output" {
+  value = data.xenserver_pool_version.version.product_version
+}
+
+# Fail early when the pool is older than required
+resource "terraform_data" "check_version" {
+  lifecycle {
+    precondition {
+      condition     = data.xenserver_pool_version.version.api_version_major > 2 || (data.xenserver_pool_version.version.api_version_major == 2 && data.xenserver_pool_version.version.api_version_minor >= 21)
+      error_message = "XenServer API version 2.21 or later is required."
+    }
+  }
+}
@@ -0,0 +1,19 @@
+# list the exports of the NFS server
+data "xenserver_sr_probe" "nfs_exports" {
+  type = "nfs"
+  device_config = {
+    server = "10.0.0.1"
+  }
+}
+
+output "nfs_exports_output" {
+  value = [for item in data.xenserver_sr_probe.nfs_exports.data_items : item.configuration["serverpath"]]
+}
+
+# list the IQNs of the iSCSI target
+data "xenserver_sr_probe" "iscsi_iqns" {
+  type = "lvmoiscsi"
+  device_config = {
+    target = "10.0.0.2"
+  }
+}
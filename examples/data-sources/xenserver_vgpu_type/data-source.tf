@@ -0,0 +1,20 @@
+data "xenserver_vgpu_type" "t4" {
+  model_name = "GRID T4-2Q"
+}
+
+output "vgpu_type_output" {
+  value = data.xenserver_vgpu_type.t4.data_items
+}
+
+# Check the GPU groups can run the vGPUs before creating the VMs
+locals {
+  vm_count     = 4
+  max_capacity = sum([for t in data.xenserver_vgpu_type.t4.data_items : t.max_capacity - t.vgpu_count])
+}
+
+check "vgpu_capacity" {
+  assert {
+    condition     = local.max_capacity >= local.vm_count
+    error_message = "Not enough vGPU capacity for the VMs."
+  }
+}
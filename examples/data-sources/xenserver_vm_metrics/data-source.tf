@@ -0,0 +1,19 @@
+data "xenserver_vm" "vm" {
+  name_label = "Web VM"
+}
+
+data "xenserver_vm_metrics" "metrics" {
+  uuid = data.xenserver_vm.vm.data_items[0].uuid
+}
+
+output "metrics_output" {
+  value = data.xenserver_vm_metrics.metrics
+}
+
+# Warn when the VM uses more memory than expected
+check "vm_memory" {
+  assert {
+    condition     = data.xenserver_vm_metrics.metrics.memory_actual <= 4 * 1024 * 1024 * 1024
+    error_message = "The VM uses more than 4 GiB of memory."
+  }
+}
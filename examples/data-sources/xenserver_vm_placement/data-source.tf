@@ -0,0 +1,21 @@
+data "xenserver_vm" "vm" {
+  name_label = "Web VM"
+}
+
+data "xenserver_vm_placement" "placement" {
+  uuid = data.xenserver_vm.vm.data_items[0].uuid
+}
+
+output "placement_output" {
+  value = data.xenserver_vm_placement.placement
+}
+
+# Fail early when the VM can't be moved to another host
+resource "terraform_data" "check_placement" {
+  lifecycle {
+    precondition {
+      condition     = length(data.xenserver_vm_placement.placement.possible_hosts) > 0
+      error_message = "No other host can run the VM."
+    }
+  }
+}
@@ -0,0 +1,7 @@
+data "xenserver_vm_templates" "templates" {
+  name_label = "Windows 11"
+}
+
+output "templates_output" {
+  value = data.xenserver_vm_templates.templates.data_items
+}
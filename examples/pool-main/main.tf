@@ -32,6 +32,7 @@ resource "xenserver_pool" "pool" {
     }
   ]
   eject_supporters = [ data.xenserver_host.supporter.data_items[0].uuid ]
+  acknowledge_data_loss = [ data.xenserver_host.supporter.data_items[0].uuid ]
 }
 
 
@@ -3,3 +3,12 @@ provider "xenserver" {
   username = "root"
   password = var.password
 }
+
+# Try the other pool hosts when the first one is down or no longer the coordinator
+provider "xenserver" {
+  alias    = "ha_pool"
+  host     = "https://192.0.2.1"
+  hosts    = ["https://192.0.2.2", "https://192.0.2.3"]
+  username = "root"
+  password = var.password
+}
@@ -0,0 +1 @@
+terraform import xenserver_host_maintenance.maintenance 00000000-0000-0000-0000-000000000000
\ No newline at end of file
@@ -0,0 +1,12 @@
+data "xenserver_host" "supporter" {
+  is_coordinator = false
+}
+
+# Disable the host and migrate its VMs to the other hosts in the pool
+resource "xenserver_host_maintenance" "maintenance" {
+  host_uuid = data.xenserver_host.supporter.data_items[0].uuid
+}
+
+output "migrated_vms" {
+  value = xenserver_host_maintenance.maintenance.migrated_vms
+}
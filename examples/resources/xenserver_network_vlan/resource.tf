@@ -9,3 +9,11 @@ resource "xenserver_network_vlan" "vlan" {
     "flag" = "1"
   }
 }
+
+# A network for the storage migration over NBD
+resource "xenserver_network_vlan" "nbd_vlan" {
+  name_label = "Test NBD network"
+  vlan_tag   = 2
+  nic        = "NIC 1"
+  purpose    = ["nbd"]
+}
@@ -0,0 +1,11 @@
+# Create the networks "Tenant VLAN 100" to "Tenant VLAN 149" over the trunk
+resource "xenserver_network_vlan_range" "tenants" {
+  name_label_prefix = "Tenant VLAN "
+  name_description  = "Tenant networks"
+  vlan_tags         = range(100, 150)
+  nic               = "NIC 1"
+}
+
+output "tenant_network_uuids" {
+  value = xenserver_network_vlan_range.tenants.networks
+}
@@ -0,0 +1,18 @@
+data "xenserver_host" "supporter" {
+  address = "10.70.58.22"
+}
+
+data "xenserver_sr" "nfs" {
+  name_label = "NFS virtual disk storage"
+}
+
+# Connect a newly joined host to an existing shared SR
+resource "xenserver_pbd" "nfs_pbd" {
+  sr_uuid   = data.xenserver_sr.nfs.data_items[0].uuid
+  host_uuid = data.xenserver_host.supporter.data_items[0].uuid
+  device_config = {
+    server     = "10.70.58.9"
+    serverpath = "/xenrtnfs"
+    nfsversion = "3"
+  }
+}
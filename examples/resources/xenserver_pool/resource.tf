@@ -30,6 +30,9 @@ resource "xenserver_pool" "pool" {
   name_label   = "pool"
   default_sr = xenserver_sr_nfs.nfs.uuid
   management_network = data.xenserver_pif.pif.data_items[0].network
+  other_config = {
+    "migration_compression" = "true"
+  }
 }
 
 # Join supporter into the pool
@@ -52,4 +55,5 @@ data "xenserver_host" "supporter" {
 resource "xenserver_pool" "pool" {
   name_label   = "pool"
   eject_supporters = [ data.xenserver_host.supporter.data_items[1].uuid ]
+  acknowledge_data_loss = [ data.xenserver_host.supporter.data_items[1].uuid ]
 }
\ No newline at end of file
@@ -0,0 +1,17 @@
+resource "xenserver_secret" "smb_password" {
+  value = "password"
+}
+
+resource "xenserver_sr_smb" "smb_test1" {
+  name_label           = "SMB SR 1"
+  storage_location     = "\\\\server\\share1"
+  username             = "username"
+  password_secret_uuid = xenserver_secret.smb_password.uuid
+}
+
+resource "xenserver_sr_smb" "smb_test2" {
+  name_label           = "SMB SR 2"
+  storage_location     = "\\\\server\\share2"
+  username             = "username"
+  password_secret_uuid = xenserver_secret.smb_password.uuid
+}
@@ -29,3 +29,25 @@ resource "xenserver_sr_smb" "smb_iso_test1" {
     \\server\path
 EOF
 }
+
+resource "xenserver_secret" "smb_password" {
+  value = "password"
+}
+
+resource "xenserver_sr_smb" "smb_secret_test" {
+  name_label           = "SMB storage"
+  storage_location     = "\\\\server\\path"
+  username             = "username"
+  password_secret_uuid = xenserver_secret.smb_password.uuid
+}
+
+# Mount a Windows share in a domain as an ISO library with SMB 3
+resource "xenserver_sr_smb" "smb_domain_test" {
+  name_label       = "SMB ISO library"
+  type             = "iso"
+  storage_location = "\\\\server\\path"
+  domain           = "CORP"
+  username         = "username"
+  password         = "password"
+  cifs_version     = "3.0"
+}
@@ -30,3 +30,11 @@ resource "xenserver_vdi" "vdi" {
   read_only        = true
   type             = "system"
 }
+
+# Spread the VDIs across the NFS SRs, each one is created on the NFS SR with the most free space
+resource "xenserver_vdi" "data_vdi" {
+  count        = 4
+  name_label   = "Data VDI ${count.index}"
+  sr_type      = "nfs"
+  virtual_size = 100 * 1024 * 1024 * 1024
+}
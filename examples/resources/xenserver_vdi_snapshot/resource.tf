@@ -0,0 +1,14 @@
+data "xenserver_sr" "sr" {
+  name_label = "Local storage"
+}
+
+resource "xenserver_vdi" "vdi" {
+  name_label   = "Test VDI"
+  sr_uuid      = data.xenserver_sr.sr.data_items[0].uuid
+  virtual_size = 1 * 1024 * 1024 * 1024
+}
+
+resource "xenserver_vdi_snapshot" "vdi_snapshot" {
+  name_label      = "Test VDI snapshot"
+  source_vdi_uuid = xenserver_vdi.vdi.uuid
+}
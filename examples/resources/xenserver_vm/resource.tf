@@ -55,7 +55,7 @@ resource "xenserver_vm" "windows_vm" {
   ]
 
   other_config = {
-    "tf_created" = "true"
+    "created_by" = "terraform"
   }
 }
 
@@ -173,3 +173,39 @@ output "vm_out" {
     for vm in xenserver_vm.vm : vm.name_label => vm
   }
 }
+
+# Boot a VM from an existing disk, for example, imported from a disk image, without a template.
+# The boot order is "c" when an item of hard_drive is bootable and boot_order is not set.
+resource "xenserver_vm" "imported_vm" {
+  name_label     = "A VM booted from an imported disk"
+  static_mem_max = 2 * 1024 * 1024 * 1024
+  vcpus          = 2
+  hard_drive = [
+    {
+      vdi_uuid = "<existing-vdi-uuid>",
+      bootable = true,
+      mode     = "RW"
+    },
+  ]
+}
+
+# Create a VM from scratch without a template
+resource "xenserver_vm" "scratch_vm" {
+  name_label     = "A scratch VM"
+  static_mem_max = 2 * 1024 * 1024 * 1024
+  vcpus          = 2
+  boot_mode      = "uefi"
+  boot_order     = "dcn"
+  hard_drive = [
+    {
+      vdi_uuid = xenserver_vdi.vdi1.uuid,
+      bootable = true,
+      mode     = "RW"
+    },
+  ]
+  network_interface = [
+    {
+      network_uuid = data.xenserver_network.network.data_items[0].uuid,
+    },
+  ]
+}
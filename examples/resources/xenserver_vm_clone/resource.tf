@@ -0,0 +1,20 @@
+data "xenserver_vm" "vm_data" {
+  name_label = "Test VM"
+}
+
+# Clone a halted VM on the same SR
+resource "xenserver_vm_clone" "clone" {
+  name_label     = "A test VM clone"
+  source_vm_uuid = data.xenserver_vm.vm_data.data_items[0].uuid
+}
+
+# Copy a halted VM with full disks to another SR
+data "xenserver_sr" "sr" {
+  name_label = "Local storage"
+}
+
+resource "xenserver_vm_clone" "copy" {
+  name_label     = "A test VM copy"
+  source_vm_uuid = data.xenserver_vm.vm_data.data_items[0].uuid
+  target_sr_uuid = data.xenserver_sr.sr.data_items[0].uuid
+}
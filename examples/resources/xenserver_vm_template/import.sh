@@ -0,0 +1 @@
+terraform import xenserver_vm_template.template 00000000-0000-0000-0000-000000000000
\ No newline at end of file
@@ -0,0 +1,11 @@
+data "xenserver_vm" "vm_data" {
+  name_label = "Golden image VM"
+}
+
+# Turn the configured halted VM into a versioned template
+resource "xenserver_vm_template" "template" {
+  name_label       = "Golden image"
+  name_description = "Golden image built by the pipeline"
+  source_vm_uuid   = data.xenserver_vm.vm_data.data_items[0].uuid
+  user_version     = 3
+}
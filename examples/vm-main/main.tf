@@ -72,7 +72,7 @@ resource "xenserver_vm" "vm" {
   ]
 
   other_config = {
-    "tf_created" = "true"
+    "created_by" = "terraform"
   }
 }
 
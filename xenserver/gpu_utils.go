@@ -0,0 +1,182 @@
+package xenserver
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"xenapi"
+)
+
+type vgpuTypeDataSourceModel struct {
+	UUID         types.String         `tfsdk:"uuid"`
+	ModelName    types.String         `tfsdk:"model_name"`
+	GPUGroupUUID types.String         `tfsdk:"gpu_group_uuid"`
+	DataItems    []vgpuTypeRecordData `tfsdk:"data_items"`
+}
+
+type vgpuTypeRecordData struct {
+	UUID            types.String `tfsdk:"uuid"`
+	VendorName      types.String `tfsdk:"vendor_name"`
+	ModelName       types.String `tfsdk:"model_name"`
+	FramebufferSize types.Int64  `tfsdk:"framebuffer_size"`
+	MaxHeads        types.Int64  `tfsdk:"max_heads"`
+	MaxResolutionX  types.Int64  `tfsdk:"max_resolution_x"`
+	MaxResolutionY  types.Int64  `tfsdk:"max_resolution_y"`
+	Implementation  types.String `tfsdk:"implementation"`
+	Experimental    types.Bool   `tfsdk:"experimental"`
+	GPUGroupUUIDs   types.List   `tfsdk:"gpu_group_uuids"`
+	MaxCapacity     types.Int64  `tfsdk:"max_capacity"`
+	VGPUCount       types.Int64  `tfsdk:"vgpu_count"`
+}
+
+func vgpuTypeDataSchema() map[string]schema.Attribute {
+	return map[string]schema.Attribute{
+		"uuid": schema.StringAttribute{
+			MarkdownDescription: "The UUID of the vGPU type.",
+			Computed:            true,
+		},
+		"vendor_name": schema.StringAttribute{
+			MarkdownDescription: "The name of the vendor of the vGPU type.",
+			Computed:            true,
+		},
+		"model_name": schema.StringAttribute{
+			MarkdownDescription: "The model name of the vGPU type.",
+			Computed:            true,
+		},
+		"framebuffer_size": schema.Int64Attribute{
+			MarkdownDescription: "The framebuffer size of the vGPU type in bytes.",
+			Computed:            true,
+		},
+		"max_heads": schema.Int64Attribute{
+			MarkdownDescription: "The maximum number of displays supported by the vGPU type.",
+			Computed:            true,
+		},
+		"max_resolution_x": schema.Int64Attribute{
+			MarkdownDescription: "The maximum resolution of the vGPU type in the X axis.",
+			Computed:            true,
+		},
+		"max_resolution_y": schema.Int64Attribute{
+			MarkdownDescription: "The maximum resolution of the vGPU type in the Y axis.",
+			Computed:            true,
+		},
+		"implementation": schema.StringAttribute{
+			MarkdownDescription: "The internal implementation of the vGPU type, for example, `passthrough` or `nvidia`.",
+			Computed:            true,
+		},
+		"experimental": schema.BoolAttribute{
+			MarkdownDescription: "Whether the vGPU type is experimental, that's to say, not supported for production use.",
+			Computed:            true,
+		},
+		"gpu_group_uuids": schema.ListAttribute{
+			MarkdownDescription: "The UUIDs of the GPU groups the vGPU type is enabled on, sorted.",
+			Computed:            true,
+			ElementType:         types.StringType,
+		},
+		"max_capacity": schema.Int64Attribute{
+			MarkdownDescription: "The maximum number of vGPUs of the type the physical GPUs it's enabled on can run at the same time, when they run no vGPU of other types.",
+			Computed:            true,
+		},
+		"vgpu_count": schema.Int64Attribute{
+			MarkdownDescription: "The number of vGPUs of the type currently attached to the running virtual machines." + "<br />" +
+				"The vGPUs of the halted virtual machines are not counted, starting them takes the capacity again.",
+			Computed: true,
+		},
+	}
+}
+
+// getVGPUTypeMaxCapacity returns the number of vGPUs of the type the physical GPUs can run in total,
+// only the physical GPUs the type is enabled on are counted
+func getVGPUTypeMaxCapacity(typeRef xenapi.VGPUTypeRef, pgpuRecords map[xenapi.PGPURef]xenapi.PGPURecord) int64 {
+	var capacity int64
+	for _, pgpuRecord := range pgpuRecords {
+		if slices.Contains(pgpuRecord.EnabledVGPUTypes, typeRef) {
+			capacity += int64(pgpuRecord.SupportedVGPUMaxCapacities[typeRef])
+		}
+	}
+	return capacity
+}
+
+// getVGPUTypeAttachedCount returns the number of vGPUs of the type which are currently attached, the vGPUs of
+// the halted VMs don't use the physical GPUs
+func getVGPUTypeAttachedCount(typeRecord xenapi.VGPUTypeRecord, vgpuRecords map[xenapi.VGPURef]xenapi.VGPURecord) int64 {
+	var count int64
+	for _, vgpuRef := range typeRecord.VGPUs {
+		if vgpuRecords[vgpuRef].CurrentlyAttached {
+			count++
+		}
+	}
+	return count
+}
+
+// getVGPUTypeRecordData returns the vGPU types matching the filters in data, sorted by the vGPU type UUID
+func getVGPUTypeRecordData(ctx context.Context, typeRecords map[xenapi.VGPUTypeRef]xenapi.VGPUTypeRecord, groupRecords map[xenapi.GPUGroupRef]xenapi.GPUGroupRecord, pgpuRecords map[xenapi.PGPURef]xenapi.PGPURecord, vgpuRecords map[xenapi.VGPURef]xenapi.VGPURecord, data vgpuTypeDataSourceModel) ([]vgpuTypeRecordData, error) {
+	items := []vgpuTypeRecordData{}
+	for typeRef, typeRecord := range typeRecords {
+		if !data.UUID.IsNull() && typeRecord.UUID != data.UUID.ValueString() {
+			continue
+		}
+		if !data.ModelName.IsNull() && typeRecord.ModelName != data.ModelName.ValueString() {
+			continue
+		}
+		groupUUIDs := []string{}
+		for _, groupRef := range typeRecord.EnabledOnGPUGroups {
+			groupRecord, ok := groupRecords[groupRef]
+			if !ok {
+				return nil, errors.New("unable to find the GPU group of vGPU type " + typeRecord.UUID)
+			}
+			groupUUIDs = append(groupUUIDs, groupRecord.UUID)
+		}
+		if !data.GPUGroupUUID.IsNull() && !slices.Contains(groupUUIDs, data.GPUGroupUUID.ValueString()) {
+			continue
+		}
+		sort.Strings(groupUUIDs)
+		groupList, diags := types.ListValueFrom(ctx, types.StringType, groupUUIDs)
+		if diags.HasError() {
+			return nil, errors.New("unable to read vGPU type GPU groups")
+		}
+		items = append(items, vgpuTypeRecordData{
+			UUID:            types.StringValue(typeRecord.UUID),
+			VendorName:      types.StringValue(typeRecord.VendorName),
+			ModelName:       types.StringValue(typeRecord.ModelName),
+			FramebufferSize: types.Int64Value(int64(typeRecord.FramebufferSize)),
+			MaxHeads:        types.Int64Value(int64(typeRecord.MaxHeads)),
+			MaxResolutionX:  types.Int64Value(int64(typeRecord.MaxResolutionX)),
+			MaxResolutionY:  types.Int64Value(int64(typeRecord.MaxResolutionY)),
+			Implementation:  types.StringValue(string(typeRecord.Implementation)),
+			Experimental:    types.BoolValue(typeRecord.Experimental),
+			GPUGroupUUIDs:   groupList,
+			MaxCapacity:     types.Int64Value(getVGPUTypeMaxCapacity(typeRef, pgpuRecords)),
+			VGPUCount:       types.Int64Value(getVGPUTypeAttachedCount(typeRecord, vgpuRecords)),
+		})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].UUID.ValueString() < items[j].UUID.ValueString()
+	})
+	return items, nil
+}
+
+func updateVGPUTypeDataSourceModel(ctx context.Context, session *xenapi.Session, data *vgpuTypeDataSourceModel) error {
+	typeRecords, err := xenapi.VGPUType.GetAllRecords(session)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	groupRecords, err := xenapi.GPUGroup.GetAllRecords(session)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	pgpuRecords, err := xenapi.PGPU.GetAllRecords(session)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	vgpuRecords, err := xenapi.VGPU.GetAllRecords(session)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	data.DataItems, err = getVGPUTypeRecordData(ctx, typeRecords, groupRecords, pgpuRecords, vgpuRecords, *data)
+	return err
+}
@@ -24,7 +24,8 @@ func NewHostDataSource() datasource.DataSource {
 
 // hostDataSource is the data source implementation.
 type hostDataSource struct {
-	session *xenapi.Session
+	session          *xenapi.Session
+	operationLimiter operationLimiter
 }
 
 // Metadata returns the data source type name.
@@ -76,6 +77,7 @@ func (d *hostDataSource) Configure(_ context.Context, req datasource.ConfigureRe
 		return
 	}
 	d.session = providerData.session
+	d.operationLimiter = providerData.operationLimiter
 }
 
 // Read refreshes the Terraform state with the latest data.
@@ -88,7 +90,16 @@ func (d *hostDataSource) Read(ctx context.Context, req datasource.ReadRequest, r
 		return
 	}
 
-	hostRecords, err := xenapi.Host.GetAllRecords(d.session)
+	ctx, done, err := d.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	hostRecords, err := withSessionRetry(d.session, func() (map[xenapi.HostRef]xenapi.HostRecord, error) {
+		return xenapi.Host.GetAllRecords(d.session)
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to read Host records",
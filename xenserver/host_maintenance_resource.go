@@ -0,0 +1,266 @@
+package xenserver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"xenapi"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var (
+	_ resource.Resource                = &hostMaintenanceResource{}
+	_ resource.ResourceWithConfigure   = &hostMaintenanceResource{}
+	_ resource.ResourceWithImportState = &hostMaintenanceResource{}
+)
+
+func NewHostMaintenanceResource() resource.Resource {
+	return &hostMaintenanceResource{}
+}
+
+// hostMaintenanceResource defines the resource implementation.
+type hostMaintenanceResource struct {
+	session          *xenapi.Session
+	operationLimiter operationLimiter
+}
+
+func (r *hostMaintenanceResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_host_maintenance"
+}
+
+func (r *hostMaintenanceResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Host maintenance resource which is used for rolling updates of the pool. When the resource is created, the host is disabled and its VMs are migrated to the other hosts in the pool. When the resource is destroyed, the host is enabled again." +
+			"\n\n-> **Note:** The migrated VMs are not moved back to the host when the resource is destroyed.",
+		Attributes: hostMaintenanceSchema(),
+	}
+}
+
+// Set the parameter of the resource, pass value from provider
+func (r *hostMaintenanceResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+	providerData, ok := req.ProviderData.(*xsProvider)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *xenserver.xsProvider, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+	r.session = providerData.session
+	r.operationLimiter = providerData.operationLimiter
+}
+
+func (r *hostMaintenanceResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data hostMaintenanceResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	hostRef, err := withSessionRetry(r.session, func() (xenapi.HostRef, error) {
+		return xenapi.Host.GetByUUID(r.session, data.HostUUID.ValueString())
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get host ref",
+			err.Error(),
+		)
+		return
+	}
+	vmsBefore, err := getHostGuestVMUUIDs(r.session, hostRef)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get host resident VMs",
+			err.Error(),
+		)
+		return
+	}
+
+	tflog.Debug(ctx, "Disabling host...")
+	err = xenapi.Host.Disable(r.session, hostRef)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to disable host",
+			err.Error(),
+		)
+		return
+	}
+
+	// keep the host disabled if the evacuation fails, the VMs which are left can be handled manually
+	tflog.Debug(ctx, "Evacuating host...")
+	evacuated := true
+	err = xenapi.Host.Evacuate(r.session, hostRef, "OpaqueRef:NULL", 0)
+	if err != nil {
+		evacuated = false
+		detail := "The host is disabled, but not all the VMs could be migrated off it.\n\n" + err.Error()
+		blockers, blockersErr := getVMsPreventEvacuation(r.session, hostRef)
+		if blockersErr == nil && blockers != "" {
+			detail += "\n\nVMs which prevent the evacuation:\n" + blockers
+		}
+		resp.Diagnostics.AddWarning("Unable to evacuate host", detail)
+	}
+
+	vmsAfter, err := getHostGuestVMUUIDs(r.session, hostRef)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get host resident VMs",
+			err.Error(),
+		)
+		return
+	}
+	err = updateHostMaintenanceResourceModelComputed(ctx, evacuated, vmsBefore, vmsAfter, &data)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to update the computed fields of HostMaintenanceResourceModel",
+			err.Error(),
+		)
+		return
+	}
+	tflog.Debug(ctx, "Host is in maintenance mode")
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *hostMaintenanceResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data hostMaintenanceResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	hostRef, err := withSessionRetry(r.session, func() (xenapi.HostRef, error) {
+		return xenapi.Host.GetByUUID(r.session, data.HostUUID.ValueString())
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get host ref",
+			err.Error(),
+		)
+		return
+	}
+	enabled, err := xenapi.Host.GetEnabled(r.session, hostRef)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get host enabled",
+			err.Error(),
+		)
+		return
+	}
+	// the host has been enabled outside of terraform, the maintenance is over
+	if enabled {
+		tflog.Debug(ctx, "Host is enabled, remove the host maintenance from state")
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	// import, the VMs migrated before are unknown
+	if data.Evacuated.IsNull() {
+		vmUUIDs, err := getHostGuestVMUUIDs(r.session, hostRef)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Unable to get host resident VMs",
+				err.Error(),
+			)
+			return
+		}
+		err = updateHostMaintenanceResourceModelComputed(ctx, len(vmUUIDs) == 0, []string{}, vmUUIDs, &data)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Unable to update the computed fields of HostMaintenanceResourceModel",
+				err.Error(),
+			)
+			return
+		}
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *hostMaintenanceResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state hostMaintenanceResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Checking if configuration changes are allowed
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	err := hostMaintenanceResourceModelUpdateCheck(plan, state)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error update xenserver_host_maintenance configuration",
+			err.Error(),
+		)
+		return
+	}
+
+	plan.Evacuated = state.Evacuated
+	plan.MigratedVMs = state.MigratedVMs
+	plan.ID = state.ID
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *hostMaintenanceResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data hostMaintenanceResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	hostRef, err := withSessionRetry(r.session, func() (xenapi.HostRef, error) {
+		return xenapi.Host.GetByUUID(r.session, data.HostUUID.ValueString())
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get host ref",
+			err.Error(),
+		)
+		return
+	}
+	tflog.Debug(ctx, "Enabling host...")
+	err = xenapi.Host.Enable(r.session, hostRef)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to enable host",
+			err.Error(),
+		)
+		return
+	}
+}
+
+func (r *hostMaintenanceResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("host_uuid"), req, resp)
+}
@@ -0,0 +1,50 @@
+package xenserver
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func testAccHostMaintenanceResourceConfig(host_index string) string {
+	return `
+data "xenserver_host" "host_data" {
+	is_coordinator = false
+}
+
+resource "xenserver_host_maintenance" "test_maintenance" {
+	host_uuid = data.xenserver_host.host_data.data_items[` + host_index + `].uuid
+}
+`
+}
+
+func TestAccHostMaintenanceResource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Create and Read testing
+			{
+				Config: providerConfig + testAccHostMaintenanceResourceConfig("0"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_host_maintenance.test_maintenance", "evacuated", "true"),
+					// Verify dynamic values have any value set in the state.
+					resource.TestCheckResourceAttrSet("xenserver_host_maintenance.test_maintenance", "migrated_vms.#"),
+					resource.TestCheckResourceAttrPair("xenserver_host_maintenance.test_maintenance", "id", "xenserver_host_maintenance.test_maintenance", "host_uuid"),
+				),
+			},
+			// ImportState testing
+			{
+				ResourceName:            "xenserver_host_maintenance.test_maintenance",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"migrated_vms"},
+			},
+			{
+				Config:      providerConfig + testAccHostMaintenanceResourceConfig("1"),
+				ExpectError: regexp.MustCompile(`"host_uuid" doesn't expected to be updated`),
+			},
+			// Delete testing automatically occurs in TestCase
+		},
+	})
+}
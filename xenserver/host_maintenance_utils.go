@@ -0,0 +1,115 @@
+package xenserver
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"xenapi"
+)
+
+type hostMaintenanceResourceModel struct {
+	HostUUID    types.String `tfsdk:"host_uuid"`
+	Evacuated   types.Bool   `tfsdk:"evacuated"`
+	MigratedVMs types.List   `tfsdk:"migrated_vms"`
+	ID          types.String `tfsdk:"id"`
+}
+
+func hostMaintenanceSchema() map[string]schema.Attribute {
+	return map[string]schema.Attribute{
+		"host_uuid": schema.StringAttribute{
+			MarkdownDescription: "The UUID of the host to put into maintenance mode." +
+				"\n\n-> **Note:** `host_uuid` is not allowed to be updated.",
+			Required: true,
+		},
+		"evacuated": schema.BoolAttribute{
+			MarkdownDescription: "True if all the VMs were migrated off the host. When the evacuation fails, the host is still disabled and a warning lists the VMs which prevent the evacuation.",
+			Computed:            true,
+		},
+		"migrated_vms": schema.ListAttribute{
+			MarkdownDescription: "The list of VMs(UUID) migrated off the host by the evacuation.",
+			Computed:            true,
+			ElementType:         types.StringType,
+		},
+		"id": schema.StringAttribute{
+			MarkdownDescription: "The test ID of the host maintenance.",
+			Computed:            true,
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.UseStateForUnknown(),
+			},
+		},
+	}
+}
+
+// getHostGuestVMUUIDs returns the UUIDs of the VMs resident on the host, the control domain is excluded
+func getHostGuestVMUUIDs(session *xenapi.Session, hostRef xenapi.HostRef) ([]string, error) {
+	hostRecord, err := xenapi.Host.GetRecord(session, hostRef)
+	if err != nil {
+		return nil, errors.New(err.Error())
+	}
+	vmUUIDs := []string{}
+	for _, vmRef := range hostRecord.ResidentVMs {
+		if vmRef == hostRecord.ControlDomain {
+			continue
+		}
+		vmUUID, err := xenapi.VM.GetUUID(session, vmRef)
+		if err != nil {
+			return nil, errors.New(err.Error())
+		}
+		vmUUIDs = append(vmUUIDs, vmUUID)
+	}
+	sort.Strings(vmUUIDs)
+	return vmUUIDs, nil
+}
+
+// getVMsPreventEvacuation returns a readable list of the VMs which can't be migrated off the host and the reasons
+func getVMsPreventEvacuation(session *xenapi.Session, hostRef xenapi.HostRef) (string, error) {
+	vmErrors, err := xenapi.Host.GetVmsWhichPreventEvacuation(session, hostRef)
+	if err != nil {
+		return "", errors.New(err.Error())
+	}
+	var lines []string
+	for vmRef, reason := range vmErrors {
+		vmUUID, err := xenapi.VM.GetUUID(session, vmRef)
+		if err != nil {
+			return "", errors.New(err.Error())
+		}
+		lines = append(lines, vmUUID+": "+strings.Join(reason, " "))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n"), nil
+}
+
+func updateHostMaintenanceResourceModelComputed(ctx context.Context, evacuated bool, vmsBefore []string, vmsAfter []string, data *hostMaintenanceResourceModel) error {
+	migratedVMs := []string{}
+	for _, vmUUID := range vmsBefore {
+		if !slices.Contains(vmsAfter, vmUUID) {
+			migratedVMs = append(migratedVMs, vmUUID)
+		}
+	}
+
+	data.ID = data.HostUUID
+	data.Evacuated = types.BoolValue(evacuated)
+	var diags diag.Diagnostics
+	data.MigratedVMs, diags = types.ListValueFrom(ctx, types.StringType, migratedVMs)
+	if diags.HasError() {
+		return errors.New("unable to read the migrated VMs")
+	}
+
+	return nil
+}
+
+func hostMaintenanceResourceModelUpdateCheck(data hostMaintenanceResourceModel, dataState hostMaintenanceResourceModel) error {
+	if data.HostUUID != dataState.HostUUID {
+		return errors.New(`"host_uuid" doesn't expected to be updated`)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package xenserver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+
+	"xenapi"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &hostMetricsDataSource{}
+	_ datasource.DataSourceWithConfigure = &hostMetricsDataSource{}
+)
+
+// NewHostMetricsDataSource is a helper function to simplify the provider implementation.
+func NewHostMetricsDataSource() datasource.DataSource {
+	return &hostMetricsDataSource{}
+}
+
+// hostMetricsDataSource is the data source implementation.
+type hostMetricsDataSource struct {
+	session          *xenapi.Session
+	operationLimiter operationLimiter
+}
+
+// Metadata returns the data source type name.
+func (d *hostMetricsDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_host_metrics"
+}
+
+func (d *hostMetricsDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Provides the memory and live status of the hosts in the pool. Combined with the memory of a virtual machine, it helps to choose the host to place it on." +
+			"\n\n-> **Note:** The free memory is the one when the data source is read, it doesn't consider the virtual machines created or started later in the same run.",
+		Attributes: map[string]schema.Attribute{
+			"uuid": schema.StringAttribute{
+				MarkdownDescription: "The UUID of the host, if not set, show the metrics of all hosts.",
+				Optional:            true,
+			},
+			"data_items": schema.ListNestedAttribute{
+				MarkdownDescription: "The metrics of the hosts, sorted by the host UUID.",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: hostMetricsDataSchema(),
+				},
+			},
+		},
+	}
+}
+
+func (d *hostMetricsDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	providerData, ok := req.ProviderData.(*xsProvider)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *xenserver.xsProvider, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+	d.session = providerData.session
+	d.operationLimiter = providerData.operationLimiter
+}
+
+// Read refreshes the Terraform state with the latest data.
+func (d *hostMetricsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data hostMetricsDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := d.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	err = retryOnSessionInvalid(d.session, func() error {
+		return updateHostMetricsDataSourceModel(d.session, &data)
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to read Host metrics",
+			err.Error(),
+		)
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
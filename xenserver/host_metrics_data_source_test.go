@@ -0,0 +1,86 @@
+package xenserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+
+	"xenapi"
+)
+
+func testAccHostMetricsDataSourceConfig() string {
+	return `
+data "xenserver_host" "coordinator" {
+  is_coordinator = true
+}
+
+data "xenserver_host_metrics" "all" {}
+
+data "xenserver_host_metrics" "coordinator" {
+  uuid = data.xenserver_host.coordinator.data_items[0].uuid
+}
+`
+}
+
+func TestAccHostMetricsDataSource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + testAccHostMetricsDataSourceConfig(),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("data.xenserver_host_metrics.all", "data_items.#"),
+					resource.TestCheckResourceAttr("data.xenserver_host_metrics.coordinator", "data_items.#", "1"),
+					resource.TestCheckResourceAttrPair("data.xenserver_host_metrics.coordinator", "data_items.0.uuid", "data.xenserver_host.coordinator", "data_items.0.uuid"),
+					resource.TestCheckResourceAttr("data.xenserver_host_metrics.coordinator", "data_items.0.live", "true"),
+					resource.TestCheckResourceAttrSet("data.xenserver_host_metrics.coordinator", "data_items.0.memory_total"),
+					resource.TestCheckResourceAttrSet("data.xenserver_host_metrics.coordinator", "data_items.0.memory_free"),
+				),
+			},
+		},
+	})
+}
+
+func TestGetHostMetricsRecordData(t *testing.T) {
+	lastUpdated := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+	hostRecords := map[xenapi.HostRef]xenapi.HostRecord{
+		"host-b": {UUID: "uuid-b", NameLabel: "host b", Metrics: "metrics-b"},
+		"host-a": {UUID: "uuid-a", NameLabel: "host a", Metrics: "metrics-a"},
+	}
+	metricsRecords := map[xenapi.HostMetricsRef]xenapi.HostMetricsRecord{
+		"metrics-a": {MemoryTotal: 4096, MemoryFree: 1024, Live: true, LastUpdated: lastUpdated},
+		"metrics-b": {MemoryTotal: 8192, MemoryFree: 2048, Live: false, LastUpdated: lastUpdated},
+	}
+	testCases := []struct {
+		uuid          string
+		expectedUUIDs []string
+		expectedFree  []int64
+	}{
+		{uuid: "", expectedUUIDs: []string{"uuid-a", "uuid-b"}, expectedFree: []int64{1024, 2048}},
+		{uuid: "uuid-b", expectedUUIDs: []string{"uuid-b"}, expectedFree: []int64{2048}},
+		{uuid: "uuid-unknown", expectedUUIDs: []string{}, expectedFree: []int64{}},
+	}
+	for _, tc := range testCases {
+		items, err := getHostMetricsRecordData(hostRecords, metricsRecords, tc.uuid)
+		if err != nil {
+			t.Fatalf("getHostMetricsRecordData(%q) returned error: %v", tc.uuid, err)
+		}
+		if len(items) != len(tc.expectedUUIDs) {
+			t.Fatalf("getHostMetricsRecordData(%q) returned %d items, expected %d", tc.uuid, len(items), len(tc.expectedUUIDs))
+		}
+		for i, item := range items {
+			if item.UUID.ValueString() != tc.expectedUUIDs[i] || item.MemoryFree.ValueInt64() != tc.expectedFree[i] {
+				t.Errorf("getHostMetricsRecordData(%q) item %d = %s with %d free, expected %s with %d free", tc.uuid, i, item.UUID, item.MemoryFree.ValueInt64(), tc.expectedUUIDs[i], tc.expectedFree[i])
+			}
+			if item.LastUpdated.ValueString() != "2024-05-01T10:30:00Z" {
+				t.Errorf("getHostMetricsRecordData(%q) item %d last updated = %s", tc.uuid, i, item.LastUpdated)
+			}
+		}
+	}
+
+	_, err := getHostMetricsRecordData(map[xenapi.HostRef]xenapi.HostRecord{"host-c": {UUID: "uuid-c", Metrics: "metrics-c"}}, metricsRecords, "")
+	if err == nil {
+		t.Errorf("getHostMetricsRecordData() with missing metrics returned no error")
+	}
+}
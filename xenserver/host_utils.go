@@ -3,6 +3,7 @@ package xenserver
 import (
 	"context"
 	"errors"
+	"sort"
 
 	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
@@ -85,3 +86,86 @@ func updateHostRecordData(ctx context.Context, session *xenapi.Session, record x
 
 	return nil
 }
+
+type hostMetricsDataSourceModel struct {
+	UUID      types.String            `tfsdk:"uuid"`
+	DataItems []hostMetricsRecordData `tfsdk:"data_items"`
+}
+
+type hostMetricsRecordData struct {
+	UUID        types.String `tfsdk:"uuid"`
+	NameLabel   types.String `tfsdk:"name_label"`
+	MemoryTotal types.Int64  `tfsdk:"memory_total"`
+	MemoryFree  types.Int64  `tfsdk:"memory_free"`
+	Live        types.Bool   `tfsdk:"live"`
+	LastUpdated types.String `tfsdk:"last_updated"`
+}
+
+func hostMetricsDataSchema() map[string]schema.Attribute {
+	return map[string]schema.Attribute{
+		"uuid": schema.StringAttribute{
+			MarkdownDescription: "The UUID of the host.",
+			Computed:            true,
+		},
+		"name_label": schema.StringAttribute{
+			MarkdownDescription: "The name of the host.",
+			Computed:            true,
+		},
+		"memory_total": schema.Int64Attribute{
+			MarkdownDescription: "The total memory of the host in bytes.",
+			Computed:            true,
+		},
+		"memory_free": schema.Int64Attribute{
+			MarkdownDescription: "The free memory of the host in bytes.",
+			Computed:            true,
+		},
+		"live": schema.BoolAttribute{
+			MarkdownDescription: "Whether the host is live, that's to say, it's heartbeating to the pool coordinator.",
+			Computed:            true,
+		},
+		"last_updated": schema.StringAttribute{
+			MarkdownDescription: "The time the metrics were last updated, in RFC 3339 format.",
+			Computed:            true,
+		},
+	}
+}
+
+// getHostMetricsRecordData returns the metrics of the hosts, filtered by the host UUID if it's not empty,
+// and sorted by the host UUID
+func getHostMetricsRecordData(hostRecords map[xenapi.HostRef]xenapi.HostRecord, metricsRecords map[xenapi.HostMetricsRef]xenapi.HostMetricsRecord, uuid string) ([]hostMetricsRecordData, error) {
+	items := []hostMetricsRecordData{}
+	for _, hostRecord := range hostRecords {
+		if uuid != "" && hostRecord.UUID != uuid {
+			continue
+		}
+		metrics, ok := metricsRecords[hostRecord.Metrics]
+		if !ok {
+			return nil, errors.New("unable to find the metrics of host " + hostRecord.UUID)
+		}
+		items = append(items, hostMetricsRecordData{
+			UUID:        types.StringValue(hostRecord.UUID),
+			NameLabel:   types.StringValue(hostRecord.NameLabel),
+			MemoryTotal: types.Int64Value(int64(metrics.MemoryTotal)),
+			MemoryFree:  types.Int64Value(int64(metrics.MemoryFree)),
+			Live:        types.BoolValue(metrics.Live),
+			LastUpdated: types.StringValue(formatXapiTime(metrics.LastUpdated)),
+		})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].UUID.ValueString() < items[j].UUID.ValueString()
+	})
+	return items, nil
+}
+
+func updateHostMetricsDataSourceModel(session *xenapi.Session, data *hostMetricsDataSourceModel) error {
+	hostRecords, err := xenapi.Host.GetAllRecords(session)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	metricsRecords, err := xenapi.HostMetrics.GetAllRecords(session)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	data.DataItems, err = getHostMetricsRecordData(hostRecords, metricsRecords, data.UUID.ValueString())
+	return err
+}
@@ -0,0 +1,106 @@
+package xenserver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+
+	"xenapi"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &isoDataSource{}
+	_ datasource.DataSourceWithConfigure = &isoDataSource{}
+)
+
+// NewISODataSource is a helper function to simplify the provider implementation.
+func NewISODataSource() datasource.DataSource {
+	return &isoDataSource{}
+}
+
+// isoDataSource is the data source implementation.
+type isoDataSource struct {
+	session          *xenapi.Session
+	operationLimiter operationLimiter
+}
+
+// Metadata returns the data source type name.
+func (d *isoDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_iso"
+}
+
+func (d *isoDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Provides the VDI of an ISO, which is resolved by its name and optionally its storage repository. The VDI UUID can be used as `cdrom_vdi_uuid` of `xenserver_vm`." +
+			"\n\n-> **Note:** It errors with the UUIDs of the matched VDIs when the name is not unique, set `sr_uuid` to tell them apart.",
+		Attributes: map[string]schema.Attribute{
+			"name_label": schema.StringAttribute{
+				MarkdownDescription: "The name of the ISO VDI.",
+				Required:            true,
+			},
+			"sr_uuid": schema.StringAttribute{
+				MarkdownDescription: "The UUID of the storage repository to look up the ISO in, default to be all the storage repositories.",
+				Optional:            true,
+			},
+			"uuid": schema.StringAttribute{
+				MarkdownDescription: "The UUID of the ISO VDI.",
+				Computed:            true,
+			},
+			"id": schema.StringAttribute{
+				MarkdownDescription: "The test ID of the ISO VDI.",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (d *isoDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	providerData, ok := req.ProviderData.(*xsProvider)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *xenserver.xsProvider, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+	d.session = providerData.session
+	d.operationLimiter = providerData.operationLimiter
+}
+
+// Read refreshes the Terraform state with the latest data.
+func (d *isoDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data isoDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := d.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	err = retryOnSessionInvalid(d.session, func() error {
+		return updateISODataSourceModel(d.session, &data)
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to read ISO VDI",
+			err.Error(),
+		)
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
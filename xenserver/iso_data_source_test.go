@@ -0,0 +1,57 @@
+package xenserver
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+
+	"xenapi"
+)
+
+func testAccISODataSourceConfig(nameLabel string) string {
+	return `
+data "xenserver_iso" "test_iso" {
+  name_label = "` + nameLabel + `"
+}
+`
+}
+
+func TestAccISODataSource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      providerConfig + testAccISODataSourceConfig("terraform-test-missing.iso"),
+				ExpectError: regexp.MustCompile(`no VDI found with name: terraform-test-missing.iso`),
+			},
+		},
+	})
+}
+
+func TestMatchISOVDIs(t *testing.T) {
+	vdiRecords := map[xenapi.VDIRef]xenapi.VDIRecord{
+		"vdi1": {UUID: "uuid-1", NameLabel: "debian.iso", SR: "sr1"},
+		"vdi2": {UUID: "uuid-2", NameLabel: "debian.iso", SR: "sr2"},
+		"vdi3": {UUID: "uuid-3", NameLabel: "ubuntu.iso", SR: "sr1"},
+		"vdi0": {UUID: "uuid-0", NameLabel: "debian.iso", SR: "sr2"},
+	}
+	testCases := []struct {
+		isoName  string
+		srRef    xenapi.SRRef
+		expected []string
+	}{
+		{isoName: "debian.iso", srRef: "", expected: []string{"uuid-0", "uuid-1", "uuid-2"}},
+		{isoName: "debian.iso", srRef: "sr1", expected: []string{"uuid-1"}},
+		{isoName: "debian.iso", srRef: "sr2", expected: []string{"uuid-0", "uuid-2"}},
+		{isoName: "ubuntu.iso", srRef: "sr2", expected: []string{}},
+		{isoName: "missing.iso", srRef: "", expected: []string{}},
+	}
+	for _, tc := range testCases {
+		vdiUUIDs := matchISOVDIs(vdiRecords, tc.isoName, tc.srRef)
+		if !slices.Equal(vdiUUIDs, tc.expected) {
+			t.Errorf("matchISOVDIs(%q, %q) = %v, expected %v", tc.isoName, tc.srRef, vdiUUIDs, tc.expected)
+		}
+	}
+}
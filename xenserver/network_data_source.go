@@ -25,7 +25,8 @@ func NewNetworkDataSource() datasource.DataSource {
 
 // networkDataSource is the data source implementation.
 type networkDataSource struct {
-	session *xenapi.Session
+	session          *xenapi.Session
+	operationLimiter operationLimiter
 }
 
 // Metadata returns the data source type name.
@@ -145,6 +146,7 @@ func (d *networkDataSource) Configure(_ context.Context, req datasource.Configur
 		return
 	}
 	d.session = providerData.session
+	d.operationLimiter = providerData.operationLimiter
 }
 
 func (d *networkDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
@@ -154,7 +156,16 @@ func (d *networkDataSource) Read(ctx context.Context, req datasource.ReadRequest
 		return
 	}
 
-	networkRecords, err := xenapi.Network.GetAllRecords(d.session)
+	ctx, done, err := d.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	networkRecords, err := withSessionRetry(d.session, func() (map[xenapi.NetworkRef]xenapi.NetworkRecord, error) {
+		return xenapi.Network.GetAllRecords(d.session)
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to get network records",
@@ -5,10 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"slices"
+	"strconv"
 	"strings"
 
+	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 
 	"xenapi"
 )
@@ -75,6 +79,7 @@ func updateNetworkRecordData(ctx context.Context, record xenapi.NetworkRecord, d
 		return errors.New("unable to read network tags")
 	}
 	data.DefaultLockingMode = types.StringValue(string(record.DefaultLockingMode))
+	data.Bridge = types.StringValue(record.Bridge)
 	data.AssignedIps, diags = types.MapValueFrom(ctx, types.StringType, record.AssignedIps)
 	if diags.HasError() {
 		return errors.New("unable to read network assigned_ips")
@@ -88,29 +93,40 @@ func updateNetworkRecordData(ctx context.Context, record xenapi.NetworkRecord, d
 }
 
 type vlanResourceModel struct {
-	NameLabel       types.String `tfsdk:"name_label"`
-	NameDescription types.String `tfsdk:"name_description"`
-	MTU             types.Int32  `tfsdk:"mtu"`
-	Managed         types.Bool   `tfsdk:"managed"`
-	OtherConfig     types.Map    `tfsdk:"other_config"`
-	Tag             types.Int32  `tfsdk:"vlan_tag"`
-	NIC             types.String `tfsdk:"nic"`
-	UUID            types.String `tfsdk:"uuid"`
-	ID              types.String `tfsdk:"id"`
+	NameLabel          types.String `tfsdk:"name_label"`
+	NameDescription    types.String `tfsdk:"name_description"`
+	MTU                types.Int32  `tfsdk:"mtu"`
+	Managed            types.Bool   `tfsdk:"managed"`
+	OtherConfig        types.Map    `tfsdk:"other_config"`
+	Purpose            types.Set    `tfsdk:"purpose"`
+	DefaultLockingMode types.String `tfsdk:"default_locking_mode"`
+	Bridge             types.String `tfsdk:"bridge"`
+	Tag                types.Int32  `tfsdk:"vlan_tag"`
+	NIC                types.String `tfsdk:"nic"`
+	UUID               types.String `tfsdk:"uuid"`
+	ID                 types.String `tfsdk:"id"`
 }
 
 type vlanCreateParams struct {
 	PifRef     xenapi.PIFRef
+	PifRefs    []xenapi.PIFRef
 	NetworkRef xenapi.NetworkRef
 	Tag        int
 }
 
-func getNetworkCreateParams(ctx context.Context, data vlanResourceModel) (xenapi.NetworkRecord, error) {
+func getNetworkCreateParams(ctx context.Context, session *xenapi.Session, data vlanResourceModel) (xenapi.NetworkRecord, error) {
 	var record xenapi.NetworkRecord
 	record.NameLabel = data.NameLabel.ValueString()
 	record.NameDescription = data.NameDescription.ValueString()
 	record.MTU = int(data.MTU.ValueInt32())
 	record.Managed = data.Managed.ValueBool()
+	if !data.Bridge.IsUnknown() && !data.Bridge.IsNull() {
+		err := checkBridgeNotInUse(session, data.Bridge.ValueString())
+		if err != nil {
+			return record, err
+		}
+		record.Bridge = data.Bridge.ValueString()
+	}
 	diags := data.OtherConfig.ElementsAs(ctx, &record.OtherConfig, false)
 	if diags.HasError() {
 		return record, errors.New("unable to access vlan other config")
@@ -119,33 +135,39 @@ func getNetworkCreateParams(ctx context.Context, data vlanResourceModel) (xenapi
 	return record, nil
 }
 
-func getBondNICDevice(session *xenapi.Session, nic string) (string, error) {
-	// nic eg. "Bond 0+1+2" return eg. "bond0"
-	// slavesDevices eg. ["0", "1", "2"]
-	slavesDevices := strings.Split(strings.Split(nic, " ")[1], "+")
-	bondRecords, err := xenapi.Bond.GetAllRecords(session)
+func checkBridgeNotInUse(session *xenapi.Session, bridge string) error {
+	networkRecords, err := xenapi.Network.GetAllRecords(session)
 	if err != nil {
-		return "", errors.New(err.Error())
+		return errors.New(err.Error())
 	}
+	for _, networkRecord := range networkRecords {
+		if networkRecord.Bridge == bridge {
+			return errors.New("bridge \"" + bridge + "\" is already used by network " + networkRecord.UUID)
+		}
+	}
+	return nil
+}
+
+// getBondMasterPIFRefs returns the bond master PIF on every host whose bond slaves match the NIC, e.g. "Bond 0+1".
+// The bond device name is not used to match, as it may differ between the hosts in a pool.
+func getBondMasterPIFRefs(nic string, bondRecords map[xenapi.BondRef]xenapi.BondRecord, pifRecords map[xenapi.PIFRef]xenapi.PIFRecord) []xenapi.PIFRef {
+	var pifRefs []xenapi.PIFRef
 	for _, bondRecord := range bondRecords {
-		devices := []string{}
+		var slaveDevices []string
 		for _, slave := range bondRecord.Slaves {
-			pifRecord, err := xenapi.PIF.GetRecord(session, slave)
-			if err != nil {
-				return "", errors.New(err.Error())
+			pifRecord, ok := pifRecords[slave]
+			if !ok {
+				continue
 			}
-			devices = append(devices, strings.Split(pifRecord.Device, "eth")[1])
+			slaveDevices = append(slaveDevices, pifRecord.Device)
 		}
-		slices.Sort(devices)
-		if slices.Equal(slavesDevices, devices) {
-			record, err := xenapi.PIF.GetRecord(session, bondRecord.Master)
-			if err != nil {
-				return "", errors.New(err.Error())
-			}
-			return record.Device, nil
+		if getNICNameForBondDevices(slaveDevices) == nic {
+			pifRefs = append(pifRefs, bondRecord.Master)
 		}
 	}
-	return "", fmt.Errorf("unable to find device for %s", nic)
+	slices.Sort(pifRefs)
+
+	return pifRefs
 }
 
 func getPifRefsForNIC(session *xenapi.Session, nic string) ([]xenapi.PIFRef, error) {
@@ -155,30 +177,30 @@ func getPifRefsForNIC(session *xenapi.Session, nic string) ([]xenapi.PIFRef, err
 	if err != nil {
 		return pifRefs, errors.New(err.Error())
 	}
-	device := "eth" + strings.Split(nic, " ")[1]
 	if strings.HasPrefix(nic, "Bond") {
-		device, err = getBondNICDevice(session, nic)
+		bondRecords, err := xenapi.Bond.GetAllRecords(session)
 		if err != nil {
-			return pifRefs, err
+			return pifRefs, errors.New(err.Error())
 		}
+		return getBondMasterPIFRefs(nic, bondRecords, pifRecords), nil
 	}
-	uuids := []string{}
-	for _, pifRecord := range pifRecords {
+
+	return getDevicePIFRefs(nic, pifRecords), nil
+}
+
+// getDevicePIFRefs returns the PIF on every host which matches the non-bond NIC, e.g. "NIC 0" or "NIC-SR-IOV 0".
+func getDevicePIFRefs(nic string, pifRecords map[xenapi.PIFRef]xenapi.PIFRecord) []xenapi.PIFRef {
+	var pifRefs []xenapi.PIFRef
+	device := "eth" + strings.Split(nic, " ")[1]
+	for pifRef, pifRecord := range pifRecords {
 		if pifRecord.Device == device && ((strings.HasPrefix(nic, "NIC-SR-IOV") && !pifRecord.Physical && len(pifRecord.SriovLogicalPIFOf) > 0) ||
-			(strings.HasPrefix(nic, "NIC") && pifRecord.Physical && string(pifRecord.BondSlaveOf) == "OpaqueRef:NULL") ||
-			(strings.HasPrefix(nic, "Bond") && !pifRecord.Physical && len(pifRecord.BondMasterOf) > 0)) {
-			uuids = append(uuids, pifRecord.UUID)
+			(strings.HasPrefix(nic, "NIC ") && pifRecord.Physical && string(pifRecord.BondSlaveOf) == "OpaqueRef:NULL")) {
+			pifRefs = append(pifRefs, pifRef)
 		}
 	}
-	for _, uuid := range uuids {
-		ref, err := xenapi.PIF.GetByUUID(session, uuid)
-		if err != nil {
-			return pifRefs, errors.New(err.Error())
-		}
-		pifRefs = append(pifRefs, ref)
-	}
+	slices.Sort(pifRefs)
 
-	return pifRefs, nil
+	return pifRefs
 }
 
 func getVlanCreateParams(session *xenapi.Session, data vlanResourceModel, networkRef xenapi.NetworkRef) (vlanCreateParams, error) {
@@ -190,13 +212,84 @@ func getVlanCreateParams(session *xenapi.Session, data vlanResourceModel, networ
 	if len(pifRefs) == 0 {
 		return params, errors.New("unable to find PIF for NIC")
 	}
+	// the VLAN can't be plugged on the hosts without the NIC
+	var nicHostRefs []xenapi.HostRef
+	for _, pifRef := range pifRefs {
+		hostRef, err := xenapi.PIF.GetHost(session, pifRef)
+		if err != nil {
+			return params, errors.New(err.Error())
+		}
+		nicHostRefs = append(nicHostRefs, hostRef)
+	}
+	hostRecords, err := xenapi.Host.GetAllRecords(session)
+	if err != nil {
+		return params, errors.New(err.Error())
+	}
+	missingHosts := getHostsMissingNIC(nicHostRefs, hostRecords)
+	if len(missingHosts) > 0 {
+		return params, fmt.Errorf("%s is not found on the hosts: %s, the VLAN would not work on them", data.NIC.ValueString(), strings.Join(missingHosts, ", "))
+	}
 	params.PifRef = pifRefs[0]
+	params.PifRefs = pifRefs
 	params.NetworkRef = networkRef
 	params.Tag = int(data.Tag.ValueInt32())
 
 	return params, nil
 }
 
+// getHostsMissingNIC returns the hosts in the pool which have none of the NIC PIFs, as "<name_label> (<uuid>)"
+func getHostsMissingNIC(nicHostRefs []xenapi.HostRef, hostRecords map[xenapi.HostRef]xenapi.HostRecord) []string {
+	var missingHosts []string
+	for hostRef, hostRecord := range hostRecords {
+		if !slices.Contains(nicHostRefs, hostRef) {
+			missingHosts = append(missingHosts, hostRecord.NameLabel+" ("+hostRecord.UUID+")")
+		}
+	}
+	slices.Sort(missingHosts)
+
+	return missingHosts
+}
+
+// createVLAN creates the pool-wide VLAN, then creates the VLAN on the hosts which are missed by
+// Pool.create_VLAN_from_PIF, e.g. the bond has a different device name on the host.
+func createVLAN(ctx context.Context, session *xenapi.Session, params vlanCreateParams) error {
+	_, err := xenapi.Pool.CreateVLANFromPIF(session, params.PifRef, params.NetworkRef, params.Tag)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+
+	networkPIFRefs, err := xenapi.Network.GetPIFs(session, params.NetworkRef)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	hostsWithVLAN := make(map[xenapi.HostRef]bool)
+	for _, pifRef := range networkPIFRefs {
+		hostRef, err := xenapi.PIF.GetHost(session, pifRef)
+		if err != nil {
+			return errors.New(err.Error())
+		}
+		hostsWithVLAN[hostRef] = true
+	}
+
+	for _, pifRef := range params.PifRefs {
+		hostRef, err := xenapi.PIF.GetHost(session, pifRef)
+		if err != nil {
+			return errors.New(err.Error())
+		}
+		if hostsWithVLAN[hostRef] {
+			continue
+		}
+		tflog.Debug(ctx, "---> Create VLAN on host: "+string(hostRef))
+		_, err = xenapi.VLAN.Create(session, pifRef, params.Tag, params.NetworkRef)
+		if err != nil {
+			return errors.New(err.Error())
+		}
+		hostsWithVLAN[hostRef] = true
+	}
+
+	return nil
+}
+
 func getNICFromPIF(session *xenapi.Session, pifRecord xenapi.PIFRecord) (string, error) {
 	// return eg. NIC 0, NIC-SR-IOV 0, Bond 0+1+2
 	name := ""
@@ -204,11 +297,11 @@ func getNICFromPIF(session *xenapi.Session, pifRecord xenapi.PIFRecord) (string,
 		index := strings.Split(pifRecord.Device, "eth")[1]
 		name = "NIC " + index
 		if !pifRecord.Physical && string(pifRecord.VLANMasterOf) != "OpaqueRef:NULL" {
-			vlanRecord, err := xenapi.VLAN.GetRecord(session, pifRecord.VLANMasterOf)
+			vlanRecord, err := vlanAPI.GetRecord(session, pifRecord.VLANMasterOf)
 			if err != nil {
 				return name, errors.New(err.Error())
 			}
-			taggedPifRecord, err := xenapi.PIF.GetRecord(session, vlanRecord.TaggedPIF)
+			taggedPifRecord, err := pifAPI.GetRecord(session, vlanRecord.TaggedPIF)
 			if err != nil {
 				return name, errors.New(err.Error())
 			}
@@ -217,15 +310,15 @@ func getNICFromPIF(session *xenapi.Session, pifRecord xenapi.PIFRecord) (string,
 			}
 		}
 	} else if strings.HasPrefix(pifRecord.Device, "bond") {
-		vlanRecord, err := xenapi.VLAN.GetRecord(session, pifRecord.VLANMasterOf)
+		vlanRecord, err := vlanAPI.GetRecord(session, pifRecord.VLANMasterOf)
 		if err != nil {
 			return name, errors.New(err.Error())
 		}
-		taggedPifRecord, err := xenapi.PIF.GetRecord(session, vlanRecord.TaggedPIF)
+		taggedPifRecord, err := pifAPI.GetRecord(session, vlanRecord.TaggedPIF)
 		if err != nil {
 			return name, errors.New(err.Error())
 		}
-		bondRecord, err := xenapi.Bond.GetRecord(session, taggedPifRecord.BondMasterOf[0])
+		bondRecord, err := bondAPI.GetRecord(session, taggedPifRecord.BondMasterOf[0])
 		if err != nil {
 			return name, errors.New(err.Error())
 		}
@@ -261,11 +354,17 @@ func updateVlanResourceModelComputed(ctx context.Context, record xenapi.NetworkR
 	data.NameDescription = types.StringValue(record.NameDescription)
 	data.MTU = types.Int32Value(int32(record.MTU))
 	data.Managed = types.BoolValue(record.Managed)
+	data.DefaultLockingMode = types.StringValue(string(record.DefaultLockingMode))
+	data.Bridge = types.StringValue(record.Bridge)
 	var diags diag.Diagnostics
 	data.OtherConfig, diags = types.MapValueFrom(ctx, types.StringType, record.OtherConfig)
 	if diags.HasError() {
 		return errors.New("unable to update data for network_vlan other_config")
 	}
+	data.Purpose, diags = types.SetValueFrom(ctx, types.StringType, record.Purpose)
+	if diags.HasError() {
+		return errors.New("unable to update data for network_vlan purpose")
+	}
 
 	return nil
 }
@@ -280,6 +379,9 @@ func vlanResourceModelUpdateCheck(data vlanResourceModel, dataState vlanResource
 	if data.Managed != dataState.Managed {
 		return errors.New(`"managed" doesn't expected to be updated`)
 	}
+	if !data.Bridge.IsUnknown() && data.Bridge != dataState.Bridge {
+		return errors.New(`"bridge" doesn't expected to be updated`)
+	}
 	return nil
 }
 
@@ -293,10 +395,21 @@ func vlanResourceModelUpdate(ctx context.Context, session *xenapi.Session, ref x
 		return errors.New(err.Error())
 	}
 	mtu := int(data.MTU.ValueInt32())
-	err = xenapi.Network.SetMTU(session, ref, mtu)
+	currentMTU, err := xenapi.Network.GetMTU(session, ref)
 	if err != nil {
 		return errors.New(err.Error())
 	}
+	if currentMTU != mtu {
+		err = xenapi.Network.SetMTU(session, ref, mtu)
+		if err != nil {
+			return errors.New(err.Error())
+		}
+		// the new MTU only takes effect on the VLAN PIFs after they are plugged again
+		err = replugNetworkPIFs(ctx, session, ref, mtu)
+		if err != nil {
+			return err
+		}
+	}
 	otherConfig := make(map[string]string)
 	diags := data.OtherConfig.ElementsAs(ctx, &otherConfig, false)
 	if diags.HasError() {
@@ -306,34 +419,180 @@ func vlanResourceModelUpdate(ctx context.Context, session *xenapi.Session, ref x
 	if err != nil {
 		return errors.New(err.Error())
 	}
+	err = xenapi.Network.SetDefaultLockingMode(session, ref, xenapi.NetworkDefaultLockingMode(data.DefaultLockingMode.ValueString()))
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	return setNetworkPurpose(ctx, session, ref, data.Purpose)
+}
+
+// exclusiveNetworkPurposeValidator rejects a purpose set with both "nbd" and "insecure_nbd", XAPI refuses
+// to add one of them to a network which has the other.
+type exclusiveNetworkPurposeValidator struct{}
+
+var _ validator.Set = exclusiveNetworkPurposeValidator{}
+
+func (v exclusiveNetworkPurposeValidator) Description(_ context.Context) string {
+	return `"nbd" and "insecure_nbd" can't be set at the same time`
+}
+
+func (v exclusiveNetworkPurposeValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v exclusiveNetworkPurposeValidator) ValidateSet(_ context.Context, req validator.SetRequest, resp *validator.SetResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	values := []string{}
+	for _, element := range req.ConfigValue.Elements() {
+		value, ok := element.(types.String)
+		if !ok || value.IsNull() || value.IsUnknown() {
+			continue
+		}
+		values = append(values, value.ValueString())
+	}
+	if slices.Contains(values, string(xenapi.NetworkPurposeNbd)) && slices.Contains(values, string(xenapi.NetworkPurposeInsecureNbd)) {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Conflicting values in "+req.Path.String(),
+			`"nbd" and "insecure_nbd" can't be set at the same time, choose one of them.`,
+		)
+	}
+}
+
+// setNetworkPurpose adds and removes the network purposes to match the plan. Purposes are removed first,
+// so that switching between "nbd" and "insecure_nbd" doesn't conflict.
+func setNetworkPurpose(ctx context.Context, session *xenapi.Session, ref xenapi.NetworkRef, purpose types.Set) error {
+	if purpose.IsUnknown() {
+		return nil
+	}
+	var values []string
+	diags := purpose.ElementsAs(ctx, &values, false)
+	if diags.HasError() {
+		return errors.New("unable to access network purpose")
+	}
+	var planPurposes []xenapi.NetworkPurpose
+	for _, value := range values {
+		planPurposes = append(planPurposes, xenapi.NetworkPurpose(value))
+	}
+	currentPurposes, err := xenapi.Network.GetPurpose(session, ref)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	for _, value := range currentPurposes {
+		if !slices.Contains(planPurposes, value) {
+			err = xenapi.Network.RemovePurpose(session, ref, value)
+			if err != nil {
+				return errors.New(err.Error())
+			}
+		}
+	}
+	for _, value := range planPurposes {
+		if !slices.Contains(currentPurposes, value) {
+			err = xenapi.Network.AddPurpose(session, ref, value)
+			if err != nil {
+				return errors.New(err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+func replugNetworkPIFs(ctx context.Context, session *xenapi.Session, ref xenapi.NetworkRef, mtu int) error {
+	pifRefs, err := xenapi.Network.GetPIFs(session, ref)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	for _, pifRef := range pifRefs {
+		pifRecord, err := xenapi.PIF.GetRecord(session, pifRef)
+		if err != nil {
+			return errors.New(err.Error())
+		}
+		// the PIF will get the new MTU when it is plugged
+		if !pifRecord.CurrentlyAttached || pifRecord.MTU == mtu {
+			continue
+		}
+		if pifRecord.Management {
+			tflog.Warn(ctx, "The new MTU will take effect on the management PIF "+pifRecord.UUID+" after the host is rebooted")
+			continue
+		}
+
+		tflog.Debug(ctx, "---> Replug PIF: "+pifRecord.UUID)
+		err = xenapi.PIF.Unplug(session, pifRef)
+		if err != nil {
+			return errors.New(err.Error())
+		}
+		err = xenapi.PIF.Plug(session, pifRef)
+		if err != nil {
+			return errors.New(err.Error())
+		}
+
+		pifMTU, err := xenapi.PIF.GetMTU(session, pifRef)
+		if err != nil {
+			return errors.New(err.Error())
+		}
+		if pifMTU != mtu {
+			return fmt.Errorf("unable to apply MTU %d to PIF %s, the current MTU is %d", mtu, pifRecord.UUID, pifMTU)
+		}
+	}
+
 	return nil
 }
 
+// getNetworkRunningVMs returns the UUIDs of the running VMs which have VIFs attached on the network
+func getNetworkRunningVMs(session *xenapi.Session, ref xenapi.NetworkRef) ([]string, error) {
+	vifRefs, err := xenapi.Network.GetVIFs(session, ref)
+	if err != nil {
+		return nil, errors.New(err.Error())
+	}
+	var vmUUIDs []string
+	for _, vifRef := range vifRefs {
+		vifRecord, err := xenapi.VIF.GetRecord(session, vifRef)
+		if err != nil {
+			return nil, errors.New(err.Error())
+		}
+		if !vifRecord.CurrentlyAttached {
+			continue
+		}
+		vmUUID, err := xenapi.VM.GetUUID(session, vifRecord.VM)
+		if err != nil {
+			return nil, errors.New(err.Error())
+		}
+		vmUUIDs = append(vmUUIDs, vmUUID)
+	}
+	slices.Sort(vmUUIDs)
+	return slices.Compact(vmUUIDs), nil
+}
+
 func cleanupVlanResource(session *xenapi.Session, ref xenapi.NetworkRef) error {
 	networkRecord, err := xenapi.Network.GetRecord(session, ref)
 	if err != nil {
-		return errors.New(err.Error())
+		return cleanupError("network", string(ref), err)
 	}
 	for _, pifRef := range networkRecord.PIFs {
 		pifRecord, err := xenapi.PIF.GetRecord(session, pifRef)
 		if err != nil {
-			return errors.New(err.Error())
+			return cleanupError("network", networkRecord.UUID, err)
 		}
 		err = xenapi.VLAN.Destroy(session, pifRecord.VLANMasterOf)
 		if err != nil {
-			return errors.New(err.Error())
+			return cleanupError("network", networkRecord.UUID, err)
 		}
 	}
 	err = xenapi.Network.Destroy(session, ref)
 	if err != nil {
-		return errors.New(err.Error())
+		return cleanupError("network", networkRecord.UUID, err)
 	}
 	return nil
 }
 
 type nicDataSourceModel struct {
-	NetworkType types.String `tfsdk:"network_type"`
-	DataItems   []string     `tfsdk:"data_items"`
+	NetworkType   types.String `tfsdk:"network_type"`
+	AvailableOnly types.Bool   `tfsdk:"available_only"`
+	Capabilities  types.Set    `tfsdk:"capabilities"`
+	DataItems     []string     `tfsdk:"data_items"`
 }
 
 func unique(items []string) []string {
@@ -345,7 +604,7 @@ func unique(items []string) []string {
 func getBondSlaveDevices(session *xenapi.Session, bondSlaves []xenapi.PIFRef) ([]string, error) {
 	var bondSlaveDevices []string
 	for _, slave := range bondSlaves {
-		record, err := xenapi.PIF.GetRecord(session, slave)
+		record, err := pifAPI.GetRecord(session, slave)
 		if err != nil {
 			return bondSlaveDevices, errors.New(err.Error())
 		}
@@ -354,28 +613,22 @@ func getBondSlaveDevices(session *xenapi.Session, bondSlaves []xenapi.PIFRef) ([
 	return bondSlaveDevices, nil
 }
 
-func getBondNICs(session *xenapi.Session) ([]string, error) {
+// getBondNICs returns the NIC names of all the bonds in the pool, e.g. "Bond 0+1".
+// The bonds are named after their slaves, as the bond device name may differ between the hosts in a pool.
+func getBondNICs(bondRecords map[xenapi.BondRef]xenapi.BondRecord, pifRecords map[xenapi.PIFRef]xenapi.PIFRecord) []string {
 	var nics []string
-	bondRecords, err := xenapi.Bond.GetAllRecords(session)
-	if err != nil {
-		return nics, errors.New(err.Error())
-	}
-	var bondDevices []string
 	for _, bondRecord := range bondRecords {
-		pifRecord, err := xenapi.PIF.GetRecord(session, bondRecord.Master)
-		if err != nil {
-			return nics, errors.New(err.Error())
-		}
-		if !slices.Contains(bondDevices, pifRecord.Device) {
-			bondDevices = append(bondDevices, pifRecord.Device)
-			bondSlaveDevices, err := getBondSlaveDevices(session, bondRecord.Slaves)
-			if err != nil {
-				return nics, err
+		var slaveDevices []string
+		for _, slave := range bondRecord.Slaves {
+			pifRecord, ok := pifRecords[slave]
+			if !ok {
+				continue
 			}
-			nics = append(nics, getNICNameForBondDevices(bondSlaveDevices))
+			slaveDevices = append(slaveDevices, pifRecord.Device)
 		}
+		nics = append(nics, getNICNameForBondDevices(slaveDevices))
 	}
-	return unique(nics), nil
+	return unique(nics)
 }
 
 func getPhysicalNICs(pifRecords map[xenapi.PIFRef]xenapi.PIFRecord) []string {
@@ -424,6 +677,39 @@ func getPhysicalSRIOVNICs(pifRecords map[xenapi.PIFRef]xenapi.PIFRecord, availab
 	return getNICsNameForDevices(unique(devices), "NIC")
 }
 
+// filterNICsByCapabilities returns the NICs whose physical PIF on at least one host in the pool has all the capabilities, e.g. "sriov".
+// The bond NICs are dropped, as the capabilities belong to the physical PIFs.
+func filterNICsByCapabilities(nics []string, pifRecords map[xenapi.PIFRef]xenapi.PIFRecord, capabilities []string) []string {
+	var devices []string
+	for _, pifRecord := range pifRecords {
+		if !pifRecord.Physical {
+			continue
+		}
+		hasAll := true
+		for _, capability := range capabilities {
+			if !slices.Contains(pifRecord.Capabilities, capability) {
+				hasAll = false
+				break
+			}
+		}
+		if hasAll {
+			devices = append(devices, pifRecord.Device)
+		}
+	}
+
+	filtered := []string{}
+	for _, nic := range nics {
+		// nic eg. 1. NIC 0 2. NIC-SR-IOV 0 3. Bond 0+1+2
+		if strings.HasPrefix(nic, "Bond") {
+			continue
+		}
+		if slices.Contains(devices, "eth"+strings.Split(nic, " ")[1]) {
+			filtered = append(filtered, nic)
+		}
+	}
+	return filtered
+}
+
 func getNICsNameForDevices(devices []string, name string) []string {
 	// devices := []string{"eth0", "eth1", "eth2"}
 	// nics := []string{"NIC 0", "NIC 1", "NIC 2"}
@@ -450,3 +736,225 @@ func getNICNameForBondDevices(devices []string) string {
 	slices.Sort(deviceNumberStrings)
 	return name + " " + strings.Join(deviceNumberStrings, "+")
 }
+
+type vlanRangeResourceModel struct {
+	NameLabelPrefix types.String `tfsdk:"name_label_prefix"`
+	NameDescription types.String `tfsdk:"name_description"`
+	MTU             types.Int32  `tfsdk:"mtu"`
+	Tags            types.Set    `tfsdk:"vlan_tags"`
+	NIC             types.String `tfsdk:"nic"`
+	Networks        types.Map    `tfsdk:"networks"`
+	ID              types.String `tfsdk:"id"`
+}
+
+// getVlanRangeTags returns the VLAN tags of the range, sorted
+func getVlanRangeTags(ctx context.Context, tags types.Set) ([]int, error) {
+	var values []int32
+	diags := tags.ElementsAs(ctx, &values, false)
+	if diags.HasError() {
+		return nil, errors.New("unable to access vlan_tags")
+	}
+	result := make([]int, 0, len(values))
+	for _, value := range values {
+		result = append(result, int(value))
+	}
+	slices.Sort(result)
+	return result, nil
+}
+
+// getVlanRangeNetworks returns the UUIDs of the networks of the range, keyed by VLAN tag
+func getVlanRangeNetworks(ctx context.Context, networks types.Map) (map[int]string, error) {
+	values := make(map[string]string)
+	if !networks.IsNull() && !networks.IsUnknown() {
+		diags := networks.ElementsAs(ctx, &values, false)
+		if diags.HasError() {
+			return nil, errors.New("unable to access networks")
+		}
+	}
+	result := make(map[int]string, len(values))
+	for key, value := range values {
+		tag, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, errors.New("unable to convert VLAN tag " + key + " to an int value")
+		}
+		result[tag] = value
+	}
+	return result, nil
+}
+
+func setVlanRangeNetworks(ctx context.Context, networks map[int]string, data *vlanRangeResourceModel) error {
+	values := make(map[string]string, len(networks))
+	for tag, uuid := range networks {
+		values[strconv.Itoa(tag)] = uuid
+	}
+	var diags diag.Diagnostics
+	data.Networks, diags = types.MapValueFrom(ctx, types.StringType, values)
+	if diags.HasError() {
+		return errors.New("unable to update data for network_vlan_range networks")
+	}
+	return nil
+}
+
+// getVlanRangeNetworkTags returns the VLAN tags of the networks of the range, sorted
+func getVlanRangeNetworkTags(networks map[int]string) []int {
+	tags := make([]int, 0, len(networks))
+	for tag := range networks {
+		tags = append(tags, tag)
+	}
+	slices.Sort(tags)
+	return tags
+}
+
+// diffVlanTags returns the tags to create and the tags to destroy to get from the current tags to the planned ones, sorted
+func diffVlanTags(planTags []int, currentTags []int) ([]int, []int) {
+	var added, removed []int
+	for _, tag := range planTags {
+		if !slices.Contains(currentTags, tag) {
+			added = append(added, tag)
+		}
+	}
+	for _, tag := range currentTags {
+		if !slices.Contains(planTags, tag) {
+			removed = append(removed, tag)
+		}
+	}
+	slices.Sort(added)
+	slices.Sort(removed)
+	return added, removed
+}
+
+// getVlanRangeNetworkModel returns the model of the VLAN network of the tag in the range
+func getVlanRangeNetworkModel(data vlanRangeResourceModel, tag int) vlanResourceModel {
+	return vlanResourceModel{
+		NameLabel:       types.StringValue(data.NameLabelPrefix.ValueString() + strconv.Itoa(tag)),
+		NameDescription: data.NameDescription,
+		MTU:             data.MTU,
+		Managed:         types.BoolValue(true),
+		OtherConfig:     types.MapValueMust(types.StringType, map[string]attr.Value{}),
+		Bridge:          types.StringUnknown(),
+		Tag:             types.Int32Value(int32(tag)),
+		NIC:             data.NIC,
+	}
+}
+
+// createVlanRangeNetwork creates the network with its VLAN on the NIC, the network is destroyed if the VLAN can't be created
+func createVlanRangeNetwork(ctx context.Context, session *xenapi.Session, data vlanResourceModel) (string, error) {
+	networkRecord, err := getNetworkCreateParams(ctx, session, data)
+	if err != nil {
+		return "", err
+	}
+	networkRef, err := withSessionRetry(session, func() (xenapi.NetworkRef, error) {
+		return xenapi.Network.Create(session, networkRecord)
+	})
+	if err != nil {
+		return "", errors.New(err.Error())
+	}
+	uuid, err := xenapi.Network.GetUUID(session, networkRef)
+	if err == nil {
+		var params vlanCreateParams
+		params, err = getVlanCreateParams(session, data, networkRef)
+		if err == nil {
+			err = createVLAN(ctx, session, params)
+		}
+	}
+	if err != nil {
+		cleanupErr := cleanupVlanResource(session, networkRef)
+		if cleanupErr != nil {
+			return "", errors.New(err.Error() + "\nerror cleaning up network: " + cleanupErr.Error())
+		}
+		return "", err
+	}
+	return uuid, nil
+}
+
+func updateVlanRangeNetwork(ctx context.Context, session *xenapi.Session, uuid string, data vlanResourceModel) error {
+	ref, err := xenapi.Network.GetByUUID(session, uuid)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	err = xenapi.Network.SetNameLabel(session, ref, data.NameLabel.ValueString())
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	err = xenapi.Network.SetNameDescription(session, ref, data.NameDescription.ValueString())
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	mtu := int(data.MTU.ValueInt32())
+	currentMTU, err := xenapi.Network.GetMTU(session, ref)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	if currentMTU != mtu {
+		err = xenapi.Network.SetMTU(session, ref, mtu)
+		if err != nil {
+			return errors.New(err.Error())
+		}
+		err = replugNetworkPIFs(ctx, session, ref, mtu)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getExistingVlanRangeNetworks returns the networks of the range which still exist, the networks removed outside of
+// terraform are dropped, so the update creates them again
+func getExistingVlanRangeNetworks(ctx context.Context, session *xenapi.Session, networks map[int]string) (map[int]string, error) {
+	existing := make(map[int]string, len(networks))
+	for _, tag := range getVlanRangeNetworkTags(networks) {
+		_, err := xenapi.Network.GetByUUID(session, networks[tag])
+		if err != nil {
+			if !strings.Contains(err.Error(), "UUID_INVALID") {
+				return nil, errors.New(err.Error())
+			}
+			tflog.Warn(ctx, fmt.Sprintf("The network %s of VLAN %d is not found, it's removed from the state", networks[tag], tag))
+			continue
+		}
+		existing[tag] = networks[tag]
+	}
+	return existing, nil
+}
+
+// updateVlanRangeResourceModel refreshes the name description and the MTU from the network of the lowest tag,
+// the networks of the range are managed together so they share the same values
+func updateVlanRangeResourceModel(ctx context.Context, session *xenapi.Session, networks map[int]string, data *vlanRangeResourceModel) error {
+	tags := getVlanRangeNetworkTags(networks)
+	if len(tags) == 0 {
+		return errors.New("no network in the VLAN range")
+	}
+	ref, err := xenapi.Network.GetByUUID(session, networks[tags[0]])
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	record, err := xenapi.Network.GetRecord(session, ref)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	data.NameDescription = types.StringValue(record.NameDescription)
+	data.MTU = types.Int32Value(int32(record.MTU))
+	var diags diag.Diagnostics
+	data.Tags, diags = types.SetValueFrom(ctx, types.Int32Type, tags)
+	if diags.HasError() {
+		return errors.New("unable to update data for network_vlan_range vlan_tags")
+	}
+	return setVlanRangeNetworks(ctx, networks, data)
+}
+
+// cleanupVlanRangeResource destroys the networks of the range, it carries on with the other networks
+// when one can't be destroyed and returns all errors
+func cleanupVlanRangeResource(session *xenapi.Session, networks map[int]string) error {
+	var errs []error
+	for _, tag := range getVlanRangeNetworkTags(networks) {
+		ref, err := xenapi.Network.GetByUUID(session, networks[tag])
+		if err != nil {
+			err = cleanupError("network", networks[tag], err)
+		} else {
+			err = cleanupVlanResource(session, ref)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("VLAN %d: %s", tag, err.Error()))
+		}
+	}
+	return errors.Join(errs...)
+}
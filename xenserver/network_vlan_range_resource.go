@@ -0,0 +1,368 @@
+package xenserver
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/int32validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/setvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int32default"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"xenapi"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var (
+	_ resource.Resource              = &vlanRangeResource{}
+	_ resource.ResourceWithConfigure = &vlanRangeResource{}
+)
+
+func NewVlanRangeResource() resource.Resource {
+	return &vlanRangeResource{}
+}
+
+// vlanRangeResource defines the resource implementation.
+type vlanRangeResource struct {
+	session          *xenapi.Session
+	operationLimiter operationLimiter
+}
+
+func (r *vlanRangeResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_network_vlan_range"
+}
+
+func (r *vlanRangeResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Provides a set of external networks, one per VLAN tag over the same NIC, for example, the VLANs of a trunk. The networks are created and destroyed as the VLAN tags are added to and removed from the set." +
+			"\n\n-> **Note:** The networks are named `<name_label_prefix><vlan_tag>`, they share the same description and MTU. Use `xenserver_network_vlan` for a network which needs its own settings.",
+		Attributes: map[string]schema.Attribute{
+			"name_label_prefix": schema.StringAttribute{
+				MarkdownDescription: "The prefix of the names of the networks, followed by the VLAN tag, for example, `\"VLAN \"` names the network of the tag `100` as `\"VLAN 100\"`.",
+				Required:            true,
+			},
+			"name_description": schema.StringAttribute{
+				MarkdownDescription: "The description of the networks, default to be `\"\"`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString(""),
+			},
+			"mtu": schema.Int32Attribute{
+				MarkdownDescription: "The MTU of the networks, default to be `1500`. The minimum value this attribute can be set is `0`." +
+					"\n\n-> **Note:** When `mtu` is updated, the attached VLAN PIFs are re-plugged to apply the new MTU.",
+				Optional: true,
+				Computed: true,
+				Default:  int32default.StaticInt32(1500),
+				Validators: []validator.Int32{
+					int32validator.AtLeast(0),
+				},
+			},
+			"vlan_tags": schema.SetAttribute{
+				MarkdownDescription: "The VLAN tags to create a network for, between `0` and `4094`.",
+				Required:            true,
+				ElementType:         types.Int32Type,
+				Validators: []validator.Set{
+					setvalidator.SizeAtLeast(1),
+					setvalidator.ValueInt32sAre(int32validator.Between(0, 4094)),
+				},
+			},
+			"nic": schema.StringAttribute{
+				MarkdownDescription: "The NIC used by the networks, for example, `\"NIC 0\"`, `\"Bond 0+1\"`, `\"NIC-SR-IOV 0\"`." + "<br />" +
+					"The NIC on target XenServer environment can be found by the `xenserver_nic` data-source." +
+					"\n\n-> **Note:** `nic` is not allowed to be updated.",
+				Required: true,
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(
+						regexp.MustCompile(`^NIC|^Bond|^NIC-SR-IOV`),
+						`must start with "NIC", "Bond" or "NIC-SR-IOV", eg. "NIC 0", "Bond 0+1", "NIC-SR-IOV 0"`,
+					),
+				},
+			},
+			"networks": schema.MapAttribute{
+				MarkdownDescription: "The UUIDs of the networks, keyed by VLAN tag.",
+				Computed:            true,
+				ElementType:         types.StringType,
+			},
+			"id": schema.StringAttribute{
+				MarkdownDescription: "The test ID of the VLAN range.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func (r *vlanRangeResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+	providerData, ok := req.ProviderData.(*xsProvider)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *xenserver.xsProvider, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+	r.session = providerData.session
+	r.operationLimiter = providerData.operationLimiter
+}
+
+func (r *vlanRangeResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data vlanRangeResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	tags, err := getVlanRangeTags(ctx, data.Tags)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get VLAN tags",
+			err.Error(),
+		)
+		return
+	}
+	networks := make(map[int]string)
+	for _, tag := range tags {
+		tflog.Debug(ctx, fmt.Sprintf("Creating VLAN %d...", tag))
+		uuid, err := createVlanRangeNetwork(ctx, r.session, getVlanRangeNetworkModel(data, tag))
+		if err != nil {
+			resp.Diagnostics.AddError(
+				fmt.Sprintf("Unable to create VLAN %d", tag),
+				err.Error(),
+			)
+			err = cleanupVlanRangeResource(r.session, networks)
+			if err != nil {
+				resp.Diagnostics.AddError(
+					"Error cleaning up network_vlan_range resource",
+					err.Error(),
+				)
+			}
+			return
+		}
+		networks[tag] = uuid
+	}
+	// the network of the lowest tag identifies the range
+	data.ID = types.StringValue(data.NIC.ValueString() + "/" + networks[tags[0]])
+	err = setVlanRangeNetworks(ctx, networks, &data)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to update the computed fields of vlanRangeResourceModel",
+			err.Error(),
+		)
+		err = cleanupVlanRangeResource(r.session, networks)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Error cleaning up network_vlan_range resource",
+				err.Error(),
+			)
+		}
+		return
+	}
+
+	tflog.Debug(ctx, "VLAN range created")
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *vlanRangeResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data vlanRangeResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	networks, err := getVlanRangeNetworks(ctx, data.Networks)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get the networks of VLAN range",
+			err.Error(),
+		)
+		return
+	}
+	networks, err = getExistingVlanRangeNetworks(ctx, r.session, networks)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get the networks of VLAN range",
+			err.Error(),
+		)
+		return
+	}
+	// all the networks have been removed outside of terraform
+	if len(networks) == 0 {
+		tflog.Debug(ctx, "No network of the VLAN range is found, remove the VLAN range from state")
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	err = updateVlanRangeResourceModel(ctx, r.session, networks, &data)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to update the fields of vlanRangeResourceModel",
+			err.Error(),
+		)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *vlanRangeResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state vlanRangeResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	// Checking if configuration changes are allowed
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if plan.NIC != state.NIC {
+		resp.Diagnostics.AddError(
+			"Error update xenserver_network_vlan_range configuration",
+			`"nic" doesn't expected to be updated`,
+		)
+		return
+	}
+
+	planTags, err := getVlanRangeTags(ctx, plan.Tags)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get VLAN tags",
+			err.Error(),
+		)
+		return
+	}
+	networks, err := getVlanRangeNetworks(ctx, state.Networks)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get the networks of VLAN range",
+			err.Error(),
+		)
+		return
+	}
+	added, removed := diffVlanTags(planTags, getVlanRangeNetworkTags(networks))
+
+	// keep the networks created and destroyed so far in the state if the update fails half way
+	saveNetworks := func() {
+		plan.Tags, _ = types.SetValueFrom(ctx, types.Int32Type, getVlanRangeNetworkTags(networks))
+		if err := setVlanRangeNetworks(ctx, networks, &plan); err == nil {
+			resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+		}
+	}
+	for _, tag := range removed {
+		tflog.Debug(ctx, fmt.Sprintf("Destroying VLAN %d...", tag))
+		err = cleanupVlanRangeResource(r.session, map[int]string{tag: networks[tag]})
+		if err != nil {
+			resp.Diagnostics.AddError(
+				fmt.Sprintf("Unable to destroy VLAN %d", tag),
+				err.Error(),
+			)
+			saveNetworks()
+			return
+		}
+		delete(networks, tag)
+	}
+	for tag, uuid := range networks {
+		err = updateVlanRangeNetwork(ctx, r.session, uuid, getVlanRangeNetworkModel(plan, tag))
+		if err != nil {
+			resp.Diagnostics.AddError(
+				fmt.Sprintf("Unable to update VLAN %d", tag),
+				err.Error(),
+			)
+			saveNetworks()
+			return
+		}
+	}
+	for _, tag := range added {
+		tflog.Debug(ctx, fmt.Sprintf("Creating VLAN %d...", tag))
+		uuid, err := createVlanRangeNetwork(ctx, r.session, getVlanRangeNetworkModel(plan, tag))
+		if err != nil {
+			resp.Diagnostics.AddError(
+				fmt.Sprintf("Unable to create VLAN %d", tag),
+				err.Error(),
+			)
+			saveNetworks()
+			return
+		}
+		networks[tag] = uuid
+	}
+
+	err = setVlanRangeNetworks(ctx, networks, &plan)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to update the computed fields of vlanRangeResourceModel",
+			err.Error(),
+		)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *vlanRangeResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data vlanRangeResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	networks, err := getVlanRangeNetworks(ctx, data.Networks)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get the networks of VLAN range",
+			err.Error(),
+		)
+		return
+	}
+	err = cleanupVlanRangeResource(r.session, networks)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to delete network_vlan_range resource",
+			err.Error(),
+		)
+		return
+	}
+}
@@ -0,0 +1,105 @@
+package xenserver
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func testAccVlanRangeResourceConfig(tags string, mtu int, nic string) string {
+	return fmt.Sprintf(`
+resource "xenserver_network_vlan_range" "test_vlan_range" {
+	name_label_prefix = "test VLAN "
+	vlan_tags = %s
+	mtu = %d
+	nic = "%s"
+}
+`, tags, mtu, nic)
+}
+
+func TestAccVlanRangeResource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      providerConfig + testAccVlanRangeResourceConfig("[11, 4095]", 1500, "NIC 0"),
+				ExpectError: regexp.MustCompile(`value must be between 0 and 4094`),
+			},
+			// Create and Read testing
+			{
+				Config: providerConfig + testAccVlanRangeResourceConfig("[11, 12, 13]", 1500, "NIC 0"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_network_vlan_range.test_vlan_range", "vlan_tags.#", "3"),
+					resource.TestCheckResourceAttr("xenserver_network_vlan_range.test_vlan_range", "networks.%", "3"),
+					resource.TestCheckResourceAttrSet("xenserver_network_vlan_range.test_vlan_range", "networks.11"),
+					resource.TestCheckResourceAttr("xenserver_network_vlan_range.test_vlan_range", "name_description", ""),
+					resource.TestCheckResourceAttr("xenserver_network_vlan_range.test_vlan_range", "mtu", "1500"),
+				),
+			},
+			{
+				Config:      providerConfig + testAccVlanRangeResourceConfig("[11, 12, 13]", 1500, "NIC 1"),
+				ExpectError: regexp.MustCompile(`"nic" doesn't expected to be updated`),
+			},
+			// Update and Read testing
+			{
+				Config: providerConfig + testAccVlanRangeResourceConfig("[12, 13, 14, 15]", 1400, "NIC 0"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_network_vlan_range.test_vlan_range", "networks.%", "4"),
+					resource.TestCheckNoResourceAttr("xenserver_network_vlan_range.test_vlan_range", "networks.11"),
+					resource.TestCheckResourceAttrSet("xenserver_network_vlan_range.test_vlan_range", "networks.15"),
+					resource.TestCheckResourceAttr("xenserver_network_vlan_range.test_vlan_range", "mtu", "1400"),
+				),
+			},
+			// Delete testing automatically occurs in TestCase
+		},
+	})
+}
+
+func TestDiffVlanTags(t *testing.T) {
+	testCases := []struct {
+		planTags        []int
+		currentTags     []int
+		expectedAdded   []int
+		expectedRemoved []int
+	}{
+		{planTags: []int{1, 2, 3}, currentTags: nil, expectedAdded: []int{1, 2, 3}, expectedRemoved: nil},
+		{planTags: []int{1, 2, 3}, currentTags: []int{1, 2, 3}, expectedAdded: nil, expectedRemoved: nil},
+		{planTags: []int{5, 2, 3}, currentTags: []int{1, 2, 4}, expectedAdded: []int{3, 5}, expectedRemoved: []int{1, 4}},
+	}
+	for _, tc := range testCases {
+		added, removed := diffVlanTags(tc.planTags, tc.currentTags)
+		if !slices.Equal(added, tc.expectedAdded) || !slices.Equal(removed, tc.expectedRemoved) {
+			t.Errorf("diffVlanTags(%v, %v) = %v, %v, expected %v, %v", tc.planTags, tc.currentTags, added, removed, tc.expectedAdded, tc.expectedRemoved)
+		}
+	}
+}
+
+func TestGetVlanRangeNetworks(t *testing.T) {
+	ctx := context.Background()
+	testCases := []struct {
+		networks    types.Map
+		expected    map[int]string
+		expectedErr bool
+	}{
+		{networks: types.MapNull(types.StringType), expected: map[int]string{}},
+		{networks: types.MapValueMust(types.StringType, map[string]attr.Value{"10": types.StringValue("uuid-10"), "200": types.StringValue("uuid-200")}), expected: map[int]string{10: "uuid-10", 200: "uuid-200"}},
+		{networks: types.MapValueMust(types.StringType, map[string]attr.Value{"ten": types.StringValue("uuid-10")}), expectedErr: true},
+	}
+	for _, tc := range testCases {
+		networks, err := getVlanRangeNetworks(ctx, tc.networks)
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("getVlanRangeNetworks(%v) returned error %v, expected error %t", tc.networks, err, tc.expectedErr)
+			continue
+		}
+		if !tc.expectedErr && !maps.Equal(networks, tc.expected) {
+			t.Errorf("getVlanRangeNetworks(%v) = %v, expected %v", tc.networks, networks, tc.expected)
+		}
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"strings"
 
 	"github.com/hashicorp/terraform-plugin-framework-validators/int32validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/setvalidator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/path"
@@ -15,6 +17,7 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int32default"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapdefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/setdefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
@@ -37,7 +40,8 @@ func NewVlanResource() resource.Resource {
 
 // vlanResource defines the resource implementation.
 type vlanResource struct {
-	session *xenapi.Session
+	session          *xenapi.Session
+	operationLimiter operationLimiter
 }
 
 func (r *vlanResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -59,10 +63,11 @@ func (r *vlanResource) Schema(_ context.Context, _ resource.SchemaRequest, resp
 				Default:             stringdefault.StaticString(""),
 			},
 			"mtu": schema.Int32Attribute{
-				MarkdownDescription: "The MTU of the network, default to be `1500`. The minimum value this attribute can be set is `0`.",
-				Optional:            true,
-				Computed:            true,
-				Default:             int32default.StaticInt32(1500),
+				MarkdownDescription: "The MTU of the network, default to be `1500`. The minimum value this attribute can be set is `0`." +
+					"\n\n-> **Note:** When `mtu` is updated, the attached VLAN PIFs are re-plugged to apply the new MTU. The VIFs of the running VMs keep the old MTU until they are plugged again, a warning lists these VMs.",
+				Optional: true,
+				Computed: true,
+				Default:  int32default.StaticInt32(1500),
 				Validators: []validator.Int32{
 					int32validator.AtLeast(0),
 				},
@@ -81,6 +86,44 @@ func (r *vlanResource) Schema(_ context.Context, _ resource.SchemaRequest, resp
 				Default:             mapdefault.StaticValue(types.MapValueMust(types.StringType, map[string]attr.Value{})),
 				ElementType:         types.StringType,
 			},
+			"purpose": schema.SetAttribute{
+				MarkdownDescription: "The set of purposes for which the server will use this network, default to be `[]`. Available values are `\"nbd\"` and `\"insecure_nbd\"`, which allow the network to be used by the network block device service, for example, in storage migration." +
+					"\n\n-> **Note:** `\"nbd\"` and `\"insecure_nbd\"` can't be set at the same time.",
+				Optional:    true,
+				Computed:    true,
+				Default:     setdefault.StaticValue(types.SetValueMust(types.StringType, []attr.Value{})),
+				ElementType: types.StringType,
+				Validators: []validator.Set{
+					setvalidator.ValueStringsAre(stringvalidator.OneOf(string(xenapi.NetworkPurposeNbd), string(xenapi.NetworkPurposeInsecureNbd))),
+					exclusiveNetworkPurposeValidator{},
+				},
+			},
+			"default_locking_mode": schema.StringAttribute{
+				MarkdownDescription: "The default locking mode of the VIFs attached to the network, `\"unlocked\"` or `\"disabled\"`, default to be `\"unlocked\"`." + "<br />" +
+					"When it's `\"unlocked\"`, the VIFs in `network_default` locking mode can send traffic with any MAC and IP address. When it's `\"disabled\"`, they drop all traffic.",
+				Optional: true,
+				Computed: true,
+				Default:  stringdefault.StaticString(string(xenapi.NetworkDefaultLockingModeUnlocked)),
+				Validators: []validator.String{
+					stringvalidator.OneOf(string(xenapi.NetworkDefaultLockingModeUnlocked), string(xenapi.NetworkDefaultLockingModeDisabled)),
+				},
+			},
+			"bridge": schema.StringAttribute{
+				MarkdownDescription: "The name of the bridge corresponding to this network on the hosts. If not set, the name is generated by XAPI, for example, `\"xapi1\"`." +
+					"\n\n-> **Note:** `bridge` is not allowed to be updated.",
+				Optional: true,
+				Computed: true,
+				Validators: []validator.String{
+					stringvalidator.LengthBetween(1, 15),
+					stringvalidator.RegexMatches(
+						regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`),
+						"must only contain letters, numbers, '_', '.' and '-'",
+					),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
 			"vlan_tag": schema.Int32Attribute{
 				MarkdownDescription: "The VLAN tag of the network." +
 					"\n\n-> **Note:** `vlan_tag` is not allowed to be updated.",
@@ -130,6 +173,7 @@ func (r *vlanResource) Configure(_ context.Context, req resource.ConfigureReques
 		return
 	}
 	r.session = providerData.session
+	r.operationLimiter = providerData.operationLimiter
 }
 
 func (r *vlanResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
@@ -139,8 +183,17 @@ func (r *vlanResource) Create(ctx context.Context, req resource.CreateRequest, r
 		return
 	}
 
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
 	tflog.Debug(ctx, "Creating Network...")
-	networkRecord, err := getNetworkCreateParams(ctx, data)
+	networkRecord, err := withSessionRetry(r.session, func() (xenapi.NetworkRecord, error) {
+		return getNetworkCreateParams(ctx, r.session, data)
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to get network create params",
@@ -156,6 +209,36 @@ func (r *vlanResource) Create(ctx context.Context, req resource.CreateRequest, r
 		)
 		return
 	}
+	err = xenapi.Network.SetDefaultLockingMode(r.session, networkRef, xenapi.NetworkDefaultLockingMode(data.DefaultLockingMode.ValueString()))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to set network default locking mode",
+			err.Error(),
+		)
+		err = cleanupVlanResource(r.session, networkRef)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Error cleaning up network resource",
+				err.Error(),
+			)
+		}
+		return
+	}
+	err = setNetworkPurpose(ctx, r.session, networkRef, data.Purpose)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to set network purpose",
+			err.Error(),
+		)
+		err = cleanupVlanResource(r.session, networkRef)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Error cleaning up network resource",
+				err.Error(),
+			)
+		}
+		return
+	}
 	networkRecord, err = xenapi.Network.GetRecord(r.session, networkRef)
 	if err != nil {
 		resp.Diagnostics.AddError(
@@ -203,7 +286,7 @@ func (r *vlanResource) Create(ctx context.Context, req resource.CreateRequest, r
 		}
 		return
 	}
-	_, err = xenapi.Pool.CreateVLANFromPIF(r.session, params.PifRef, params.NetworkRef, params.Tag)
+	err = createVLAN(ctx, r.session, params)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to create vlan",
@@ -231,8 +314,17 @@ func (r *vlanResource) Read(ctx context.Context, req resource.ReadRequest, resp
 		return
 	}
 
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
 	// Overwrite data with refreshed resource state
-	networkRef, err := xenapi.Network.GetByUUID(r.session, data.UUID.ValueString())
+	networkRef, err := withSessionRetry(r.session, func() (xenapi.NetworkRef, error) {
+		return xenapi.Network.GetByUUID(r.session, data.UUID.ValueString())
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to get network ref",
@@ -267,12 +359,19 @@ func (r *vlanResource) Update(ctx context.Context, req resource.UpdateRequest, r
 		return
 	}
 
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
 	// Checking if configuration changes are allowed
 	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
-	err := vlanResourceModelUpdateCheck(plan, state)
+	err = vlanResourceModelUpdateCheck(plan, state)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Error update xenserver_network_vlan configuration",
@@ -282,7 +381,9 @@ func (r *vlanResource) Update(ctx context.Context, req resource.UpdateRequest, r
 	}
 
 	// Update the resource with new configuration
-	networkRef, err := xenapi.Network.GetByUUID(r.session, plan.UUID.ValueString())
+	networkRef, err := withSessionRetry(r.session, func() (xenapi.NetworkRef, error) {
+		return xenapi.Network.GetByUUID(r.session, plan.UUID.ValueString())
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to get network ref",
@@ -298,6 +399,23 @@ func (r *vlanResource) Update(ctx context.Context, req resource.UpdateRequest, r
 		)
 		return
 	}
+	// the attached VIFs keep the old MTU until they are plugged again, e.g. when the VM is restarted
+	if plan.MTU != state.MTU {
+		vmUUIDs, err := getNetworkRunningVMs(r.session, networkRef)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Unable to get the running VMs on network",
+				err.Error(),
+			)
+			return
+		}
+		if len(vmUUIDs) > 0 {
+			resp.Diagnostics.AddWarning(
+				"The new MTU doesn't take effect on the attached VIFs",
+				"Restart the VMs, or unplug and plug their VIFs on the network, to apply the new MTU. VMs(UUID): "+strings.Join(vmUUIDs, ", "),
+			)
+		}
+	}
 	networkRecord, err := xenapi.Network.GetRecord(r.session, networkRef)
 	if err != nil {
 		resp.Diagnostics.AddError(
@@ -325,7 +443,16 @@ func (r *vlanResource) Delete(ctx context.Context, req resource.DeleteRequest, r
 		return
 	}
 
-	networkRef, err := xenapi.Network.GetByUUID(r.session, data.UUID.ValueString())
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	networkRef, err := withSessionRetry(r.session, func() (xenapi.NetworkRef, error) {
+		return xenapi.Network.GetByUUID(r.session, data.UUID.ValueString())
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to get network ref",
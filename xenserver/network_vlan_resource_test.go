@@ -1,11 +1,18 @@
 package xenserver
 
 import (
+	"context"
 	"fmt"
 	"regexp"
+	"slices"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+
+	"xenapi"
 )
 
 func testAccVlanResourceConfig(name_label string, name_description string, mtu int, tag int, nic string, extra_config string) string {
@@ -44,7 +51,10 @@ func TestAccVlanResource(t *testing.T) {
 					resource.TestCheckResourceAttr("xenserver_network_vlan.test_vlan", "managed", "true"),
 					resource.TestCheckResourceAttr("xenserver_network_vlan.test_vlan", "vlan_tag", "1"),
 					resource.TestCheckResourceAttr("xenserver_network_vlan.test_vlan", "nic", "NIC 0"),
+					resource.TestCheckResourceAttr("xenserver_network_vlan.test_vlan", "purpose.#", "0"),
+					resource.TestCheckResourceAttr("xenserver_network_vlan.test_vlan", "default_locking_mode", "unlocked"),
 					// Verify dynamic values have any value set in the state.
+					resource.TestCheckResourceAttrSet("xenserver_network_vlan.test_vlan", "bridge"),
 					resource.TestCheckResourceAttrSet("xenserver_network_vlan.test_vlan", "uuid"),
 				),
 			},
@@ -67,7 +77,35 @@ func TestAccVlanResource(t *testing.T) {
 				Config:      providerConfig + testAccVlanResourceConfig("test external network 1", "", 1500, 1, "NIC 0", "managed = false"),
 				ExpectError: regexp.MustCompile(`"managed" doesn't expected to be updated`),
 			},
+			{
+				Config:      providerConfig + testAccVlanResourceConfig("test external network 1", "", 1500, 1, "NIC 0", `bridge = "tfbr0"`),
+				ExpectError: regexp.MustCompile(`"bridge" doesn't expected to be updated`),
+			},
+			{
+				Config:      providerConfig + testAccVlanResourceConfig("test external network 1", "", 1500, 1, "NIC 0", `purpose = ["unknown"]`),
+				ExpectError: regexp.MustCompile(`value must be one of`),
+			},
 			// Update and Read testing
+			{
+				Config: providerConfig + testAccVlanResourceConfig("test external network 1", "", 1500, 1, "NIC 0", `purpose = ["nbd"]`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_network_vlan.test_vlan", "purpose.#", "1"),
+					resource.TestCheckTypeSetElemAttr("xenserver_network_vlan.test_vlan", "purpose.*", "nbd"),
+				),
+			},
+			{
+				Config: providerConfig + testAccVlanResourceConfig("test external network 1", "", 1500, 1, "NIC 0", `purpose = ["insecure_nbd"]`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_network_vlan.test_vlan", "purpose.#", "1"),
+					resource.TestCheckTypeSetElemAttr("xenserver_network_vlan.test_vlan", "purpose.*", "insecure_nbd"),
+				),
+			},
+			{
+				Config: providerConfig + testAccVlanResourceConfig("test external network 1", "", 1500, 1, "NIC 0", `default_locking_mode = "disabled"`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_network_vlan.test_vlan", "default_locking_mode", "disabled"),
+				),
+			},
 			{
 				Config: providerConfig + testAccVlanResourceConfig("test external network 2", "Test description", 1600, 1, "NIC 0", ""),
 				Check: resource.ComposeAggregateTestCheckFunc(
@@ -75,6 +113,7 @@ func TestAccVlanResource(t *testing.T) {
 					resource.TestCheckResourceAttr("xenserver_network_vlan.test_vlan", "name_description", "Test description"),
 					resource.TestCheckResourceAttr("xenserver_network_vlan.test_vlan", "other_config.%", "0"),
 					resource.TestCheckResourceAttr("xenserver_network_vlan.test_vlan", "mtu", "1600"),
+					resource.TestCheckResourceAttr("xenserver_network_vlan.test_vlan", "purpose.#", "0"),
 					resource.TestCheckResourceAttr("xenserver_network_vlan.test_vlan", "managed", "true"),
 					resource.TestCheckResourceAttr("xenserver_network_vlan.test_vlan", "vlan_tag", "1"),
 					resource.TestCheckResourceAttr("xenserver_network_vlan.test_vlan", "nic", "NIC 0"),
@@ -84,3 +123,181 @@ func TestAccVlanResource(t *testing.T) {
 		},
 	})
 }
+
+func TestAccVlanResourceBridge(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      providerConfig + testAccVlanResourceConfig("test external network 1", "", 1500, 1, "NIC 0", `bridge = "xenbr0"`),
+				ExpectError: regexp.MustCompile(`bridge "xenbr0" is already used by network`),
+			},
+			// Create and Read testing
+			{
+				Config: providerConfig + testAccVlanResourceConfig("test external network 1", "", 1500, 1, "NIC 0", `bridge = "tfbr0"`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_network_vlan.test_vlan", "bridge", "tfbr0"),
+				),
+			},
+			// Delete testing automatically occurs in TestCase
+		},
+	})
+}
+
+func TestGetBondMasterPIFRefs(t *testing.T) {
+	// two hosts have the bond on eth0+eth1 with different bond device names,
+	// host1 has another bond on eth2+eth3
+	pifRecords := map[xenapi.PIFRef]xenapi.PIFRecord{
+		"host1-eth0":  {Device: "eth0"},
+		"host1-eth1":  {Device: "eth1"},
+		"host1-eth2":  {Device: "eth2"},
+		"host1-eth3":  {Device: "eth3"},
+		"host1-bond0": {Device: "bond0"},
+		"host1-bond1": {Device: "bond1"},
+		"host2-eth0":  {Device: "eth0"},
+		"host2-eth1":  {Device: "eth1"},
+		"host2-bond1": {Device: "bond1"},
+	}
+	bondRecords := map[xenapi.BondRef]xenapi.BondRecord{
+		"bond-host1-01": {Master: "host1-bond0", Slaves: []xenapi.PIFRef{"host1-eth1", "host1-eth0"}},
+		"bond-host1-23": {Master: "host1-bond1", Slaves: []xenapi.PIFRef{"host1-eth2", "host1-eth3"}},
+		"bond-host2-01": {Master: "host2-bond1", Slaves: []xenapi.PIFRef{"host2-eth0", "host2-eth1"}},
+	}
+
+	testCases := []struct {
+		nic      string
+		expected []xenapi.PIFRef
+	}{
+		{nic: "Bond 0+1", expected: []xenapi.PIFRef{"host1-bond0", "host2-bond1"}},
+		{nic: "Bond 2+3", expected: []xenapi.PIFRef{"host1-bond1"}},
+		{nic: "Bond 0+2", expected: nil},
+	}
+	for _, tc := range testCases {
+		pifRefs := getBondMasterPIFRefs(tc.nic, bondRecords, pifRecords)
+		if !slices.Equal(pifRefs, tc.expected) {
+			t.Errorf("getBondMasterPIFRefs(%q) = %v, expected %v", tc.nic, pifRefs, tc.expected)
+		}
+	}
+}
+
+func TestGetDevicePIFRefs(t *testing.T) {
+	// host1 has eth1 in a bond and a SR-IOV logical PIF on eth2
+	pifRecords := map[xenapi.PIFRef]xenapi.PIFRecord{
+		"host1-eth0":        {Device: "eth0", Physical: true, BondSlaveOf: "OpaqueRef:NULL"},
+		"host1-eth1":        {Device: "eth1", Physical: true, BondSlaveOf: "bond-host1"},
+		"host1-eth2":        {Device: "eth2", Physical: true, BondSlaveOf: "OpaqueRef:NULL"},
+		"host1-eth2-sriov":  {Device: "eth2", SriovLogicalPIFOf: []xenapi.NetworkSriovRef{"sriov-host1"}},
+		"host1-eth0-vlan10": {Device: "eth0", BondSlaveOf: "OpaqueRef:NULL"},
+		"host2-eth0":        {Device: "eth0", Physical: true, BondSlaveOf: "OpaqueRef:NULL"},
+		"host2-eth1":        {Device: "eth1", Physical: true, BondSlaveOf: "OpaqueRef:NULL"},
+	}
+
+	testCases := []struct {
+		nic      string
+		expected []xenapi.PIFRef
+	}{
+		{nic: "NIC 0", expected: []xenapi.PIFRef{"host1-eth0", "host2-eth0"}},
+		{nic: "NIC 1", expected: []xenapi.PIFRef{"host2-eth1"}},
+		{nic: "NIC 2", expected: []xenapi.PIFRef{"host1-eth2"}},
+		{nic: "NIC-SR-IOV 2", expected: []xenapi.PIFRef{"host1-eth2-sriov"}},
+		{nic: "NIC 3", expected: nil},
+	}
+	for _, tc := range testCases {
+		pifRefs := getDevicePIFRefs(tc.nic, pifRecords)
+		if !slices.Equal(pifRefs, tc.expected) {
+			t.Errorf("getDevicePIFRefs(%q) = %v, expected %v", tc.nic, pifRefs, tc.expected)
+		}
+	}
+}
+
+func TestGetHostsMissingNIC(t *testing.T) {
+	hostRecords := map[xenapi.HostRef]xenapi.HostRecord{
+		"host1": {NameLabel: "host-a", UUID: "uuid-1"},
+		"host2": {NameLabel: "host-b", UUID: "uuid-2"},
+		"host3": {NameLabel: "host-c", UUID: "uuid-3"},
+	}
+
+	testCases := []struct {
+		nicHostRefs []xenapi.HostRef
+		expected    []string
+	}{
+		{nicHostRefs: []xenapi.HostRef{"host1", "host2", "host3"}, expected: nil},
+		{nicHostRefs: []xenapi.HostRef{"host2"}, expected: []string{"host-a (uuid-1)", "host-c (uuid-3)"}},
+		{nicHostRefs: []xenapi.HostRef{"host1", "host3"}, expected: []string{"host-b (uuid-2)"}},
+	}
+	for _, tc := range testCases {
+		missingHosts := getHostsMissingNIC(tc.nicHostRefs, hostRecords)
+		if !slices.Equal(missingHosts, tc.expected) {
+			t.Errorf("getHostsMissingNIC(%v) = %v, expected %v", tc.nicHostRefs, missingHosts, tc.expected)
+		}
+	}
+}
+
+func TestGetNICFromPIF(t *testing.T) {
+	pifRecords := fakeRecords[xenapi.PIFRef, xenapi.PIFRecord]{
+		"eth0":       {Device: "eth0", Physical: true},
+		"eth1":       {Device: "eth1", Physical: true},
+		"eth1-sriov": {Device: "eth1", SriovLogicalPIFOf: []xenapi.NetworkSriovRef{"sriov"}},
+		"eth2":       {Device: "eth2", Physical: true},
+		"eth3":       {Device: "eth3", Physical: true},
+		"bond0":      {Device: "bond0", BondMasterOf: []xenapi.BondRef{"bond0"}},
+	}
+	vlanRecords := fakeRecords[xenapi.VLANRef, xenapi.VLANRecord]{
+		"vlan-eth0":       {TaggedPIF: "eth0"},
+		"vlan-eth1-sriov": {TaggedPIF: "eth1-sriov"},
+		"vlan-bond0":      {TaggedPIF: "bond0"},
+	}
+	bondRecords := fakeRecords[xenapi.BondRef, xenapi.BondRecord]{
+		"bond0": {Master: "bond0", Slaves: []xenapi.PIFRef{"eth3", "eth2"}},
+	}
+	defer func(pif pifClient, vlan vlanClient, bond bondClient) {
+		pifAPI, vlanAPI, bondAPI = pif, vlan, bond
+	}(pifAPI, vlanAPI, bondAPI)
+	pifAPI, vlanAPI, bondAPI = pifRecords, vlanRecords, bondRecords
+
+	testCases := []struct {
+		pifRecord   xenapi.PIFRecord
+		expected    string
+		expectedErr bool
+	}{
+		{pifRecord: xenapi.PIFRecord{Device: "eth0", Physical: true, VLANMasterOf: "OpaqueRef:NULL"}, expected: "NIC 0"},
+		{pifRecord: xenapi.PIFRecord{Device: "eth0", VLANMasterOf: "vlan-eth0"}, expected: "NIC 0"},
+		{pifRecord: xenapi.PIFRecord{Device: "eth1", VLANMasterOf: "vlan-eth1-sriov"}, expected: "NIC-SR-IOV 1"},
+		{pifRecord: xenapi.PIFRecord{Device: "bond0", VLANMasterOf: "vlan-bond0"}, expected: "Bond 2+3"},
+		{pifRecord: xenapi.PIFRecord{Device: "eth0", VLANMasterOf: "vlan-missing"}, expected: "NIC 0", expectedErr: true},
+		{pifRecord: xenapi.PIFRecord{Device: "xenbr0"}, expected: ""},
+	}
+	for _, tc := range testCases {
+		name, err := getNICFromPIF(nil, tc.pifRecord)
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("getNICFromPIF(%v) returned error %v, expected error %t", tc.pifRecord, err, tc.expectedErr)
+		}
+		if name != tc.expected {
+			t.Errorf("getNICFromPIF(%v) = %q, expected %q", tc.pifRecord, name, tc.expected)
+		}
+	}
+}
+
+func TestExclusiveNetworkPurposeValidator(t *testing.T) {
+	testCases := []struct {
+		purpose     []string
+		expectedErr bool
+	}{
+		{purpose: []string{}, expectedErr: false},
+		{purpose: []string{"nbd"}, expectedErr: false},
+		{purpose: []string{"insecure_nbd"}, expectedErr: false},
+		{purpose: []string{"nbd", "insecure_nbd"}, expectedErr: true},
+	}
+	for _, tc := range testCases {
+		config, diags := types.SetValueFrom(context.Background(), types.StringType, tc.purpose)
+		if diags.HasError() {
+			t.Fatalf("unable to build the purpose set %v", tc.purpose)
+		}
+		req := validator.SetRequest{Path: path.Root("purpose"), ConfigValue: config}
+		resp := &validator.SetResponse{}
+		exclusiveNetworkPurposeValidator{}.ValidateSet(context.Background(), req, resp)
+		if resp.Diagnostics.HasError() != tc.expectedErr {
+			t.Errorf("exclusiveNetworkPurposeValidator(%v) returned diagnostics %v, expected error %t", tc.purpose, resp.Diagnostics, tc.expectedErr)
+		}
+	}
+}
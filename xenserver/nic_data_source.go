@@ -25,7 +25,8 @@ func NewNICDataSource() datasource.DataSource {
 
 // nicDataSource is the data source implementation.
 type nicDataSource struct {
-	session *xenapi.Session
+	session          *xenapi.Session
+	operationLimiter operationLimiter
 }
 
 // Metadata returns the data source type name.
@@ -43,6 +44,15 @@ func (d *nicDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, re
 				MarkdownDescription: "The type of the network, choose one of [`\"bond\"` - Bonded networks | `\"vlan\"` - External networks | `\"sriov\"` - SR-IOV networks | `\"private\"` - Single-Server Private networks], learn more on [page](https://docs.xenserver.com/en-us/xenserver/8/networking.html#xenserver-networking-overview).",
 				Optional:            true,
 			},
+			"available_only": schema.BoolAttribute{
+				MarkdownDescription: "Only return the NICs which are not used by any SR-IOV network yet, default to be `true`. It's only applied when `network_type` is `\"sriov\"`.",
+				Optional:            true,
+			},
+			"capabilities": schema.SetAttribute{
+				MarkdownDescription: "Only return the NICs which have all the capabilities on at least one host in the pool, eg. `[\"sriov\"]`. The bond NICs are not returned when it's set.",
+				Optional:            true,
+				ElementType:         types.StringType,
+			},
 			"data_items": schema.ListAttribute{
 				MarkdownDescription: "The return list of available NICs for selected network type, eg. `\"NIC 0\"`, `\"Bond 0+1\"`, `\"NIC-SR-IOV 0\"`.",
 				Computed:            true,
@@ -66,6 +76,7 @@ func (d *nicDataSource) Configure(_ context.Context, req datasource.ConfigureReq
 		return
 	}
 	d.session = providerData.session
+	d.operationLimiter = providerData.operationLimiter
 }
 
 func (d *nicDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
@@ -75,16 +86,26 @@ func (d *nicDataSource) Read(ctx context.Context, req datasource.ReadRequest, re
 		return
 	}
 
-	bondNICs, err := getBondNICs(d.session)
+	ctx, done, err := d.operationLimiter.start(ctx)
 	if err != nil {
-		resp.Diagnostics.AddError("Failed to get bond type NICs", err.Error())
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
 		return
 	}
-	pifRecords, err := xenapi.PIF.GetAllRecords(d.session)
+	defer done()
+
+	pifRecords, err := withSessionRetry(d.session, func() (map[xenapi.PIFRef]xenapi.PIFRecord, error) {
+		return xenapi.PIF.GetAllRecords(d.session)
+	})
 	if err != nil {
 		resp.Diagnostics.AddError("Failed to get PIF records", err.Error())
 		return
 	}
+	bondRecords, err := xenapi.Bond.GetAllRecords(d.session)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to get bond records", err.Error())
+		return
+	}
+	bondNICs := getBondNICs(bondRecords, pifRecords)
 	physicalWithoutBondNICs := getPhysicalWithoutBondNICs(pifRecords)
 	nonPhysicalSRIOVNICs := getNonPhysicalSRIOVNICs(pifRecords)
 
@@ -96,13 +117,22 @@ func (d *nicDataSource) Read(ctx context.Context, req datasource.ReadRequest, re
 		case "bond":
 			availableNICs = physicalWithoutBondNICs
 		case "sriov":
-			availableNICs = getPhysicalSRIOVNICs(pifRecords, true)
+			availableOnly := data.AvailableOnly.IsNull() || data.AvailableOnly.ValueBool()
+			availableNICs = getPhysicalSRIOVNICs(pifRecords, availableOnly)
 		default:
 			availableNICs = []string{}
 		}
 	} else {
 		availableNICs = slices.Concat(bondNICs, getPhysicalNICs(pifRecords), nonPhysicalSRIOVNICs)
 	}
+	if !data.Capabilities.IsNull() {
+		var capabilities []string
+		resp.Diagnostics.Append(data.Capabilities.ElementsAs(ctx, &capabilities, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		availableNICs = filterNICsByCapabilities(availableNICs, pifRecords, capabilities)
+	}
 	data.DataItems = unique(availableNICs)
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
@@ -2,9 +2,12 @@ package xenserver
 
 import (
 	"fmt"
+	"slices"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+
+	"xenapi"
 )
 
 func testAccNICDataSourceConfig(network_type string) string {
@@ -15,6 +18,16 @@ data "xenserver_nic" "test_nic_data" {
 `, network_type)
 }
 
+func testAccNICDataSourceFilterConfig(network_type string, available_only bool, capabilities string) string {
+	return fmt.Sprintf(`
+data "xenserver_nic" "test_nic_data" {
+	network_type   = "%s"
+	available_only = %t
+	capabilities   = %s
+}
+`, network_type, available_only, capabilities)
+}
+
 func TestAccNICDataSource(t *testing.T) {
 	resource.Test(t, resource.TestCase{
 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
@@ -26,6 +39,78 @@ func TestAccNICDataSource(t *testing.T) {
 					resource.TestCheckResourceAttrSet("data.xenserver_nic.test_nic_data", "data_items.#"),
 				),
 			},
+			{
+				Config: providerConfig + testAccNICDataSourceFilterConfig("sriov", false, `["sriov"]`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.xenserver_nic.test_nic_data", "network_type", "sriov"),
+					resource.TestCheckResourceAttr("data.xenserver_nic.test_nic_data", "available_only", "false"),
+					resource.TestCheckResourceAttrSet("data.xenserver_nic.test_nic_data", "data_items.#"),
+				),
+			},
 		},
 	})
 }
+
+func TestGetBondNICs(t *testing.T) {
+	// both hosts have the bond on eth0+eth1 with different bond device names,
+	// host1 has another bond on eth2+eth3 with the same device name as the bond on host2
+	pifRecords := map[xenapi.PIFRef]xenapi.PIFRecord{
+		"host1-eth0":  {Device: "eth0"},
+		"host1-eth1":  {Device: "eth1"},
+		"host1-eth2":  {Device: "eth2"},
+		"host1-eth3":  {Device: "eth3"},
+		"host1-bond0": {Device: "bond0"},
+		"host1-bond1": {Device: "bond1"},
+		"host2-eth0":  {Device: "eth0"},
+		"host2-eth1":  {Device: "eth1"},
+		"host2-bond1": {Device: "bond1"},
+	}
+	bondRecords := map[xenapi.BondRef]xenapi.BondRecord{
+		"bond-host1-01": {Master: "host1-bond0", Slaves: []xenapi.PIFRef{"host1-eth1", "host1-eth0"}},
+		"bond-host1-23": {Master: "host1-bond1", Slaves: []xenapi.PIFRef{"host1-eth2", "host1-eth3"}},
+		"bond-host2-01": {Master: "host2-bond1", Slaves: []xenapi.PIFRef{"host2-eth0", "host2-eth1"}},
+	}
+
+	testCases := []struct {
+		bondRecords map[xenapi.BondRef]xenapi.BondRecord
+		expected    []string
+	}{
+		{bondRecords: bondRecords, expected: []string{"Bond 0+1", "Bond 2+3"}},
+		{bondRecords: map[xenapi.BondRef]xenapi.BondRecord{}, expected: nil},
+	}
+	for _, tc := range testCases {
+		nics := getBondNICs(tc.bondRecords, pifRecords)
+		if !slices.Equal(nics, tc.expected) {
+			t.Errorf("getBondNICs() = %v, expected %v", nics, tc.expected)
+		}
+	}
+}
+
+func TestFilterNICsByCapabilities(t *testing.T) {
+	// eth1 is SR-IOV capable on host1 only
+	pifRecords := map[xenapi.PIFRef]xenapi.PIFRecord{
+		"host1-eth0":  {Device: "eth0", Physical: true, Capabilities: []string{"fcoe"}},
+		"host1-eth1":  {Device: "eth1", Physical: true, Capabilities: []string{"fcoe", "sriov"}},
+		"host1-bond0": {Device: "bond0", Capabilities: []string{"sriov"}},
+		"host2-eth0":  {Device: "eth0", Physical: true, Capabilities: []string{}},
+		"host2-eth1":  {Device: "eth1", Physical: true, Capabilities: []string{"fcoe"}},
+	}
+	nics := []string{"Bond 0+1", "NIC 0", "NIC 1", "NIC-SR-IOV 1"}
+
+	testCases := []struct {
+		capabilities []string
+		expected     []string
+	}{
+		{capabilities: []string{}, expected: []string{"NIC 0", "NIC 1", "NIC-SR-IOV 1"}},
+		{capabilities: []string{"sriov"}, expected: []string{"NIC 1", "NIC-SR-IOV 1"}},
+		{capabilities: []string{"fcoe", "sriov"}, expected: []string{"NIC 1", "NIC-SR-IOV 1"}},
+		{capabilities: []string{"fcoe"}, expected: []string{"NIC 0", "NIC 1", "NIC-SR-IOV 1"}},
+		{capabilities: []string{"unknown"}, expected: []string{}},
+	}
+	for _, tc := range testCases {
+		filtered := filterNICsByCapabilities(nics, pifRecords, tc.capabilities)
+		if !slices.Equal(filtered, tc.expected) {
+			t.Errorf("filterNICsByCapabilities(%v) = %v, expected %v", tc.capabilities, filtered, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package xenserver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"xenapi"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var (
+	_ resource.Resource                = &pbdResource{}
+	_ resource.ResourceWithConfigure   = &pbdResource{}
+	_ resource.ResourceWithImportState = &pbdResource{}
+)
+
+func NewPBDResource() resource.Resource {
+	return &pbdResource{}
+}
+
+// pbdResource defines the resource implementation.
+type pbdResource struct {
+	session          *xenapi.Session
+	operationLimiter operationLimiter
+}
+
+func (r *pbdResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_pbd"
+}
+
+func (r *pbdResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Provides a physical block device (PBD) resource, which connects a host to a storage repository. It's useful to attach an existing shared SR to a newly joined host, or to use a different device config per host, without recreating the SR." +
+			"\n\n-> **Note:** The PBD is unplugged and destroyed with the resource, the SR is kept.",
+		Attributes: pbdSchema(),
+	}
+}
+
+// Set the parameter of the resource, pass value from provider
+func (r *pbdResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+	providerData, ok := req.ProviderData.(*xsProvider)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *xenserver.xsProvider, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+	r.session = providerData.session
+	r.operationLimiter = providerData.operationLimiter
+}
+
+func (r *pbdResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data pbdResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	tflog.Debug(ctx, "Creating PBD...")
+	pbdRef, err := createPBD(ctx, r.session, data)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to create PBD",
+			err.Error(),
+		)
+		if string(pbdRef) != "" {
+			err = cleanupPBDResource(r.session, pbdRef)
+			if err != nil {
+				resp.Diagnostics.AddError(
+					"Error cleaning up PBD resource",
+					err.Error(),
+				)
+			}
+		}
+		return
+	}
+	pbdRecord, err := xenapi.PBD.GetRecord(r.session, pbdRef)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get PBD record",
+			err.Error(),
+		)
+		err = cleanupPBDResource(r.session, pbdRef)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Error cleaning up PBD resource",
+				err.Error(),
+			)
+		}
+		return
+	}
+	updatePBDResourceModelComputed(pbdRecord, &data)
+	tflog.Debug(ctx, "PBD created")
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *pbdResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data pbdResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	// Overwrite data with refreshed resource state
+	pbdRef, err := withSessionRetry(r.session, func() (xenapi.PBDRef, error) {
+		return xenapi.PBD.GetByUUID(r.session, data.UUID.ValueString())
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get PBD ref",
+			err.Error(),
+		)
+		return
+	}
+	pbdRecord, err := xenapi.PBD.GetRecord(r.session, pbdRef)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get PBD record",
+			err.Error(),
+		)
+		return
+	}
+	err = updatePBDResourceModel(ctx, r.session, pbdRecord, &data)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to update the fields of PBDResourceModel",
+			err.Error(),
+		)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *pbdResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state pbdResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Checking if configuration changes are allowed
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	err = pbdResourceModelUpdateCheck(plan, state)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error update xenserver_pbd configuration",
+			err.Error(),
+		)
+		return
+	}
+
+	// Update the resource with new configuration
+	pbdRef, err := withSessionRetry(r.session, func() (xenapi.PBDRef, error) {
+		return xenapi.PBD.GetByUUID(r.session, plan.UUID.ValueString())
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get PBD ref",
+			err.Error(),
+		)
+		return
+	}
+	err = setPBDPlugged(r.session, pbdRef, plan.Plugged.ValueBool())
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to update PBD resource",
+			err.Error(),
+		)
+		return
+	}
+	pbdRecord, err := xenapi.PBD.GetRecord(r.session, pbdRef)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get PBD record",
+			err.Error(),
+		)
+		return
+	}
+	updatePBDResourceModelComputed(pbdRecord, &plan)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *pbdResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data pbdResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	pbdRef, err := withSessionRetry(r.session, func() (xenapi.PBDRef, error) {
+		return xenapi.PBD.GetByUUID(r.session, data.UUID.ValueString())
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get PBD ref",
+			err.Error(),
+		)
+		return
+	}
+	err = cleanupPBDResource(r.session, pbdRef)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to delete PBD resource",
+			err.Error(),
+		)
+		return
+	}
+}
+
+func (r *pbdResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("uuid"), req, resp)
+}
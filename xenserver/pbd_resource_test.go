@@ -0,0 +1,38 @@
+package xenserver
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func testAccPBDResourceConfig(extra_config string) string {
+	return fmt.Sprintf(`
+resource "xenserver_sr" "test_sr" {
+	name_label = "Test PBD SR"
+	type       = "dummy"
+	shared     = false
+}
+
+resource "xenserver_pbd" "test_pbd" {
+	sr_uuid   = xenserver_sr.test_sr.uuid
+	host_uuid = xenserver_sr.test_sr.host
+	%s
+}
+`, extra_config)
+}
+
+func TestAccPBDResource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// The SR is already connected to its host by the PBD created with it
+			{
+				Config:      providerConfig + testAccPBDResourceConfig(""),
+				ExpectError: regexp.MustCompile(`the host is already connected to the SR`),
+			},
+		},
+	})
+}
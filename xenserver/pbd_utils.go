@@ -0,0 +1,206 @@
+package xenserver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"xenapi"
+)
+
+type pbdResourceModel struct {
+	SR           types.String `tfsdk:"sr_uuid"`
+	Host         types.String `tfsdk:"host_uuid"`
+	DeviceConfig types.Map    `tfsdk:"device_config"`
+	Plugged      types.Bool   `tfsdk:"plugged"`
+	UUID         types.String `tfsdk:"uuid"`
+	ID           types.String `tfsdk:"id"`
+}
+
+func pbdSchema() map[string]schema.Attribute {
+	return map[string]schema.Attribute{
+		"sr_uuid": schema.StringAttribute{
+			MarkdownDescription: "The UUID of the storage repository to connect the host to." +
+				"\n\n-> **Note:** `sr_uuid` is not allowed to be updated.",
+			Required: true,
+		},
+		"host_uuid": schema.StringAttribute{
+			MarkdownDescription: "The UUID of the host to connect to the storage repository." +
+				"\n\n-> **Note:** `host_uuid` is not allowed to be updated.",
+			Required: true,
+		},
+		"device_config": schema.MapAttribute{
+			MarkdownDescription: "The device config that will be passed to backend SR driver on the host, default to be `{}`. Use the `*_secret` keys with `xenserver_secret` for the passwords." +
+				"\n\n-> **Note:** `device_config` is not allowed to be updated.",
+			Optional:    true,
+			Computed:    true,
+			Default:     mapdefault.StaticValue(types.MapValueMust(types.StringType, map[string]attr.Value{})),
+			ElementType: types.StringType,
+		},
+		"plugged": schema.BoolAttribute{
+			MarkdownDescription: "True if the PBD is plugged, which makes the storage repository available on the host, default to be `true`.",
+			Optional:            true,
+			Computed:            true,
+			Default:             booldefault.StaticBool(true),
+		},
+		"uuid": schema.StringAttribute{
+			MarkdownDescription: "The UUID of the PBD.",
+			Computed:            true,
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.UseStateForUnknown(),
+			},
+		},
+		"id": schema.StringAttribute{
+			MarkdownDescription: "The test ID of the PBD.",
+			Computed:            true,
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.UseStateForUnknown(),
+			},
+		},
+	}
+}
+
+// findPBD returns the PBD which connects the host to the SR, or an empty ref if there is none
+func findPBD(session *xenapi.Session, srRef xenapi.SRRef, hostRef xenapi.HostRef) (xenapi.PBDRef, error) {
+	var pbdRef xenapi.PBDRef
+	pbdRefs, err := xenapi.SR.GetPBDs(session, srRef)
+	if err != nil {
+		return pbdRef, errors.New(err.Error())
+	}
+	for _, ref := range pbdRefs {
+		pbdHostRef, err := xenapi.PBD.GetHost(session, ref)
+		if err != nil {
+			return pbdRef, errors.New(err.Error())
+		}
+		if pbdHostRef == hostRef {
+			return ref, nil
+		}
+	}
+	return pbdRef, nil
+}
+
+func createPBD(ctx context.Context, session *xenapi.Session, data pbdResourceModel) (xenapi.PBDRef, error) {
+	var pbdRef xenapi.PBDRef
+	srRef, err := withSessionRetry(session, func() (xenapi.SRRef, error) {
+		return xenapi.SR.GetByUUID(session, data.SR.ValueString())
+	})
+	if err != nil {
+		return pbdRef, errors.New(err.Error())
+	}
+	hostRef, err := xenapi.Host.GetByUUID(session, data.Host.ValueString())
+	if err != nil {
+		return pbdRef, errors.New(err.Error())
+	}
+	existingRef, err := findPBD(session, srRef, hostRef)
+	if err != nil {
+		return pbdRef, err
+	}
+	if existingRef != "" {
+		uuid, err := xenapi.PBD.GetUUID(session, existingRef)
+		if err != nil {
+			return pbdRef, errors.New(err.Error())
+		}
+		return pbdRef, errors.New("the host is already connected to the SR with PBD " + uuid + ", import it instead")
+	}
+
+	record := xenapi.PBDRecord{
+		SR:          srRef,
+		Host:        hostRef,
+		OtherConfig: map[string]string{},
+	}
+	diags := data.DeviceConfig.ElementsAs(ctx, &record.DeviceConfig, false)
+	if diags.HasError() {
+		return pbdRef, errors.New("unable to access PBD device config")
+	}
+	pbdRef, err = xenapi.PBD.Create(session, record)
+	if err != nil {
+		return pbdRef, errors.New(err.Error())
+	}
+	err = setPBDPlugged(session, pbdRef, data.Plugged.ValueBool())
+	if err != nil {
+		return pbdRef, err
+	}
+	return pbdRef, nil
+}
+
+func setPBDPlugged(session *xenapi.Session, ref xenapi.PBDRef, plugged bool) error {
+	currentlyAttached, err := xenapi.PBD.GetCurrentlyAttached(session, ref)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	if currentlyAttached == plugged {
+		return nil
+	}
+	if plugged {
+		err = xenapi.PBD.Plug(session, ref)
+	} else {
+		err = xenapi.PBD.Unplug(session, ref)
+	}
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	return nil
+}
+
+func updatePBDResourceModel(ctx context.Context, session *xenapi.Session, record xenapi.PBDRecord, data *pbdResourceModel) error {
+	srUUID, err := xenapi.SR.GetUUID(session, record.SR)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	data.SR = types.StringValue(srUUID)
+	hostUUID, err := xenapi.Host.GetUUID(session, record.Host)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	data.Host = types.StringValue(hostUUID)
+	var diags diag.Diagnostics
+	data.DeviceConfig, diags = types.MapValueFrom(ctx, types.StringType, record.DeviceConfig)
+	if diags.HasError() {
+		return errors.New("unable to access PBD device config")
+	}
+	data.Plugged = types.BoolValue(record.CurrentlyAttached)
+	updatePBDResourceModelComputed(record, data)
+	return nil
+}
+
+func updatePBDResourceModelComputed(record xenapi.PBDRecord, data *pbdResourceModel) {
+	data.UUID = types.StringValue(record.UUID)
+	data.ID = types.StringValue(record.UUID)
+}
+
+func pbdResourceModelUpdateCheck(plan pbdResourceModel, state pbdResourceModel) error {
+	if plan.SR != state.SR {
+		return errors.New(`"sr_uuid" doesn't expected to be updated`)
+	}
+	if plan.Host != state.Host {
+		return errors.New(`"host_uuid" doesn't expected to be updated`)
+	}
+	if !plan.DeviceConfig.Equal(state.DeviceConfig) {
+		return errors.New(`"device_config" doesn't expected to be updated`)
+	}
+	return nil
+}
+
+func cleanupPBDResource(session *xenapi.Session, ref xenapi.PBDRef) error {
+	uuid, err := xenapi.PBD.GetUUID(session, ref)
+	if err != nil {
+		return cleanupError("PBD", string(ref), err)
+	}
+	err = setPBDPlugged(session, ref, false)
+	if err != nil {
+		return cleanupError("PBD", uuid, err)
+	}
+	err = xenapi.PBD.Destroy(session, ref)
+	if err != nil {
+		return cleanupError("PBD", uuid, err)
+	}
+	return nil
+}
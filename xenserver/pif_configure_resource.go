@@ -29,7 +29,8 @@ func NewPIFConfigureResource() resource.Resource {
 
 // pifConfigureResource defines the resource implementation.
 type pifConfigureResource struct {
-	session *xenapi.Session
+	session          *xenapi.Session
+	operationLimiter operationLimiter
 }
 
 func (r *pifConfigureResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -49,11 +50,11 @@ func (r *pifConfigureResource) Schema(_ context.Context, _ resource.SchemaReques
 				Optional:            true,
 			},
 			"interface": schema.SingleNestedAttribute{
-				MarkdownDescription: "The IP interface of the PIF. Currently only support IPv4.",
+				MarkdownDescription: "The IP interface of the PIF. The current IPv4 and IPv6 configuration is read back from the PIF, so the changes made on the host are detected.",
 				Optional:            true,
 				Attributes: map[string]schema.Attribute{
 					"name_label": schema.StringAttribute{
-						MarkdownDescription: "The name of the interface in IP Address Configuration.",
+						MarkdownDescription: "The name of the interface in IP Address Configuration, which is stored as the management purpose of the PIF. The management purpose is removed when `name_label` is removed from the configuration.",
 						Optional:            true,
 					},
 					"mode": schema.StringAttribute{
@@ -66,18 +67,40 @@ func (r *pifConfigureResource) Schema(_ context.Context, _ resource.SchemaReques
 					"ip": schema.StringAttribute{
 						MarkdownDescription: "The IP address.",
 						Optional:            true,
+						Computed:            true,
 					},
 					"gateway": schema.StringAttribute{
 						MarkdownDescription: "The IP gateway.",
 						Optional:            true,
+						Computed:            true,
 					},
 					"netmask": schema.StringAttribute{
 						MarkdownDescription: "The IP netmask.",
 						Optional:            true,
+						Computed:            true,
 					},
 					"dns": schema.StringAttribute{
 						MarkdownDescription: "Comma separated list of the IP addresses of the DNS servers to use.",
 						Optional:            true,
+						Computed:            true,
+					},
+					"ipv6_mode": schema.StringAttribute{
+						MarkdownDescription: "The IPv6 configuration mode of this PIF, for example, `\"None\"`, `\"DHCP\"`, `\"Static\"`, `\"Autoconf\"`. The IPv6 configuration is left as it is if not set.",
+						Optional:            true,
+						Computed:            true,
+						Validators: []validator.String{
+							stringvalidator.OneOf("None", "DHCP", "Static", "Autoconf"),
+						},
+					},
+					"ipv6": schema.StringAttribute{
+						MarkdownDescription: "The IPv6 address in CIDR format, for example, `\"2001:db8::10/64\"`. Used when `ipv6_mode` is `\"Static\"`.",
+						Optional:            true,
+						Computed:            true,
+					},
+					"ipv6_gateway": schema.StringAttribute{
+						MarkdownDescription: "The IPv6 gateway. Used when `ipv6_mode` is `\"Static\"`.",
+						Optional:            true,
+						Computed:            true,
 					},
 				},
 			},
@@ -107,6 +130,7 @@ func (r *pifConfigureResource) Configure(_ context.Context, req resource.Configu
 		return
 	}
 	r.session = providerData.session
+	r.operationLimiter = providerData.operationLimiter
 }
 
 func (r *pifConfigureResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
@@ -116,7 +140,14 @@ func (r *pifConfigureResource) Create(ctx context.Context, req resource.CreateRe
 		return
 	}
 
-	err := pifConfigureResourceModelUpdate(ctx, r.session, data)
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	err = pifConfigureResourceModelUpdate(ctx, r.session, data, pifConfigureResourceModel{})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to update PIF configuration",
@@ -124,8 +155,23 @@ func (r *pifConfigureResource) Create(ctx context.Context, req resource.CreateRe
 		)
 		return
 	}
+	pifRecord, err := getPIFRecordByUUID(r.session, data.UUID.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get PIF record",
+			err.Error(),
+		)
+		return
+	}
+	err = updatePIFConfigureResourceModel(ctx, pifRecord, &data)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to update the fields of PIFConfigureResourceModel",
+			err.Error(),
+		)
+		return
+	}
 
-	data.ID = data.UUID
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
@@ -138,18 +184,52 @@ func (r *pifConfigureResource) Read(ctx context.Context, req resource.ReadReques
 		return
 	}
 
-	data.ID = data.UUID
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	pifRecord, err := getPIFRecordByUUID(r.session, data.UUID.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get PIF record",
+			err.Error(),
+		)
+		return
+	}
+	err = updatePIFConfigureResourceModel(ctx, pifRecord, &data)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to update the fields of PIFConfigureResourceModel",
+			err.Error(),
+		)
+		return
+	}
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
 func (r *pifConfigureResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
-	var plan pifConfigureResourceModel
+	var plan, state pifConfigureResourceModel
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
 
-	err := pifConfigureResourceModelUpdate(ctx, r.session, plan)
+	err = pifConfigureResourceModelUpdate(ctx, r.session, plan, state)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to update PIF configuration",
@@ -157,8 +237,23 @@ func (r *pifConfigureResource) Update(ctx context.Context, req resource.UpdateRe
 		)
 		return
 	}
+	pifRecord, err := getPIFRecordByUUID(r.session, plan.UUID.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get PIF record",
+			err.Error(),
+		)
+		return
+	}
+	err = updatePIFConfigureResourceModel(ctx, pifRecord, &plan)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to update the fields of PIFConfigureResourceModel",
+			err.Error(),
+		)
+		return
+	}
 
-	plan.ID = plan.UUID
 	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
 
@@ -53,6 +53,8 @@ func TestAccPIFConfigureResource(t *testing.T) {
 				Check: resource.ComposeAggregateTestCheckFunc(
 					resource.TestCheckResourceAttr("xenserver_pif_configure.pif_update", "disallow_unplug", "true"),
 					resource.TestCheckResourceAttr("xenserver_pif_configure.pif_update", "interface.mode", "DHCP"),
+					resource.TestCheckResourceAttrSet("xenserver_pif_configure.pif_update", "interface.ip"),
+					resource.TestCheckResourceAttrSet("xenserver_pif_configure.pif_update", "interface.ipv6_mode"),
 				),
 			},
 			// Revert changes
@@ -67,3 +69,21 @@ func TestAccPIFConfigureResource(t *testing.T) {
 		},
 	})
 }
+
+func TestGetPIFIPv6Address(t *testing.T) {
+	testCases := []struct {
+		addresses []string
+		expected  string
+	}{
+		{[]string{}, ""},
+		{[]string{"fe80::1/64"}, ""},
+		{[]string{"fe80::1/64", "2001:db8::10/64"}, "2001:db8::10/64"},
+		{[]string{"2001:db8::10"}, "2001:db8::10"},
+	}
+	for _, tc := range testCases {
+		result := getPIFIPv6Address(tc.addresses)
+		if result != tc.expected {
+			t.Errorf("getPIFIPv6Address(%v) = %q, expected %q", tc.addresses, result, tc.expected)
+		}
+	}
+}
@@ -25,7 +25,8 @@ func NewPIFDataSource() datasource.DataSource {
 
 // pifDataSource is the data source implementation.
 type pifDataSource struct {
-	session *xenapi.Session
+	session          *xenapi.Session
+	operationLimiter operationLimiter
 }
 
 // Metadata returns the data source type name.
@@ -224,6 +225,7 @@ func (d *pifDataSource) Configure(_ context.Context, req datasource.ConfigureReq
 		return
 	}
 	d.session = providerData.session
+	d.operationLimiter = providerData.operationLimiter
 }
 
 // Read refreshes the Terraform state with the latest data.
@@ -236,7 +238,16 @@ func (d *pifDataSource) Read(ctx context.Context, req datasource.ReadRequest, re
 		return
 	}
 
-	pifRecords, err := xenapi.PIF.GetAllRecords(d.session)
+	ctx, done, err := d.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	pifRecords, err := withSessionRetry(d.session, func() (map[xenapi.PIFRef]xenapi.PIFRecord, error) {
+		return xenapi.PIF.GetAllRecords(d.session)
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to read PIF records",
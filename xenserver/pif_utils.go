@@ -6,6 +6,7 @@ import (
 	"net"
 	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
@@ -193,12 +194,27 @@ type pifConfigureResourceModel struct {
 }
 
 type InterfaceObject struct {
-	NameLabel types.String `tfsdk:"name_label"`
-	Mode      types.String `tfsdk:"mode"`
-	IP        types.String `tfsdk:"ip"`
-	Gateway   types.String `tfsdk:"gateway"`
-	Netmask   types.String `tfsdk:"netmask"`
-	DNS       types.String `tfsdk:"dns"`
+	NameLabel   types.String `tfsdk:"name_label"`
+	Mode        types.String `tfsdk:"mode"`
+	IP          types.String `tfsdk:"ip"`
+	Gateway     types.String `tfsdk:"gateway"`
+	Netmask     types.String `tfsdk:"netmask"`
+	DNS         types.String `tfsdk:"dns"`
+	IPv6Mode    types.String `tfsdk:"ipv6_mode"`
+	IPv6        types.String `tfsdk:"ipv6"`
+	IPv6Gateway types.String `tfsdk:"ipv6_gateway"`
+}
+
+var interfaceObjectAttrTypes = map[string]attr.Type{
+	"name_label":   types.StringType,
+	"mode":         types.StringType,
+	"ip":           types.StringType,
+	"gateway":      types.StringType,
+	"netmask":      types.StringType,
+	"dns":          types.StringType,
+	"ipv6_mode":    types.StringType,
+	"ipv6":         types.StringType,
+	"ipv6_gateway": types.StringType,
 }
 
 func getIPConfigurationMode(mode string) xenapi.IPConfigurationMode {
@@ -216,12 +232,19 @@ func getIPConfigurationMode(mode string) xenapi.IPConfigurationMode {
 	return value
 }
 
-func pifConfigureResourceModelUpdate(ctx context.Context, session *xenapi.Session, data pifConfigureResourceModel) error {
-	pifRef, err := xenapi.PIF.GetByUUID(session, data.UUID.ValueString())
+func pifConfigureResourceModelUpdate(ctx context.Context, session *xenapi.Session, data pifConfigureResourceModel, dataState pifConfigureResourceModel) error {
+	pifRef, err := withSessionRetry(session, func() (xenapi.PIFRef, error) {
+		return xenapi.PIF.GetByUUID(session, data.UUID.ValueString())
+	})
 	if err != nil {
 		return errors.New(err.Error() + ", uuid: " + data.UUID.ValueString())
 	}
 
+	err = updatePIFManagementPurpose(ctx, session, pifRef, data, dataState)
+	if err != nil {
+		return err
+	}
+
 	if !data.DisallowUnplug.IsNull() {
 		err := xenapi.PIF.SetDisallowUnplug(session, pifRef, data.DisallowUnplug.ValueBool())
 		if err != nil {
@@ -251,20 +274,6 @@ func pifConfigureResourceModelUpdate(ctx context.Context, session *xenapi.Sessio
 			return errors.New("unable to read PIF interface config")
 		}
 
-		if !interfaceObject.NameLabel.IsNull() {
-			oc, err := xenapi.PIF.GetOtherConfig(session, pifRef)
-			if err != nil {
-				return errors.New(err.Error())
-			}
-
-			oc["management_purpose"] = interfaceObject.NameLabel.ValueString()
-
-			err = xenapi.PIF.SetOtherConfig(session, pifRef, oc)
-			if err != nil {
-				return errors.New(err.Error())
-			}
-		}
-
 		mode := getIPConfigurationMode(interfaceObject.Mode.ValueString())
 		ip := interfaceObject.IP.ValueString()
 		netmask := interfaceObject.Netmask.ValueString()
@@ -283,8 +292,146 @@ func pifConfigureResourceModelUpdate(ctx context.Context, session *xenapi.Sessio
 				return err
 			}
 		}
+
+		err = reconfigurePIFIPv6(ctx, session, pifRef, interfaceObject)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getInterfaceNameLabel returns the name_label of the interface, it's null if the interface isn't set
+func getInterfaceNameLabel(ctx context.Context, data pifConfigureResourceModel) (types.String, error) {
+	if data.Interface.IsNull() || data.Interface.IsUnknown() {
+		return types.StringNull(), nil
+	}
+	var interfaceObject InterfaceObject
+	diags := data.Interface.As(ctx, &interfaceObject, basetypes.ObjectAsOptions{})
+	if diags.HasError() {
+		return types.StringNull(), errors.New("unable to read PIF interface config")
+	}
+	return interfaceObject.NameLabel, nil
+}
+
+// updatePIFManagementPurpose sets other_config["management_purpose"] from interface.name_label,
+// and removes the key when the name_label is removed from the configuration
+func updatePIFManagementPurpose(ctx context.Context, session *xenapi.Session, pifRef xenapi.PIFRef, data pifConfigureResourceModel, dataState pifConfigureResourceModel) error {
+	nameLabel, err := getInterfaceNameLabel(ctx, data)
+	if err != nil {
+		return err
+	}
+	stateNameLabel, err := getInterfaceNameLabel(ctx, dataState)
+	if err != nil {
+		return err
+	}
+	if nameLabel.IsNull() && stateNameLabel.IsNull() {
+		return nil
+	}
+
+	oc, err := xenapi.PIF.GetOtherConfig(session, pifRef)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+
+	if nameLabel.IsNull() {
+		delete(oc, "management_purpose")
+	} else {
+		oc["management_purpose"] = nameLabel.ValueString()
+	}
+
+	err = xenapi.PIF.SetOtherConfig(session, pifRef, oc)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	return nil
+}
+
+// reconfigurePIFIPv6 reconfigures the IPv6 of the PIF only when it's changed, IPv6 is left as it is if not configured
+func reconfigurePIFIPv6(ctx context.Context, session *xenapi.Session, pifRef xenapi.PIFRef, interfaceObject InterfaceObject) error {
+	if interfaceObject.IPv6Mode.IsNull() || interfaceObject.IPv6Mode.IsUnknown() {
+		return nil
+	}
+	record, err := xenapi.PIF.GetRecord(session, pifRef)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	mode := xenapi.Ipv6ConfigurationMode(interfaceObject.IPv6Mode.ValueString())
+	ipv6 := interfaceObject.IPv6.ValueString()
+	gateway := interfaceObject.IPv6Gateway.ValueString()
+	if record.Ipv6ConfigurationMode == mode && (mode != xenapi.Ipv6ConfigurationModeStatic ||
+		(getPIFIPv6Address(record.IPv6) == ipv6 && record.Ipv6Gateway == gateway)) {
+		return nil
+	}
+
+	dns := interfaceObject.DNS.ValueString()
+	tflog.Debug(ctx, "Reconfigure PIF IPv6 with mode: "+string(mode)+", ipv6: "+ipv6+", gateway: "+gateway+", dns: "+dns)
+	err = xenapi.PIF.ReconfigureIpv6(session, pifRef, mode, ipv6, gateway, dns)
+	if err != nil {
+		tflog.Error(ctx, "unable to update the PIF 'interface' IPv6")
+		return errors.New(err.Error())
+	}
+	return nil
+}
+
+// getPIFIPv6Address returns the first IPv6 address of the PIF which isn't link-local
+func getPIFIPv6Address(addresses []string) string {
+	for _, address := range addresses {
+		ip, _, err := net.ParseCIDR(address)
+		if err != nil {
+			ip = net.ParseIP(address)
+		}
+		if ip != nil && !ip.IsLinkLocalUnicast() {
+			return address
+		}
+	}
+	return ""
+}
+
+func getPIFRecordByUUID(session *xenapi.Session, uuid string) (xenapi.PIFRecord, error) {
+	pifRef, err := xenapi.PIF.GetByUUID(session, uuid)
+	if err != nil {
+		return xenapi.PIFRecord{}, errors.New(err.Error() + ", uuid: " + uuid)
+	}
+	record, err := xenapi.PIF.GetRecord(session, pifRef)
+	if err != nil {
+		return xenapi.PIFRecord{}, errors.New(err.Error())
+	}
+	return record, nil
+}
+
+// updatePIFConfigureResourceModel refreshes the configuration managed by the resource from the PIF record,
+// so the changes made on the host are detected
+func updatePIFConfigureResourceModel(ctx context.Context, record xenapi.PIFRecord, data *pifConfigureResourceModel) error {
+	data.ID = data.UUID
+	if !data.DisallowUnplug.IsNull() {
+		data.DisallowUnplug = types.BoolValue(record.DisallowUnplug)
+	}
+	if data.Interface.IsNull() {
+		return nil
 	}
 
+	var interfaceObject InterfaceObject
+	diags := data.Interface.As(ctx, &interfaceObject, basetypes.ObjectAsOptions{})
+	if diags.HasError() {
+		return errors.New("unable to read PIF interface config")
+	}
+	if !interfaceObject.NameLabel.IsNull() {
+		interfaceObject.NameLabel = types.StringValue(record.OtherConfig["management_purpose"])
+	}
+	interfaceObject.Mode = types.StringValue(string(record.IPConfigurationMode))
+	interfaceObject.IP = types.StringValue(record.IP)
+	interfaceObject.Netmask = types.StringValue(record.Netmask)
+	interfaceObject.Gateway = types.StringValue(record.Gateway)
+	interfaceObject.DNS = types.StringValue(record.DNS)
+	interfaceObject.IPv6Mode = types.StringValue(string(record.Ipv6ConfigurationMode))
+	interfaceObject.IPv6 = types.StringValue(getPIFIPv6Address(record.IPv6))
+	interfaceObject.IPv6Gateway = types.StringValue(record.Ipv6Gateway)
+	data.Interface, diags = types.ObjectValueFrom(ctx, interfaceObjectAttrTypes, interfaceObject)
+	if diags.HasError() {
+		return errors.New("unable to update PIF interface data")
+	}
 	return nil
 }
 
@@ -292,22 +439,23 @@ func checkPIFHasIP(ctx context.Context, session *xenapi.Session, ref xenapi.PIFR
 	// set timeout channel to check if IP address is available
 	timeoutChan := time.After(time.Duration(60) * time.Second)
 	for {
+		ip, err := xenapi.PIF.GetIP(session, ref)
+		if err != nil {
+			tflog.Error(ctx, "unable to get the PIF IP")
+			return errors.New(err.Error())
+		}
+		if isValidIpAddress(net.ParseIP(ip)) {
+			tflog.Debug(ctx, "PIF IP is available: "+ip)
+			return nil
+		}
+
+		tflog.Debug(ctx, "-----> Retry get PIF IP")
 		select {
 		case <-timeoutChan:
 			return errors.New("get PIF IP timeout in 60 seconds, please check if the interface is connected")
-		default:
-			ip, err := xenapi.PIF.GetIP(session, ref)
-			if err != nil {
-				tflog.Error(ctx, "unable to get the PIF IP")
-				return errors.New(err.Error())
-			}
-			if isValidIpAddress(net.ParseIP(ip)) {
-				tflog.Debug(ctx, "PIF IP is available: "+ip)
-				return nil
-			}
-
-			tflog.Debug(ctx, "-----> Retry get PIF IP")
-			time.Sleep(5 * time.Second)
+		case <-ctx.Done():
+			return errors.New("get PIF IP stopped: " + ctx.Err().Error())
+		case <-time.After(5 * time.Second):
 		}
 	}
 }
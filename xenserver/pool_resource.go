@@ -17,6 +17,7 @@ var (
 	_ resource.Resource                = &poolResource{}
 	_ resource.ResourceWithConfigure   = &poolResource{}
 	_ resource.ResourceWithImportState = &poolResource{}
+	_ resource.ResourceWithModifyPlan  = &poolResource{}
 )
 
 func NewPoolResource() resource.Resource {
@@ -25,8 +26,9 @@ func NewPoolResource() resource.Resource {
 
 // poolResource defines the resource implementation.
 type poolResource struct {
-	session         *xenapi.Session
-	coordinatorConf *coordinatorConf
+	session          *xenapi.Session
+	coordinatorConf  *coordinatorConf
+	operationLimiter operationLimiter
 }
 
 func (r *poolResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -57,9 +59,40 @@ func (r *poolResource) Configure(_ context.Context, req resource.ConfigureReques
 	}
 
 	r.session = providerData.session
+	r.operationLimiter = providerData.operationLimiter
 	r.coordinatorConf = &providerData.coordinatorConf
 }
 
+// ModifyPlan refuses to eject the supporters before the data loss on them is acknowledged, and the hosts to
+// share an iSCSI IQN.
+func (r *poolResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+	var plan poolResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	err := checkEjectAcknowledged(ctx, plan)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("acknowledge_data_loss"),
+			"Eject not acknowledged",
+			err.Error(),
+		)
+	}
+	params := getPoolParams(plan)
+	err = checkISCSIIQNsUnique(params.ISCSIIQNs)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("iscsi_iqns"),
+			"Duplicate iSCSI IQN",
+			err.Error(),
+		)
+	}
+}
+
 func (r *poolResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
 	var plan poolResourceModel
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
@@ -67,6 +100,13 @@ func (r *poolResource) Create(ctx context.Context, req resource.CreateRequest, r
 		return
 	}
 
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
 	tflog.Debug(ctx, "Creating pool...")
 	poolParams := getPoolParams(plan)
 
@@ -97,7 +137,7 @@ func (r *poolResource) Create(ctx context.Context, req resource.CreateRequest, r
 		return
 	}
 
-	err = setPool(r.session, poolRef, poolParams)
+	err = setPool(ctx, r.session, poolRef, poolParams)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to set pool in Create stage",
@@ -116,7 +156,7 @@ func (r *poolResource) Create(ctx context.Context, req resource.CreateRequest, r
 		return
 	}
 
-	err = updatePoolResourceModelComputed(r.session, poolRecord, &plan)
+	err = updatePoolResourceModelComputed(ctx, r.session, poolRecord, &plan)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to update the computed fields of PoolResourceModel in Create stage",
@@ -135,7 +175,16 @@ func (r *poolResource) Read(ctx context.Context, req resource.ReadRequest, resp
 		return
 	}
 
-	poolRef, err := xenapi.Pool.GetByUUID(r.session, state.UUID.ValueString())
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	poolRef, err := withSessionRetry(r.session, func() (xenapi.PoolRef, error) {
+		return xenapi.Pool.GetByUUID(r.session, state.UUID.ValueString())
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to get pool ref",
@@ -153,7 +202,7 @@ func (r *poolResource) Read(ctx context.Context, req resource.ReadRequest, resp
 		return
 	}
 
-	err = updatePoolResourceModel(r.session, poolRecord, &state)
+	err = updatePoolResourceModel(ctx, r.session, poolRecord, &state)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to update the computed fields of PoolResourceModel in Read stage",
@@ -176,6 +225,13 @@ func (r *poolResource) Update(ctx context.Context, req resource.UpdateRequest, r
 		return
 	}
 
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
 	poolParams := getPoolParams(plan)
 
 	poolRef, err := getPoolRef(r.session)
@@ -205,7 +261,7 @@ func (r *poolResource) Update(ctx context.Context, req resource.UpdateRequest, r
 		return
 	}
 
-	err = setPool(r.session, poolRef, poolParams)
+	err = setPool(ctx, r.session, poolRef, poolParams)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to set pool in Update stage",
@@ -224,7 +280,7 @@ func (r *poolResource) Update(ctx context.Context, req resource.UpdateRequest, r
 		return
 	}
 
-	err = updatePoolResourceModelComputed(r.session, poolRecord, &plan)
+	err = updatePoolResourceModelComputed(ctx, r.session, poolRecord, &plan)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to update the computed fields of PoolResourceModel in Update stage",
@@ -243,8 +299,17 @@ func (r *poolResource) Delete(ctx context.Context, req resource.DeleteRequest, r
 		return
 	}
 
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
 	tflog.Debug(ctx, "Deleting pool...")
-	poolRef, err := xenapi.Pool.GetByUUID(r.session, state.UUID.ValueString())
+	poolRef, err := withSessionRetry(r.session, func() (xenapi.PoolRef, error) {
+		return xenapi.Pool.GetByUUID(r.session, state.UUID.ValueString())
+	})
 	if err != nil {
 		resp.Diagnostics.AddError("Unable to get pool ref", err.Error())
 		return
@@ -1,11 +1,14 @@
 package xenserver
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"regexp"
 	"testing"
 	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
 )
 
@@ -94,12 +97,17 @@ func testJoinSupporterParams(supporterHost string, supporterUsername string, sup
 `, supporterHost, supporterUsername, supporterPassowd)
 }
 
-func ejectSupporterParams(index string) string {
+func ejectSupporterParams(index string, acknowledge bool) string {
+	acknowledged := ""
+	if acknowledge {
+		acknowledged = "data.xenserver_host.supporter.data_items[" + index + "].uuid"
+	}
 	return fmt.Sprintf(`
 	eject_supporters = [
 		data.xenserver_host.supporter.data_items[%s].uuid
 	]
-`, index)
+	acknowledge_data_loss = [%s]
+`, index, acknowledged)
 }
 
 func TestAccPoolResource(t *testing.T) {
@@ -118,13 +126,15 @@ func TestAccPoolResource(t *testing.T) {
 				Config: providerConfig + testPoolResource("Test Pool A",
 					"Test Pool Join",
 					storageLocation,
-					"",
+					`other_config = { "test_flag" = "1" }`,
 					joinSupporterParams,
 					""),
 				Check: resource.ComposeAggregateTestCheckFunc(
 					resource.TestCheckResourceAttr("xenserver_pool.pool", "name_label", "Test Pool A"),
 					resource.TestCheckResourceAttr("xenserver_pool.pool", "name_description", "Test Pool Join"),
 					resource.TestCheckResourceAttrSet("xenserver_pool.pool", "default_sr"),
+					resource.TestCheckResourceAttr("xenserver_pool.pool", "other_config.%", "1"),
+					resource.TestCheckResourceAttr("xenserver_pool.pool", "other_config.test_flag", "1"),
 				),
 			},
 			// ImportState testing
@@ -134,6 +144,16 @@ func TestAccPoolResource(t *testing.T) {
 				ImportStateVerify:       true,
 				ImportStateVerifyIgnore: []string{"join_supporters"},
 			},
+			// Eject supporter without acknowledging the data loss
+			{
+				Config: providerConfig + testPoolResource("Test Pool B",
+					"Test Pool Eject",
+					storageLocation,
+					"",
+					"",
+					ejectSupporterParams("1", false)),
+				ExpectError: regexp.MustCompile(`Eject not acknowledged`),
+			},
 			// Update and Read testing For Pool eject supporter
 			{
 				Config: providerConfig + testPoolResource("Test Pool B",
@@ -141,10 +161,11 @@ func TestAccPoolResource(t *testing.T) {
 					storageLocation,
 					"",
 					"",
-					ejectSupporterParams("1")),
+					ejectSupporterParams("1", true)),
 				Check: resource.ComposeAggregateTestCheckFunc(
 					resource.TestCheckResourceAttr("xenserver_pool.pool", "name_label", "Test Pool B"),
 					resource.TestCheckResourceAttr("xenserver_pool.pool", "name_description", "Test Pool Eject"),
+					resource.TestCheckResourceAttr("xenserver_pool.pool", "other_config.%", "0"),
 				),
 			},
 			// Update and Read testing For Pool Management Network
@@ -168,3 +189,90 @@ func TestAccPoolResource(t *testing.T) {
 	// sleep 30s to wait for supporters and management network back to enable
 	time.Sleep(30 * time.Second)
 }
+
+func TestISCSIIQNRegex(t *testing.T) {
+	testCases := []struct {
+		iqn      string
+		expected bool
+	}{
+		{iqn: "iqn.2024-01.com.example:host1", expected: true},
+		{iqn: "iqn.2024-01.com.example", expected: true},
+		{iqn: "iqn.2016-04.com.open-iscsi:6b2fd3d8a1c5", expected: true},
+		{iqn: "iqn.24-01.com.example:host1", expected: false},
+		{iqn: "iqn.2024-01.com.example.:host1", expected: false},
+		{iqn: "IQN.2024-01.com.example:host1", expected: false},
+		{iqn: "eui.02004567A425678D", expected: false},
+		{iqn: "", expected: false},
+	}
+	for _, tc := range testCases {
+		if iscsiIQNRegex.MatchString(tc.iqn) != tc.expected {
+			t.Errorf("iscsiIQNRegex.MatchString(%q) = %t, expected %t", tc.iqn, !tc.expected, tc.expected)
+		}
+	}
+}
+
+func TestCheckISCSIIQNsUnique(t *testing.T) {
+	testCases := []struct {
+		iqns        map[string]string
+		expectedErr string
+	}{
+		{iqns: nil},
+		{iqns: map[string]string{"host-a": "iqn.2024-01.com.example:a", "host-b": "iqn.2024-01.com.example:b"}},
+		{
+			iqns:        map[string]string{"host-b": "iqn.2024-01.com.example:a", "host-a": "iqn.2024-01.com.example:a", "host-c": "iqn.2024-01.com.example:c"},
+			expectedErr: "each host should use a different IQN, iqn.2024-01.com.example:a is used by hosts host-a, host-b",
+		},
+	}
+	for _, tc := range testCases {
+		err := checkISCSIIQNsUnique(tc.iqns)
+		errMsg := ""
+		if err != nil {
+			errMsg = err.Error()
+		}
+		if errMsg != tc.expectedErr {
+			t.Errorf("checkISCSIIQNsUnique(%v) returned error %q, expected %q", tc.iqns, errMsg, tc.expectedErr)
+		}
+	}
+}
+
+func TestIsHostSpecificDeviceConfig(t *testing.T) {
+	testCases := []struct {
+		deviceConfig map[string]string
+		expected     bool
+	}{
+		{deviceConfig: map[string]string{"server": "10.0.0.1", "serverpath": "/vms", "nfsversion": "4"}, expected: false},
+		{deviceConfig: map[string]string{"server": `\\10.0.0.1\share`, "username": "user"}, expected: false},
+		{deviceConfig: map[string]string{"target": "10.0.0.1", "targetIQN": "iqn.2024-01.com.example:target", "SCSIid": "36001405abcdef"}, expected: true},
+		{deviceConfig: map[string]string{"device": "/dev/sdb"}, expected: true},
+	}
+	for _, tc := range testCases {
+		hostSpecific := isHostSpecificDeviceConfig(tc.deviceConfig)
+		if hostSpecific != tc.expected {
+			t.Errorf("isHostSpecificDeviceConfig(%v) = %t, expected %t", tc.deviceConfig, hostSpecific, tc.expected)
+		}
+	}
+}
+
+func TestCheckEjectAcknowledged(t *testing.T) {
+	testCases := []struct {
+		ejectSupporters []string
+		acknowledged    []string
+		expectedErr     bool
+	}{
+		{ejectSupporters: nil, acknowledged: nil, expectedErr: false},
+		{ejectSupporters: []string{"host-a"}, acknowledged: []string{"host-a"}, expectedErr: false},
+		{ejectSupporters: []string{"host-a"}, acknowledged: []string{"host-a", "host-b"}, expectedErr: false},
+		{ejectSupporters: []string{"host-a"}, acknowledged: nil, expectedErr: true},
+		{ejectSupporters: []string{"host-a", "host-b"}, acknowledged: []string{"host-a"}, expectedErr: true},
+	}
+	for _, tc := range testCases {
+		ctx := context.Background()
+		var plan poolResourceModel
+		plan.EjectSupporters, _ = types.SetValueFrom(ctx, types.StringType, tc.ejectSupporters)
+		plan.AcknowledgeDataLoss, _ = types.SetValueFrom(ctx, types.StringType, tc.acknowledged)
+		err := checkEjectAcknowledged(ctx, plan)
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("checkEjectAcknowledged(%v, %v) returned error %v, expected error %t", tc.ejectSupporters, tc.acknowledged, err, tc.expectedErr)
+		}
+	}
+}
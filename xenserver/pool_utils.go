@@ -3,16 +3,26 @@ package xenserver
 import (
 	"context"
 	"errors"
+	"fmt"
 	"regexp"
 	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
+	"github.com/hashicorp/terraform-plugin-framework-validators/mapvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapdefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/setdefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 
@@ -26,6 +36,9 @@ type poolResourceModel struct {
 	ManagementNetworkUUID types.String `tfsdk:"management_network"`
 	JoinSupporters        types.Set    `tfsdk:"join_supporters"`
 	EjectSupporters       types.Set    `tfsdk:"eject_supporters"`
+	AcknowledgeDataLoss   types.Set    `tfsdk:"acknowledge_data_loss"`
+	OtherConfig           types.Map    `tfsdk:"other_config"`
+	ISCSIIQNs             types.Map    `tfsdk:"iscsi_iqns"`
 	UUID                  types.String `tfsdk:"uuid"`
 	ID                    types.String `tfsdk:"id"`
 }
@@ -36,11 +49,27 @@ type joinSupporterResourceModel struct {
 	Password types.String `tfsdk:"password"`
 }
 
+type poolVersionDataSourceModel struct {
+	CoordinatorUUID    types.String `tfsdk:"coordinator_uuid"`
+	APIVersionMajor    types.Int64  `tfsdk:"api_version_major"`
+	APIVersionMinor    types.Int64  `tfsdk:"api_version_minor"`
+	APIVersion         types.String `tfsdk:"api_version"`
+	ProductBrand       types.String `tfsdk:"product_brand"`
+	ProductVersion     types.String `tfsdk:"product_version"`
+	ProductVersionText types.String `tfsdk:"product_version_text"`
+	PlatformVersion    types.String `tfsdk:"platform_version"`
+	BuildNumber        types.String `tfsdk:"build_number"`
+	XapiVersion        types.String `tfsdk:"xapi_version"`
+	SoftwareVersion    types.Map    `tfsdk:"software_version"`
+}
+
 type poolParams struct {
 	NameLabel             string
 	NameDescription       string
 	DefaultSRUUID         string
 	ManagementNetworkUUID string
+	OtherConfig           map[string]string
+	ISCSIIQNs             map[string]string
 }
 
 func PoolSchema() map[string]schema.Attribute {
@@ -56,7 +85,7 @@ func PoolSchema() map[string]schema.Attribute {
 			Default:             stringdefault.StaticString(""),
 		},
 		"default_sr": schema.StringAttribute{
-			MarkdownDescription: "The default SR UUID of the pool. this SR should be shared SR.",
+			MarkdownDescription: "The default SR UUID of the pool. this SR should be shared SR and connected to all the hosts of the pool, the unplugged PBDs of the SR are plugged when it is set.",
 			Optional:            true,
 			Computed:            true,
 		},
@@ -72,7 +101,8 @@ func PoolSchema() map[string]schema.Attribute {
 		"join_supporters": schema.SetNestedAttribute{
 			MarkdownDescription: "The set of pool supporters which will join the pool." +
 				"\n\n-> **Note:** 1. It would raise error if a supporter is in both join_supporters and eject_supporters.<br>" +
-				"2. The join operation would be performed only when the host, username, and password are provided.<br>",
+				"2. The join operation would be performed only when the host, username, and password are provided.<br>" +
+				"3. After the join, the supporters are connected to all the shared SRs of the pool, the missing PBDs are created with the device config of the coordinator's PBD, except for the SRs whose device config is host specific, like iSCSI and HBA.<br>",
 			NestedObject: schema.NestedAttributeObject{
 				Attributes: map[string]schema.Attribute{
 					"host": schema.StringAttribute{
@@ -93,9 +123,42 @@ func PoolSchema() map[string]schema.Attribute {
 			Optional: true,
 		},
 		"eject_supporters": schema.SetAttribute{
-			MarkdownDescription: "The set of pool supporters which will be ejected from the pool.",
-			ElementType:         types.StringType,
-			Optional:            true,
+			MarkdownDescription: "The set of pool supporters which will be ejected from the pool." +
+				"\n\n-> **Note:** The ejected hosts are reinstalled and all the data on their local storage is lost, each of them should be listed in `acknowledge_data_loss` to eject it.",
+			ElementType: types.StringType,
+			Optional:    true,
+		},
+		"acknowledge_data_loss": schema.SetAttribute{
+			MarkdownDescription: "The set of host UUIDs in `eject_supporters` acknowledged to be reinstalled and lose all the data on their local storage, default to be `[]`." + "<br />" +
+				"The acknowledgement is given per host, so a host added to `eject_supporters` later is not ejected until it's acknowledged too.",
+			ElementType: types.StringType,
+			Optional:    true,
+			Computed:    true,
+			Default:     setdefault.StaticValue(types.SetValueMust(types.StringType, []attr.Value{})),
+		},
+		"other_config": schema.MapAttribute{
+			MarkdownDescription: "The additional configuration of the pool, for example, `{ \"migration_compression\" = \"true\" }`, default to be `{}`." + "<br />" +
+				"The settings are merged with the existing ones of the pool, only the keys set by terraform are tracked and removed when they are removed from `other_config`." +
+				"\n\n-> **Note:** The keys with the `tf_` prefix are reserved for the provider to record its own state, they are not allowed in `other_config`.",
+			Optional:    true,
+			Computed:    true,
+			ElementType: types.StringType,
+			Default:     mapdefault.StaticValue(types.MapValueMust(types.StringType, map[string]attr.Value{})),
+			Validators: []validator.Map{
+				mapvalidator.KeysAre(reservedKeyPrefixValidator{prefix: vmReservedOtherConfigPrefix}),
+			},
+		},
+		"iscsi_iqns": schema.MapAttribute{
+			MarkdownDescription: "The iSCSI initiator IQNs of the hosts, keyed by the host UUID, for example, `{ \"<host uuid>\" = \"iqn.2024-01.com.example:host1\" }`. The iSCSI targets usually grant the access to the storage by the initiator IQN, so it's required for the iSCSI storage repositories to be attached to all the hosts of the pool." + "<br />" +
+				"Only the hosts in the map are managed, each host should use a different IQN." +
+				"\n\n-> **Note:** The IQN of a host is not allowed to be changed while an iSCSI storage repository is attached to it.",
+			Optional:    true,
+			ElementType: types.StringType,
+			Validators: []validator.Map{
+				mapvalidator.ValueStringsAre(
+					stringvalidator.RegexMatches(iscsiIQNRegex, "the value should be an IQN like \"iqn.2024-01.com.example:host1\""),
+				),
+			},
 		},
 		"uuid": schema.StringAttribute{
 			MarkdownDescription: "The UUID of the pool.",
@@ -122,6 +185,22 @@ func getPoolParams(plan poolResourceModel) poolParams {
 	if !plan.ManagementNetworkUUID.IsUnknown() {
 		params.ManagementNetworkUUID = plan.ManagementNetworkUUID.ValueString()
 	}
+	if !plan.ISCSIIQNs.IsUnknown() && !plan.ISCSIIQNs.IsNull() {
+		params.ISCSIIQNs = make(map[string]string)
+		for key, value := range plan.ISCSIIQNs.Elements() {
+			if value, ok := value.(types.String); ok {
+				params.ISCSIIQNs[key] = value.ValueString()
+			}
+		}
+	}
+	if !plan.OtherConfig.IsUnknown() {
+		params.OtherConfig = make(map[string]string)
+		for key, value := range plan.OtherConfig.Elements() {
+			if value, ok := value.(types.String); ok {
+				params.OtherConfig[key] = value.ValueString()
+			}
+		}
+	}
 
 	return params
 }
@@ -134,7 +213,7 @@ func poolJoin(ctx context.Context, coordinatorSession *xenapi.Session, coordinat
 		return errors.New("unable to access join supporters in config data")
 	}
 	for _, supporter := range joinSupporters {
-		supporterSession, err := loginServer(supporter.Host.ValueString(), supporter.Username.ValueString(), supporter.Password.ValueString())
+		supporterSession, err := loginServer(supporter.Host.ValueString(), supporter.Username.ValueString(), supporter.Password.ValueString(), coordinatorConf.LoginTimeout)
 		if err != nil {
 			if strings.Contains(err.Error(), "HOST_IS_SLAVE") {
 				tflog.Debug(ctx, "Host is already in the pool, continue")
@@ -190,26 +269,146 @@ func poolJoin(ctx context.Context, coordinatorSession *xenapi.Session, coordinat
 		joinedSupporterUUIDs = append(joinedSupporterUUIDs, supporterUUID)
 	}
 
-	return waitAllSupportersLive(ctx, coordinatorSession, joinedSupporterUUIDs)
+	err := waitAllSupportersLive(ctx, coordinatorSession, joinedSupporterUUIDs)
+	if err != nil {
+		return err
+	}
+
+	return connectSharedSRs(ctx, coordinatorSession, joinedSupporterUUIDs)
+}
+
+// hostSpecificDeviceConfigKeys are the PBD device config keys whose values may differ between the hosts, e.g. the
+// SCSI ID or the device path of a LUN seen through the host's own HBA or iSCSI initiator
+var hostSpecificDeviceConfigKeys = []string{"SCSIid", "device", "path", "adapter", "localIQN"}
+
+// isHostSpecificDeviceConfig returns true if the device config can't be copied from one host's PBD to another
+func isHostSpecificDeviceConfig(deviceConfig map[string]string) bool {
+	for _, key := range hostSpecificDeviceConfigKeys {
+		if _, ok := deviceConfig[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// connectSharedSRs makes sure the new supporters are connected to all the shared SRs of the pool, the PBDs
+// are usually created by the join but not in all cases, e.g. when the storage can't be reached from the host
+// at the time. A missing PBD is created with the device config of the coordinator's PBD, the SRs whose device
+// config is host specific are skipped. The errors of each SR are collected, so one SR doesn't stop the others.
+func connectSharedSRs(ctx context.Context, session *xenapi.Session, supporterUUIDs []string) error {
+	if len(supporterUUIDs) == 0 {
+		return nil
+	}
+	srRecords, err := xenapi.SR.GetAllRecords(session)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	coordinatorRef, _, err := getCoordinatorRef(session)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for srRef, srRecord := range srRecords {
+		if !srRecord.Shared {
+			continue
+		}
+		coordinatorPBDRef, err := findPBD(session, srRef, coordinatorRef)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if coordinatorPBDRef == "" {
+			tflog.Debug(ctx, "SR "+srRecord.UUID+" has no PBD on the coordinator, skip connecting it to the new supporters")
+			continue
+		}
+		deviceConfig, err := xenapi.PBD.GetDeviceConfig(session, coordinatorPBDRef)
+		if err != nil {
+			errs = append(errs, errors.New(err.Error()))
+			continue
+		}
+		hostSpecific := isHostSpecificDeviceConfig(deviceConfig)
+		for _, supporterUUID := range supporterUUIDs {
+			err = connectSharedSR(ctx, session, srRef, srRecord.UUID, supporterUUID, deviceConfig, hostSpecific)
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// connectSharedSR plugs the PBD of the SR on the supporter, the PBD is created if it's missing and destroyed
+// again if it can't be plugged, so the SR isn't left with a broken PBD
+func connectSharedSR(ctx context.Context, session *xenapi.Session, srRef xenapi.SRRef, srUUID string, supporterUUID string, deviceConfig map[string]string, hostSpecific bool) error {
+	hostRef, err := xenapi.Host.GetByUUID(session, supporterUUID)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	pbdRef, err := findPBD(session, srRef, hostRef)
+	if err != nil {
+		return err
+	}
+	created := false
+	if pbdRef == "" {
+		if hostSpecific {
+			tflog.Warn(ctx, "The device config of SR "+srUUID+" is host specific, create its PBD on host "+supporterUUID+" with xenserver_pbd")
+			return nil
+		}
+		tflog.Debug(ctx, "Creating PBD for SR "+srUUID+" on host "+supporterUUID)
+		pbdRef, err = xenapi.PBD.Create(session, xenapi.PBDRecord{
+			SR:           srRef,
+			Host:         hostRef,
+			DeviceConfig: deviceConfig,
+			OtherConfig:  map[string]string{},
+		})
+		if err != nil {
+			return errors.New(err.Error() + ". \n\nunable to create PBD for SR " + srUUID + " on host " + supporterUUID)
+		}
+		created = true
+	}
+	err = setPBDPlugged(session, pbdRef, true)
+	if err == nil {
+		return nil
+	}
+	err = errors.New(err.Error() + ". \n\nunable to plug PBD for SR " + srUUID + " on host " + supporterUUID)
+	if created {
+		destroyErr := xenapi.PBD.Destroy(session, pbdRef)
+		if destroyErr != nil {
+			return errors.Join(err, cleanupError("PBD", string(pbdRef), destroyErr))
+		}
+	}
+	return err
 }
 
 func waitAllSupportersLive(ctx context.Context, session *xenapi.Session, supporterUUIDs []string) error {
 	tflog.Debug(ctx, "Waiting for all supporters to join the pool...")
+	// the supporter may not be known by the pool yet, only stop retrying on the fatal errors
+	checkError := func(err error, message string) error {
+		err = errors.New(message + "\n" + err.Error())
+		if kind := classifyXapiError(err); kind == xapiErrorFatal || kind == xapiErrorSessionInvalid {
+			return backoff.Permanent(err)
+		}
+		return err
+	}
 	operation := func() error {
 		for _, supporterUUID := range supporterUUIDs {
-			hostRef, err := xenapi.Host.GetByUUID(session, supporterUUID)
+			hostRef, err := withSessionRetry(session, func() (xenapi.HostRef, error) {
+				return xenapi.Host.GetByUUID(session, supporterUUID)
+			})
 			if err != nil {
-				return errors.New("unable to Get Host by UUID " + supporterUUID + "!\n" + err.Error())
+				return checkError(err, "unable to Get Host by UUID "+supporterUUID+"!")
 			}
 
 			hostMetricsRef, err := xenapi.Host.GetMetrics(session, hostRef)
 			if err != nil {
-				return errors.New("unable to Get Host Metrics with UUID " + supporterUUID + "!\n" + err.Error())
+				return checkError(err, "unable to Get Host Metrics with UUID "+supporterUUID+"!")
 			}
 
 			hostIsLive, err := xenapi.HostMetrics.GetLive(session, hostMetricsRef)
 			if err != nil {
-				return errors.New("unable to Get Host Live Status with UUID " + supporterUUID + "!\n" + err.Error())
+				return checkError(err, "unable to Get Host Live Status with UUID "+supporterUUID+"!")
 			}
 
 			if hostIsLive {
@@ -226,7 +425,7 @@ func waitAllSupportersLive(ctx context.Context, session *xenapi.Session, support
 	b := backoff.NewExponentialBackOff()
 	b.MaxInterval = 10 * time.Second
 	b.MaxElapsedTime = 5 * time.Minute
-	err := backoff.Retry(operation, b)
+	err := backoff.Retry(operation, backoff.WithContext(b, ctx))
 	if err != nil {
 		return errors.New(err.Error())
 	}
@@ -234,7 +433,43 @@ func waitAllSupportersLive(ctx context.Context, session *xenapi.Session, support
 	return nil
 }
 
+// checkEjectAcknowledged refuses to eject the supporters unless the data loss on each of them is acknowledged
+func checkEjectAcknowledged(ctx context.Context, plan poolResourceModel) error {
+	if plan.EjectSupporters.IsNull() || plan.EjectSupporters.IsUnknown() || len(plan.EjectSupporters.Elements()) == 0 {
+		return nil
+	}
+	// checked again at apply time when the values are known
+	if plan.AcknowledgeDataLoss.IsUnknown() {
+		return nil
+	}
+	var ejectSupporters, acknowledged []string
+	diags := plan.EjectSupporters.ElementsAs(ctx, &ejectSupporters, false)
+	if diags.HasError() {
+		return errors.New("unable to access eject supporters in config data")
+	}
+	diags = plan.AcknowledgeDataLoss.ElementsAs(ctx, &acknowledged, false)
+	if diags.HasError() {
+		return errors.New("unable to access acknowledge data loss in config data")
+	}
+	var unacknowledged []string
+	for _, hostUUID := range ejectSupporters {
+		if !slices.Contains(acknowledged, hostUUID) {
+			unacknowledged = append(unacknowledged, hostUUID)
+		}
+	}
+	if len(unacknowledged) == 0 {
+		return nil
+	}
+	slices.Sort(unacknowledged)
+	return errors.New("the hosts in eject_supporters are reinstalled when they are ejected from the pool, all the data on their local storage including the VM disks is lost. " +
+		"Add " + strings.Join(unacknowledged, ", ") + " to acknowledge_data_loss to eject them")
+}
+
 func poolEject(ctx context.Context, session *xenapi.Session, plan poolResourceModel) error {
+	err := checkEjectAcknowledged(ctx, plan)
+	if err != nil {
+		return err
+	}
 	ejectSupporters := make([]string, 0, len(plan.EjectSupporters.Elements()))
 	diags := plan.EjectSupporters.ElementsAs(ctx, &ejectSupporters, false)
 	if diags.HasError() {
@@ -276,7 +511,9 @@ func getCoordinatorRef(session *xenapi.Session) (xenapi.HostRef, string, error)
 }
 
 func getPoolRef(session *xenapi.Session) (xenapi.PoolRef, error) {
-	poolRefs, err := xenapi.Pool.GetAll(session)
+	poolRefs, err := withSessionRetry(session, func() ([]xenapi.PoolRef, error) {
+		return xenapi.Pool.GetAll(session)
+	})
 	if err != nil {
 		return "", errors.New(err.Error())
 	}
@@ -285,21 +522,31 @@ func getPoolRef(session *xenapi.Session) (xenapi.PoolRef, error) {
 }
 
 func cleanupPoolResource(session *xenapi.Session, poolRef xenapi.PoolRef) error {
-	err := xenapi.Pool.SetNameLabel(session, poolRef, "")
+	uuid, err := xenapi.Pool.GetUUID(session, poolRef)
 	if err != nil {
-		return errors.New(err.Error())
+		return cleanupError("pool", string(poolRef), err)
+	}
+	err = xenapi.Pool.SetNameLabel(session, poolRef, "")
+	if err != nil {
+		return cleanupError("pool", uuid, err)
+	}
+
+	// remove the other_config keys set by terraform
+	err = setPoolOtherConfig(session, poolRef, map[string]string{})
+	if err != nil {
+		return cleanupError("pool", uuid, err)
 	}
 
 	// eject supporters
 	coordinatorRef, _, err := getCoordinatorRef(session)
 	if err != nil {
-		return errors.New(err.Error())
+		return cleanupError("pool", uuid, err)
 	}
 
 	// eject supporters
 	hostRefs, err := xenapi.Host.GetAll(session)
 	if err != nil {
-		return errors.New(err.Error())
+		return cleanupError("pool", uuid, err)
 	}
 
 	for _, hostRef := range hostRefs {
@@ -309,15 +556,118 @@ func cleanupPoolResource(session *xenapi.Session, poolRef xenapi.PoolRef) error
 		}
 
 		err = xenapi.Pool.Eject(session, hostRef)
+		if err != nil {
+			return cleanupError("pool", uuid, err)
+		}
+	}
+
+	return nil
+}
+
+// iscsiIQNRegex matches the iSCSI qualified names in the "iqn.yyyy-mm.naming-authority[:unique name]" format of RFC 3720
+var iscsiIQNRegex = regexp.MustCompile(`^iqn\.[0-9]{4}-[0-9]{2}\.[a-z0-9]([a-z0-9.-]*[a-z0-9])?(:.+)?$`)
+
+// checkISCSIIQNsUnique returns an error if the hosts share an IQN, the iSCSI targets can't tell them apart
+func checkISCSIIQNsUnique(iqns map[string]string) error {
+	hostUUIDs := make(map[string][]string)
+	for hostUUID, iqn := range iqns {
+		hostUUIDs[iqn] = append(hostUUIDs[iqn], hostUUID)
+	}
+	var duplicates []string
+	for iqn, uuids := range hostUUIDs {
+		if len(uuids) > 1 {
+			slices.Sort(uuids)
+			duplicates = append(duplicates, iqn+" is used by hosts "+strings.Join(uuids, ", "))
+		}
+	}
+	if len(duplicates) > 0 {
+		slices.Sort(duplicates)
+		return errors.New("each host should use a different IQN, " + strings.Join(duplicates, "; "))
+	}
+	return nil
+}
+
+// setHostsISCSIIQN sets the iSCSI initiator IQN of the hosts, keyed by the host UUID
+func setHostsISCSIIQN(session *xenapi.Session, iqns map[string]string) error {
+	if len(iqns) == 0 {
+		return nil
+	}
+	version, err := getAPIVersion(session)
+	if err != nil {
+		return err
+	}
+	err = checkFeature(version, featureHostISCSIIQN)
+	if err != nil {
+		return err
+	}
+	for hostUUID, iqn := range iqns {
+		hostRef, err := xenapi.Host.GetByUUID(session, hostUUID)
+		if err != nil {
+			return errors.New("unable to get host " + hostUUID + "!\n" + err.Error())
+		}
+		currentIQN, err := xenapi.Host.GetIscsiIqn(session, hostRef)
 		if err != nil {
 			return errors.New(err.Error())
 		}
+		if currentIQN == iqn {
+			continue
+		}
+		err = xenapi.Host.SetIscsiIqn(session, hostRef, iqn)
+		if err != nil {
+			return errors.New("unable to set the iSCSI IQN of host " + hostUUID + "!\n" + featureError(version, featureHostISCSIIQN, err).Error())
+		}
 	}
+	return nil
+}
 
+// getHostsISCSIIQN returns the iSCSI initiator IQN of the hosts in the map, the hosts which are not in the pool
+// anymore are left out
+func getHostsISCSIIQN(session *xenapi.Session, iqns types.Map) (types.Map, error) {
+	if iqns.IsNull() || iqns.IsUnknown() {
+		return iqns, nil
+	}
+	values := make(map[string]attr.Value)
+	for hostUUID := range iqns.Elements() {
+		hostRef, err := xenapi.Host.GetByUUID(session, hostUUID)
+		if err != nil {
+			continue
+		}
+		iqn, err := xenapi.Host.GetIscsiIqn(session, hostRef)
+		if err != nil {
+			return iqns, errors.New(err.Error())
+		}
+		values[hostUUID] = types.StringValue(iqn)
+	}
+	mapValue, diags := types.MapValue(types.StringType, values)
+	if diags.HasError() {
+		return iqns, errors.New("unable to get iSCSI IQNs map value")
+	}
+	return mapValue, nil
+}
+
+// poolOtherConfigKeysKey is the pool other_config key which records the keys set by terraform
+const poolOtherConfigKeysKey = vmReservedOtherConfigPrefix + "other_config_keys"
+
+// setPoolOtherConfig merges the other_config in plan with the pool other_config, the keys set by
+// terraform before and removed from the plan are removed
+func setPoolOtherConfig(session *xenapi.Session, poolRef xenapi.PoolRef, planOtherConfig map[string]string) error {
+	otherConfig, err := xenapi.Pool.GetOtherConfig(session, poolRef)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	otherConfigKeys := mergeTrackedKeys(otherConfig, otherConfig[poolOtherConfigKeysKey], planOtherConfig, []string{})
+	delete(otherConfig, poolOtherConfigKeysKey)
+	if otherConfigKeys != "" {
+		otherConfig[poolOtherConfigKeysKey] = otherConfigKeys
+	}
+	err = xenapi.Pool.SetOtherConfig(session, poolRef, otherConfig)
+	if err != nil {
+		return errors.New(err.Error())
+	}
 	return nil
 }
 
-func setPool(session *xenapi.Session, poolRef xenapi.PoolRef, poolParams poolParams) error {
+func setPool(ctx context.Context, session *xenapi.Session, poolRef xenapi.PoolRef, poolParams poolParams) error {
 	err := xenapi.Pool.SetNameLabel(session, poolRef, poolParams.NameLabel)
 	if err != nil {
 		return errors.New("unable to Set NameLabel!\n" + err.Error())
@@ -328,6 +678,18 @@ func setPool(session *xenapi.Session, poolRef xenapi.PoolRef, poolParams poolPar
 		return errors.New("unable to Set NameDescription!\n" + err.Error())
 	}
 
+	if poolParams.OtherConfig != nil {
+		err = setPoolOtherConfig(session, poolRef, poolParams.OtherConfig)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = setHostsISCSIIQN(session, poolParams.ISCSIIQNs)
+	if err != nil {
+		return err
+	}
+
 	if poolParams.DefaultSRUUID != "" {
 		srRef, err := xenapi.SR.GetByUUID(session, poolParams.DefaultSRUUID)
 		if err != nil {
@@ -344,6 +706,11 @@ func setPool(session *xenapi.Session, poolRef xenapi.PoolRef, poolParams poolPar
 			return errors.New("SR with uuid " + poolParams.DefaultSRUUID + " is non-shared SR")
 		}
 
+		err = checkSRAttachedOnAllHosts(session, srRef, poolParams.DefaultSRUUID)
+		if err != nil {
+			return err
+		}
+
 		err = xenapi.Pool.SetDefaultSR(session, poolRef, srRef)
 		if err != nil {
 			return errors.New("unable to Set DefaultSR on the Pool!\n" + err.Error())
@@ -362,12 +729,43 @@ func setPool(session *xenapi.Session, poolRef xenapi.PoolRef, poolParams poolPar
 		}
 
 		// wait for toolstack restart
-		time.Sleep(60 * time.Second)
+		select {
+		case <-ctx.Done():
+			return errors.New("unable to wait for the toolstack restart: " + ctx.Err().Error())
+		case <-time.After(60 * time.Second):
+		}
 	}
 
 	return nil
 }
 
+// checkSRAttachedOnAllHosts makes sure every host of the pool can use the SR, the unplugged PBDs are plugged.
+// Otherwise the VMs fail to start on the hosts which aren't connected to the default SR.
+func checkSRAttachedOnAllHosts(session *xenapi.Session, srRef xenapi.SRRef, srUUID string) error {
+	hostRefs, err := xenapi.Host.GetAll(session)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	for _, hostRef := range hostRefs {
+		pbdRef, err := findPBD(session, srRef, hostRef)
+		if err != nil {
+			return err
+		}
+		if pbdRef == "" {
+			hostUUID, err := xenapi.Host.GetUUID(session, hostRef)
+			if err != nil {
+				return errors.New(err.Error())
+			}
+			return errors.New("SR with uuid " + srUUID + " is not connected to host " + hostUUID + ", create a PBD for the host with xenserver_pbd first")
+		}
+		err = setPBDPlugged(session, pbdRef, true)
+		if err != nil {
+			return errors.New("unable to plug the PBD of SR with uuid " + srUUID + "!\n" + err.Error())
+		}
+	}
+	return nil
+}
+
 func getManagementNetworkUUID(session *xenapi.Session, coordinatorRef xenapi.HostRef) (string, error) {
 	pifRefs, err := xenapi.Host.GetPIFs(session, coordinatorRef)
 	if err != nil {
@@ -397,16 +795,31 @@ func getManagementNetworkUUID(session *xenapi.Session, coordinatorRef xenapi.Hos
 	return "", errors.New("no management network found")
 }
 
-func updatePoolResourceModel(session *xenapi.Session, record xenapi.PoolRecord, data *poolResourceModel) error {
+func updatePoolResourceModel(ctx context.Context, session *xenapi.Session, record xenapi.PoolRecord, data *poolResourceModel) error {
 	data.NameLabel = types.StringValue(record.NameLabel)
-	return updatePoolResourceModelComputed(session, record, data)
+	// the acknowledgement is only kept in state, it's unset on import
+	if data.AcknowledgeDataLoss.IsNull() {
+		data.AcknowledgeDataLoss = types.SetValueMust(types.StringType, []attr.Value{})
+	}
+	return updatePoolResourceModelComputed(ctx, session, record, data)
 }
 
-func updatePoolResourceModelComputed(session *xenapi.Session, record xenapi.PoolRecord, data *poolResourceModel) error {
+func updatePoolResourceModelComputed(ctx context.Context, session *xenapi.Session, record xenapi.PoolRecord, data *poolResourceModel) error {
 	data.UUID = types.StringValue(record.UUID)
 	data.ID = types.StringValue(record.UUID)
 	data.NameDescription = types.StringValue(record.NameDescription)
 
+	otherConfig, err := getTrackedMapValue(ctx, record.OtherConfig, record.OtherConfig[poolOtherConfigKeysKey])
+	if err != nil {
+		return err
+	}
+	data.OtherConfig = otherConfig
+
+	data.ISCSIIQNs, err = getHostsISCSIIQN(session, data.ISCSIIQNs)
+	if err != nil {
+		return err
+	}
+
 	data.DefaultSRUUID = types.StringValue("")
 	if string(record.DefaultSR) != "OpaqueRef:NULL" {
 		srUUID, err := xenapi.SR.GetUUID(session, record.DefaultSR)
@@ -424,3 +837,106 @@ func updatePoolResourceModelComputed(session *xenapi.Session, record xenapi.Pool
 
 	return nil
 }
+
+// updatePoolVersionDataSourceModel reads the versions from the coordinator, the pool doesn't have its own
+// software version and the API version of the pool is the one of the coordinator
+func updatePoolVersionDataSourceModel(ctx context.Context, session *xenapi.Session, data *poolVersionDataSourceModel) error {
+	coordinatorRef, coordinatorUUID, err := getCoordinatorRef(session)
+	if err != nil {
+		return err
+	}
+	hostRecord, err := xenapi.Host.GetRecord(session, coordinatorRef)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+
+	data.CoordinatorUUID = types.StringValue(coordinatorUUID)
+	data.APIVersionMajor = types.Int64Value(int64(hostRecord.APIVersionMajor))
+	data.APIVersionMinor = types.Int64Value(int64(hostRecord.APIVersionMinor))
+	data.APIVersion = types.StringValue(strconv.Itoa(hostRecord.APIVersionMajor) + "." + strconv.Itoa(hostRecord.APIVersionMinor))
+	data.ProductBrand = types.StringValue(hostRecord.SoftwareVersion["product_brand"])
+	data.ProductVersion = types.StringValue(hostRecord.SoftwareVersion["product_version"])
+	data.ProductVersionText = types.StringValue(hostRecord.SoftwareVersion["product_version_text"])
+	data.PlatformVersion = types.StringValue(hostRecord.SoftwareVersion["platform_version"])
+	data.BuildNumber = types.StringValue(hostRecord.SoftwareVersion["build_number"])
+	data.XapiVersion = types.StringValue(hostRecord.SoftwareVersion["xapi"])
+	var diags diag.Diagnostics
+	data.SoftwareVersion, diags = types.MapValueFrom(ctx, types.StringType, hostRecord.SoftwareVersion)
+	if diags.HasError() {
+		return errors.New("unable to read coordinator software version")
+	}
+	return nil
+}
+
+// apiVersion is the XenServer API version of the pool
+type apiVersion struct {
+	Major int
+	Minor int
+}
+
+func (v apiVersion) String() string {
+	return strconv.Itoa(v.Major) + "." + strconv.Itoa(v.Minor)
+}
+
+func (v apiVersion) atLeast(required apiVersion) bool {
+	return v.Major > required.Major || (v.Major == required.Major && v.Minor >= required.Minor)
+}
+
+// xapiFeature is a feature which is only available since a XenServer version
+type xapiFeature struct {
+	Name       string
+	APIVersion apiVersion
+	Product    string
+}
+
+var featureSRProbeExt = xapiFeature{Name: "SR probe", APIVersion: apiVersion{Major: 2, Minor: 10}, Product: "7.5"}
+var featureHostISCSIIQN = xapiFeature{Name: "Host iSCSI IQN", APIVersion: apiVersion{Major: 2, Minor: 10}, Product: "7.5"}
+
+// apiVersions caches the API version of the pool per session, it doesn't change during the provider run
+var apiVersions sync.Map
+
+// getAPIVersion returns the API version of the pool, it's read from the coordinator once per session
+func getAPIVersion(session *xenapi.Session) (apiVersion, error) {
+	if version, ok := apiVersions.Load(session); ok {
+		return version.(apiVersion), nil
+	}
+	coordinatorRef, _, err := getCoordinatorRef(session)
+	if err != nil {
+		return apiVersion{}, err
+	}
+	major, err := xenapi.Host.GetAPIVersionMajor(session, coordinatorRef)
+	if err != nil {
+		return apiVersion{}, errors.New(err.Error())
+	}
+	minor, err := xenapi.Host.GetAPIVersionMinor(session, coordinatorRef)
+	if err != nil {
+		return apiVersion{}, errors.New(err.Error())
+	}
+	version := apiVersion{Major: major, Minor: minor}
+	apiVersions.Store(session, version)
+	return version, nil
+}
+
+func featureRequirementError(version apiVersion, feature xapiFeature) error {
+	return fmt.Errorf("%s requires XenServer %s (API version %s) or later, the pool API version is %s", feature.Name, feature.Product, feature.APIVersion, version)
+}
+
+// checkFeature returns an error telling the XenServer version required if the feature isn't available in the pool
+func checkFeature(version apiVersion, feature xapiFeature) error {
+	if version.atLeast(feature.APIVersion) {
+		return nil
+	}
+	return featureRequirementError(version, feature)
+}
+
+// featureError replaces the raw MESSAGE_METHOD_UNKNOWN error of a call with the XenServer version required by
+// the feature, the other errors are returned as they are
+func featureError(version apiVersion, feature xapiFeature, err error) error {
+	if err == nil {
+		return nil
+	}
+	if strings.Contains(err.Error(), "MESSAGE_METHOD_UNKNOWN") {
+		return featureRequirementError(version, feature)
+	}
+	return errors.New(err.Error())
+}
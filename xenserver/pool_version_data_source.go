@@ -0,0 +1,135 @@
+package xenserver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"xenapi"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &poolVersionDataSource{}
+	_ datasource.DataSourceWithConfigure = &poolVersionDataSource{}
+)
+
+// NewPoolVersionDataSource is a helper function to simplify the provider implementation.
+func NewPoolVersionDataSource() datasource.DataSource {
+	return &poolVersionDataSource{}
+}
+
+// poolVersionDataSource is the data source implementation.
+type poolVersionDataSource struct {
+	session          *xenapi.Session
+	operationLimiter operationLimiter
+}
+
+// Metadata returns the data source type name.
+func (d *poolVersionDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_pool_version"
+}
+
+func (d *poolVersionDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Provides the XenServer API version and product version of the pool, which are read from the pool coordinator. It's useful to check the features depending on the XenServer version are available, for example, with a `precondition`.",
+		Attributes: map[string]schema.Attribute{
+			"coordinator_uuid": schema.StringAttribute{
+				MarkdownDescription: "The UUID of the pool coordinator.",
+				Computed:            true,
+			},
+			"api_version_major": schema.Int64Attribute{
+				MarkdownDescription: "The major version number of the XenServer API.",
+				Computed:            true,
+			},
+			"api_version_minor": schema.Int64Attribute{
+				MarkdownDescription: "The minor version number of the XenServer API.",
+				Computed:            true,
+			},
+			"api_version": schema.StringAttribute{
+				MarkdownDescription: "The XenServer API version in the format `<major>.<minor>`.",
+				Computed:            true,
+			},
+			"product_brand": schema.StringAttribute{
+				MarkdownDescription: "The product brand, for example, `XenServer`.",
+				Computed:            true,
+			},
+			"product_version": schema.StringAttribute{
+				MarkdownDescription: "The product version, for example, `8.4.0`.",
+				Computed:            true,
+			},
+			"product_version_text": schema.StringAttribute{
+				MarkdownDescription: "The human-readable product version, for example, `8`.",
+				Computed:            true,
+			},
+			"platform_version": schema.StringAttribute{
+				MarkdownDescription: "The platform version.",
+				Computed:            true,
+			},
+			"build_number": schema.StringAttribute{
+				MarkdownDescription: "The build number of the product.",
+				Computed:            true,
+			},
+			"xapi_version": schema.StringAttribute{
+				MarkdownDescription: "The version of [XAPI](https://github.com/xapi-project/xen-api).",
+				Computed:            true,
+			},
+			"software_version": schema.MapAttribute{
+				MarkdownDescription: "All the software versions of the pool coordinator.",
+				Computed:            true,
+				ElementType:         types.StringType,
+			},
+		},
+	}
+}
+
+func (d *poolVersionDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	providerData, ok := req.ProviderData.(*xsProvider)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *xenserver.xsProvider, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+	d.session = providerData.session
+	d.operationLimiter = providerData.operationLimiter
+}
+
+// Read refreshes the Terraform state with the latest data.
+func (d *poolVersionDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data poolVersionDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := d.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	err = retryOnSessionInvalid(d.session, func() error {
+		return updatePoolVersionDataSourceModel(ctx, d.session, &data)
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to read pool version",
+			err.Error(),
+		)
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
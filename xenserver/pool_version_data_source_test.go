@@ -0,0 +1,61 @@
+package xenserver
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func testAccPoolVersionDataSourceConfig() string {
+	return `
+data "xenserver_pool_version" "test_pool_version" {}
+`
+}
+
+func TestAccPoolVersionDataSource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + testAccPoolVersionDataSourceConfig(),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("data.xenserver_pool_version.test_pool_version", "coordinator_uuid"),
+					resource.TestCheckResourceAttrSet("data.xenserver_pool_version.test_pool_version", "api_version"),
+					resource.TestCheckResourceAttrSet("data.xenserver_pool_version.test_pool_version", "product_version"),
+					resource.TestCheckResourceAttrSet("data.xenserver_pool_version.test_pool_version", "build_number"),
+				),
+			},
+		},
+	})
+}
+
+func TestCheckFeature(t *testing.T) {
+	feature := xapiFeature{Name: "Test feature", APIVersion: apiVersion{Major: 2, Minor: 10}, Product: "7.5"}
+	testCases := []struct {
+		version   apiVersion
+		expectErr bool
+	}{
+		{version: apiVersion{Major: 2, Minor: 10}},
+		{version: apiVersion{Major: 2, Minor: 21}},
+		{version: apiVersion{Major: 3, Minor: 0}},
+		{version: apiVersion{Major: 2, Minor: 9}, expectErr: true},
+		{version: apiVersion{Major: 1, Minor: 11}, expectErr: true},
+	}
+	for _, tc := range testCases {
+		err := checkFeature(tc.version, feature)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("checkFeature(%s) = %v, expected error: %t", tc.version, err, tc.expectErr)
+		}
+	}
+
+	err := featureError(apiVersion{Major: 2, Minor: 9}, feature, errors.New("API error: code 1, message MESSAGE_METHOD_UNKNOWN, data [SR.probe_ext]"))
+	expected := "Test feature requires XenServer 7.5 (API version 2.10) or later, the pool API version is 2.9"
+	if err == nil || err.Error() != expected {
+		t.Errorf("featureError() = %v, expected %q", err, expected)
+	}
+	err = featureError(apiVersion{Major: 2, Minor: 21}, feature, errors.New("SR_BACKEND_FAILURE"))
+	if err == nil || err.Error() != "SR_BACKEND_FAILURE" {
+		t.Errorf("featureError() = %v, expected the original error", err)
+	}
+}
@@ -4,14 +4,19 @@ import (
 	"context"
 	"errors"
 	"os"
+	"regexp"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/function"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 
@@ -27,15 +32,17 @@ type xsProvider struct {
 	// version is set to the provider version on release, "dev" when the
 	// provider is built and ran locally, and "test" when running acceptance
 	// testing.
-	version         string
-	session         *xenapi.Session
-	coordinatorConf coordinatorConf
+	version          string
+	session          *xenapi.Session
+	coordinatorConf  coordinatorConf
+	operationLimiter operationLimiter
 }
 
 type coordinatorConf struct {
-	Host     string
-	Username string
-	Password string
+	Host         string
+	Username     string
+	Password     string
+	LoginTimeout time.Duration
 }
 
 func New(version string) func() provider.Provider {
@@ -48,9 +55,13 @@ func New(version string) func() provider.Provider {
 
 // providerModel describes the provider data model.
 type providerModel struct {
-	Host     types.String `tfsdk:"host"`
-	Username types.String `tfsdk:"username"`
-	Password types.String `tfsdk:"password"`
+	Host                    types.String `tfsdk:"host"`
+	Hosts                   types.List   `tfsdk:"hosts"`
+	Username                types.String `tfsdk:"username"`
+	Password                types.String `tfsdk:"password"`
+	MaxConcurrentOperations types.Int64  `tfsdk:"max_concurrent_operations"`
+	OperationTimeout        types.Int64  `tfsdk:"operation_timeout"`
+	LoginTimeout            types.Int64  `tfsdk:"login_timeout"`
 }
 
 func (p *xsProvider) Metadata(_ context.Context, _ provider.MetadataRequest, resp *provider.MetadataResponse) {
@@ -67,6 +78,12 @@ func (p *xsProvider) Schema(_ context.Context, _ provider.SchemaRequest, resp *p
 					"Can be set by using the environment variable **XENSERVER_HOST**.",
 				Optional: true,
 			},
+			"hosts": schema.ListAttribute{
+				MarkdownDescription: "The addresses of the other hosts in the pool, which are tried in order after `host` until the login succeeds." + "<br />" +
+					"When a host is a pool supporter, the login is redirected to the pool coordinator it reports, so the provider keeps working after the coordinator is moved, for example, by HA.",
+				Optional:    true,
+				ElementType: types.StringType,
+			},
 			"username": schema.StringAttribute{
 				MarkdownDescription: "The user name of target XenServer host." + "<br />" +
 					"Can be set by using the environment variable **XENSERVER_USERNAME**.",
@@ -78,6 +95,31 @@ func (p *xsProvider) Schema(_ context.Context, _ provider.SchemaRequest, resp *p
 				Optional:  true,
 				Sensitive: true,
 			},
+			"max_concurrent_operations": schema.Int64Attribute{
+				MarkdownDescription: "The maximum number of resource create, read, update and delete operations and data source reads sent to XenServer at the same time, default to be `5`." + "<br />" +
+					"Terraform runs up to 10 operations at the same time by default, see `-parallelism`, lower it to smooth the load on the pool coordinator when applying a large configuration.",
+				Optional: true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+			"operation_timeout": schema.Int64Attribute{
+				MarkdownDescription: "The maximum time in seconds a resource operation or a data source read waits, no limit by default." + "<br />" +
+					"It bounds the wait for the other operations to finish when `max_concurrent_operations` is reached, and the long-running waits, for example, waiting for the VM IP address or the pool supporters." + "<br />" +
+					"The XenServer API calls themselves can't be canceled, so an operation may take longer than the timeout when a call is slow to return.",
+				Optional: true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+			"login_timeout": schema.Int64Attribute{
+				MarkdownDescription: "The maximum time in seconds to wait for the login to a host, default to be `60`." + "<br />" +
+					"When the host is unreachable, the provider reports it once the timeout is reached instead of waiting for the connection to fail.",
+				Optional: true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
 		},
 	}
 }
@@ -98,6 +140,13 @@ func (p *xsProvider) Configure(ctx context.Context, req provider.ConfigureReques
 	if !data.Host.IsNull() {
 		host = data.Host.ValueString()
 	}
+	hosts := []string{}
+	if !data.Hosts.IsNull() {
+		resp.Diagnostics.Append(data.Hosts.ElementsAs(ctx, &hosts, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
 	if !data.Username.IsNull() {
 		username = data.Username.ValueString()
 	}
@@ -108,7 +157,7 @@ func (p *xsProvider) Configure(ctx context.Context, req provider.ConfigureReques
 	// If any of the expected configurations are missing, return
 	// errors with provider-specific guidance.
 
-	if host == "" {
+	if host == "" && len(hosts) == 0 {
 		resp.Diagnostics.AddAttributeError(
 			path.Root("host"),
 			"Missing Host Configuration",
@@ -144,28 +193,48 @@ func (p *xsProvider) Configure(ctx context.Context, req provider.ConfigureReques
 	ctx = tflog.SetField(ctx, "username", username)
 	tflog.Debug(ctx, "Creating XenServer API session")
 
-	session, err := loginServer(host, username, password)
+	loginTimeout := defaultLoginTimeout
+	if !data.LoginTimeout.IsNull() {
+		loginTimeout = time.Duration(data.LoginTimeout.ValueInt64()) * time.Second
+	}
+
+	session, coordinator, err := loginCoordinator(ctx, append([]string{host}, hosts...), username, password, loginTimeout)
 	if err != nil {
+		summary, detail := describeLoginError(err)
 		resp.Diagnostics.AddError(
-			"Unable to create XenServer API client",
-			"An unexpected error occurred when creating the XenServer API client. "+
-				"If the error is not clear, please contact the provider developers.\n\n"+
+			summary,
+			detail+"\n\n"+
 				"XenServer client Error: "+err.Error(),
 		)
 		return
 	}
 
-	p.coordinatorConf.Host = host
+	p.coordinatorConf.Host = coordinator
 	p.coordinatorConf.Username = username
 	p.coordinatorConf.Password = password
+	p.coordinatorConf.LoginTimeout = loginTimeout
 	p.session = session
 
+	maxConcurrentOperations := int64(defaultMaxConcurrentOperations)
+	if !data.MaxConcurrentOperations.IsNull() {
+		maxConcurrentOperations = data.MaxConcurrentOperations.ValueInt64()
+	}
+	// the provider process may live longer than the session, e.g. a long apply with pool join
+	keeper := registerSessionKeeper(session, p.coordinatorConf)
+
+	p.operationLimiter = operationLimiter{slots: make(chan struct{}, maxConcurrentOperations), keeper: keeper}
+	if !data.OperationTimeout.IsNull() {
+		p.operationLimiter.timeout = time.Duration(data.OperationTimeout.ValueInt64()) * time.Second
+	}
+
 	// the xsProvider type itself is made available for resources and data sources
 	resp.DataSourceData = p
 	resp.ResourceData = p
 }
 
-func loginServer(host string, username string, password string) (*xenapi.Session, error) {
+const defaultLoginTimeout = 60 * time.Second
+
+func loginServer(host string, username string, password string, timeout time.Duration) (*xenapi.Session, error) {
 	// check if host, username, password are non-empty
 	if host == "" || username == "" || password == "" {
 		return nil, errors.New("host, username, password cannot be empty")
@@ -182,14 +251,333 @@ func loginServer(host string, username string, password string) (*xenapi.Session
 		},
 	})
 
-	_, err := session.LoginWithPassword(username, password, "1.0", "terraform provider")
-	if err != nil {
-		return nil, errors.New(err.Error())
+	// the SDK doesn't support timeout, so don't wait for the login of an unreachable host longer than the timeout
+	loginErr := make(chan error, 1)
+	go func() {
+		_, err := session.LoginWithPassword(username, password, "1.0", "terraform provider")
+		loginErr <- err
+	}()
+	select {
+	case err := <-loginErr:
+		if err != nil {
+			return nil, errors.New(err.Error())
+		}
+	case <-time.After(timeout):
+		// the login request can't be canceled, log out the session if the login still succeeds so it isn't left on the host
+		go func() {
+			if err := <-loginErr; err == nil {
+				_ = session.Logout()
+			}
+		}()
+		return nil, errors.New("login timed out after " + timeout.String())
 	}
 
 	return session, nil
 }
 
+// loginErrorHints maps the patterns of the common login errors to the diagnostic which tells the user
+// what to check, the first matching one is reported
+var loginErrorHints = []struct {
+	pattern *regexp.Regexp
+	summary string
+	detail  string
+}{
+	{
+		pattern: regexp.MustCompile(`SESSION_AUTHENTICATION_FAILED`),
+		summary: "XenServer authentication failed",
+		detail:  "The host rejected the credentials, check the username and password.",
+	},
+	{
+		pattern: regexp.MustCompile(`(?i)x509|certificate|tls:`),
+		summary: "XenServer certificate not trusted",
+		detail:  "The TLS connection to the host failed, check the certificate of the host is trusted by the machine running Terraform.",
+	},
+	{
+		pattern: regexp.MustCompile(`(?i)connection refused|no such host|no route to host|network is unreachable|i/o timeout|timed out`),
+		summary: "XenServer host unreachable",
+		detail:  "The host can't be reached, check the host address and that it's reachable from the machine running Terraform.",
+	},
+}
+
+// describeLoginError returns the summary and the detail of the diagnostic for the login error
+func describeLoginError(err error) (string, string) {
+	for _, hint := range loginErrorHints {
+		if hint.pattern.MatchString(err.Error()) {
+			return hint.summary, hint.detail
+		}
+	}
+	return "Unable to create XenServer API client",
+		"An unexpected error occurred when creating the XenServer API client. " +
+			"If the error is not clear, please contact the provider developers."
+}
+
+// hostIsSlaveRegex matches the coordinator address in the HOST_IS_SLAVE error, for example,
+// "API error: code 1, message HOST_IS_SLAVE, data [10.70.0.1]"
+var hostIsSlaveRegex = regexp.MustCompile(`HOST_IS_SLAVE.*\[([^\]\s]+)\]`)
+
+// getCoordinatorAddress returns the coordinator address reported by the HOST_IS_SLAVE error, "" if not found
+func getCoordinatorAddress(err error) string {
+	if err == nil {
+		return ""
+	}
+	match := hostIsSlaveRegex.FindStringSubmatch(err.Error())
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+// loginCoordinator tries to log in the hosts in order, when a host is a pool supporter the login is
+// redirected to the coordinator it reports. It returns the session and the address of the coordinator.
+func loginCoordinator(ctx context.Context, hosts []string, username string, password string, timeout time.Duration) (*xenapi.Session, string, error) {
+	var errs []error
+	for _, host := range hosts {
+		if host == "" {
+			continue
+		}
+		session, err := loginServer(host, username, password, timeout)
+		if err == nil {
+			return session, host, nil
+		}
+		coordinator := getCoordinatorAddress(err)
+		if coordinator != "" {
+			tflog.Debug(ctx, "Host "+host+" is a pool supporter, redirect to the coordinator "+coordinator)
+			session, err = loginServer(coordinator, username, password, timeout)
+			if err == nil {
+				return session, coordinator, nil
+			}
+			host = coordinator
+		}
+		tflog.Warn(ctx, "Unable to log in host "+host+": "+err.Error())
+		errs = append(errs, errors.New(host+": "+err.Error()))
+	}
+	if len(errs) == 0 {
+		return nil, "", errors.New("host cannot be empty")
+	}
+	return nil, "", errors.Join(errs...)
+}
+
+const defaultMaxConcurrentOperations = 5
+
+// operationLimiter limits the number of resource operations and data source reads sent to XAPI at the same time,
+// and how long each operation can take
+type operationLimiter struct {
+	slots   chan struct{}
+	timeout time.Duration
+	// keeper is the keeper of the provider session, the operations hold its lock while using the session
+	keeper *sessionKeeper
+}
+
+// withTimeout returns the context of the operation, it's canceled when the operation timeout is reached.
+// It bounds the wait for a slot and the waits of the operation, the XAPI calls take no context and aren't bounded.
+func (l operationLimiter) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if l.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, l.timeout)
+}
+
+func (l operationLimiter) acquire(ctx context.Context) error {
+	// no limit if the provider is not configured
+	if l.slots == nil {
+		return nil
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return errors.New("unable to wait for other operations to finish: " + ctx.Err().Error())
+	}
+}
+
+func (l operationLimiter) release() {
+	if l.slots == nil {
+		return
+	}
+	<-l.slots
+}
+
+// start waits for a free slot and returns the context of the operation, done must be called once the operation ends.
+// The operation holds the read lock of the session until it ends, so the session isn't logged in again under it.
+func (l operationLimiter) start(ctx context.Context) (context.Context, func(), error) {
+	ctx, cancel := l.withTimeout(ctx)
+	if err := l.acquire(ctx); err != nil {
+		cancel()
+		return ctx, func() {}, err
+	}
+	if l.keeper != nil {
+		l.keeper.lock.RLock()
+	}
+	return ctx, func() {
+		if l.keeper != nil {
+			l.keeper.lock.RUnlock()
+		}
+		l.release()
+		cancel()
+	}, nil
+}
+
+// parallelLimit limits the calls an operation makes at the same time from its goroutines, for example, to create
+// the disks of a VM. They don't take the slots of the operations, the operation running them holds one already.
+type parallelLimit chan struct{}
+
+// newParallelLimit returns the limit of the calls made at the same time by one operation, which is the same as
+// the limit of the operations
+func (l operationLimiter) newParallelLimit() parallelLimit {
+	limit := cap(l.slots)
+	if limit == 0 {
+		limit = defaultMaxConcurrentOperations
+	}
+	return make(parallelLimit, limit)
+}
+
+// run makes the calls at the same time within the limit and returns all errors, the calls may share the limit
+// with the ones run by the other goroutines of the operation
+func (p parallelLimit) run(count int, call func(i int) error) error {
+	var wg sync.WaitGroup
+	errs := make([]error, count)
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			p <- struct{}{}
+			defer func() { <-p }()
+			errs[i] = call(i)
+		}(i)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// sessionKeeper holds what is needed to log in the shared session again once XAPI reports it is invalid,
+// for example, expired during a long apply or removed by the XAPI session limit
+type sessionKeeper struct {
+	// lock is held for reading by each operation using the session, see operationLimiter.start, and for writing
+	// by the re-login, which changes the session in place
+	lock sync.RWMutex
+	conf coordinatorConf
+	// generation is increased on each re-login, so the calls failed with the same session only re-login once
+	generation int
+}
+
+// sessionKeepers maps the sessions created by the provider to their keepers, the resources and data sources
+// only get the session, so they find the keeper through it
+var sessionKeepers sync.Map
+
+// sessionLogin logs in the session again in place, the unit tests replace it with a fake
+var sessionLogin = func(session *xenapi.Session, conf coordinatorConf) error {
+	_, err := session.LoginWithPassword(conf.Username, conf.Password, "1.0", "terraform provider")
+	return err
+}
+
+func registerSessionKeeper(session *xenapi.Session, conf coordinatorConf) *sessionKeeper {
+	keeper := &sessionKeeper{conf: conf}
+	sessionKeepers.Store(session, keeper)
+	return keeper
+}
+
+// relogin logs in the session again unless another operation has done it since the failed call was made.
+// The caller holds the read lock, it's released while waiting for the other operations to stop using the session.
+func (k *sessionKeeper) relogin(session *xenapi.Session, generation int) error {
+	k.lock.RUnlock()
+	defer k.lock.RLock()
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if k.generation != generation {
+		return nil
+	}
+	err := sessionLogin(session, k.conf)
+	if err != nil {
+		return errors.New("unable to log in the expired session again: " + err.Error())
+	}
+	k.generation++
+	return nil
+}
+
+// retryOnSessionInvalid makes the XAPI calls, when they fail with SESSION_INVALID the session is logged in again
+// with the stored credentials and the calls are retried once. XAPI checks the session before running a call,
+// so the failed call hasn't changed anything and is safe to retry.
+// It must be called by the operation started with operationLimiter.start and not from the goroutines of the
+// operation, as the re-login releases the read lock of the operation while the session is changed.
+func retryOnSessionInvalid(session *xenapi.Session, call func() error) error {
+	value, ok := sessionKeepers.Load(session)
+	if !ok {
+		return call()
+	}
+	keeper := value.(*sessionKeeper)
+	// the generation is only changed under the write lock, so it's safe to read with the read lock of the operation
+	generation := keeper.generation
+
+	err := call()
+	if classifyXapiError(err) != xapiErrorSessionInvalid {
+		return err
+	}
+	err = keeper.relogin(session, generation)
+	if err != nil {
+		return err
+	}
+	return call()
+}
+
+// withSessionRetry is retryOnSessionInvalid for the XAPI call returning a value
+func withSessionRetry[T any](session *xenapi.Session, call func() (T, error)) (T, error) {
+	var result T
+	err := retryOnSessionInvalid(session, func() error {
+		var err error
+		result, err = call()
+		return err
+	})
+	return result, err
+}
+
+// xapiErrorKind tells whether the operation failed with a XAPI error is worth retrying
+type xapiErrorKind int
+
+const (
+	// xapiErrorUnknown is the error which is not a known XAPI error, the caller decides how to handle it
+	xapiErrorUnknown xapiErrorKind = iota
+	// xapiErrorRetryable is the error caused by a temporary state of the object, the operation may succeed later
+	xapiErrorRetryable
+	// xapiErrorFatal is the error which retrying the operation with the same session can't fix
+	xapiErrorFatal
+	// xapiErrorSessionInvalid is the error of the session which is expired or logged out, see withSessionRetry
+	xapiErrorSessionInvalid
+)
+
+// xapiErrorKinds lists the known XAPI error codes, the SR_BACKEND_FAILURE code is followed by the backend error number
+var xapiErrorKinds = []struct {
+	code string
+	kind xapiErrorKind
+}{
+	{code: "OTHER_OPERATION_IN_PROGRESS", kind: xapiErrorRetryable},
+	{code: "OPERATION_NOT_ALLOWED", kind: xapiErrorRetryable},
+	{code: "VDI_IN_USE", kind: xapiErrorRetryable},
+	{code: "SR_BACKEND_FAILURE", kind: xapiErrorRetryable},
+	{code: "HOST_IS_SLAVE", kind: xapiErrorFatal},
+	{code: "SESSION_INVALID", kind: xapiErrorSessionInvalid},
+}
+
+// classifyXapiError returns the kind of the XAPI error, the retry loops use it to stop on the errors which won't go away
+func classifyXapiError(err error) xapiErrorKind {
+	if err == nil {
+		return xapiErrorUnknown
+	}
+	for _, errorKind := range xapiErrorKinds {
+		if strings.Contains(err.Error(), errorKind.code) {
+			return errorKind.kind
+		}
+	}
+	return xapiErrorUnknown
+}
+
+// cleanupError names the resource which can't be cleaned up, one apply may clean up many resources of the same type.
+// Before the UUID is known, the XAPI reference is used instead.
+func cleanupError(resourceType string, uuid string, err error) error {
+	return errors.New("unable to clean up " + resourceType + " " + uuid + ": " + err.Error())
+}
+
 func (p *xsProvider) Resources(_ context.Context) []func() resource.Resource {
 	return []func() resource.Resource{
 		NewVMResource,
@@ -199,8 +587,15 @@ func (p *xsProvider) Resources(_ context.Context) []func() resource.Resource {
 		NewSMBResource,
 		NewVDIResource,
 		NewVlanResource,
+		NewVlanRangeResource,
 		NewSnapshotResource,
 		NewPIFConfigureResource,
+		NewHostMaintenanceResource,
+		NewVMCloneResource,
+		NewVDISnapshotResource,
+		NewSecretResource,
+		NewVMTemplateResource,
+		NewPBDResource,
 	}
 }
 
@@ -212,6 +607,14 @@ func (p *xsProvider) DataSources(_ context.Context) []func() datasource.DataSour
 		NewNetworkDataSource,
 		NewNICDataSource,
 		NewHostDataSource,
+		NewVMTemplatesDataSource,
+		NewPoolVersionDataSource,
+		NewSRProbeDataSource,
+		NewISODataSource,
+		NewVMPlacementDataSource,
+		NewVMMetricsDataSource,
+		NewHostMetricsDataSource,
+		NewVGPUTypeDataSource,
 	}
 }
 
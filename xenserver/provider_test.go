@@ -1,11 +1,20 @@
 package xenserver
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
 	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+
+	"xenapi"
 )
 
 // testAccProtoV6ProviderFactories are used to instantiate a provider during
@@ -25,3 +34,227 @@ provider "xenserver" {
 }
 `, os.Getenv("XENSERVER_HOST"), os.Getenv("XENSERVER_USERNAME"), os.Getenv("XENSERVER_PASSWORD"))
 )
+
+// fakeRecords fakes the GetRecord call of a XAPI class with the records in the map, see xapi_client.go
+type fakeRecords[R ~string, T any] map[R]T
+
+func (f fakeRecords[R, T]) GetRecord(_ *xenapi.Session, ref R) (T, error) {
+	record, ok := f[ref]
+	if !ok {
+		return record, errors.New("API error: HANDLE_INVALID [" + string(ref) + "]")
+	}
+	return record, nil
+}
+
+func TestGetCoordinatorAddress(t *testing.T) {
+	testCases := []struct {
+		err      error
+		expected string
+	}{
+		{err: nil, expected: ""},
+		{err: errors.New("API error: code 1, message HOST_IS_SLAVE, data [10.70.0.1]"), expected: "10.70.0.1"},
+		{err: errors.New("API error: code 1, message HOST_IS_SLAVE, data [coordinator.example.com]"), expected: "coordinator.example.com"},
+		{err: errors.New("API error: code 1, message SESSION_AUTHENTICATION_FAILED, data [root]"), expected: ""},
+		{err: errors.New("HOST_IS_SLAVE"), expected: ""},
+	}
+	for _, tc := range testCases {
+		address := getCoordinatorAddress(tc.err)
+		if address != tc.expected {
+			t.Errorf("getCoordinatorAddress(%v) = %q, expected %q", tc.err, address, tc.expected)
+		}
+	}
+}
+
+func TestClassifyXapiError(t *testing.T) {
+	testCases := []struct {
+		err      error
+		expected xapiErrorKind
+	}{
+		{err: nil, expected: xapiErrorUnknown},
+		{err: errors.New("API error: OTHER_OPERATION_IN_PROGRESS [VM, OpaqueRef:1234]"), expected: xapiErrorRetryable},
+		{err: errors.New("API error: OPERATION_NOT_ALLOWED [VM is not running]"), expected: xapiErrorRetryable},
+		{err: errors.New("API error: VDI_IN_USE [OpaqueRef:1234, destroy]"), expected: xapiErrorRetryable},
+		{err: errors.New("API error: SR_BACKEND_FAILURE_73 [, NFS mount error, ]"), expected: xapiErrorRetryable},
+		{err: errors.New("API error: code 1, message HOST_IS_SLAVE, data [10.70.0.1]"), expected: xapiErrorFatal},
+		{err: errors.New("API error: SESSION_INVALID [OpaqueRef:1234]"), expected: xapiErrorSessionInvalid},
+		{err: errors.New("API error: UUID_INVALID [host, 1234]"), expected: xapiErrorUnknown},
+	}
+	for _, tc := range testCases {
+		kind := classifyXapiError(tc.err)
+		if kind != tc.expected {
+			t.Errorf("classifyXapiError(%v) = %d, expected %d", tc.err, kind, tc.expected)
+		}
+	}
+}
+
+func TestRetryOnSessionInvalid(t *testing.T) {
+	sessionInvalid := errors.New("API error: SESSION_INVALID [OpaqueRef:1234]")
+	testCases := []struct {
+		name           string
+		errs           []error
+		loginErr       error
+		expectedErr    bool
+		expectedCalls  int
+		expectedLogins int
+	}{
+		{name: "success", errs: []error{nil}, expectedCalls: 1},
+		{name: "other error is not retried", errs: []error{errors.New("API error: UUID_INVALID [VM, 1234]")}, expectedErr: true, expectedCalls: 1},
+		{name: "retried once after re-login", errs: []error{sessionInvalid, nil}, expectedCalls: 2, expectedLogins: 1},
+		{name: "still invalid after re-login", errs: []error{sessionInvalid, sessionInvalid}, expectedErr: true, expectedCalls: 2, expectedLogins: 1},
+		{name: "re-login failed", errs: []error{sessionInvalid}, loginErr: errors.New("API error: SESSION_AUTHENTICATION_FAILED"), expectedErr: true, expectedCalls: 1, expectedLogins: 1},
+	}
+
+	defaultSessionLogin := sessionLogin
+	defer func() { sessionLogin = defaultSessionLogin }()
+
+	for _, tc := range testCases {
+		logins := 0
+		sessionLogin = func(_ *xenapi.Session, _ coordinatorConf) error {
+			logins++
+			return tc.loginErr
+		}
+		session := &xenapi.Session{}
+		keeper := registerSessionKeeper(session, coordinatorConf{Username: "root", Password: "password"})
+		_, done, err := operationLimiter{keeper: keeper}.start(context.Background())
+		if err != nil {
+			t.Fatalf("%s: unable to start the operation: %v", tc.name, err)
+		}
+
+		calls := 0
+		err = retryOnSessionInvalid(session, func() error {
+			calls++
+			return tc.errs[calls-1]
+		})
+		done()
+		sessionKeepers.Delete(session)
+
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+		}
+		if calls != tc.expectedCalls {
+			t.Errorf("%s: called %d times, expected %d", tc.name, calls, tc.expectedCalls)
+		}
+		if logins != tc.expectedLogins {
+			t.Errorf("%s: logged in %d times, expected %d", tc.name, logins, tc.expectedLogins)
+		}
+	}
+}
+
+func TestRetryOnSessionInvalidConcurrent(t *testing.T) {
+	sessionInvalid := errors.New("API error: SESSION_INVALID [OpaqueRef:1234]")
+	defaultSessionLogin := sessionLogin
+	defer func() { sessionLogin = defaultSessionLogin }()
+
+	// the logins are made with the write lock, so the counter is not shared with the calls
+	logins := 0
+	sessionLogin = func(_ *xenapi.Session, _ coordinatorConf) error {
+		logins++
+		return nil
+	}
+	session := &xenapi.Session{}
+	keeper := registerSessionKeeper(session, coordinatorConf{Username: "root", Password: "password"})
+	defer sessionKeepers.Delete(session)
+	limiter := operationLimiter{slots: make(chan struct{}, 2), keeper: keeper}
+
+	// both operations fail with the same session before any of them logs in again
+	var failed, wg sync.WaitGroup
+	failed.Add(2)
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, done, err := limiter.start(context.Background())
+			if err != nil {
+				errs[i] = err
+				failed.Done()
+				return
+			}
+			defer done()
+			calls := 0
+			errs[i] = retryOnSessionInvalid(session, func() error {
+				calls++
+				if calls > 1 {
+					return nil
+				}
+				failed.Done()
+				failed.Wait()
+				return sessionInvalid
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if logins != 1 {
+		t.Errorf("logged in %d times, expected 1", logins)
+	}
+}
+
+func TestParallelLimitRun(t *testing.T) {
+	testCases := []struct {
+		name         string
+		limit        int
+		count        int
+		failed       []int
+		expectedErrs int
+	}{
+		{name: "no call", limit: 2, count: 0},
+		{name: "calls within the limit", limit: 5, count: 3},
+		{name: "calls over the limit", limit: 2, count: 7},
+		{name: "errors are joined", limit: 2, count: 4, failed: []int{1, 3}, expectedErrs: 2},
+	}
+
+	for _, tc := range testCases {
+		var mutex sync.Mutex
+		running, maxRunning := 0, 0
+		err := operationLimiter{slots: make(chan struct{}, tc.limit)}.newParallelLimit().run(tc.count, func(i int) error {
+			mutex.Lock()
+			running++
+			maxRunning = max(maxRunning, running)
+			mutex.Unlock()
+			time.Sleep(time.Millisecond)
+			mutex.Lock()
+			running--
+			mutex.Unlock()
+			if slices.Contains(tc.failed, i) {
+				return fmt.Errorf("call %d failed", i)
+			}
+			return nil
+		})
+
+		if maxRunning > tc.limit {
+			t.Errorf("%s: %d calls ran at the same time, expected at most %d", tc.name, maxRunning, tc.limit)
+		}
+		errs := 0
+		if err != nil {
+			errs = len(strings.Split(err.Error(), "\n"))
+		}
+		if errs != tc.expectedErrs {
+			t.Errorf("%s: got %d errors, expected %d", tc.name, errs, tc.expectedErrs)
+		}
+	}
+}
+
+func TestDescribeLoginError(t *testing.T) {
+	testCases := []struct {
+		err      error
+		expected string
+	}{
+		{err: errors.New("API error: code 1, message SESSION_AUTHENTICATION_FAILED, data [root, Authentication failure]"), expected: "XenServer authentication failed"},
+		{err: errors.New(`Post "https://10.70.0.1": tls: failed to verify certificate: x509: certificate signed by unknown authority`), expected: "XenServer certificate not trusted"},
+		{err: errors.New(`Post "https://10.70.0.1": dial tcp 10.70.0.1:443: connect: connection refused`), expected: "XenServer host unreachable"},
+		{err: errors.New(`Post "https://xs.example.com": dial tcp: lookup xs.example.com: no such host`), expected: "XenServer host unreachable"},
+		{err: errors.New("login timed out after 1m0s"), expected: "XenServer host unreachable"},
+		{err: errors.New("10.70.0.1: connection refused\n10.70.0.2: API error: code 1, message SESSION_AUTHENTICATION_FAILED"), expected: "XenServer authentication failed"},
+		{err: errors.New("API error: code 1, message HOST_STILL_BOOTING"), expected: "Unable to create XenServer API client"},
+	}
+	for _, tc := range testCases {
+		summary, _ := describeLoginError(tc.err)
+		if summary != tc.expected {
+			t.Errorf("describeLoginError(%v) = %q, expected %q", tc.err, summary, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package xenserver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"xenapi"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var (
+	_ resource.Resource                = &secretResource{}
+	_ resource.ResourceWithConfigure   = &secretResource{}
+	_ resource.ResourceWithImportState = &secretResource{}
+)
+
+func NewSecretResource() resource.Resource {
+	return &secretResource{}
+}
+
+// secretResource defines the resource implementation.
+type secretResource struct {
+	session          *xenapi.Session
+	operationLimiter operationLimiter
+}
+
+func (r *secretResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_secret"
+}
+
+func (r *secretResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Provides a secret resource, which stores a credential once on the pool so that it can be shared by multiple storage repositories, for example, with `password_secret_uuid` of `xenserver_sr_smb`.",
+		Attributes:          secretSchema(),
+	}
+}
+
+// Set the parameter of the resource, pass value from provider
+func (r *secretResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+	providerData, ok := req.ProviderData.(*xsProvider)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *xenserver.xsProvider, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+	r.session = providerData.session
+	r.operationLimiter = providerData.operationLimiter
+}
+
+func (r *secretResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data secretResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	record, err := getSecretCreateParams(ctx, data)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get secret create params",
+			err.Error(),
+		)
+		return
+	}
+	tflog.Debug(ctx, "Creating secret...")
+	secretRef, err := withSessionRetry(r.session, func() (xenapi.SecretRef, error) {
+		return xenapi.Secret.Create(r.session, record)
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to create secret",
+			err.Error(),
+		)
+		return
+	}
+	secretRecord, err := xenapi.Secret.GetRecord(r.session, secretRef)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get secret record",
+			err.Error(),
+		)
+		err = cleanupSecretResource(r.session, secretRef)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Error cleaning up secret resource",
+				err.Error(),
+			)
+		}
+		return
+	}
+	err = updateSecretResourceModelComputed(ctx, secretRecord, &data)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to update the computed fields of SecretResourceModel",
+			err.Error(),
+		)
+		err = cleanupSecretResource(r.session, secretRef)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Error cleaning up secret resource",
+				err.Error(),
+			)
+		}
+		return
+	}
+	tflog.Debug(ctx, "Secret created")
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *secretResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data secretResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	// Overwrite data with refreshed resource state
+	secretRef, err := withSessionRetry(r.session, func() (xenapi.SecretRef, error) {
+		return xenapi.Secret.GetByUUID(r.session, data.UUID.ValueString())
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get secret ref",
+			err.Error(),
+		)
+		return
+	}
+	secretRecord, err := xenapi.Secret.GetRecord(r.session, secretRef)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get secret record",
+			err.Error(),
+		)
+		return
+	}
+	err = updateSecretResourceModel(ctx, secretRecord, &data)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to update the fields of SecretResourceModel",
+			err.Error(),
+		)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *secretResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan secretResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	// Update the resource with new configuration
+	secretRef, err := withSessionRetry(r.session, func() (xenapi.SecretRef, error) {
+		return xenapi.Secret.GetByUUID(r.session, plan.UUID.ValueString())
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get secret ref",
+			err.Error(),
+		)
+		return
+	}
+	err = secretResourceModelUpdate(ctx, r.session, secretRef, plan)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to update secret resource",
+			err.Error(),
+		)
+		return
+	}
+	secretRecord, err := xenapi.Secret.GetRecord(r.session, secretRef)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get secret record",
+			err.Error(),
+		)
+		return
+	}
+	err = updateSecretResourceModelComputed(ctx, secretRecord, &plan)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to update the computed fields of SecretResourceModel",
+			err.Error(),
+		)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *secretResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data secretResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	secretRef, err := withSessionRetry(r.session, func() (xenapi.SecretRef, error) {
+		return xenapi.Secret.GetByUUID(r.session, data.UUID.ValueString())
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get secret ref",
+			err.Error(),
+		)
+		return
+	}
+	err = cleanupSecretResource(r.session, secretRef)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to delete secret resource",
+			err.Error(),
+		)
+		return
+	}
+}
+
+func (r *secretResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("uuid"), req, resp)
+}
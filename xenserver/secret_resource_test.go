@@ -0,0 +1,52 @@
+package xenserver
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func testAccSecretResourceConfig(value string, extra_config string) string {
+	return fmt.Sprintf(`
+resource "xenserver_secret" "test_secret" {
+	value = "%s"
+	%s
+}
+`, value, extra_config)
+}
+
+func TestAccSecretResource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Create and Read testing
+			{
+				Config: providerConfig + testAccSecretResourceConfig("test-password", ""),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_secret.test_secret", "value", "test-password"),
+					resource.TestCheckResourceAttr("xenserver_secret.test_secret", "other_config.%", "0"),
+					// Verify dynamic values have any value set in the state.
+					resource.TestCheckResourceAttrSet("xenserver_secret.test_secret", "uuid"),
+				),
+			},
+			// ImportState testing
+			{
+				ResourceName:            "xenserver_secret.test_secret",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{},
+			},
+			// Update and Read testing
+			{
+				Config: providerConfig + testAccSecretResourceConfig("test-password-2", `other_config = { "flag" = "1" }`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_secret.test_secret", "value", "test-password-2"),
+					resource.TestCheckResourceAttr("xenserver_secret.test_secret", "other_config.%", "1"),
+					resource.TestCheckResourceAttr("xenserver_secret.test_secret", "other_config.flag", "1"),
+				),
+			},
+			// Delete testing automatically occurs in TestCase
+		},
+	})
+}
@@ -0,0 +1,110 @@
+package xenserver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"xenapi"
+)
+
+type secretResourceModel struct {
+	Value       types.String `tfsdk:"value"`
+	OtherConfig types.Map    `tfsdk:"other_config"`
+	UUID        types.String `tfsdk:"uuid"`
+	ID          types.String `tfsdk:"id"`
+}
+
+func secretSchema() map[string]schema.Attribute {
+	return map[string]schema.Attribute{
+		"value": schema.StringAttribute{
+			MarkdownDescription: "The secret value, for example, the password of a storage repository." +
+				"\n\n-> **Note:** This value will be stored in terraform state file, follow document [Sensitive values in state](https://developer.hashicorp.com/terraform/tutorials/configuration-language/sensitive-variables#sensitive-values-in-state) to protect your sensitive data.",
+			Required:  true,
+			Sensitive: true,
+		},
+		"other_config": schema.MapAttribute{
+			MarkdownDescription: "The additional configuration of the secret, default to be `{}`.",
+			Optional:            true,
+			Computed:            true,
+			Default:             mapdefault.StaticValue(types.MapValueMust(types.StringType, map[string]attr.Value{})),
+			ElementType:         types.StringType,
+		},
+		"uuid": schema.StringAttribute{
+			MarkdownDescription: "The UUID of the secret, which can be referenced in the device config of storage repositories, for example, `password_secret`.",
+			Computed:            true,
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.UseStateForUnknown(),
+			},
+		},
+		"id": schema.StringAttribute{
+			MarkdownDescription: "The test ID of the secret.",
+			Computed:            true,
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.UseStateForUnknown(),
+			},
+		},
+	}
+}
+
+func getSecretCreateParams(ctx context.Context, data secretResourceModel) (xenapi.SecretRecord, error) {
+	var record xenapi.SecretRecord
+	record.Value = data.Value.ValueString()
+	diags := data.OtherConfig.ElementsAs(ctx, &record.OtherConfig, false)
+	if diags.HasError() {
+		return record, errors.New("unable to access secret other config")
+	}
+	return record, nil
+}
+
+func updateSecretResourceModel(ctx context.Context, record xenapi.SecretRecord, data *secretResourceModel) error {
+	data.Value = types.StringValue(record.Value)
+	return updateSecretResourceModelComputed(ctx, record, data)
+}
+
+func updateSecretResourceModelComputed(ctx context.Context, record xenapi.SecretRecord, data *secretResourceModel) error {
+	data.UUID = types.StringValue(record.UUID)
+	data.ID = types.StringValue(record.UUID)
+	var diags diag.Diagnostics
+	data.OtherConfig, diags = types.MapValueFrom(ctx, types.StringType, record.OtherConfig)
+	if diags.HasError() {
+		return errors.New("unable to access secret other config")
+	}
+	return nil
+}
+
+func secretResourceModelUpdate(ctx context.Context, session *xenapi.Session, ref xenapi.SecretRef, data secretResourceModel) error {
+	err := xenapi.Secret.SetValue(session, ref, data.Value.ValueString())
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	otherConfig := make(map[string]string)
+	diags := data.OtherConfig.ElementsAs(ctx, &otherConfig, false)
+	if diags.HasError() {
+		return errors.New("unable to access secret other config")
+	}
+	err = xenapi.Secret.SetOtherConfig(session, ref, otherConfig)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	return nil
+}
+
+func cleanupSecretResource(session *xenapi.Session, ref xenapi.SecretRef) error {
+	uuid, err := xenapi.Secret.GetUUID(session, ref)
+	if err != nil {
+		return cleanupError("secret", string(ref), err)
+	}
+	err = xenapi.Secret.Destroy(session, ref)
+	if err != nil {
+		return cleanupError("secret", uuid, err)
+	}
+	return nil
+}
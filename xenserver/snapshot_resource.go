@@ -28,7 +28,8 @@ func NewSnapshotResource() resource.Resource {
 
 // snapshotResource defines the resource implementation.
 type snapshotResource struct {
-	session *xenapi.Session
+	session          *xenapi.Session
+	operationLimiter operationLimiter
 }
 
 func (r *snapshotResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -109,6 +110,7 @@ func (r *snapshotResource) Configure(_ context.Context, req resource.ConfigureRe
 		return
 	}
 	r.session = providerData.session
+	r.operationLimiter = providerData.operationLimiter
 }
 
 func (r *snapshotResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
@@ -118,8 +120,17 @@ func (r *snapshotResource) Create(ctx context.Context, req resource.CreateReques
 		return
 	}
 
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
 	tflog.Debug(ctx, "Creating snapshot...")
-	vmRef, err := xenapi.VM.GetByUUID(r.session, data.VM.ValueString())
+	vmRef, err := withSessionRetry(r.session, func() (xenapi.VMRef, error) {
+		return xenapi.VM.GetByUUID(r.session, data.VM.ValueString())
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to get VM by UUID",
@@ -181,18 +192,9 @@ func (r *snapshotResource) Create(ctx context.Context, req resource.CreateReques
 					)
 					return
 				}
-				for _, srRecord := range srRecords {
-					if srRecord.Type == "nfs" || srRecord.Type == "lvm" {
-						srRef, err = xenapi.SR.GetByUUID(r.session, srRecord.UUID)
-						if err != nil {
-							resp.Diagnostics.AddError(
-								"Unable to get SR UUID",
-								err.Error(),
-							)
-							return
-						}
-						break
-					}
+				availableSRRef, err := getSRWithMostFreeSpace(srRecords, []string{"nfs", "lvm"})
+				if err == nil {
+					srRef = availableSRRef
 				}
 			}
 			err = xenapi.VM.SetSuspendSR(r.session, vmRef, srRef)
@@ -265,8 +267,17 @@ func (r *snapshotResource) Read(ctx context.Context, req resource.ReadRequest, r
 		return
 	}
 
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
 	// Overwrite data with refreshed resource state
-	snapshotRef, err := xenapi.VM.GetByUUID(r.session, data.UUID.ValueString())
+	snapshotRef, err := withSessionRetry(r.session, func() (xenapi.VMRef, error) {
+		return xenapi.VM.GetByUUID(r.session, data.UUID.ValueString())
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to get snapshot by UUID",
@@ -313,7 +324,15 @@ func (r *snapshotResource) Update(ctx context.Context, req resource.UpdateReques
 	if resp.Diagnostics.HasError() {
 		return
 	}
-	err := snapshotResourceModelUpdateCheck(plan, state)
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	err = snapshotResourceModelUpdateCheck(plan, state)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Error update xenserver_snapshot configuration",
@@ -323,7 +342,9 @@ func (r *snapshotResource) Update(ctx context.Context, req resource.UpdateReques
 	}
 
 	// Update the resource with new configuration
-	snapshotRef, err := xenapi.VM.GetByUUID(r.session, plan.UUID.ValueString())
+	snapshotRef, err := withSessionRetry(r.session, func() (xenapi.VMRef, error) {
+		return xenapi.VM.GetByUUID(r.session, plan.UUID.ValueString())
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to get snapshot by UUID",
@@ -388,8 +409,17 @@ func (r *snapshotResource) Delete(ctx context.Context, req resource.DeleteReques
 		return
 	}
 
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
 	tflog.Debug(ctx, "Deleting snapshot...")
-	snapshotRef, err := xenapi.VM.GetByUUID(r.session, data.UUID.ValueString())
+	snapshotRef, err := withSessionRetry(r.session, func() (xenapi.VMRef, error) {
+		return xenapi.VM.GetByUUID(r.session, data.UUID.ValueString())
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to get snapshot by UUID",
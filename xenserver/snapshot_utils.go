@@ -83,6 +83,14 @@ func updateSnapshotResourceModelComputed(ctx context.Context, session *xenapi.Se
 			if diags.HasError() {
 				return errors.New("unable to access VDI other config")
 			}
+			smConfig, diags := types.MapValueFrom(ctx, types.StringType, getUserVDISmConfig(vdiRecord.SmConfig))
+			if diags.HasError() {
+				return errors.New("unable to access VDI SM config")
+			}
+			allocation, err := getVDIAllocation(session, vdiRecord)
+			if err != nil {
+				return err
+			}
 			vdiData := vdiResourceModel{
 				NameLabel:       types.StringValue(vdiRecord.NameLabel),
 				NameDescription: types.StringValue(vdiRecord.NameDescription),
@@ -94,6 +102,8 @@ func updateSnapshotResourceModelComputed(ctx context.Context, session *xenapi.Se
 				Sharable:        types.BoolValue(vdiRecord.Sharable),
 				ReadOnly:        types.BoolValue(vdiRecord.ReadOnly),
 				OtherConfig:     otherConfig,
+				SmConfig:        smConfig,
+				Allocation:      allocation,
 			}
 			vdiDataList = append(vdiDataList, vdiData)
 		}
@@ -127,19 +137,30 @@ func snapshotResourceModelUpdate(session *xenapi.Session, ref xenapi.VMRef, data
 }
 
 func cleanupSnapshotResource(session *xenapi.Session, ref xenapi.VMRef) error {
+	vmRecord, err := xenapi.VM.GetRecord(session, ref)
+	if err != nil {
+		return cleanupError("VM", string(ref), err)
+	}
+	// the snapshots, templates and clones are all destroyed with their disks here
+	resourceType := "VM"
+	if vmRecord.IsASnapshot {
+		resourceType = "snapshot"
+	} else if vmRecord.IsATemplate {
+		resourceType = "template"
+	}
 	vdiRefs, err := getAllDiskTypeVDIs(session, ref)
 	if err != nil {
-		return err
+		return cleanupError(resourceType, vmRecord.UUID, err)
 	}
 	for _, vdiRef := range vdiRefs {
 		err := xenapi.VDI.Destroy(session, vdiRef)
 		if err != nil && !strings.Contains(err.Error(), "HANDLE_INVALID") {
-			return errors.New(err.Error())
+			return cleanupError(resourceType, vmRecord.UUID, err)
 		}
 	}
 	err = xenapi.VM.Destroy(session, ref)
 	if err != nil {
-		return errors.New(err.Error())
+		return cleanupError(resourceType, vmRecord.UUID, err)
 	}
 	return nil
 }
@@ -25,7 +25,8 @@ func NewSRDataSource() datasource.DataSource {
 
 // srDataSource is the data source implementation.
 type srDataSource struct {
-	session *xenapi.Session
+	session          *xenapi.Session
+	operationLimiter operationLimiter
 }
 
 // Metadata returns the data source type name.
@@ -164,6 +165,7 @@ func (d *srDataSource) Configure(_ context.Context, req datasource.ConfigureRequ
 		return
 	}
 	d.session = providerData.session
+	d.operationLimiter = providerData.operationLimiter
 }
 
 // Read refreshes the Terraform state with the latest data.
@@ -174,7 +176,16 @@ func (d *srDataSource) Read(ctx context.Context, req datasource.ReadRequest, res
 		return
 	}
 
-	srRecords, err := xenapi.SR.GetAllRecords(d.session)
+	ctx, done, err := d.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	srRecords, err := withSessionRetry(d.session, func() (map[xenapi.SRRef]xenapi.SRRecord, error) {
+		return xenapi.SR.GetAllRecords(d.session)
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to get SR records",
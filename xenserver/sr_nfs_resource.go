@@ -22,6 +22,7 @@ var (
 	_ resource.Resource                = &nfsResource{}
 	_ resource.ResourceWithConfigure   = &nfsResource{}
 	_ resource.ResourceWithImportState = &nfsResource{}
+	_ resource.ResourceWithModifyPlan  = &nfsResource{}
 )
 
 func NewNFSResource() resource.Resource {
@@ -30,7 +31,8 @@ func NewNFSResource() resource.Resource {
 
 // nfsResource defines the resource implementation.
 type nfsResource struct {
-	session *xenapi.Session
+	session          *xenapi.Session
+	operationLimiter operationLimiter
 }
 
 func (r *nfsResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -39,7 +41,7 @@ func (r *nfsResource) Metadata(_ context.Context, req resource.MetadataRequest,
 
 func (r *nfsResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
-		MarkdownDescription: "Provides an NFS storage repository resource.",
+		MarkdownDescription: "Provides an NFS storage repository resource. When `type` is `\"nfs\"`, the NFS server is probed when planning the creation, so an unreachable `storage_location` is reported before apply. The ISO libraries are not probed, an unreachable `storage_location` is reported when they are created.",
 		Attributes: map[string]schema.Attribute{
 			"name_label": schema.StringAttribute{
 				MarkdownDescription: "The name of the NFS storage repository.",
@@ -64,22 +66,22 @@ func (r *nfsResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *
 			},
 			"storage_location": schema.StringAttribute{
 				MarkdownDescription: "The server and server path of the NFS storage repository." + "<br />" +
-					"Follow the format `\"server:/path\"`." +
+					"Follow the format `\"server:/path\"`. The server is case insensitive and the trailing slashes of the path are ignored, so the equivalent forms are not reported as a change." +
 					"\n\n-> **Note:** `storage_location` is not allowed to be updated.",
 				Required: true,
 			},
 			"version": schema.StringAttribute{
 				MarkdownDescription: "The version of NFS storage repository." + "<br />" +
-					"Can be set as `\"3\"` or `\"4\"`." +
+					"Can be set as `\"3\"`, `\"4\"` or `\"4.1\"`." +
 					"\n\n-> **Note:** `version` is not allowed to be updated.",
 				Required: true,
 				Validators: []validator.String{
-					stringvalidator.OneOf("3", "4"),
+					stringvalidator.OneOf("3", "4", "4.1"),
 				},
 			},
 			"advanced_options": schema.StringAttribute{
-				MarkdownDescription: "The advanced options of the NFS storage repository, default to be `\"\"`." +
-					"\n\n-> **Note:** `advanced_options` is not allowed to be updated.",
+				MarkdownDescription: "The advanced options of the NFS storage repository, which are passed to the NFS mount, eg. `\"rsize=65536,wsize=65536\"`, default to be `\"\"`." +
+					"\n\n-> **Note:** Updating `advanced_options` unplugs the storage repository on all the hosts and plugs it again with the new options, it fails if the storage repository is in use by running VMs.",
 				Optional: true,
 				Computed: true,
 				Default:  stringdefault.StaticString(""),
@@ -117,6 +119,51 @@ func (r *nfsResource) Configure(_ context.Context, req resource.ConfigureRequest
 		return
 	}
 	r.session = providerData.session
+	r.operationLimiter = providerData.operationLimiter
+}
+
+// ModifyPlan probes the NFS server when the SR is to be created, so a wrong storage_location is reported at plan time.
+// Only the "nfs" type is probed, see srProbeTypes.
+func (r *nfsResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if r.session == nil || req.Plan.Raw.IsNull() || !req.State.Raw.IsNull() {
+		return
+	}
+	var plan nfsResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	// some values are only known at apply time, leave the check to SR.Create
+	if plan.Type.IsUnknown() ||
+		plan.StorageLocation.IsUnknown() ||
+		plan.Version.IsUnknown() ||
+		plan.AdvancedOptions.IsUnknown() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	params, err := getNFSCreateParams(r.session, plan)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get SR create params",
+			err.Error(),
+		)
+		return
+	}
+	err = probeSR(r.session, params)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("storage_location"),
+			"Unable to reach the storage target",
+			"Probing the storage with the configuration failed, please check it.\n\n"+err.Error(),
+		)
+	}
 }
 
 func (r *nfsResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
@@ -126,6 +173,13 @@ func (r *nfsResource) Create(ctx context.Context, req resource.CreateRequest, re
 		return
 	}
 
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
 	tflog.Debug(ctx, "Creating NFS SR...")
 	params, err := getNFSCreateParams(r.session, data)
 	if err != nil {
@@ -135,7 +189,7 @@ func (r *nfsResource) Create(ctx context.Context, req resource.CreateRequest, re
 		)
 		return
 	}
-	srRef, err := createSRResource(r.session, params)
+	srRef, err := createSRResource(ctx, r.session, params)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to create SR",
@@ -187,8 +241,17 @@ func (r *nfsResource) Read(ctx context.Context, req resource.ReadRequest, resp *
 		return
 	}
 
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
 	// Overwrite data with refreshed resource state
-	srRef, err := xenapi.SR.GetByUUID(r.session, data.UUID.ValueString())
+	srRef, err := withSessionRetry(r.session, func() (xenapi.SRRef, error) {
+		return xenapi.SR.GetByUUID(r.session, data.UUID.ValueString())
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to get SR ref in Read stage",
@@ -223,12 +286,19 @@ func (r *nfsResource) Update(ctx context.Context, req resource.UpdateRequest, re
 		return
 	}
 
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
 	// Checking if configuration changes are allowed
 	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
-	err := nfsResourceModelUpdateCheck(plan, state)
+	err = nfsResourceModelUpdateCheck(plan, state)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Error update xenserver_sr_nfs configuration",
@@ -238,7 +308,9 @@ func (r *nfsResource) Update(ctx context.Context, req resource.UpdateRequest, re
 	}
 
 	// Update the resource with new configuration
-	srRef, err := xenapi.SR.GetByUUID(r.session, plan.UUID.ValueString())
+	srRef, err := withSessionRetry(r.session, func() (xenapi.SRRef, error) {
+		return xenapi.SR.GetByUUID(r.session, plan.UUID.ValueString())
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to get SR ref in Update stage",
@@ -281,7 +353,16 @@ func (r *nfsResource) Delete(ctx context.Context, req resource.DeleteRequest, re
 		return
 	}
 
-	srRef, err := xenapi.SR.GetByUUID(r.session, data.UUID.ValueString())
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	srRef, err := withSessionRetry(r.session, func() (xenapi.SRRef, error) {
+		return xenapi.SR.GetByUUID(r.session, data.UUID.ValueString())
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to get SR ref in Delete stage",
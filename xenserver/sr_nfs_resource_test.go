@@ -2,10 +2,12 @@ package xenserver
 
 import (
 	"fmt"
+	"maps"
 	"os"
 	"regexp"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
 )
 
@@ -60,9 +62,12 @@ func TestAccNFSResource(t *testing.T) {
 				Config:      providerConfig + testAccNFSResourceConfig("Test NFS storage repository 2", "Test NFS Description", "4", storage_location, ""),
 				ExpectError: regexp.MustCompile(`"version" doesn't expected to be updated`),
 			},
+			// Update advanced_options in place
 			{
-				Config:      providerConfig + testAccNFSResourceConfig("Test NFS storage repository 2", "Test NFS Description", "3", storage_location, `advanced_options = "key:value"`),
-				ExpectError: regexp.MustCompile(`"advanced_options" doesn't expected to be updated`),
+				Config: providerConfig + testAccNFSResourceConfig("Test NFS storage repository", "", "3", storage_location, `advanced_options = "rsize=65536,wsize=65536"`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_sr_nfs.test_nfs", "advanced_options", "rsize=65536,wsize=65536"),
+				),
 			},
 			// Update and Read testing
 			{
@@ -136,3 +141,85 @@ func TestAccNFSISOResource(t *testing.T) {
 		},
 	})
 }
+
+func TestGetNFSDeviceConfig(t *testing.T) {
+	testCases := []struct {
+		data     nfsResourceModel
+		expected map[string]string
+	}{
+		{
+			data: nfsResourceModel{
+				Type:            types.StringValue("nfs"),
+				StorageLocation: types.StringValue("10.0.0.1:/share/sr"),
+				Version:         types.StringValue("3"),
+				AdvancedOptions: types.StringValue(""),
+			},
+			expected: map[string]string{"server": "10.0.0.1", "serverpath": "/share/sr", "nfsversion": "3", "options": ""},
+		},
+		{
+			data: nfsResourceModel{
+				Type:            types.StringValue("nfs"),
+				StorageLocation: types.StringValue("10.0.0.1:/share/sr"),
+				Version:         types.StringValue("4.1"),
+				AdvancedOptions: types.StringValue("rsize=65536"),
+			},
+			expected: map[string]string{"server": "10.0.0.1", "serverpath": "/share/sr", "nfsversion": "4.1", "options": "rsize=65536"},
+		},
+		{
+			data: nfsResourceModel{
+				Type:            types.StringValue("iso"),
+				StorageLocation: types.StringValue("10.0.0.1:/share/iso"),
+				Version:         types.StringValue("4.1"),
+				AdvancedOptions: types.StringValue(""),
+			},
+			expected: map[string]string{"location": "10.0.0.1:/share/iso", "type": "nfs_iso", "nfsversion": "4.1", "options": ""},
+		},
+	}
+	for _, tc := range testCases {
+		deviceConfig := getNFSDeviceConfig(tc.data)
+		if !maps.Equal(deviceConfig, tc.expected) {
+			t.Errorf("getNFSDeviceConfig(%v) = %v, expected %v", tc.data, deviceConfig, tc.expected)
+		}
+	}
+}
+
+func TestNormalizeNFSStorageLocation(t *testing.T) {
+	testCases := []struct {
+		storageLocation string
+		expected        string
+	}{
+		{storageLocation: "10.0.0.1:/share/sr", expected: "10.0.0.1:/share/sr"},
+		{storageLocation: " 10.0.0.1:/share/sr\n", expected: "10.0.0.1:/share/sr"},
+		{storageLocation: "10.0.0.1:/share/sr/", expected: "10.0.0.1:/share/sr"},
+		{storageLocation: "NFS.Example.com:/Share/SR", expected: "nfs.example.com:/Share/SR"},
+		{storageLocation: "10.0.0.1:/", expected: "10.0.0.1:/"},
+		{storageLocation: "10.0.0.1", expected: "10.0.0.1"},
+	}
+	for _, tc := range testCases {
+		location := normalizeNFSStorageLocation(tc.storageLocation)
+		if location != tc.expected {
+			t.Errorf("normalizeNFSStorageLocation(%q) = %q, expected %q", tc.storageLocation, location, tc.expected)
+		}
+	}
+}
+
+func TestNFSResourceModelUpdateCheck(t *testing.T) {
+	state := nfsResourceModel{Type: types.StringValue("nfs"), StorageLocation: types.StringValue("10.0.0.1:/share/sr"), Version: types.StringValue("3")}
+	testCases := []struct {
+		storageLocation string
+		expectError     bool
+	}{
+		{storageLocation: "10.0.0.1:/share/sr/", expectError: false},
+		{storageLocation: "10.0.0.1:/share/sr\n", expectError: false},
+		{storageLocation: "10.0.0.2:/share/sr", expectError: true},
+		{storageLocation: "10.0.0.1:/share/SR", expectError: true},
+	}
+	for _, tc := range testCases {
+		plan := state
+		plan.StorageLocation = types.StringValue(tc.storageLocation)
+		err := nfsResourceModelUpdateCheck(plan, state)
+		if (err != nil) != tc.expectError {
+			t.Errorf("nfsResourceModelUpdateCheck(%q) returned error %v, expected error %t", tc.storageLocation, err, tc.expectError)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package xenserver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"xenapi"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &srProbeDataSource{}
+	_ datasource.DataSourceWithConfigure = &srProbeDataSource{}
+)
+
+// NewSRProbeDataSource is a helper function to simplify the provider implementation.
+func NewSRProbeDataSource() datasource.DataSource {
+	return &srProbeDataSource{}
+}
+
+// srProbeDataSource is the data source implementation.
+type srProbeDataSource struct {
+	session          *xenapi.Session
+	operationLimiter operationLimiter
+}
+
+// Metadata returns the data source type name.
+func (d *srProbeDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_sr_probe"
+}
+
+// Schema defines the schema for the data source.
+func (d *srProbeDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Probes a storage target from the pool coordinator before the storage repository is created. It discovers the storage repositories on the target, or the items to complete the device config, for example, the exports of an NFS server, the IQNs of an iSCSI target and the LUNs of an IQN." +
+			"\n\n-> **Note:** It requires XenServer 7.5 (API version 2.10) or later.",
+
+		Attributes: map[string]schema.Attribute{
+			"type": schema.StringAttribute{
+				MarkdownDescription: "The type of the storage repository to probe, for example, `\"nfs\"`, `\"smb\"` or `\"lvmoiscsi\"`.",
+				Required:            true,
+			},
+			"device_config": schema.MapAttribute{
+				MarkdownDescription: "The device config to probe with, for example, `{ server = \"10.0.0.1\" }` for NFS, or `{ target = \"10.0.0.2\" }` for iSCSI.",
+				Required:            true,
+				ElementType:         types.StringType,
+			},
+			"sm_config": schema.MapAttribute{
+				MarkdownDescription: "The SM dependent data to probe with.",
+				Optional:            true,
+				ElementType:         types.StringType,
+			},
+			"data_items": schema.ListNestedAttribute{
+				MarkdownDescription: "The return items of the probe.",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"configuration": schema.MapAttribute{
+							MarkdownDescription: "The device config of the item, which is the probed device config with the discovered keys, for example, `serverpath` of the NFS export.",
+							Computed:            true,
+							ElementType:         types.StringType,
+						},
+						"complete": schema.BoolAttribute{
+							MarkdownDescription: "True if the storage repository can be created with the `configuration`.",
+							Computed:            true,
+						},
+						"sr_uuid": schema.StringAttribute{
+							MarkdownDescription: "The UUID of the existing storage repository found on the target, or `\"\"`.",
+							Computed:            true,
+						},
+						"sr_name_label": schema.StringAttribute{
+							MarkdownDescription: "The name of the existing storage repository found on the target, or `\"\"`.",
+							Computed:            true,
+						},
+						"extra_info": schema.MapAttribute{
+							MarkdownDescription: "The additional information of the item reported by the storage backend.",
+							Computed:            true,
+							ElementType:         types.StringType,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *srProbeDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	providerData, ok := req.ProviderData.(*xsProvider)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *xenserver.xsProvider, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+	d.session = providerData.session
+	d.operationLimiter = providerData.operationLimiter
+}
+
+// Read refreshes the Terraform state with the latest data.
+func (d *srProbeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data srProbeDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := d.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	deviceConfig := make(map[string]string)
+	resp.Diagnostics.Append(data.DeviceConfig.ElementsAs(ctx, &deviceConfig, false)...)
+	smConfig := make(map[string]string)
+	if !data.SmConfig.IsNull() {
+		resp.Diagnostics.Append(data.SmConfig.ElementsAs(ctx, &smConfig, false)...)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	results, err := withSessionRetry(d.session, func() ([]xenapi.ProbeResultRecord, error) {
+		return probeSRTargets(d.session, data.Type.ValueString(), deviceConfig, smConfig)
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to probe the storage target",
+			err.Error(),
+		)
+		return
+	}
+
+	probeItems := []srProbeItemData{}
+	for _, result := range results {
+		var probeData srProbeItemData
+		err = updateSRProbeItemData(ctx, result, &probeData)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Unable to update SR probe item data",
+				err.Error(),
+			)
+			return
+		}
+		probeItems = append(probeItems, probeData)
+	}
+	data.DataItems = probeItems
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+}
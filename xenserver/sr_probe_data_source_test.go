@@ -0,0 +1,105 @@
+package xenserver
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func testAccSRProbeDataSourceConfig(server string) string {
+	return fmt.Sprintf(`
+data "xenserver_sr_probe" "test_sr_probe_data" {
+	type          = "nfs"
+	device_config = {
+		server = "%s"
+	}
+}
+`, server)
+}
+
+func TestAccSRProbeDataSource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + testAccSRProbeDataSourceConfig(os.Getenv("NFS_SERVER")),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.xenserver_sr_probe.test_sr_probe_data", "type", "nfs"),
+					resource.TestCheckResourceAttrSet("data.xenserver_sr_probe.test_sr_probe_data", "data_items.#"),
+					resource.TestCheckResourceAttrSet("data.xenserver_sr_probe.test_sr_probe_data", "data_items.0.configuration.serverpath"),
+				),
+			},
+		},
+	})
+}
+
+func TestParseSRProbeXML(t *testing.T) {
+	deviceConfig := map[string]string{"server": "10.0.0.1"}
+	testCases := []struct {
+		message               string
+		expectedConfiguration []map[string]string
+		expectedComplete      []bool
+	}{
+		{
+			message: `API error: SR_BACKEND_FAILURE_101 [, NFS SR creation error, <?xml version="1.0" ?>
+<nfs-exports>
+	<Export>
+		<Target>10.0.0.1</Target>
+		<Path>/share/sr1</Path>
+		<Accesslist>*</Accesslist>
+	</Export>
+	<Export>
+		<Target>10.0.0.1</Target>
+		<Path>/share/sr2</Path>
+		<Accesslist>*</Accesslist>
+	</Export>
+</nfs-exports>
+]`,
+			expectedConfiguration: []map[string]string{
+				{"server": "10.0.0.1", "serverpath": "/share/sr1"},
+				{"server": "10.0.0.1", "serverpath": "/share/sr2"},
+			},
+			expectedComplete: []bool{true, true},
+		},
+		{
+			message: `API error: SR_BACKEND_FAILURE_96 [, The request is missing or has an incorrect target IQN parameter, <?xml version="1.0" ?>
+<iscsi-target-iqns>
+	<TGT>
+		<Index>0</Index>
+		<IPAddress>10.0.0.1</IPAddress>
+		<TargetIQN>iqn.2024-01.com.example:target</TargetIQN>
+	</TGT>
+</iscsi-target-iqns>
+]`,
+			expectedConfiguration: []map[string]string{
+				{"server": "10.0.0.1", "targetIQN": "iqn.2024-01.com.example:target"},
+			},
+			expectedComplete: []bool{false},
+		},
+		{
+			message:               `API error: SR_BACKEND_FAILURE_140 [, Incorrect DNS name, unable to resolve., ]`,
+			expectedConfiguration: []map[string]string{},
+			expectedComplete:      []bool{},
+		},
+	}
+	for _, tc := range testCases {
+		results := parseSRProbeXML(tc.message, deviceConfig)
+		if len(results) != len(tc.expectedConfiguration) {
+			t.Fatalf("parseSRProbeXML() returned %d items, expected %d", len(results), len(tc.expectedConfiguration))
+		}
+		for i, result := range results {
+			if !maps.Equal(result.Configuration, tc.expectedConfiguration[i]) {
+				t.Errorf("parseSRProbeXML() item %d configuration = %v, expected %v", i, result.Configuration, tc.expectedConfiguration[i])
+			}
+			if result.Complete != tc.expectedComplete[i] {
+				t.Errorf("parseSRProbeXML() item %d complete = %t, expected %t", i, result.Complete, tc.expectedComplete[i])
+			}
+		}
+	}
+	if deviceConfig["serverpath"] != "" {
+		t.Errorf("parseSRProbeXML() modified the probed device config: %v", deviceConfig)
+	}
+}
@@ -4,15 +4,18 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
 	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapdefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 
@@ -24,15 +27,21 @@ var (
 	_ resource.Resource                = &srResource{}
 	_ resource.ResourceWithConfigure   = &srResource{}
 	_ resource.ResourceWithImportState = &srResource{}
+	_ resource.ResourceWithModifyPlan  = &srResource{}
 )
 
+// srImportedPrivateStateKey is the private state key which marks the imported SR, whose physical_size
+// is unknown
+const srImportedPrivateStateKey = "imported"
+
 func NewSRResource() resource.Resource {
 	return &srResource{}
 }
 
 // srResource defines the resource implementation.
 type srResource struct {
-	session *xenapi.Session
+	session          *xenapi.Session
+	operationLimiter operationLimiter
 }
 
 func (r *srResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -41,7 +50,7 @@ func (r *srResource) Metadata(_ context.Context, req resource.MetadataRequest, r
 
 func (r *srResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
-		MarkdownDescription: "Provides a general storage repository resource.",
+		MarkdownDescription: "Provides a general storage repository resource. For the `nfs`, `smb` and `lvmoiscsi` types, the storage target is probed with `device_config` when planning the creation, and the available options are reported if it can't be reached.",
 		Attributes: map[string]schema.Attribute{
 			"name_label": schema.StringAttribute{
 				MarkdownDescription: "The name of the storage repository.",
@@ -61,7 +70,7 @@ func (r *srResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *r
 				Default:  stringdefault.StaticString("dummy"),
 			},
 			"content_type": schema.StringAttribute{
-				MarkdownDescription: "The type of the SR's content, if required (for example. \"ISOs\"), default to be `\"\"`." +
+				MarkdownDescription: "The type of the SR's content, if required (for example. \"iso\"), default to be `\"\"`. The `\"iso\"` content type can only be used with the `\"iso\"` and `\"udev\"` SR types, an `\"iso\"` SR with the empty content type is taken as `\"iso\"`." +
 					"\n\n-> **Note:** `content_type` is not allowed to be updated.",
 				Optional: true,
 				Computed: true,
@@ -95,6 +104,29 @@ func (r *srResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *r
 				Optional: true,
 				Computed: true,
 			},
+			"local_cache_enabled": schema.BoolAttribute{
+				MarkdownDescription: "True if the host-local cache (IntelliCache) is enabled on this SR, which caches the reads of the VDIs on the host, default to be `false`. Only a non-shared SR can be used for the local cache, one SR per host." +
+					"\n\n-> **Note:** XAPI requires the host to be disabled and to have no running VMs to change `local_cache_enabled`, see `xenserver_host_maintenance`.",
+				Optional: true,
+				Computed: true,
+				Default:  booldefault.StaticBool(false),
+			},
+			"auto_scan": schema.BoolAttribute{
+				MarkdownDescription: "True if the SR is scanned periodically for the changes made outside of XAPI, e.g. the ISO files copied to an ISO library. Default to be `true` when `content_type` is `\"iso\"` or `type` is `\"iso\"`, otherwise `false`.",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"physical_size": schema.Int64Attribute{
+				MarkdownDescription: "The physical size in bytes to create the storage repository with, which caps the allocation of a local storage repository. It's only used by the types `\"ext\"`, `\"file\"` and `\"lvm\"`, the other types use the size of the underlying storage." +
+					"\n\n-> **Note:** `physical_size` is not allowed to be updated, it's not read back from the storage repository. It can be set once on an imported storage repository, the value is only recorded in the state.",
+				Optional: true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(0),
+				},
+			},
 			"uuid": schema.StringAttribute{
 				MarkdownDescription: "The UUID of the storage repository.",
 				Computed:            true,
@@ -128,6 +160,85 @@ func (r *srResource) Configure(_ context.Context, req resource.ConfigureRequest,
 		return
 	}
 	r.session = providerData.session
+	r.operationLimiter = providerData.operationLimiter
+}
+
+// ModifyPlan checks the local cache, the physical size and the content type can be used on the SR and probes the storage target of a new SR, see
+// srProbeTypes for the types which are checked.
+func (r *srResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+	var plan srResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if plan.LocalCacheEnabled.ValueBool() && plan.Shared.ValueBool() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("local_cache_enabled"),
+			"Invalid local_cache_enabled",
+			"The local cache can only be enabled on a non-shared SR, set shared = false or local_cache_enabled = false.",
+		)
+		return
+	}
+	if !plan.Type.IsUnknown() && !plan.PhysicalSize.IsUnknown() {
+		err := checkSRPhysicalSize(plan.Type.ValueString(), plan.PhysicalSize.ValueInt64())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("physical_size"),
+				"Invalid physical_size",
+				err.Error(),
+			)
+			return
+		}
+	}
+	// only check the new SR, the existing ones, e.g. imported, are kept as they are
+	if req.State.Raw.IsNull() && !plan.Type.IsUnknown() && !plan.ContentType.IsUnknown() {
+		err := checkSRContentType(plan.Type.ValueString(), plan.ContentType.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("content_type"),
+				"Invalid content_type",
+				err.Error(),
+			)
+			return
+		}
+	}
+
+	if r.session == nil || !req.State.Raw.IsNull() {
+		return
+	}
+	// some values are only known at apply time, leave the check to SR.Create
+	if plan.Type.IsUnknown() ||
+		plan.DeviceConfig.IsUnknown() ||
+		plan.SmConfig.IsUnknown() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	params, err := getSRCreateParams(ctx, r.session, plan)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get SR create params",
+			err.Error(),
+		)
+		return
+	}
+	err = probeSR(r.session, params)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("device_config"),
+			"Unable to reach the storage target",
+			"Probing the storage with the configuration failed, please check it.\n\n"+err.Error(),
+		)
+	}
 }
 
 func (r *srResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
@@ -137,8 +248,17 @@ func (r *srResource) Create(ctx context.Context, req resource.CreateRequest, res
 		return
 	}
 
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
 	tflog.Debug(ctx, "Creating SR ...")
-	params, err := getSRCreateParams(ctx, r.session, data)
+	params, err := withSessionRetry(r.session, func() (srCreateParams, error) {
+		return getSRCreateParams(ctx, r.session, data)
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to get SR create params",
@@ -146,7 +266,7 @@ func (r *srResource) Create(ctx context.Context, req resource.CreateRequest, res
 		)
 		return
 	}
-	srRef, err := createSRResource(r.session, params)
+	srRef, err := createSRResource(ctx, r.session, params)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to create SR",
@@ -154,6 +274,21 @@ func (r *srResource) Create(ctx context.Context, req resource.CreateRequest, res
 		)
 		return
 	}
+	err = updateSRLocalCache(r.session, srRef, data.LocalCacheEnabled.ValueBool())
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to enable SR local cache",
+			err.Error(),
+		)
+		err = cleanupSRResource(r.session, srRef)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Error cleaning up SR resource",
+				err.Error(),
+			)
+		}
+		return
+	}
 	srRecord, pbdRecord, err := getSRRecordAndPBDRecord(r.session, srRef)
 	if err != nil {
 		resp.Diagnostics.AddError(
@@ -198,8 +333,17 @@ func (r *srResource) Read(ctx context.Context, req resource.ReadRequest, resp *r
 		return
 	}
 
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
 	// Overwrite data with refreshed resource state
-	srRef, err := xenapi.SR.GetByUUID(r.session, data.UUID.ValueString())
+	srRef, err := withSessionRetry(r.session, func() (xenapi.SRRef, error) {
+		return xenapi.SR.GetByUUID(r.session, data.UUID.ValueString())
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to get SR ref",
@@ -234,12 +378,25 @@ func (r *srResource) Update(ctx context.Context, req resource.UpdateRequest, res
 		return
 	}
 
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
 	// Checking if configuration changes are allowed
 	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
-	err := srResourceModelUpdateCheck(plan, state)
+	// the imported SR has no physical_size in state, record the configured one
+	imported, diags := req.Private.GetKey(ctx, srImportedPrivateStateKey)
+	resp.Diagnostics.Append(diags...)
+	if string(imported) == "true" && state.PhysicalSize.IsNull() {
+		state.PhysicalSize = plan.PhysicalSize
+	}
+	err = srResourceModelUpdateCheck(plan, state)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Error update xenserver_sr configuration",
@@ -249,7 +406,9 @@ func (r *srResource) Update(ctx context.Context, req resource.UpdateRequest, res
 	}
 
 	// Update the resource with new configuration
-	srRef, err := xenapi.SR.GetByUUID(r.session, plan.UUID.ValueString())
+	srRef, err := withSessionRetry(r.session, func() (xenapi.SRRef, error) {
+		return xenapi.SR.GetByUUID(r.session, plan.UUID.ValueString())
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to get SR ref",
@@ -265,6 +424,14 @@ func (r *srResource) Update(ctx context.Context, req resource.UpdateRequest, res
 		)
 		return
 	}
+	err = updateSRLocalCache(r.session, srRef, plan.LocalCacheEnabled.ValueBool())
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to update SR local cache",
+			err.Error(),
+		)
+		return
+	}
 	srRecord, pbdRecord, err := getSRRecordAndPBDRecord(r.session, srRef)
 	if err != nil {
 		resp.Diagnostics.AddError(
@@ -292,7 +459,16 @@ func (r *srResource) Delete(ctx context.Context, req resource.DeleteRequest, res
 		return
 	}
 
-	srRef, err := xenapi.SR.GetByUUID(r.session, data.UUID.ValueString())
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	srRef, err := withSessionRetry(r.session, func() (xenapi.SRRef, error) {
+		return xenapi.SR.GetByUUID(r.session, data.UUID.ValueString())
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to get SR ref",
@@ -300,6 +476,10 @@ func (r *srResource) Delete(ctx context.Context, req resource.DeleteRequest, res
 		)
 		return
 	}
+	// XAPI only changes the local cache on a disabled host, leave it to the SR removal which drops the cache
+	if data.LocalCacheEnabled.ValueBool() {
+		tflog.Warn(ctx, "The local cache is enabled on SR "+data.UUID.ValueString()+", it's removed with the SR")
+	}
 	err = cleanupSRResource(r.session, srRef)
 	if err != nil {
 		resp.Diagnostics.AddError(
@@ -312,4 +492,5 @@ func (r *srResource) Delete(ctx context.Context, req resource.DeleteRequest, res
 
 func (r *srResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
 	resource.ImportStatePassthroughID(ctx, path.Root("uuid"), req, resp)
+	resp.Diagnostics.Append(resp.Private.SetKey(ctx, srImportedPrivateStateKey, []byte("true"))...)
 }
@@ -7,6 +7,8 @@ import (
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+
+	"xenapi"
 )
 
 func testAccSRResourceConfigLocal(name_label string, name_description string, typeString string, shared string, extra_config string) string {
@@ -37,6 +39,8 @@ func TestAccSRResourceLocal(t *testing.T) {
 					resource.TestCheckResourceAttr("xenserver_sr.test_sr", "sm_config.%", "0"),
 					resource.TestCheckResourceAttr("xenserver_sr.test_sr", "device_config.%", "0"),
 					// Verify dynamic values have any value set in the state.
+					resource.TestCheckResourceAttr("xenserver_sr.test_sr", "local_cache_enabled", "false"),
+					resource.TestCheckResourceAttr("xenserver_sr.test_sr", "auto_scan", "false"),
 					resource.TestCheckResourceAttrSet("xenserver_sr.test_sr", "host"),
 					resource.TestCheckResourceAttrSet("xenserver_sr.test_sr", "uuid"),
 				),
@@ -68,6 +72,18 @@ func TestAccSRResourceLocal(t *testing.T) {
 				Config:      providerConfig + testAccSRResourceConfigLocal("Test SR Local 2", "Test SR Description", "dummy", "false", `content_type = "etx4"`),
 				ExpectError: regexp.MustCompile(`"content_type" doesn't expected to be updated`),
 			},
+			{
+				Config:      providerConfig + testAccSRResourceConfigLocal("Test SR Local 2", "Test SR Description", "dummy", "true", `local_cache_enabled = true`),
+				ExpectError: regexp.MustCompile(`The local cache can only be enabled on a non-shared SR`),
+			},
+			{
+				Config:      providerConfig + testAccSRResourceConfigLocal("Test SR Local 2", "Test SR Description", "dummy", "false", `physical_size = 1073741824`),
+				ExpectError: regexp.MustCompile(`physical_size can only be used with type`),
+			},
+			{
+				Config:      providerConfig + testAccSRResourceConfigLocal("Test SR Local 2", "Test SR Description", "dummy", "false", `content_type = "iso"`),
+				ExpectError: regexp.MustCompile(`content_type "iso" can only be used with type "iso"`),
+			},
 			// Update and Read testing
 			{
 				Config: providerConfig + testAccSRResourceConfigLocal("Test SR Local 2", "Test SR Description", "dummy", "false", ""),
@@ -84,6 +100,12 @@ func TestAccSRResourceLocal(t *testing.T) {
 					resource.TestCheckResourceAttrSet("xenserver_sr.test_sr", "uuid"),
 				),
 			},
+			{
+				Config: providerConfig + testAccSRResourceConfigLocal("Test SR Local 2", "Test SR Description", "dummy", "false", `auto_scan = true`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_sr.test_sr", "auto_scan", "true"),
+				),
+			},
 			// Delete testing automatically occurs in TestCase
 		},
 	})
@@ -157,3 +179,56 @@ func TestAccSRResourceShared(t *testing.T) {
 		},
 	})
 }
+
+func TestGetSRWithMostFreeSpace(t *testing.T) {
+	vdiCreate := []xenapi.StorageOperations{xenapi.StorageOperationsVdiCreate}
+	srRecords := map[xenapi.SRRef]xenapi.SRRecord{
+		"nfs-small": {UUID: "a", Type: "nfs", PhysicalSize: 100, PhysicalUtilisation: 90, AllowedOperations: vdiCreate},
+		"nfs-large": {UUID: "b", Type: "nfs", PhysicalSize: 200, PhysicalUtilisation: 50, AllowedOperations: vdiCreate},
+		"nfs-tie":   {UUID: "c", Type: "nfs", PhysicalSize: 150, PhysicalUtilisation: 0, AllowedOperations: vdiCreate},
+		"nfs-down":  {UUID: "d", Type: "nfs", PhysicalSize: 1000, PhysicalUtilisation: 0},
+		"lvm":       {UUID: "e", Type: "lvm", PhysicalSize: 500, PhysicalUtilisation: 100, AllowedOperations: vdiCreate},
+	}
+	testCases := []struct {
+		srTypes   []string
+		expected  xenapi.SRRef
+		expectErr bool
+	}{
+		{srTypes: []string{"nfs"}, expected: "nfs-large"},
+		{srTypes: []string{"lvm"}, expected: "lvm"},
+		{srTypes: []string{"nfs", "lvm"}, expected: "lvm"},
+		{srTypes: []string{"ext"}, expectErr: true},
+	}
+	for _, tc := range testCases {
+		srRef, err := getSRWithMostFreeSpace(srRecords, tc.srTypes)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("getSRWithMostFreeSpace(%v) returned error %v, expected error %t", tc.srTypes, err, tc.expectErr)
+			continue
+		}
+		if !tc.expectErr && srRef != tc.expected {
+			t.Errorf("getSRWithMostFreeSpace(%v) = %s, expected %s", tc.srTypes, srRef, tc.expected)
+		}
+	}
+}
+
+func TestCheckSRContentType(t *testing.T) {
+	testCases := []struct {
+		typeKey     string
+		contentType string
+		expectErr   bool
+	}{
+		{typeKey: "iso", contentType: "iso"},
+		{typeKey: "iso", contentType: ""},
+		{typeKey: "udev", contentType: "iso"},
+		{typeKey: "udev", contentType: "disk"},
+		{typeKey: "dummy", contentType: ""},
+		{typeKey: "lvm", contentType: "iso", expectErr: true},
+		{typeKey: "iso", contentType: "disk", expectErr: true},
+	}
+	for _, tc := range testCases {
+		err := checkSRContentType(tc.typeKey, tc.contentType)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("checkSRContentType(%q, %q) returned %v, expected error: %t", tc.typeKey, tc.contentType, err, tc.expectErr)
+		}
+	}
+}
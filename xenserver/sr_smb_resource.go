@@ -22,6 +22,7 @@ var (
 	_ resource.Resource                = &smbResource{}
 	_ resource.ResourceWithConfigure   = &smbResource{}
 	_ resource.ResourceWithImportState = &smbResource{}
+	_ resource.ResourceWithModifyPlan  = &smbResource{}
 )
 
 func NewSMBResource() resource.Resource {
@@ -30,7 +31,8 @@ func NewSMBResource() resource.Resource {
 
 // smbResource defines the resource implementation.
 type smbResource struct {
-	session *xenapi.Session
+	session          *xenapi.Session
+	operationLimiter operationLimiter
 }
 
 func (r *smbResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -39,7 +41,7 @@ func (r *smbResource) Metadata(_ context.Context, req resource.MetadataRequest,
 
 func (r *smbResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
-		MarkdownDescription: "Provides an SMB storage repository resource.",
+		MarkdownDescription: "Provides an SMB storage repository resource. When `type` is `\"smb\"`, the SMB share is probed when planning the creation, so an unreachable `storage_location` or a wrong credential is reported before apply. The ISO libraries are not probed, their errors are reported when they are created.",
 		Attributes: map[string]schema.Attribute{
 			"name_label": schema.StringAttribute{
 				MarkdownDescription: "The name of the SMB storage repository.",
@@ -64,7 +66,7 @@ func (r *smbResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *
 			},
 			"storage_location": schema.StringAttribute{
 				MarkdownDescription: "The server and server path of the SMB storage repository." + "<br />" +
-					"Follow the format `\"\\\\\\\\server\\\\path\"`." +
+					"Follow the format `\"\\\\\\\\server\\\\path\"`. Forward slashes are accepted too, the server and the share are case insensitive and the trailing slashes are ignored, so the equivalent forms are not reported as a change." +
 					"\n\n-> **Note:** `storage_location` is not allowed to be updated.",
 				Required: true,
 			},
@@ -78,6 +80,30 @@ func (r *smbResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *
 				Optional:  true,
 				Sensitive: true,
 			},
+			"domain": schema.StringAttribute{
+				MarkdownDescription: "The domain of the user of the SMB storage repository, for example, `\"CORP\"`. Used when creating the SR." + "<br />" +
+					"It's combined with `username` as `domain\\username` for the storage." +
+					"\n\n-> **Note:** `domain` requires `username`.",
+				Optional: true,
+				Validators: []validator.String{
+					stringvalidator.AlsoRequires(path.MatchRoot("username")),
+				},
+			},
+			"cifs_version": schema.StringAttribute{
+				MarkdownDescription: "The SMB protocol version used to mount the ISO library, the storage picks it when not set." + "<br />" +
+					"Can be set as `\"1.0\"` or `\"3.0\"` when `type` is `\"iso\"`. Modern Windows servers usually reject SMB 1, set `\"3.0\"` for them." +
+					"\n\n-> **Note:** `cifs_version` is not allowed to be updated, and it can't be set when `type` is `\"smb\"` as the SR picks the SMB version itself.",
+				Optional: true,
+			},
+			"password_secret_uuid": schema.StringAttribute{
+				MarkdownDescription: "The UUID of an existing secret which holds the password of the SMB storage repository, for example, `xenserver_secret.smb_password.uuid`. Used when creating the SR." + "<br />" +
+					"The secret can be shared by multiple storage repositories, and it is not destroyed with the storage repository." +
+					"\n\n-> **Note:** `password_secret_uuid` conflicts with `password`, and it is not allowed to be updated.",
+				Optional: true,
+				Validators: []validator.String{
+					stringvalidator.ConflictsWith(path.MatchRoot("password")),
+				},
+			},
 			"uuid": schema.StringAttribute{
 				MarkdownDescription: "The UUID of the SMB storage repository.",
 				Computed:            true,
@@ -111,6 +137,68 @@ func (r *smbResource) Configure(_ context.Context, req resource.ConfigureRequest
 		return
 	}
 	r.session = providerData.session
+	r.operationLimiter = providerData.operationLimiter
+}
+
+// ModifyPlan checks the cifs_version is supported by the SR type and probes the SMB share when the SR is to be created,
+// so a wrong storage_location or credential is reported at plan time. Only the "smb" type is probed, see srProbeTypes.
+func (r *smbResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() || !req.State.Raw.IsNull() {
+		return
+	}
+	var plan smbResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if !plan.Type.IsUnknown() && !plan.CIFSVersion.IsUnknown() && !plan.CIFSVersion.IsNull() {
+		err := checkSMBCIFSVersion(plan.Type.ValueString(), plan.CIFSVersion.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("cifs_version"),
+				"Invalid cifs_version",
+				err.Error(),
+			)
+			return
+		}
+	}
+	if r.session == nil {
+		return
+	}
+	// some values are only known at apply time, leave the check to SR.Create
+	if plan.Type.IsUnknown() ||
+		plan.StorageLocation.IsUnknown() ||
+		plan.Username.IsUnknown() ||
+		plan.Domain.IsUnknown() ||
+		plan.CIFSVersion.IsUnknown() ||
+		plan.Password.IsUnknown() ||
+		plan.PasswordSecret.IsUnknown() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	params, err := getSMBCreateParams(r.session, plan)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get SR create params",
+			err.Error(),
+		)
+		return
+	}
+	err = probeSR(r.session, params)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("storage_location"),
+			"Unable to reach the storage target",
+			"Probing the storage with the configuration failed, please check it.\n\n"+err.Error(),
+		)
+	}
 }
 
 func (r *smbResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
@@ -120,6 +208,13 @@ func (r *smbResource) Create(ctx context.Context, req resource.CreateRequest, re
 		return
 	}
 
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
 	tflog.Debug(ctx, "Creating SMB SR...")
 	params, err := getSMBCreateParams(r.session, data)
 	if err != nil {
@@ -129,7 +224,7 @@ func (r *smbResource) Create(ctx context.Context, req resource.CreateRequest, re
 		)
 		return
 	}
-	srRef, err := createSRResource(r.session, params)
+	srRef, err := createSRResource(ctx, r.session, params)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to create SR",
@@ -181,8 +276,17 @@ func (r *smbResource) Read(ctx context.Context, req resource.ReadRequest, resp *
 		return
 	}
 
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
 	// Overwrite data with refreshed resource state
-	srRef, err := xenapi.SR.GetByUUID(r.session, data.UUID.ValueString())
+	srRef, err := withSessionRetry(r.session, func() (xenapi.SRRef, error) {
+		return xenapi.SR.GetByUUID(r.session, data.UUID.ValueString())
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to get SR ref",
@@ -217,12 +321,19 @@ func (r *smbResource) Update(ctx context.Context, req resource.UpdateRequest, re
 		return
 	}
 
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
 	// Checking if configuration changes are allowed
 	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
-	err := smbResourceModelUpdateCheck(plan, state)
+	err = smbResourceModelUpdateCheck(plan, state)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Error update xenserver_sr_smb configuration",
@@ -232,7 +343,9 @@ func (r *smbResource) Update(ctx context.Context, req resource.UpdateRequest, re
 	}
 
 	// Update the resource with new configuration
-	srRef, err := xenapi.SR.GetByUUID(r.session, plan.UUID.ValueString())
+	srRef, err := withSessionRetry(r.session, func() (xenapi.SRRef, error) {
+		return xenapi.SR.GetByUUID(r.session, plan.UUID.ValueString())
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to get SR ref",
@@ -275,7 +388,16 @@ func (r *smbResource) Delete(ctx context.Context, req resource.DeleteRequest, re
 		return
 	}
 
-	srRef, err := xenapi.SR.GetByUUID(r.session, data.UUID.ValueString())
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	srRef, err := withSessionRetry(r.session, func() (xenapi.SRRef, error) {
+		return xenapi.SR.GetByUUID(r.session, data.UUID.ValueString())
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to get SR ref",
@@ -2,12 +2,16 @@ package xenserver
 
 import (
 	"fmt"
+	"maps"
 	"os"
 	"regexp"
 	"strings"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+
+	"xenapi"
 )
 
 func testAccSMBResourceConfig(name_label string, name_description string, storage_location string, username string, password string, extra_config string) string {
@@ -32,6 +36,10 @@ func TestAccSMBResource(t *testing.T) {
 	resource.Test(t, resource.TestCase{
 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
 		Steps: []resource.TestStep{
+			{
+				Config:      providerConfig + testAccSMBResourceConfig("Test SMB storage repository", "", storage_location, username, password, `cifs_version = "3.0"`),
+				ExpectError: regexp.MustCompile(`cifs_version can't be set with type "smb"`),
+			},
 			// Create and Read testing
 			{
 				Config: providerConfig + testAccSMBResourceConfig("Test SMB storage repository", "", storage_location, username, password, ""),
@@ -52,7 +60,7 @@ func TestAccSMBResource(t *testing.T) {
 				ResourceName:            "xenserver_sr_smb.test_smb",
 				ImportState:             true,
 				ImportStateVerify:       true,
-				ImportStateVerifyIgnore: []string{"username", "password"},
+				ImportStateVerifyIgnore: []string{"username", "domain", "password"},
 			},
 			{
 				Config:      providerConfig + testAccSMBResourceConfig("Test SMB storage repository 2", "Test SMB Description", "", username, password, ""),
@@ -92,7 +100,11 @@ func TestAccSMBISOResource(t *testing.T) {
 				ExpectError: regexp.MustCompile(`Invalid Attribute Value Match`),
 			},
 			{
-				Config: providerConfig + testAccSMBResourceConfig("Test SMB ISO library", "", storage_location, username, password, "type = \"iso\""),
+				Config:      providerConfig + testAccSMBResourceConfig("Test SMB ISO library", "", storage_location, username, password, "type = \"iso\"\ncifs_version = \"2.1\""),
+				ExpectError: regexp.MustCompile(`type "iso" only supports cifs_version "1.0", "3.0"`),
+			},
+			{
+				Config: providerConfig + testAccSMBResourceConfig("Test SMB ISO library", "", storage_location, username, password, "type = \"iso\"\ncifs_version = \"3.0\""),
 				Check: resource.ComposeAggregateTestCheckFunc(
 					resource.TestCheckResourceAttr("xenserver_sr_smb.test_smb", "name_label", "Test SMB ISO library"),
 					resource.TestCheckResourceAttr("xenserver_sr_smb.test_smb", "name_description", ""),
@@ -100,6 +112,7 @@ func TestAccSMBISOResource(t *testing.T) {
 					resource.TestCheckResourceAttr("xenserver_sr_smb.test_smb", "storage_location", expected_storage_location),
 					resource.TestCheckResourceAttr("xenserver_sr_smb.test_smb", "username", username),
 					resource.TestCheckResourceAttr("xenserver_sr_smb.test_smb", "password", password),
+					resource.TestCheckResourceAttr("xenserver_sr_smb.test_smb", "cifs_version", "3.0"),
 					// Verify dynamic values have any value set in the state.
 
 					resource.TestCheckResourceAttrSet("xenserver_sr_smb.test_smb", "uuid"),
@@ -110,15 +123,19 @@ func TestAccSMBISOResource(t *testing.T) {
 				ResourceName:            "xenserver_sr_smb.test_smb",
 				ImportState:             true,
 				ImportStateVerify:       true,
-				ImportStateVerifyIgnore: []string{"username", "password"},
+				ImportStateVerifyIgnore: []string{"username", "domain", "password"},
 			},
 			{
 				Config:      providerConfig + testAccSMBResourceConfig("Test SMB ISO library 2", "Test SMB Description", "", username, password, "type = \"smb\""),
 				ExpectError: regexp.MustCompile(`"type" doesn't expected to be updated`),
 			},
+			{
+				Config:      providerConfig + testAccSMBResourceConfig("Test SMB ISO library 2", "Test SMB Description", storage_location, username, password, "type = \"iso\"\ncifs_version = \"1.0\""),
+				ExpectError: regexp.MustCompile(`"cifs_version" doesn't expected to be updated`),
+			},
 			// Update and Read testing
 			{
-				Config: providerConfig + testAccSMBResourceConfig("Test SMB ISO library 2", "Test SMB Description", storage_location, username, password, "type = \"iso\""),
+				Config: providerConfig + testAccSMBResourceConfig("Test SMB ISO library 2", "Test SMB Description", storage_location, username, password, "type = \"iso\"\ncifs_version = \"3.0\""),
 				Check: resource.ComposeAggregateTestCheckFunc(
 					resource.TestCheckResourceAttr("xenserver_sr_smb.test_smb", "name_label", "Test SMB ISO library 2"),
 					resource.TestCheckResourceAttr("xenserver_sr_smb.test_smb", "name_description", "Test SMB Description"),
@@ -134,3 +151,196 @@ func TestAccSMBISOResource(t *testing.T) {
 		},
 	})
 }
+
+func TestGetSMBDeviceConfig(t *testing.T) {
+	testCases := []struct {
+		data     smbResourceModel
+		expected map[string]string
+	}{
+		{
+			data: smbResourceModel{
+				Type:            types.StringValue("smb"),
+				StorageLocation: types.StringValue(`\\10.0.0.1\share`),
+				Username:        types.StringValue("user"),
+				Password:        types.StringValue("pass"),
+				CIFSVersion:     types.StringNull(),
+			},
+			expected: map[string]string{"server": `\\10.0.0.1\share`, "username": "user", "password": "pass"},
+		},
+		{
+			data: smbResourceModel{
+				Type:            types.StringValue("smb"),
+				StorageLocation: types.StringValue(`\\Server\Share:/VMs/Prod`),
+				CIFSVersion:     types.StringNull(),
+			},
+			expected: map[string]string{"server": `\\server\share`, "serverpath": "/VMs/Prod"},
+		},
+		{
+			data: smbResourceModel{
+				Type:            types.StringValue("smb"),
+				StorageLocation: types.StringValue(`\\10.0.0.1\share`),
+				Username:        types.StringValue("user"),
+				Domain:          types.StringValue("CORP"),
+				PasswordSecret:  types.StringValue("secret-uuid"),
+				CIFSVersion:     types.StringNull(),
+			},
+			expected: map[string]string{"server": `\\10.0.0.1\share`, "username": `CORP\user`, "password_secret": "secret-uuid"},
+		},
+		{
+			data: smbResourceModel{
+				Type:            types.StringValue("iso"),
+				StorageLocation: types.StringValue(`\\10.0.0.1\iso`),
+				Username:        types.StringValue("user"),
+				Domain:          types.StringValue("CORP"),
+				Password:        types.StringValue("pass"),
+				CIFSVersion:     types.StringValue("1.0"),
+			},
+			expected: map[string]string{"location": "//10.0.0.1/iso", "type": "cifs", "username": `CORP\user`, "cifspassword": "pass", "vers": "1.0"},
+		},
+		{
+			data: smbResourceModel{
+				Type:            types.StringValue("iso"),
+				StorageLocation: types.StringValue(`\\10.0.0.1\iso\linux`),
+				CIFSVersion:     types.StringNull(),
+			},
+			expected: map[string]string{"location": "//10.0.0.1/iso", "iso_path": "/linux", "type": "cifs"},
+		},
+	}
+	for _, tc := range testCases {
+		deviceConfig := getSMBDeviceConfig(tc.data)
+		if !maps.Equal(deviceConfig, tc.expected) {
+			t.Errorf("getSMBDeviceConfig(%v) = %v, expected %v", tc.data, deviceConfig, tc.expected)
+		}
+	}
+}
+
+func TestSMBISOLocationRoundTrip(t *testing.T) {
+	testCases := []struct {
+		storageLocation  string
+		expectedLocation string
+		expectedISOPath  string
+	}{
+		{storageLocation: `\\10.0.0.1\iso`, expectedLocation: "//10.0.0.1/iso", expectedISOPath: ""},
+		{storageLocation: `\\10.0.0.1\iso\linux`, expectedLocation: "//10.0.0.1/iso", expectedISOPath: "/linux"},
+		{storageLocation: `\\10.0.0.1\iso\linux\debian\12`, expectedLocation: "//10.0.0.1/iso", expectedISOPath: "/linux/debian/12"},
+		{storageLocation: `\\file server\ISO share\Windows Server\2022`, expectedLocation: "//file server/ISO share", expectedISOPath: "/Windows Server/2022"},
+		{storageLocation: `\\10.0.0.1\iso\linux\`, expectedLocation: "//10.0.0.1/iso", expectedISOPath: "/linux/"},
+	}
+	for _, tc := range testCases {
+		location, isoPath := splitSMBISOLocation(tc.storageLocation)
+		if location != tc.expectedLocation || isoPath != tc.expectedISOPath {
+			t.Errorf("splitSMBISOLocation(%q) = %q, %q, expected %q, %q", tc.storageLocation, location, isoPath, tc.expectedLocation, tc.expectedISOPath)
+		}
+		storageLocation := joinSMBISOLocation(location, isoPath)
+		if storageLocation != tc.storageLocation {
+			t.Errorf("joinSMBISOLocation(%q, %q) = %q, expected %q", location, isoPath, storageLocation, tc.storageLocation)
+		}
+	}
+}
+
+func TestUpdateSMBResourceModelISOLocation(t *testing.T) {
+	srRecord := xenapi.SRRecord{Type: "iso"}
+	testCases := []struct {
+		deviceConfig     map[string]string
+		configured       types.String
+		expectedLocation string
+	}{
+		// import, there is no configured value
+		{
+			deviceConfig:     map[string]string{"location": "//10.0.0.1/iso", "iso_path": "/linux/debian"},
+			configured:       types.StringNull(),
+			expectedLocation: `\\10.0.0.1\iso\linux\debian`,
+		},
+		{
+			deviceConfig:     map[string]string{"location": "//10.0.0.1/iso", "iso_path": "/linux/debian"},
+			configured:       types.StringValue("//10.0.0.1/iso/linux/debian"),
+			expectedLocation: "//10.0.0.1/iso/linux/debian",
+		},
+		// the value of a heredoc ends with a new line
+		{
+			deviceConfig:     map[string]string{"location": "//10.0.0.1/iso"},
+			configured:       types.StringValue("\\\\10.0.0.1\\iso\n"),
+			expectedLocation: "\\\\10.0.0.1\\iso\n",
+		},
+		// the SR is changed outside of terraform
+		{
+			deviceConfig:     map[string]string{"location": "//10.0.0.2/iso", "iso_path": "/linux"},
+			configured:       types.StringValue(`\\10.0.0.1\iso\linux`),
+			expectedLocation: `\\10.0.0.2\iso\linux`,
+		},
+	}
+	for _, tc := range testCases {
+		data := smbResourceModel{StorageLocation: tc.configured}
+		err := updateSMBResourceModel(srRecord, xenapi.PBDRecord{DeviceConfig: tc.deviceConfig}, &data)
+		if err != nil {
+			t.Fatalf("updateSMBResourceModel(%v) returned error: %v", tc.deviceConfig, err)
+		}
+		if data.StorageLocation.ValueString() != tc.expectedLocation {
+			t.Errorf("updateSMBResourceModel(%v) storage_location = %q, expected %q", tc.deviceConfig, data.StorageLocation.ValueString(), tc.expectedLocation)
+		}
+	}
+}
+
+func TestNormalizeSMBStorageLocation(t *testing.T) {
+	testCases := []struct {
+		storageLocation string
+		expected        string
+	}{
+		{storageLocation: `\\10.0.0.1\share`, expected: `\\10.0.0.1\share`},
+		{storageLocation: "//10.0.0.1/share", expected: `\\10.0.0.1\share`},
+		{storageLocation: `\\10.0.0.1\share\`, expected: `\\10.0.0.1\share`},
+		{storageLocation: "    \\\\10.0.0.1\\share\n", expected: `\\10.0.0.1\share`},
+		{storageLocation: `\\FileServer\ISO\Linux`, expected: `\\fileserver\iso\Linux`},
+		{storageLocation: `//FileServer/ISO/Linux/`, expected: `\\fileserver\iso\Linux`},
+		{storageLocation: `\\file server\ISO share\Windows Server`, expected: `\\file server\iso share\Windows Server`},
+		{storageLocation: `\\Server\Share:/VMs/Prod`, expected: `\\server\share:/VMs/Prod`},
+		{storageLocation: `//Server/Share:/VMs/Prod/`, expected: `\\server\share:/VMs/Prod`},
+		{storageLocation: `\\Server\Share\:/`, expected: `\\server\share:/`},
+	}
+	for _, tc := range testCases {
+		location := normalizeSMBStorageLocation(tc.storageLocation)
+		if location != tc.expected {
+			t.Errorf("normalizeSMBStorageLocation(%q) = %q, expected %q", tc.storageLocation, location, tc.expected)
+		}
+	}
+}
+
+func TestCheckSMBCIFSVersion(t *testing.T) {
+	testCases := []struct {
+		typeKey   string
+		version   string
+		expectErr bool
+	}{
+		{typeKey: "iso", version: "1.0"},
+		{typeKey: "iso", version: "3.0"},
+		{typeKey: "iso", version: "2.1", expectErr: true},
+		{typeKey: "smb", version: "3.0", expectErr: true},
+	}
+	for _, tc := range testCases {
+		err := checkSMBCIFSVersion(tc.typeKey, tc.version)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("checkSMBCIFSVersion(%q, %q) returned %v, expected error: %t", tc.typeKey, tc.version, err, tc.expectErr)
+		}
+	}
+}
+
+func TestSMBResourceModelUpdateCheck(t *testing.T) {
+	state := smbResourceModel{Type: types.StringValue("smb"), StorageLocation: types.StringValue(`\\10.0.0.1\share`), PasswordSecret: types.StringValue("secret-uuid")}
+	testCases := []struct {
+		passwordSecret types.String
+		expectError    bool
+	}{
+		{passwordSecret: types.StringValue("secret-uuid"), expectError: false},
+		{passwordSecret: types.StringValue(" secret-uuid\n"), expectError: false},
+		{passwordSecret: types.StringValue("other-secret-uuid"), expectError: true},
+		{passwordSecret: types.StringNull(), expectError: true},
+	}
+	for _, tc := range testCases {
+		plan := state
+		plan.PasswordSecret = tc.passwordSecret
+		err := smbResourceModelUpdateCheck(plan, state)
+		if (err != nil) != tc.expectError {
+			t.Errorf("smbResourceModelUpdateCheck(%v) returned error %v, expected error %t", tc.passwordSecret, err, tc.expectError)
+		}
+	}
+}
@@ -2,12 +2,19 @@ package xenserver
 
 import (
 	"context"
+	"encoding/xml"
 	"errors"
+	"maps"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/cenkalti/backoff/v4"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 
 	"xenapi"
 )
@@ -103,20 +110,46 @@ type srCreateParams struct {
 	ContentType     string
 	Shared          bool
 	SmConfig        map[string]string
+	AutoScan        bool
 }
 
 // srResourceModel describes the resource data model.
 type srResourceModel struct {
-	NameLabel       types.String `tfsdk:"name_label"`
-	NameDescription types.String `tfsdk:"name_description"`
-	Type            types.String `tfsdk:"type"`
-	ContentType     types.String `tfsdk:"content_type"`
-	Shared          types.Bool   `tfsdk:"shared"`
-	SmConfig        types.Map    `tfsdk:"sm_config"`
-	DeviceConfig    types.Map    `tfsdk:"device_config"`
-	Host            types.String `tfsdk:"host"`
-	UUID            types.String `tfsdk:"uuid"`
-	ID              types.String `tfsdk:"id"`
+	NameLabel         types.String `tfsdk:"name_label"`
+	NameDescription   types.String `tfsdk:"name_description"`
+	Type              types.String `tfsdk:"type"`
+	ContentType       types.String `tfsdk:"content_type"`
+	Shared            types.Bool   `tfsdk:"shared"`
+	SmConfig          types.Map    `tfsdk:"sm_config"`
+	DeviceConfig      types.Map    `tfsdk:"device_config"`
+	Host              types.String `tfsdk:"host"`
+	LocalCacheEnabled types.Bool   `tfsdk:"local_cache_enabled"`
+	AutoScan          types.Bool   `tfsdk:"auto_scan"`
+	PhysicalSize      types.Int64  `tfsdk:"physical_size"`
+	UUID              types.String `tfsdk:"uuid"`
+	ID                types.String `tfsdk:"id"`
+}
+
+// getSRWithMostFreeSpace returns the SR of one of the types with the most free space, among the SRs which
+// VDIs can be created on. The SRs with the same free space are ordered by UUID, so the choice is stable.
+func getSRWithMostFreeSpace(srRecords map[xenapi.SRRef]xenapi.SRRecord, srTypes []string) (xenapi.SRRef, error) {
+	var bestRef xenapi.SRRef
+	var bestRecord xenapi.SRRecord
+	found := false
+	for srRef, srRecord := range srRecords {
+		if !slices.Contains(srTypes, srRecord.Type) || !slices.Contains(srRecord.AllowedOperations, xenapi.StorageOperationsVdiCreate) {
+			continue
+		}
+		freeSpace := srRecord.PhysicalSize - srRecord.PhysicalUtilisation
+		bestFreeSpace := bestRecord.PhysicalSize - bestRecord.PhysicalUtilisation
+		if !found || freeSpace > bestFreeSpace || (freeSpace == bestFreeSpace && srRecord.UUID < bestRecord.UUID) {
+			bestRef, bestRecord, found = srRef, srRecord, true
+		}
+	}
+	if !found {
+		return bestRef, errors.New("no SR of type " + strings.Join(srTypes, ", ") + " which virtual disk images can be created on")
+	}
+	return bestRef, nil
 }
 
 func getSRCreateParams(ctx context.Context, session *xenapi.Session, data srResourceModel) (srCreateParams, error) {
@@ -126,6 +159,12 @@ func getSRCreateParams(ctx context.Context, session *xenapi.Session, data srReso
 	params.TypeKey = data.Type.ValueString()
 	params.ContentType = data.ContentType.ValueString()
 	params.Shared = data.Shared.ValueBool()
+	params.PhysicalSize = int(data.PhysicalSize.ValueInt64())
+	// the ISO library is scanned for new ISOs by default
+	params.AutoScan = getSRDefaultContentType(params.TypeKey, params.ContentType) == "iso"
+	if !data.AutoScan.IsUnknown() {
+		params.AutoScan = data.AutoScan.ValueBool()
+	}
 	diags := data.DeviceConfig.ElementsAs(ctx, &params.DeviceConfig, false)
 	if diags.HasError() {
 		return params, errors.New("unable to access SR device config data")
@@ -167,6 +206,7 @@ func getSRRecordAndPBDRecord(session *xenapi.Session, srRef xenapi.SRRef) (xenap
 
 func updateSRResourceModel(ctx context.Context, session *xenapi.Session, srRecord xenapi.SRRecord, pbdRecord xenapi.PBDRecord, data *srResourceModel) error {
 	data.NameLabel = types.StringValue(srRecord.NameLabel)
+	data.LocalCacheEnabled = types.BoolValue(srRecord.LocalCacheEnabled)
 
 	return updateSRResourceModelComputed(ctx, session, srRecord, pbdRecord, data)
 }
@@ -178,6 +218,7 @@ func updateSRResourceModelComputed(ctx context.Context, session *xenapi.Session,
 	data.Type = types.StringValue(srRecord.Type)
 	data.ContentType = types.StringValue(srRecord.ContentType)
 	data.Shared = types.BoolValue(srRecord.Shared)
+	data.AutoScan = types.BoolValue(srRecord.OtherConfig["auto-scan"] == "true")
 	var diags diag.Diagnostics
 	data.SmConfig, diags = types.MapValueFrom(ctx, types.StringType, srRecord.SmConfig)
 	if diags.HasError() {
@@ -219,6 +260,9 @@ func srResourceModelUpdateCheck(data srResourceModel, dataState srResourceModel)
 	if data.ContentType != dataState.ContentType {
 		return errors.New(`"content_type" doesn't expected to be updated`)
 	}
+	if !data.PhysicalSize.Equal(dataState.PhysicalSize) {
+		return errors.New(`"physical_size" doesn't expected to be updated`)
+	}
 	return nil
 }
 
@@ -240,6 +284,97 @@ func srResourceModelUpdate(ctx context.Context, session *xenapi.Session, ref xen
 	if err != nil {
 		return errors.New(err.Error())
 	}
+	if !data.AutoScan.IsUnknown() {
+		err = xenapi.SR.RemoveFromOtherConfig(session, ref, "auto-scan")
+		if err != nil {
+			return errors.New(err.Error())
+		}
+		err = xenapi.SR.AddToOtherConfig(session, ref, "auto-scan", strconv.FormatBool(data.AutoScan.ValueBool()))
+		if err != nil {
+			return errors.New(err.Error())
+		}
+	}
+	return nil
+}
+
+// isoContentSRTypes are the SR types which hold the ISO content, "udev" is the SR of the host DVD drive
+var isoContentSRTypes = []string{"iso", "udev"}
+
+// getSRDefaultContentType returns the content type the SR is taken to have when content_type is empty,
+// the ISO library SRs hold the ISO content
+func getSRDefaultContentType(typeKey string, contentType string) string {
+	if contentType == "" && typeKey == "iso" {
+		return "iso"
+	}
+	return contentType
+}
+
+// checkSRContentType makes sure the ISO content is only used with the ISO SR types, the ISO library
+// SRs are scanned for the ISO files while the other types hold the VM disks
+func checkSRContentType(typeKey string, contentType string) error {
+	contentType = getSRDefaultContentType(typeKey, contentType)
+	if contentType == "iso" && !slices.Contains(isoContentSRTypes, typeKey) {
+		return errors.New(`content_type "iso" can only be used with type "` + strings.Join(isoContentSRTypes, `", "`) + `", got type "` + typeKey + `"`)
+	}
+	if typeKey == "iso" && contentType != "iso" {
+		return errors.New(`type "iso" should be created with content_type "iso", got content_type "` + contentType + `"`)
+	}
+	return nil
+}
+
+// srSizedTypes are the SR types which take the physical size given on creation, the other types
+// use the size of the underlying storage
+var srSizedTypes = []string{"ext", "file", "lvm"}
+
+// checkSRPhysicalSize makes sure the physical size is only given to the SR types which use it
+func checkSRPhysicalSize(typeKey string, physicalSize int64) error {
+	if physicalSize > 0 && !slices.Contains(srSizedTypes, typeKey) {
+		return errors.New(`physical_size can only be used with type "` + strings.Join(srSizedTypes, `", "`) + `", got type "` + typeKey + `"`)
+	}
+	return nil
+}
+
+// updateSRLocalCache enables or disables the host-local cache (IntelliCache) on the host of a non-shared SR,
+// XAPI requires the host to be disabled and to have no running VMs for the change
+func updateSRLocalCache(session *xenapi.Session, ref xenapi.SRRef, enabled bool) error {
+	srRecord, err := xenapi.SR.GetRecord(session, ref)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	if srRecord.LocalCacheEnabled == enabled {
+		return nil
+	}
+	if srRecord.Shared {
+		return errors.New("local cache can only be enabled on a non-shared SR")
+	}
+	if len(srRecord.PBDs) == 0 {
+		return errors.New("unable to find the host of the SR, the SR has no PBD")
+	}
+	hostRef, err := xenapi.PBD.GetHost(session, srRecord.PBDs[0])
+	if err != nil {
+		return errors.New(err.Error())
+	}
+
+	if enabled {
+		err = xenapi.Host.EnableLocalStorageCaching(session, hostRef, ref)
+		if err != nil {
+			return errors.New(err.Error())
+		}
+		return nil
+	}
+
+	// the host may cache on another SR now, leave it as it is
+	cacheSRRef, err := xenapi.Host.GetLocalCacheSr(session, hostRef)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	if cacheSRRef != ref {
+		return nil
+	}
+	err = xenapi.Host.DisableLocalStorageCaching(session, hostRef)
+	if err != nil {
+		return errors.New(err.Error())
+	}
 	return nil
 }
 
@@ -283,24 +418,125 @@ func unplugPBDs(session *xenapi.Session, pbdRefs []xenapi.PBDRef) error {
 	return nil
 }
 
+// srSecretOtherConfigKey marks the secrets created by createSRResource, only these secrets are destroyed with the SR,
+// the secrets referenced from device config by the user may be shared with other SRs.
+const srSecretOtherConfigKey = "tf_sr_secret"
+
+// getSRSecretUUIDs returns the UUIDs of the secrets referenced by the "*_secret" keys of the PBDs device config
+func getSRSecretUUIDs(session *xenapi.Session, pbdRefs []xenapi.PBDRef) ([]string, error) {
+	var secretUUIDs []string
+	for _, pbdRef := range pbdRefs {
+		deviceConfig, err := xenapi.PBD.GetDeviceConfig(session, pbdRef)
+		if err != nil {
+			return nil, errors.New(err.Error())
+		}
+		for key, value := range deviceConfig {
+			if strings.HasSuffix(key, "_secret") && value != "" && !slices.Contains(secretUUIDs, value) {
+				secretUUIDs = append(secretUUIDs, value)
+			}
+		}
+	}
+	return secretUUIDs, nil
+}
+
+// cleanupSRSecrets destroys the secrets created for the SR, the secrets which have already gone or
+// weren't created by the provider are skipped
+func cleanupSRSecrets(session *xenapi.Session, secretUUIDs []string) error {
+	for _, secretUUID := range secretUUIDs {
+		secretRef, err := xenapi.Secret.GetByUUID(session, secretUUID)
+		if err != nil {
+			continue
+		}
+		otherConfig, err := xenapi.Secret.GetOtherConfig(session, secretRef)
+		if err != nil {
+			return cleanupError("secret", secretUUID, err)
+		}
+		if otherConfig[srSecretOtherConfigKey] != "true" {
+			continue
+		}
+		err = xenapi.Secret.Destroy(session, secretRef)
+		if err != nil {
+			return cleanupError("secret", secretUUID, err)
+		}
+	}
+	return nil
+}
+
 func cleanupSRResource(session *xenapi.Session, ref xenapi.SRRef) error {
-	pbdRefs, err := xenapi.SR.GetPBDs(session, ref)
+	srRecord, err := xenapi.SR.GetRecord(session, ref)
 	if err != nil {
-		return errors.New(err.Error())
+		return cleanupError("SR", string(ref), err)
 	}
-	err = unplugPBDs(session, pbdRefs)
+	// the PBDs are destroyed with the SR, get the secrets before forgetting it
+	secretUUIDs, err := getSRSecretUUIDs(session, srRecord.PBDs)
 	if err != nil {
-		return err
+		return cleanupError("SR", srRecord.UUID, err)
+	}
+	err = unplugPBDs(session, srRecord.PBDs)
+	if err != nil {
+		return cleanupError("SR", srRecord.UUID, err)
 	}
 	err = xenapi.SR.Forget(session, ref)
+	if err != nil {
+		return cleanupError("SR", srRecord.UUID, err)
+	}
+	// the SR is forgotten, the secret left behind is named by its own error
+	return cleanupSRSecrets(session, secretUUIDs)
+}
+
+// srProbeTypes are the SR types whose storage target can be checked by probing before the SR is created
+var srProbeTypes = []string{"nfs", "smb", "lvmoiscsi"}
+
+// probeSR checks that the device config reaches a real storage target. When the target can't be reached
+// or the device config is incomplete, the XAPI error lists the available options, e.g. the NFS exports
+// on the server or the IQNs of the iSCSI target.
+func probeSR(session *xenapi.Session, params srCreateParams) error {
+	if !slices.Contains(srProbeTypes, params.TypeKey) {
+		return nil
+	}
+	// the probe is only a plan-time check, skip it on the pools which don't support it
+	version, err := getAPIVersion(session)
+	if err != nil {
+		return err
+	}
+	if checkFeature(version, featureSRProbeExt) != nil {
+		return nil
+	}
+	_, err = xenapi.SR.ProbeExt(session, params.Host, params.DeviceConfig, params.TypeKey, params.SmConfig)
+	return featureError(version, featureSRProbeExt, err)
+}
+
+// plugSRPBD plugs the PBD of the SR just created, it retries for a while as the storage target can be briefly
+// unreachable from the host right after the SR is created
+func plugSRPBD(ctx context.Context, session *xenapi.Session, pbdRef xenapi.PBDRef) error {
+	operation := func() error {
+		err := retryOnSessionInvalid(session, func() error {
+			return xenapi.PBD.Plug(session, pbdRef)
+		})
+		if err == nil {
+			return nil
+		}
+		if classifyXapiError(err) != xapiErrorRetryable {
+			return backoff.Permanent(err)
+		}
+		tflog.Debug(ctx, "Unable to plug PBD "+string(pbdRef)+", retrying...\n"+err.Error())
+		return err
+	}
+
+	b := backoff.NewExponentialBackOff()
+	b.MaxInterval = 10 * time.Second
+	b.MaxElapsedTime = 1 * time.Minute
+	err := backoff.Retry(operation, backoff.WithContext(b, ctx))
 	if err != nil {
 		return errors.New(err.Error())
 	}
+
 	return nil
 }
 
-func createSRResource(session *xenapi.Session, params srCreateParams) (xenapi.SRRef, error) {
+func createSRResource(ctx context.Context, session *xenapi.Session, params srCreateParams) (xenapi.SRRef, error) {
 	var srRef xenapi.SRRef
+	var err error
 	// Create secret for password
 	var secretRef xenapi.SecretRef
 	keys := []string{"cifspassword", "password", "chappassword"}
@@ -309,8 +545,11 @@ func createSRResource(session *xenapi.Session, params srCreateParams) (xenapi.SR
 			value, exists := params.DeviceConfig[key]
 			if exists {
 				delete(params.DeviceConfig, key)
-				secretRecord := xenapi.SecretRecord{Value: value}
-				secretRef, err := xenapi.Secret.Create(session, secretRecord)
+				secretRecord := xenapi.SecretRecord{
+					Value:       value,
+					OtherConfig: map[string]string{srSecretOtherConfigKey: "true"},
+				}
+				secretRef, err = xenapi.Secret.Create(session, secretRecord)
 				if err != nil {
 					return srRef, errors.New(err.Error())
 				}
@@ -324,8 +563,11 @@ func createSRResource(session *xenapi.Session, params srCreateParams) (xenapi.SR
 		}
 	}
 	// Create SR
-	srRef, err := xenapi.SR.Create(session, params.Host, params.DeviceConfig, params.PhysicalSize, params.NameLabel, params.NameDescription, params.TypeKey, params.ContentType, params.Shared, params.SmConfig)
+	srRef, err = xenapi.SR.Create(session, params.Host, params.DeviceConfig, params.PhysicalSize, params.NameLabel, params.NameDescription, params.TypeKey, params.ContentType, params.Shared, params.SmConfig)
 	if err != nil {
+		if secretRef == "" {
+			return srRef, errors.New(err.Error())
+		}
 		errDestroy := xenapi.Secret.Destroy(session, secretRef)
 		if errDestroy != nil {
 			return srRef, errors.New(err.Error() + "\n" + errDestroy.Error())
@@ -343,9 +585,9 @@ func createSRResource(session *xenapi.Session, params srCreateParams) (xenapi.SR
 			return srRef, errors.New(err.Error())
 		}
 		if !currentlyAttached {
-			err = xenapi.PBD.Plug(session, pbdRef)
+			err = plugSRPBD(ctx, session, pbdRef)
 			if err != nil {
-				return srRef, errors.New(err.Error())
+				return srRef, err
 			}
 		}
 	}
@@ -353,10 +595,7 @@ func createSRResource(session *xenapi.Session, params srCreateParams) (xenapi.SR
 	if err != nil {
 		return srRef, errors.New(err.Error())
 	}
-	otherConfig["auto-scan"] = "false"
-	if params.ContentType == "iso" {
-		otherConfig["auto-scan"] = "true"
-	}
+	otherConfig["auto-scan"] = strconv.FormatBool(params.AutoScan)
 	err = xenapi.SR.SetOtherConfig(session, srRef, otherConfig)
 	if err != nil {
 		return srRef, errors.New(err.Error())
@@ -383,11 +622,42 @@ func getNFSCreateParams(session *xenapi.Session, data nfsResourceModel) (srCreat
 	}
 	params.Host = coordinatorRef
 	params.TypeKey = data.Type.ValueString()
-	deviceConfig := make(map[string]string)
-	storageLocation := strings.Split(data.StorageLocation.ValueString(), ":")
 	if params.TypeKey == "iso" {
 		params.ContentType = "iso"
-		deviceConfig["location"] = strings.TrimSpace(data.StorageLocation.ValueString())
+		params.AutoScan = true
+	}
+	params.DeviceConfig = getNFSDeviceConfig(data)
+	params.NameLabel = data.NameLabel.ValueString()
+	params.NameDescription = data.NameDescription.ValueString()
+	params.Shared = true
+	params.SmConfig = make(map[string]string)
+
+	return params, nil
+}
+
+// normalizeNFSStorageLocation returns the canonical form of the NFS storage location "server:/path", without the
+// spaces around it, with the server in lower case and without the trailing slashes of the path, so the equivalent
+// forms compare equal
+func normalizeNFSStorageLocation(storageLocation string) string {
+	storageLocation = strings.TrimSpace(storageLocation)
+	server, serverPath, found := strings.Cut(storageLocation, ":")
+	if !found {
+		return strings.ToLower(storageLocation)
+	}
+	serverPath = strings.TrimSpace(serverPath)
+	if trimmed := strings.TrimRight(serverPath, "/"); trimmed != "" {
+		serverPath = trimmed
+	}
+	return strings.ToLower(strings.TrimSpace(server)) + ":" + serverPath
+}
+
+// getNFSDeviceConfig returns the device config for the NFS SR, the version is passed as it is, eg. "3", "4" or "4.1"
+func getNFSDeviceConfig(data nfsResourceModel) map[string]string {
+	deviceConfig := make(map[string]string)
+	location := normalizeNFSStorageLocation(data.StorageLocation.ValueString())
+	storageLocation := strings.Split(location, ":")
+	if data.Type.ValueString() == "iso" {
+		deviceConfig["location"] = location
 		deviceConfig["type"] = "nfs_iso"
 	} else {
 		deviceConfig["server"] = strings.TrimSpace(storageLocation[0])
@@ -395,23 +665,19 @@ func getNFSCreateParams(session *xenapi.Session, data nfsResourceModel) (srCreat
 	}
 	deviceConfig["options"] = data.AdvancedOptions.ValueString()
 	deviceConfig["nfsversion"] = data.Version.ValueString()
-	params.DeviceConfig = deviceConfig
-	params.NameLabel = data.NameLabel.ValueString()
-	params.NameDescription = data.NameDescription.ValueString()
-	params.Shared = true
-	params.SmConfig = make(map[string]string)
 
-	return params, nil
+	return deviceConfig
 }
 
 func updateNFSResourceModel(srRecord xenapi.SRRecord, pbdRecord xenapi.PBDRecord, data *nfsResourceModel) error {
 	data.NameLabel = types.StringValue(srRecord.NameLabel)
+	var location string
 	if srRecord.Type == "iso" {
-		location, ok := pbdRecord.DeviceConfig["location"]
+		var ok bool
+		location, ok = pbdRecord.DeviceConfig["location"]
 		if !ok {
 			return errors.New(`unable to find "location" in PBD device config`)
 		}
-		data.StorageLocation = types.StringValue(location)
 	} else {
 		server, ok := pbdRecord.DeviceConfig["server"]
 		if !ok {
@@ -421,7 +687,11 @@ func updateNFSResourceModel(srRecord xenapi.SRRecord, pbdRecord xenapi.PBDRecord
 		if !ok {
 			return errors.New(`unable to find "serverpath" in PBD device config`)
 		}
-		data.StorageLocation = types.StringValue(server + ":" + serverPath)
+		location = server + ":" + serverPath
+	}
+	// keep the configured form of the same location, otherwise the plan would always show a diff
+	if data.StorageLocation.IsNull() || normalizeNFSStorageLocation(data.StorageLocation.ValueString()) != normalizeNFSStorageLocation(location) {
+		data.StorageLocation = types.StringValue(location)
 	}
 	nfsVersion, ok := pbdRecord.DeviceConfig["nfsversion"]
 	if !ok {
@@ -451,15 +721,12 @@ func nfsResourceModelUpdateCheck(data nfsResourceModel, dataState nfsResourceMod
 	if data.Type != dataState.Type {
 		return errors.New(`"type" doesn't expected to be updated`)
 	}
-	if strings.TrimSpace(data.StorageLocation.ValueString()) != strings.TrimSpace(dataState.StorageLocation.ValueString()) {
+	if normalizeNFSStorageLocation(data.StorageLocation.ValueString()) != normalizeNFSStorageLocation(dataState.StorageLocation.ValueString()) {
 		return errors.New(`"storage_location" doesn't expected to be updated`)
 	}
 	if data.Version != dataState.Version {
 		return errors.New(`"version" doesn't expected to be updated`)
 	}
-	if data.AdvancedOptions != dataState.AdvancedOptions {
-		return errors.New(`"advanced_options" doesn't expected to be updated`)
-	}
 	return nil
 }
 
@@ -472,6 +739,83 @@ func nfsResourceModelUpdate(session *xenapi.Session, ref xenapi.SRRef, data nfsR
 	if err != nil {
 		return errors.New(err.Error())
 	}
+	err = updateNFSAdvancedOptions(session, ref, data.AdvancedOptions.ValueString())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// updateNFSAdvancedOptions sets the mount options in the device config of all the PBDs of the SR,
+// the PBDs are unplugged first and plugged again to remount the NFS share with the new options.
+func updateNFSAdvancedOptions(session *xenapi.Session, ref xenapi.SRRef, options string) error {
+	pbdRefs, err := xenapi.SR.GetPBDs(session, ref)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	pbdRecords := make(map[xenapi.PBDRef]xenapi.PBDRecord)
+	changed := false
+	for _, pbdRef := range pbdRefs {
+		pbdRecord, err := xenapi.PBD.GetRecord(session, pbdRef)
+		if err != nil {
+			return errors.New(err.Error())
+		}
+		pbdRecords[pbdRef] = pbdRecord
+		if pbdRecord.DeviceConfig["options"] != options {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	err = unplugPBDs(session, pbdRefs)
+	if err != nil {
+		return err
+	}
+	err = setPBDsOption(session, pbdRecords, "options", &options)
+	if err != nil {
+		// put the previous mount options back, so the SR isn't left detached from the pool
+		restoreErr := unplugPBDs(session, pbdRefs)
+		if restoreErr == nil {
+			restoreErr = setPBDsOption(session, pbdRecords, "options", nil)
+		}
+		if restoreErr != nil {
+			return errors.Join(err, errors.New("unable to restore the previous advanced options: "+restoreErr.Error()))
+		}
+		return err
+	}
+
+	return nil
+}
+
+// setPBDsOption sets the key in the device config of the unplugged PBDs and plugs the PBDs which were plugged
+// before, the previous device config in pbdRecords is restored when value is nil
+func setPBDsOption(session *xenapi.Session, pbdRecords map[xenapi.PBDRef]xenapi.PBDRecord, key string, value *string) error {
+	for pbdRef, pbdRecord := range pbdRecords {
+		deviceConfig := maps.Clone(pbdRecord.DeviceConfig)
+		if deviceConfig == nil {
+			deviceConfig = make(map[string]string)
+		}
+		if value != nil {
+			deviceConfig[key] = *value
+		}
+		err := xenapi.PBD.SetDeviceConfig(session, pbdRef, deviceConfig)
+		if err != nil {
+			return errors.New(err.Error())
+		}
+	}
+	// only plug the PBDs which were plugged before the update
+	for pbdRef, pbdRecord := range pbdRecords {
+		if !pbdRecord.CurrentlyAttached {
+			continue
+		}
+		err := setPBDPlugged(session, pbdRef, true)
+		if err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -482,11 +826,32 @@ type smbResourceModel struct {
 	Type            types.String `tfsdk:"type"`
 	StorageLocation types.String `tfsdk:"storage_location"`
 	Username        types.String `tfsdk:"username"`
+	Domain          types.String `tfsdk:"domain"`
+	CIFSVersion     types.String `tfsdk:"cifs_version"`
 	Password        types.String `tfsdk:"password"`
+	PasswordSecret  types.String `tfsdk:"password_secret_uuid"`
 	UUID            types.String `tfsdk:"uuid"`
 	ID              types.String `tfsdk:"id"`
 }
 
+// smbTypeVersions are the SMB protocol versions the SR types can mount the share with, set as "vers" in the device
+// config. The "smb" SR driver picks the version with its own mount options, so it takes no version.
+var smbTypeVersions = map[string][]string{
+	"iso": {"1.0", "3.0"},
+}
+
+// checkSMBCIFSVersion makes sure the SR type mounts the share with the version
+func checkSMBCIFSVersion(typeKey string, version string) error {
+	versions, ok := smbTypeVersions[typeKey]
+	if !ok {
+		return errors.New(`cifs_version can't be set with type "` + typeKey + `", the SR picks the SMB version itself`)
+	}
+	if !slices.Contains(versions, version) {
+		return errors.New(`type "` + typeKey + `" only supports cifs_version "` + strings.Join(versions, `", "`) + `", got "` + version + `"`)
+	}
+	return nil
+}
+
 func getSMBCreateParams(session *xenapi.Session, data smbResourceModel) (srCreateParams, error) {
 	var params srCreateParams
 	coordinatorRef, _, err := getCoordinatorRef(session)
@@ -494,18 +859,38 @@ func getSMBCreateParams(session *xenapi.Session, data smbResourceModel) (srCreat
 		return params, err
 	}
 	params.Host = coordinatorRef
-	deviceConfig := make(map[string]string)
-	username := strings.TrimSpace(data.Username.ValueString())
-	password := strings.TrimSpace(data.Password.ValueString())
-	storageLocation := strings.Split(strings.TrimSpace(data.StorageLocation.ValueString()), ":")
 	params.TypeKey = data.Type.ValueString()
 	if params.TypeKey == "iso" {
 		params.ContentType = "iso"
-		deviceConfig["location"] = strings.ReplaceAll(storageLocation[0], "\\", "/")
-		bits := strings.Split(deviceConfig["location"], "/")
-		if len(bits) > 4 {
-			deviceConfig["location"] = "//" + bits[2] + "/" + bits[3]
-			deviceConfig["iso_path"] = "/" + strings.Join(bits[4:], "/")
+		params.AutoScan = true
+	}
+	params.DeviceConfig = getSMBDeviceConfig(data)
+	params.NameLabel = data.NameLabel.ValueString()
+	params.NameDescription = data.NameDescription.ValueString()
+	params.Shared = true
+	params.SmConfig = make(map[string]string)
+
+	return params, nil
+}
+
+// getSMBDeviceConfig returns the device config for the SMB SR, the ISO SR takes the credentials with
+// different keys from the SMB one
+func getSMBDeviceConfig(data smbResourceModel) map[string]string {
+	deviceConfig := make(map[string]string)
+	username := strings.TrimSpace(data.Username.ValueString())
+	// the storage takes the domain of the user from the "domain\username" form
+	domain := strings.TrimSpace(data.Domain.ValueString())
+	if domain != "" && username != "" {
+		username = domain + "\\" + username
+	}
+	password := strings.TrimSpace(data.Password.ValueString())
+	passwordSecret := strings.TrimSpace(data.PasswordSecret.ValueString())
+	server, serverPath, hasServerPath := strings.Cut(normalizeSMBStorageLocation(data.StorageLocation.ValueString()), ":")
+	if data.Type.ValueString() == "iso" {
+		location, isoPath := splitSMBISOLocation(server)
+		deviceConfig["location"] = location
+		if isoPath != "" {
+			deviceConfig["iso_path"] = isoPath
 		}
 		deviceConfig["type"] = "cifs"
 		if username != "" {
@@ -514,10 +899,13 @@ func getSMBCreateParams(session *xenapi.Session, data smbResourceModel) (srCreat
 		if password != "" {
 			deviceConfig["cifspassword"] = password
 		}
+		if passwordSecret != "" {
+			deviceConfig["cifspassword_secret"] = passwordSecret
+		}
 	} else {
-		deviceConfig["server"] = storageLocation[0]
-		if len(storageLocation) > 1 {
-			deviceConfig["serverpath"] = storageLocation[1]
+		deviceConfig["server"] = server
+		if hasServerPath {
+			deviceConfig["serverpath"] = serverPath
 		}
 		if username != "" {
 			deviceConfig["username"] = username
@@ -525,40 +913,88 @@ func getSMBCreateParams(session *xenapi.Session, data smbResourceModel) (srCreat
 		if password != "" {
 			deviceConfig["password"] = password
 		}
+		if passwordSecret != "" {
+			deviceConfig["password_secret"] = passwordSecret
+		}
+	}
+	if !data.CIFSVersion.IsNull() {
+		deviceConfig["vers"] = data.CIFSVersion.ValueString()
 	}
-	params.DeviceConfig = deviceConfig
-	params.NameLabel = data.NameLabel.ValueString()
-	params.NameDescription = data.NameDescription.ValueString()
-	params.Shared = true
-	params.SmConfig = make(map[string]string)
 
-	return params, nil
+	return deviceConfig
+}
+
+// normalizeSMBStorageLocation returns the canonical form of the SMB storage location "\\\\server\\share\\path" or
+// "\\\\server\\share:serverpath", so the equivalent forms compare equal. The UNC path is trimmed of the spaces and the
+// trailing backslashes, uses backslashes only and has the server and the share in lower case as they're case insensitive.
+// The serverpath is kept as it is apart from the trailing slashes, the directories may be case sensitive on the server.
+func normalizeSMBStorageLocation(storageLocation string) string {
+	location, serverPath, hasServerPath := strings.Cut(strings.TrimSpace(storageLocation), ":")
+	location = strings.ReplaceAll(strings.TrimSpace(location), "/", "\\")
+	bits := strings.Split(strings.TrimLeft(location, "\\"), "\\")
+	for i := 0; i < len(bits) && i < 2; i++ {
+		bits[i] = strings.ToLower(bits[i])
+	}
+	location = strings.TrimRight("\\\\"+strings.Join(bits, "\\"), "\\")
+	if !hasServerPath {
+		return location
+	}
+	serverPath = strings.TrimRight(strings.TrimSpace(serverPath), "/")
+	if serverPath == "" {
+		serverPath = "/"
+	}
+	return location + ":" + serverPath
+}
+
+// splitSMBISOLocation splits the UNC path of the ISO library into the share mounted by the SR and the path
+// of the ISOs in the share, e.g. \\server\share\linux\iso is split into //server/share and /linux/iso
+func splitSMBISOLocation(storageLocation string) (string, string) {
+	location := strings.ReplaceAll(strings.TrimSpace(storageLocation), "\\", "/")
+	bits := strings.Split(location, "/")
+	if len(bits) > 4 {
+		return "//" + bits[2] + "/" + bits[3], "/" + strings.Join(bits[4:], "/")
+	}
+	return location, ""
+}
+
+// joinSMBISOLocation joins the share and the ISO path of the ISO library into the UNC path,
+// it's the reverse of splitSMBISOLocation
+func joinSMBISOLocation(location string, isoPath string) string {
+	if isoPath != "" {
+		location = strings.TrimSuffix(location, "/") + "/" + strings.TrimPrefix(isoPath, "/")
+	}
+	return strings.ReplaceAll(location, "/", "\\")
 }
 
 func updateSMBResourceModel(srRecord xenapi.SRRecord, pbdRecord xenapi.PBDRecord, data *smbResourceModel) error {
 	data.NameLabel = types.StringValue(srRecord.NameLabel)
+	var location string
 	if srRecord.Type == "iso" {
-		location, ok := pbdRecord.DeviceConfig["location"]
+		isoLocation, ok := pbdRecord.DeviceConfig["location"]
 		if !ok {
 			return errors.New(`unable to find "location" in PBD device config`)
 		}
-		isoPath, ok := pbdRecord.DeviceConfig["iso_path"]
-		if ok && isoPath != "" {
-			location += isoPath
-		}
-		location = strings.ReplaceAll(location, "/", "\\")
-		data.StorageLocation = types.StringValue(location)
+		location = joinSMBISOLocation(isoLocation, pbdRecord.DeviceConfig["iso_path"])
 	} else {
 		server, ok := pbdRecord.DeviceConfig["server"]
 		if !ok {
 			return errors.New(`unable to find "server" in PBD device config`)
 		}
-		data.StorageLocation = types.StringValue(server)
+		location = server
 		serverPath, ok := pbdRecord.DeviceConfig["serverpath"]
 		if ok && serverPath != "" {
-			data.StorageLocation = types.StringValue(server + ":" + serverPath)
+			location = server + ":" + serverPath
 		}
 	}
+	// keep the configured form of the same location, e.g. written with forward slashes or with a new
+	// line in a heredoc, otherwise the plan would always show a diff
+	if data.StorageLocation.IsNull() || normalizeSMBStorageLocation(data.StorageLocation.ValueString()) != normalizeSMBStorageLocation(location) {
+		data.StorageLocation = types.StringValue(location)
+	}
+	data.CIFSVersion = types.StringNull()
+	if version, ok := pbdRecord.DeviceConfig["vers"]; ok && version != "" {
+		data.CIFSVersion = types.StringValue(version)
+	}
 	err := updateSMBResourceModelComputed(srRecord, data)
 
 	return err
@@ -577,9 +1013,15 @@ func smbResourceModelUpdateCheck(data smbResourceModel, dataState smbResourceMod
 	if data.Type != dataState.Type {
 		return errors.New(`"type" doesn't expected to be updated`)
 	}
-	if strings.TrimSpace(data.StorageLocation.ValueString()) != strings.TrimSpace(dataState.StorageLocation.ValueString()) {
+	if normalizeSMBStorageLocation(data.StorageLocation.ValueString()) != normalizeSMBStorageLocation(dataState.StorageLocation.ValueString()) {
 		return errors.New(`"storage_location" doesn't expected to be updated`)
 	}
+	if data.CIFSVersion != dataState.CIFSVersion {
+		return errors.New(`"cifs_version" doesn't expected to be updated`)
+	}
+	if strings.TrimSpace(data.PasswordSecret.ValueString()) != strings.TrimSpace(dataState.PasswordSecret.ValueString()) {
+		return errors.New(`"password_secret_uuid" doesn't expected to be updated`)
+	}
 	return nil
 }
 
@@ -595,3 +1037,120 @@ func smbResourceModelUpdate(session *xenapi.Session, ref xenapi.SRRef, data smbR
 
 	return nil
 }
+
+type srProbeDataSourceModel struct {
+	Type         types.String      `tfsdk:"type"`
+	DeviceConfig types.Map         `tfsdk:"device_config"`
+	SmConfig     types.Map         `tfsdk:"sm_config"`
+	DataItems    []srProbeItemData `tfsdk:"data_items"`
+}
+
+type srProbeItemData struct {
+	Configuration types.Map    `tfsdk:"configuration"`
+	Complete      types.Bool   `tfsdk:"complete"`
+	SRUUID        types.String `tfsdk:"sr_uuid"`
+	SRNameLabel   types.String `tfsdk:"sr_name_label"`
+	ExtraInfo     types.Map    `tfsdk:"extra_info"`
+}
+
+// srProbeXMLFields maps the fields of the items listed by the storage backend to the device config keys,
+// the item is complete when the SR can be created with the mapped device config.
+var srProbeXMLFields = map[string]struct {
+	DeviceConfigKeys map[string]string
+	Complete         bool
+}{
+	// NFS exports on the server
+	"Export": {DeviceConfigKeys: map[string]string{"Path": "serverpath"}, Complete: true},
+	// IQNs of the iSCSI target
+	"TGT": {DeviceConfigKeys: map[string]string{"TargetIQN": "targetIQN"}, Complete: false},
+	// LUNs of the iSCSI target IQN
+	"LUN": {DeviceConfigKeys: map[string]string{"SCSIid": "SCSIid"}, Complete: true},
+}
+
+type srProbeXMLItem struct {
+	XMLName xml.Name
+	Fields  []struct {
+		XMLName xml.Name
+		Value   string `xml:",chardata"`
+	} `xml:",any"`
+}
+
+// parseSRProbeXML reads the items listed by the storage backend when the device config is not complete,
+// e.g. the NFS exports on the server, they are returned in the XML of the probe error.
+func parseSRProbeXML(message string, deviceConfig map[string]string) []xenapi.ProbeResultRecord {
+	var results []xenapi.ProbeResultRecord
+	start := strings.Index(message, "<?xml")
+	end := strings.LastIndex(message, ">")
+	if start < 0 || end < start {
+		return results
+	}
+	var list struct {
+		Items []srProbeXMLItem `xml:",any"`
+	}
+	err := xml.Unmarshal([]byte(message[start:end+1]), &list)
+	if err != nil {
+		return results
+	}
+	for _, item := range list.Items {
+		itemFields := srProbeXMLFields[item.XMLName.Local]
+		configuration := maps.Clone(deviceConfig)
+		if configuration == nil {
+			configuration = make(map[string]string)
+		}
+		extraInfo := make(map[string]string)
+		for _, field := range item.Fields {
+			value := strings.TrimSpace(field.Value)
+			extraInfo[field.XMLName.Local] = value
+			if key, ok := itemFields.DeviceConfigKeys[field.XMLName.Local]; ok {
+				configuration[key] = value
+			}
+		}
+		results = append(results, xenapi.ProbeResultRecord{
+			Configuration: configuration,
+			Complete:      itemFields.Complete,
+			ExtraInfo:     extraInfo,
+		})
+	}
+	return results
+}
+
+// probeSRTargets probes the storage with the device config on the pool coordinator, it returns the SRs found
+// on the storage, or the items listed by the storage backend to complete the device config.
+func probeSRTargets(session *xenapi.Session, typeKey string, deviceConfig map[string]string, smConfig map[string]string) ([]xenapi.ProbeResultRecord, error) {
+	version, err := getAPIVersion(session)
+	if err != nil {
+		return nil, err
+	}
+	err = checkFeature(version, featureSRProbeExt)
+	if err != nil {
+		return nil, err
+	}
+	coordinatorRef, _, err := getCoordinatorRef(session)
+	if err != nil {
+		return nil, err
+	}
+	results, err := xenapi.SR.ProbeExt(session, coordinatorRef, deviceConfig, typeKey, smConfig)
+	if err != nil {
+		results = parseSRProbeXML(err.Error(), deviceConfig)
+		if len(results) == 0 {
+			return nil, featureError(version, featureSRProbeExt, err)
+		}
+	}
+	return results, nil
+}
+
+func updateSRProbeItemData(ctx context.Context, result xenapi.ProbeResultRecord, data *srProbeItemData) error {
+	var diags diag.Diagnostics
+	data.Configuration, diags = types.MapValueFrom(ctx, types.StringType, result.Configuration)
+	if diags.HasError() {
+		return errors.New("unable to access probe result configuration")
+	}
+	data.Complete = types.BoolValue(result.Complete)
+	data.SRUUID = types.StringValue(result.Sr.UUID)
+	data.SRNameLabel = types.StringValue(result.Sr.NameLabel)
+	data.ExtraInfo, diags = types.MapValueFrom(ctx, types.StringType, result.ExtraInfo)
+	if diags.HasError() {
+		return errors.New("unable to access probe result extra info")
+	}
+	return nil
+}
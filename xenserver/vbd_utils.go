@@ -3,10 +3,14 @@ package xenserver
 import (
 	"context"
 	"errors"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 	"xenapi"
 
+	"github.com/cenkalti/backoff/v4"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
@@ -16,17 +20,31 @@ import (
 )
 
 type vbdResourceModel struct {
-	VDI      types.String `tfsdk:"vdi_uuid"`
-	VBD      types.String `tfsdk:"vbd_ref"`
-	Mode     types.String `tfsdk:"mode"`
-	Bootable types.Bool   `tfsdk:"bootable"`
+	VDI               types.String `tfsdk:"vdi_uuid"`
+	VBD               types.String `tfsdk:"vbd_ref"`
+	Mode              types.String `tfsdk:"mode"`
+	Bootable          types.Bool   `tfsdk:"bootable"`
+	CurrentlyAttached types.Bool   `tfsdk:"currently_attached"`
 }
 
 var vbdResourceModelAttrTypes = map[string]attr.Type{
-	"vdi_uuid": types.StringType,
-	"vbd_ref":  types.StringType,
-	"mode":     types.StringType,
-	"bootable": types.BoolType,
+	"vdi_uuid":           types.StringType,
+	"vbd_ref":            types.StringType,
+	"mode":               types.StringType,
+	"bootable":           types.BoolType,
+	"currently_attached": types.BoolType,
+}
+
+type cdromDriveModel struct {
+	VBD               types.String `tfsdk:"vbd_ref"`
+	Empty             types.Bool   `tfsdk:"empty"`
+	CurrentlyAttached types.Bool   `tfsdk:"currently_attached"`
+}
+
+var cdromDriveModelAttrTypes = map[string]attr.Type{
+	"vbd_ref":            types.StringType,
+	"empty":              types.BoolType,
+	"currently_attached": types.BoolType,
 }
 
 func vbdSchema() map[string]schema.Attribute {
@@ -53,6 +71,67 @@ func vbdSchema() map[string]schema.Attribute {
 				stringvalidator.OneOf("RO", "RW"),
 			},
 		},
+		"currently_attached": schema.BoolAttribute{
+			MarkdownDescription: "True if the VBD is plugged to the running virtual machine.",
+			Computed:            true,
+		},
+	}
+}
+
+func cdromDriveSchema() map[string]schema.Attribute {
+	return map[string]schema.Attribute{
+		"vbd_ref": schema.StringAttribute{
+			Computed: true,
+		},
+		"empty": schema.BoolAttribute{
+			MarkdownDescription: "True if no ISO is inserted in the CD-ROM drive.",
+			Computed:            true,
+		},
+		"currently_attached": schema.BoolAttribute{
+			MarkdownDescription: "True if the CD-ROM drive is plugged to the running virtual machine.",
+			Computed:            true,
+		},
+	}
+}
+
+// uniqueVDIValidator rejects a hard_drive set that references the same VDI from more than one item,
+// updateVBDs keys the VBDs by VDI UUID so the duplicated items would collapse into one.
+type uniqueVDIValidator struct{}
+
+var _ validator.Set = uniqueVDIValidator{}
+
+func (v uniqueVDIValidator) Description(_ context.Context) string {
+	return "each item must reference a different vdi_uuid"
+}
+
+func (v uniqueVDIValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v uniqueVDIValidator) ValidateSet(_ context.Context, req validator.SetRequest, resp *validator.SetResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, element := range req.ConfigValue.Elements() {
+		object, ok := element.(types.Object)
+		if !ok || object.IsNull() || object.IsUnknown() {
+			continue
+		}
+		vdiUUID, ok := object.Attributes()["vdi_uuid"].(types.String)
+		if !ok || vdiUUID.IsNull() || vdiUUID.IsUnknown() {
+			continue
+		}
+		if seen[vdiUUID.ValueString()] {
+			resp.Diagnostics.AddAttributeError(
+				req.Path,
+				"Duplicate VDI in "+req.Path.String(),
+				"VDI "+vdiUUID.ValueString()+" is referenced by more than one item in "+req.Path.String()+", each VDI can only be attached to the VM once.",
+			)
+			continue
+		}
+		seen[vdiUUID.ValueString()] = true
 	}
 }
 
@@ -67,22 +146,101 @@ func setVBDDefaults(vbd *vbdResourceModel) {
 	}
 }
 
-func createVBD(session *xenapi.Session, vmRef xenapi.VMRef, vbd vbdResourceModel, vbdType xenapi.VbdType) error {
-	var vbdRef xenapi.VBDRef
-	vdiRef, err := xenapi.VDI.GetByUUID(session, vbd.VDI.ValueString())
+func createVBD(ctx context.Context, session *xenapi.Session, vmRef xenapi.VMRef, vbd vbdResourceModel, vbdType xenapi.VbdType) error {
+	var err error
+	// a CD type VBD without VDI is created as an empty drive
+	empty := vbdType == xenapi.VbdTypeCD && vbd.VDI.ValueString() == ""
+	vdiRef := xenapi.VDIRef("OpaqueRef:NULL")
+	if !empty {
+		vdiRef, err = xenapi.VDI.GetByUUID(session, vbd.VDI.ValueString())
+		if err != nil {
+			return errors.New(err.Error())
+		}
+		err = checkVDIAttachable(session, vdiRef, vmRef)
+		if err != nil {
+			return err
+		}
+	}
+
+	userDevices, err := getAllowedVBDDevices(session, vmRef, 1)
+	if err != nil {
+		return err
+	}
+
+	return createVBDOnDevice(ctx, session, vmRef, vbd, vbdType, vdiRef, empty, userDevices[0])
+}
+
+// getOtherAttachedVMs returns the VMs other than the given one which the VDI is currently attached to
+func getOtherAttachedVMs(vbdRecords []xenapi.VBDRecord, vmRef xenapi.VMRef) []xenapi.VMRef {
+	var vmRefs []xenapi.VMRef
+	for _, vbdRecord := range vbdRecords {
+		if vbdRecord.VM != vmRef && vbdRecord.CurrentlyAttached && !slices.Contains(vmRefs, vbdRecord.VM) {
+			vmRefs = append(vmRefs, vbdRecord.VM)
+		}
+	}
+	return vmRefs
+}
+
+// checkVDIAttachable makes sure the VDI can be attached to the VM, a VDI which is not sharable can't be
+// attached while it's in use by another VM, while a sharable one can be attached to several VMs
+func checkVDIAttachable(session *xenapi.Session, vdiRef xenapi.VDIRef, vmRef xenapi.VMRef) error {
+	vdiRecord, err := xenapi.VDI.GetRecord(session, vdiRef)
 	if err != nil {
 		return errors.New(err.Error())
 	}
+	if vdiRecord.Sharable {
+		return nil
+	}
+	var vbdRecords []xenapi.VBDRecord
+	for _, vbdRef := range vdiRecord.VBDs {
+		vbdRecord, err := xenapi.VBD.GetRecord(session, vbdRef)
+		if err != nil {
+			return errors.New(err.Error())
+		}
+		vbdRecords = append(vbdRecords, vbdRecord)
+	}
+	var vmUUIDs []string
+	for _, otherVMRef := range getOtherAttachedVMs(vbdRecords, vmRef) {
+		vmUUID, err := xenapi.VM.GetUUID(session, otherVMRef)
+		if err != nil {
+			return errors.New(err.Error())
+		}
+		vmUUIDs = append(vmUUIDs, vmUUID)
+	}
+	if len(vmUUIDs) > 0 {
+		return errors.New("VDI " + vdiRecord.UUID + " is not sharable and it's in use by VMs(UUID): " + strings.Join(vmUUIDs, ", "))
+	}
+	return nil
+}
+
+// isVMVBD returns true if the VBD belongs to the VM, the VBDs of a sharable VDI may belong to other VMs.
+// It returns false if the VBD doesn't exist anymore.
+func isVMVBD(session *xenapi.Session, vbdRef xenapi.VBDRef, vmRef xenapi.VMRef) (bool, error) {
+	vbdVMRef, err := xenapi.VBD.GetVM(session, vbdRef)
+	if err != nil {
+		if strings.Contains(err.Error(), "HANDLE_INVALID") {
+			return false, nil
+		}
+		return false, errors.New(err.Error())
+	}
+	return vbdVMRef == vmRef, nil
+}
 
+// getAllowedVBDDevices returns the first count devices which are free to attach VBDs to the VM
+func getAllowedVBDDevices(session *xenapi.Session, vmRef xenapi.VMRef, count int) ([]string, error) {
 	userDevices, err := xenapi.VM.GetAllowedVBDDevices(session, vmRef)
 	if err != nil {
-		return errors.New(err.Error())
+		return nil, errors.New(err.Error())
 	}
 
-	if len(userDevices) == 0 {
-		return errors.New("unable to find available vbd devices to attach to vm " + string(vmRef))
+	if len(userDevices) < count {
+		return nil, errors.New("unable to find available vbd devices to attach to vm " + string(vmRef))
 	}
 
+	return userDevices[:count], nil
+}
+
+func createVBDOnDevice(ctx context.Context, session *xenapi.Session, vmRef xenapi.VMRef, vbd vbdResourceModel, vbdType xenapi.VbdType, vdiRef xenapi.VDIRef, empty bool, userDevice string) error {
 	setVBDDefaults(&vbd)
 
 	vbdMode := xenapi.VbdMode(vbd.Mode.ValueString())
@@ -96,11 +254,11 @@ func createVBD(session *xenapi.Session, vmRef xenapi.VMRef, vbd vbdResourceModel
 		Type:       vbdType,
 		Mode:       vbdMode,
 		Bootable:   vbd.Bootable.ValueBool(),
-		Empty:      false,
-		Userdevice: userDevices[0],
+		Empty:      empty,
+		Userdevice: userDevice,
 	}
 
-	vbdRef, err = xenapi.VBD.Create(session, vbdRecord)
+	vbdRef, err := xenapi.VBD.Create(session, vbdRecord)
 	if err != nil {
 		return errors.New(err.Error())
 	}
@@ -112,16 +270,46 @@ func createVBD(session *xenapi.Session, vmRef xenapi.VMRef, vbd vbdResourceModel
 	}
 
 	if vmPowerState == xenapi.VMPowerStateRunning {
-		err = xenapi.VBD.Plug(session, vbdRef)
+		err = plugVBD(ctx, session, vbdRef)
 		if err != nil {
-			return errors.New(err.Error())
+			return err
 		}
 	}
 
 	return nil
 }
 
-func createVBDs(ctx context.Context, session *xenapi.Session, vmRef xenapi.VMRef, data vmResourceModel, vbdType xenapi.VbdType) error {
+// plugVBD plugs the VBD to the running VM, it retries for a short time when the VM isn't ready for the hot-plug
+func plugVBD(ctx context.Context, session *xenapi.Session, vbdRef xenapi.VBDRef) error {
+	operation := func() error {
+		// not retried on SESSION_INVALID, the VBDs may be plugged from the goroutines of the operation
+		err := xenapi.VBD.Plug(session, vbdRef)
+		if err == nil {
+			return nil
+		}
+		// the VBD is plugged already, e.g. by the VM start racing with this plug
+		if strings.Contains(err.Error(), "DEVICE_ALREADY_ATTACHED") {
+			return nil
+		}
+		// e.g. OPERATION_NOT_ALLOWED is raised while the VM is still booting
+		if classifyXapiError(err) == xapiErrorRetryable {
+			return err
+		}
+		return backoff.Permanent(err)
+	}
+
+	b := backoff.NewExponentialBackOff()
+	b.MaxInterval = 5 * time.Second
+	b.MaxElapsedTime = 1 * time.Minute
+	err := backoff.Retry(operation, backoff.WithContext(b, ctx))
+	if err != nil {
+		return errors.New(err.Error())
+	}
+
+	return nil
+}
+
+func createVBDs(ctx context.Context, session *xenapi.Session, vmRef xenapi.VMRef, data vmResourceModel, vbdType xenapi.VbdType, parallel parallelLimit) error {
 	if data.HardDrive.IsUnknown() || len(data.HardDrive.Elements()) == 0 {
 		tflog.Debug(ctx, "---> Skip create VBDs")
 		return nil
@@ -138,26 +326,80 @@ func createVBDs(ctx context.Context, session *xenapi.Session, vmRef xenapi.VMRef
 		return elements[i].Bootable.ValueBool() && !elements[j].Bootable.ValueBool()
 	})
 
-	for _, vbd := range elements {
-		tflog.Debug(ctx, "---> Create VBD with VDI: "+vbd.VDI.String()+"  Mode: "+vbd.Mode.String()+"  Bootable: "+vbd.Bootable.String())
-		err := createVBD(session, vmRef, vbd, vbdType)
+	// reserve the devices in the sorted order first, so the VBDs can be created at the same time
+	userDevices, err := getAllowedVBDDevices(session, vmRef, len(elements))
+	if err != nil {
+		return err
+	}
+
+	return parallel.run(len(elements), func(i int) error {
+		vbd := elements[i]
+		vdiRef, err := xenapi.VDI.GetByUUID(session, vbd.VDI.ValueString())
+		if err != nil {
+			return errors.New(err.Error())
+		}
+		err = checkVDIAttachable(session, vdiRef, vmRef)
 		if err != nil {
 			return err
 		}
-	}
+		tflog.Debug(ctx, "---> Create VBD with VDI: "+vbd.VDI.String()+"  Mode: "+vbd.Mode.String()+"  Bootable: "+vbd.Bootable.String()+"  Device: "+userDevices[i])
+		return createVBDOnDevice(ctx, session, vmRef, vbd, vbdType, vdiRef, false, userDevices[i])
+	})
+}
 
-	return nil
+// hasBootableHardDrive returns true if one of the items in hard_drive is bootable
+func hasBootableHardDrive(ctx context.Context, plan vmResourceModel) (bool, error) {
+	if plan.HardDrive.IsUnknown() || plan.HardDrive.IsNull() {
+		return false, nil
+	}
+	elements := make([]vbdResourceModel, 0, len(plan.HardDrive.Elements()))
+	diags := plan.HardDrive.ElementsAs(ctx, &elements, false)
+	if diags.HasError() {
+		return false, errors.New("unable to get HardDrive elements")
+	}
+	for _, vbd := range elements {
+		if vbd.Bootable.ValueBool() {
+			return true, nil
+		}
+	}
+	return false, nil
 }
 
-func updateVBDs(ctx context.Context, plan vmResourceModel, state vmResourceModel, vmRef xenapi.VMRef, session *xenapi.Session) error {
-	planHardDrives := make([]vbdResourceModel, 0, len(state.HardDrive.Elements()))
-	if !plan.HardDrive.IsUnknown() {
-		diags := plan.HardDrive.ElementsAs(ctx, &planHardDrives, false)
-		if diags.HasError() {
-			return errors.New("unable to get HardDrives in plan data")
+// checkBootableHardDrive makes sure the VM has a disk to boot from when boot_order contains "c", the
+// disks inherited from the template are bootable as they are
+func checkBootableHardDrive(ctx context.Context, session *xenapi.Session, plan vmResourceModel) error {
+	if plan.BootOrder.IsUnknown() || !strings.Contains(plan.BootOrder.ValueString(), "c") {
+		return nil
+	}
+	// the disks are kept as they are when hard_drive is not configured
+	if plan.HardDrive.IsUnknown() {
+		return nil
+	}
+
+	bootable, err := hasBootableHardDrive(ctx, plan)
+	if err != nil || bootable {
+		return err
+	}
+
+	if !plan.TemplateName.IsNull() {
+		// the template may be removed after the VM is created, leave the template check to Create
+		templateRef, err := getFirstTemplate(session, plan.TemplateName.ValueString())
+		if err != nil {
+			return nil
+		}
+		templateDisks, err := getAllDiskTypeVBDs(session, templateRef)
+		if err != nil {
+			return err
+		}
+		if len(templateDisks) > 0 {
+			return nil
 		}
 	}
 
+	return errors.New(`boot_order contains "c" but there is no disk to boot from, set bootable = true on one of the items in hard_drive`)
+}
+
+func updateVBDs(ctx context.Context, plan vmResourceModel, state vmResourceModel, vmRef xenapi.VMRef, session *xenapi.Session) error {
 	stateHardDrives := make([]vbdResourceModel, 0, len(state.HardDrive.Elements()))
 	if !state.HardDrive.IsUnknown() && !state.HardDrive.IsNull() {
 		diags := state.HardDrive.ElementsAs(ctx, &stateHardDrives, false)
@@ -166,6 +408,16 @@ func updateVBDs(ctx context.Context, plan vmResourceModel, state vmResourceModel
 		}
 	}
 
+	// hard_drive is not configured, keep the disks as they are instead of detaching all of them
+	planHardDrives := stateHardDrives
+	if !plan.HardDrive.IsUnknown() {
+		planHardDrives = make([]vbdResourceModel, 0, len(plan.HardDrive.Elements()))
+		diags := plan.HardDrive.ElementsAs(ctx, &planHardDrives, false)
+		if diags.HasError() {
+			return errors.New("unable to get HardDrives in plan data")
+		}
+	}
+
 	var err error
 	planHardDrivesMap := make(map[string]vbdResourceModel)
 	for _, vbd := range planHardDrives {
@@ -188,8 +440,18 @@ func updateVBDs(ctx context.Context, plan vmResourceModel, state vmResourceModel
 			if vmState == xenapi.VMPowerStateRunning {
 				return errors.New("unable to delete the item in hard_drive for a running VM")
 			}
+			// only destroy the VBD of this VM, the VDI may be shared with other VMs
+			vbdRef := xenapi.VBDRef(stateVBD.VBD.ValueString())
+			owned, err := isVMVBD(session, vbdRef, vmRef)
+			if err != nil {
+				return err
+			}
+			if !owned {
+				tflog.Debug(ctx, "---> Skip destroying VBD not attached to the VM: "+stateVBD.VBD.String())
+				continue
+			}
 			tflog.Debug(ctx, "---> Destroy VBD:	"+stateVBD.VBD.String())
-			err = xenapi.VBD.Destroy(session, xenapi.VBDRef(stateVBD.VBD.ValueString()))
+			err = xenapi.VBD.Destroy(session, vbdRef)
 			if err != nil {
 				if !strings.Contains(err.Error(), "HANDLE_INVALID") {
 					return errors.New(err.Error())
@@ -207,13 +469,28 @@ func updateVBDs(ctx context.Context, plan vmResourceModel, state vmResourceModel
 				return errors.New("unable to create the item with 'RO' mode in hard_drive for a running VM")
 			}
 			tflog.Debug(ctx, "---> Create VBD for VDI: "+vdiUUID+" <---")
-			err = createVBD(session, vmRef, planVBD, xenapi.VbdTypeDisk)
+			err = createVBD(ctx, session, vmRef, planVBD, xenapi.VbdTypeDisk)
 			if err != nil {
 				return err
 			}
 		} else {
-			// Update VBD if attributes changed
+			// Update VBD if attributes changed, the attributes unknown in plan are kept as they are
+			if planVBD.Mode.IsUnknown() {
+				planVBD.Mode = stateVBD.Mode
+			}
+			if planVBD.Bootable.IsUnknown() {
+				planVBD.Bootable = stateVBD.Bootable
+			}
 			setVBDDefaults(&planVBD)
+			if !planVBD.Mode.Equal(stateVBD.Mode) || !planVBD.Bootable.Equal(stateVBD.Bootable) {
+				owned, err := isVMVBD(session, xenapi.VBDRef(stateVBD.VBD.ValueString()), vmRef)
+				if err != nil {
+					return err
+				}
+				if !owned {
+					return errors.New("VBD " + stateVBD.VBD.ValueString() + " of VDI " + vdiUUID + " is not attached to the VM, refresh the state and try again")
+				}
+			}
 
 			if !planVBD.Mode.Equal(stateVBD.Mode) {
 				if vmState == xenapi.VMPowerStateRunning {
@@ -261,10 +538,7 @@ func getAllDiskTypeVBDs(session *xenapi.Session, vmRef xenapi.VMRef) ([]string,
 }
 
 func getTemplateVBDRefListFromVMRecord(vmRecord xenapi.VMRecord) []xenapi.VBDRef {
-	templateVBDRefs, ok := vmRecord.OtherConfig["tf_template_vbds"]
-	if !ok {
-		templateVBDRefs = ""
-	}
+	templateVBDRefs := getVMState(vmRecord.OtherConfig)["template_vbds"]
 	templateVBDRefList := []xenapi.VBDRef{}
 	if templateVBDRefs != "" {
 		refs := strings.Split(templateVBDRefs, ",")
@@ -276,73 +550,115 @@ func getTemplateVBDRefListFromVMRecord(vmRecord xenapi.VMRecord) []xenapi.VBDRef
 }
 
 func getVDIUUIDFromISOName(session *xenapi.Session, isoName string) (string, error) {
-	var vdiUUID string
-	vdiRecords, err := xenapi.VDI.GetAllRecords(session)
-	if err != nil {
-		return vdiUUID, errors.New(err.Error())
-	}
+	return getISOVDIUUID(session, isoName, "")
+}
 
-	vdiUUIDList := make([]string, 0)
-	for _, vdiRecord := range vdiRecords {
-		if vdiRecord.NameLabel == isoName {
-			vdiUUIDList = append(vdiUUIDList, vdiRecord.UUID)
-		}
-	}
+// cdromItem is a CD-ROM drive in the plan, the ISO in it is given by either its name or its VDI UUID
+type cdromItem struct {
+	isoName string
+	vdiUUID string
+	byUUID  bool
+}
 
-	if len(vdiUUIDList) == 0 {
-		return vdiUUID, errors.New("no VDI found with name: " + isoName)
+// matches returns true if the CD-ROM drive already holds the ISO of the item
+func (item cdromItem) matches(cd cdVBD) bool {
+	if item.byUUID {
+		return item.vdiUUID == cd.vdiUUID
 	}
+	return item.isoName == cd.isoName
+}
 
-	if len(vdiUUIDList) != 0 && len(vdiUUIDList) > 1 {
-		return vdiUUID, errors.New("multiple VDIs found with name: " + isoName)
+// getVDIUUID returns the VDI UUID of the ISO to insert, "" for an empty drive
+func (item cdromItem) getVDIUUID(session *xenapi.Session) (string, error) {
+	if item.byUUID || item.isoName == "" {
+		return item.vdiUUID, nil
 	}
+	return getVDIUUIDFromISOName(session, item.isoName)
+}
 
-	vdiUUID = vdiUUIDList[0]
-
-	return vdiUUID, nil
+// getCDROMItems returns the CD-ROM drives in the plan, and whether the drives not in the plan
+// should be removed. It returns nil if the plan doesn't manage the CD-ROM drives.
+func getCDROMItems(ctx context.Context, plan vmResourceModel) ([]cdromItem, bool, error) {
+	switch {
+	case !plan.CDROMs.IsUnknown() && !plan.CDROMs.IsNull():
+		var isoNames []string
+		diags := plan.CDROMs.ElementsAs(ctx, &isoNames, false)
+		if diags.HasError() {
+			return nil, false, errors.New("unable to get CD-ROMs in plan data")
+		}
+		items := make([]cdromItem, 0, len(isoNames))
+		for _, isoName := range isoNames {
+			items = append(items, cdromItem{isoName: isoName})
+		}
+		return items, true, nil
+	case !plan.CDROMVDI.IsUnknown() && !plan.CDROMVDI.IsNull():
+		// the scalar forms only manage the first CD-ROM drive
+		return []cdromItem{{vdiUUID: plan.CDROMVDI.ValueString(), byUUID: true}}, false, nil
+	case !plan.CDROM.IsUnknown() && !plan.CDROM.IsNull():
+		return []cdromItem{{isoName: plan.CDROM.ValueString()}}, false, nil
+	}
+	return nil, false, nil
 }
 
 func setCDROM(ctx context.Context, session *xenapi.Session, vmRef xenapi.VMRef, plan vmResourceModel) error {
-	if plan.CDROM.IsUnknown() {
+	items, manageDrives, err := getCDROMItems(ctx, plan)
+	if err != nil {
+		return err
+	}
+	if items == nil {
 		tflog.Debug(ctx, "---> CD-ROM is not set, use the default value")
 		return nil
 	}
-	planCDROM := plan.CDROM.ValueString()
+
 	vmRecord, err := xenapi.VM.GetRecord(session, vmRef)
 	if err != nil {
 		return errors.New(err.Error())
 	}
-	baseCD, err := getCDFromVMRecord(ctx, session, vmRecord)
+	baseCDs, err := getCDsFromVMRecord(session, vmRecord)
 	if err != nil {
 		return err
 	}
 
-	if string(baseCD.vbdRef) == "OpaqueRef:NULL" || string(baseCD.vbdRef) == "" {
-		if planCDROM != "" {
-			// create the CD-ROM if not exist
-			err = createCDROM(session, vmRef, planCDROM)
-			if err != nil {
-				return err
-			}
+	for i, item := range items {
+		if i < len(baseCDs) && item.matches(baseCDs[i]) {
+			continue
 		}
-	} else {
 		// get the new vdiUUID
-		vdiUUID := ""
-		if planCDROM != "" && planCDROM != baseCD.isoName {
-			uuid, err := getVDIUUIDFromISOName(session, planCDROM)
-			if err != nil {
-				return err
-			}
-			vdiUUID = uuid
+		vdiUUID, err := item.getVDIUUID(session)
+		if err != nil {
+			return err
 		}
-		if planCDROM != baseCD.isoName {
-			// change the CD-ROM
-			err = changeVMISO(ctx, session, baseCD, vdiUUID)
+		if i >= len(baseCDs) {
+			// create the CD-ROM if not exist, an empty drive is created when no ISO is given
+			err = createCDROM(ctx, session, vmRef, vdiUUID)
 			if err != nil {
 				return err
 			}
+			continue
+		}
+		// change the CD-ROM
+		err = changeVMISO(ctx, session, baseCDs[i], vdiUUID)
+		if err != nil {
+			return err
 		}
 	}
+
+	if !manageDrives || len(baseCDs) <= len(items) {
+		return nil
+	}
+
+	// remove the CD-ROM drives which are not in plan
+	if vmRecord.PowerState == xenapi.VMPowerStateRunning {
+		return errors.New("unable to remove the CD-ROM drive for a running VM")
+	}
+	for _, cd := range baseCDs[len(items):] {
+		tflog.Debug(ctx, "---> Destroy CD-ROM VBD: "+string(cd.vbdRef))
+		err = xenapi.VBD.Destroy(session, cd.vbdRef)
+		if err != nil {
+			return errors.New(err.Error())
+		}
+	}
+
 	return nil
 }
 
@@ -368,14 +684,10 @@ func changeVMISO(ctx context.Context, session *xenapi.Session, cd cdVBD, vdiUUID
 	return nil
 }
 
-func createCDROM(session *xenapi.Session, vmRef xenapi.VMRef, isoName string) error {
-	vdiUUID, err := getVDIUUIDFromISOName(session, isoName)
-	if err != nil {
-		return err
-	}
+func createCDROM(ctx context.Context, session *xenapi.Session, vmRef xenapi.VMRef, vdiUUID string) error {
 	var vbdRes vbdResourceModel
 	vbdRes.VDI = types.StringValue(vdiUUID)
-	err = createVBD(session, vmRef, vbdRes, xenapi.VbdTypeCD)
+	err := createVBD(ctx, session, vmRef, vbdRes, xenapi.VbdTypeCD)
 	if err != nil {
 		return err
 	}
@@ -384,47 +696,52 @@ func createCDROM(session *xenapi.Session, vmRef xenapi.VMRef, isoName string) er
 }
 
 type cdVBD struct {
-	vbdRef  xenapi.VBDRef
-	empty   bool
-	isoName string
+	vbdRef            xenapi.VBDRef
+	empty             bool
+	currentlyAttached bool
+	isoName           string
+	vdiUUID           string
+	userdevice        string
 }
 
-func getCDFromVMRecord(ctx context.Context, session *xenapi.Session, vmRecord xenapi.VMRecord) (cdVBD, error) {
-	var cd cdVBD
-	_, vbdSet, err := getVBDsFromVMRecord(ctx, session, vmRecord, xenapi.VbdTypeCD)
-	if err != nil {
-		return cd, err
-	}
-
-	if len(vbdSet) == 0 {
-		return cd, nil
-	}
-
-	// if vbdSet is not empty, but it should only have one CDROM
-	if len(vbdSet) != 0 && len(vbdSet) > 1 {
-		return cd, errors.New("multiple CD-ROMs found")
-	}
-
-	cd.vbdRef = xenapi.VBDRef(vbdSet[0].VBD.ValueString())
-	if string(cd.vbdRef) != "OpaqueRef:NULL" {
-		empty, err := xenapi.VBD.GetEmpty(session, cd.vbdRef)
+// getCDsFromVMRecord returns the CD-ROM drives of the VM sorted by device order
+func getCDsFromVMRecord(session *xenapi.Session, vmRecord xenapi.VMRecord) ([]cdVBD, error) {
+	cds := []cdVBD{}
+	for _, vbdRef := range vmRecord.VBDs {
+		vbdRecord, err := xenapi.VBD.GetRecord(session, vbdRef)
 		if err != nil {
-			return cd, errors.New(err.Error())
+			return cds, errors.New(err.Error())
 		}
-		cd.empty = empty
-	}
-	vdiUUID := vbdSet[0].VDI.ValueString()
-	if vdiUUID != "" {
-		vdiRef, err := xenapi.VDI.GetByUUID(session, vdiUUID)
-		if err != nil {
-			return cd, errors.New(err.Error())
+		if vbdRecord.Type != xenapi.VbdTypeCD {
+			continue
 		}
-		isoName, err := xenapi.VDI.GetNameLabel(session, vdiRef)
-		if err != nil {
-			return cd, errors.New(err.Error())
+
+		cd := cdVBD{
+			vbdRef:            vbdRef,
+			empty:             vbdRecord.Empty,
+			currentlyAttached: vbdRecord.CurrentlyAttached,
+			userdevice:        vbdRecord.Userdevice,
 		}
-		cd.isoName = isoName
+		// for CD type VBD, VDI can be NULL
+		if string(vbdRecord.VDI) != "OpaqueRef:NULL" {
+			vdiRecord, err := xenapi.VDI.GetRecord(session, vbdRecord.VDI)
+			if err != nil {
+				return cds, errors.New(err.Error())
+			}
+			cd.isoName = vdiRecord.NameLabel
+			cd.vdiUUID = vdiRecord.UUID
+		}
+		cds = append(cds, cd)
 	}
 
-	return cd, nil
+	sort.Slice(cds, func(i, j int) bool {
+		deviceI, errI := strconv.Atoi(cds[i].userdevice)
+		deviceJ, errJ := strconv.Atoi(cds[j].userdevice)
+		if errI != nil || errJ != nil {
+			return cds[i].userdevice < cds[j].userdevice
+		}
+		return deviceI < deviceJ
+	})
+
+	return cds, nil
 }
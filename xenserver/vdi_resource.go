@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/hashicorp/terraform-plugin-framework-validators/resourcevalidator"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
@@ -14,9 +15,10 @@ import (
 
 // Ensure provider defined types fully satisfy framework interfaces.
 var (
-	_ resource.Resource                = &vdiResource{}
-	_ resource.ResourceWithConfigure   = &vdiResource{}
-	_ resource.ResourceWithImportState = &vdiResource{}
+	_ resource.Resource                     = &vdiResource{}
+	_ resource.ResourceWithConfigure        = &vdiResource{}
+	_ resource.ResourceWithImportState      = &vdiResource{}
+	_ resource.ResourceWithConfigValidators = &vdiResource{}
 )
 
 func NewVDIResource() resource.Resource {
@@ -25,7 +27,8 @@ func NewVDIResource() resource.Resource {
 
 // vdiResource defines the resource implementation.
 type vdiResource struct {
-	session *xenapi.Session
+	session          *xenapi.Session
+	operationLimiter operationLimiter
 }
 
 func (r *vdiResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -39,6 +42,13 @@ func (r *vdiResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *
 	}
 }
 
+// Either sr_uuid or sr_type must be set to choose the SR to create the VDI on
+func (r *vdiResource) ConfigValidators(_ context.Context) []resource.ConfigValidator {
+	return []resource.ConfigValidator{
+		resourcevalidator.AtLeastOneOf(path.MatchRoot("sr_uuid"), path.MatchRoot("sr_type")),
+	}
+}
+
 // Set the parameter of the resource, pass value from provider
 func (r *vdiResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
 	// Prevent panic if the provider has not been configured.
@@ -54,6 +64,7 @@ func (r *vdiResource) Configure(_ context.Context, req resource.ConfigureRequest
 		return
 	}
 	r.session = providerData.session
+	r.operationLimiter = providerData.operationLimiter
 }
 
 func (r *vdiResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
@@ -63,8 +74,17 @@ func (r *vdiResource) Create(ctx context.Context, req resource.CreateRequest, re
 		return
 	}
 
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
 	tflog.Debug(ctx, "Creating VDI...")
-	record, err := getVDICreateParams(ctx, r.session, data)
+	record, err := withSessionRetry(r.session, func() (xenapi.VDIRecord, error) {
+		return getVDICreateParams(ctx, r.session, data)
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to get VDI create params",
@@ -95,7 +115,7 @@ func (r *vdiResource) Create(ctx context.Context, req resource.CreateRequest, re
 		}
 		return
 	}
-	err = updateVDIResourceModelComputed(ctx, vdiRecord, &data)
+	err = updateVDIResourceModelComputed(ctx, r.session, vdiRecord, &data)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to update the computed fields of VDIResourceModel",
@@ -113,6 +133,16 @@ func (r *vdiResource) Create(ctx context.Context, req resource.CreateRequest, re
 	tflog.Debug(ctx, "VDI created")
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+	// the VDI is not tracked in state, destroy it to not leave it orphaned
+	if resp.Diagnostics.HasError() {
+		err = cleanupVDIResource(r.session, vdiRef)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Error cleaning up VDI resource",
+				err.Error(),
+			)
+		}
+	}
 }
 
 func (r *vdiResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
@@ -122,8 +152,17 @@ func (r *vdiResource) Read(ctx context.Context, req resource.ReadRequest, resp *
 		return
 	}
 
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
 	// Overwrite data with refreshed resource state
-	vdiRef, err := xenapi.VDI.GetByUUID(r.session, data.UUID.ValueString())
+	vdiRef, err := withSessionRetry(r.session, func() (xenapi.VDIRef, error) {
+		return xenapi.VDI.GetByUUID(r.session, data.UUID.ValueString())
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to get VDI ref",
@@ -158,12 +197,19 @@ func (r *vdiResource) Update(ctx context.Context, req resource.UpdateRequest, re
 		return
 	}
 
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
 	// Checking if configuration changes are allowed
 	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
-	err := vdiResourceModelUpdateCheck(plan, state)
+	err = vdiResourceModelUpdateCheck(plan, state)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Error update xenserver_vdi configuration",
@@ -173,7 +219,9 @@ func (r *vdiResource) Update(ctx context.Context, req resource.UpdateRequest, re
 	}
 
 	// Update the resource with new configuration
-	vdiRef, err := xenapi.VDI.GetByUUID(r.session, plan.UUID.ValueString())
+	vdiRef, err := withSessionRetry(r.session, func() (xenapi.VDIRef, error) {
+		return xenapi.VDI.GetByUUID(r.session, plan.UUID.ValueString())
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to get VDI ref",
@@ -197,7 +245,7 @@ func (r *vdiResource) Update(ctx context.Context, req resource.UpdateRequest, re
 		)
 		return
 	}
-	err = updateVDIResourceModelComputed(ctx, vdiRecord, &plan)
+	err = updateVDIResourceModelComputed(ctx, r.session, vdiRecord, &plan)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to update the computed fields of VDIResourceModel",
@@ -216,7 +264,16 @@ func (r *vdiResource) Delete(ctx context.Context, req resource.DeleteRequest, re
 		return
 	}
 
-	vdiRef, err := xenapi.VDI.GetByUUID(r.session, data.UUID.ValueString())
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	vdiRef, err := withSessionRetry(r.session, func() (xenapi.VDIRef, error) {
+		return xenapi.VDI.GetByUUID(r.session, data.UUID.ValueString())
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to get VDI ref",
@@ -2,11 +2,14 @@ package xenserver
 
 import (
 	"fmt"
+	"maps"
 	"os"
 	"regexp"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+
+	"xenapi"
 )
 
 func testAccVDIResourceConfig(name_label string, name_description string, virtual_size string, extra_config string) string {
@@ -34,6 +37,10 @@ func TestAccVDIResource(t *testing.T) {
 	resource.Test(t, resource.TestCase{
 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
 		Steps: []resource.TestStep{
+			{
+				Config:      providerConfig + testAccVDIResourceConfig("Test VDI", "", "1 * 1024 * 1024 * 1024", `type = "cbt_metadata"`),
+				ExpectError: regexp.MustCompile(`Attribute type value must be one of`),
+			},
 			// Create and Read testing
 			{
 				Config: providerConfig + testAccVDIResourceConfig("Test VDI", "", "1 * 1024 * 1024 * 1024", ""),
@@ -43,6 +50,8 @@ func TestAccVDIResource(t *testing.T) {
 					resource.TestCheckResourceAttr("xenserver_vdi.test_vdi", "virtual_size", "1073741824"),
 					resource.TestCheckResourceAttr("xenserver_vdi.test_vdi", "other_config.%", "1"),
 					resource.TestCheckResourceAttr("xenserver_vdi.test_vdi", "other_config.flag", "1"),
+					resource.TestCheckResourceAttr("xenserver_vdi.test_vdi", "sm_config.%", "0"),
+					resource.TestCheckResourceAttr("xenserver_vdi.test_vdi", "allocation", "thin"),
 					// Verify dynamic values have any value set in the state.
 
 					resource.TestCheckResourceAttrSet("xenserver_vdi.test_vdi", "uuid"),
@@ -71,6 +80,14 @@ func TestAccVDIResource(t *testing.T) {
 				Config:      providerConfig + testAccVDIResourceConfig("Test VDI 2", "Test VDI description", "1 * 1024 * 1024 * 1024", "read_only = true"),
 				ExpectError: regexp.MustCompile(`"read_only" doesn't expected to be updated`),
 			},
+			{
+				Config:      providerConfig + testAccVDIResourceConfig("Test VDI 2", "Test VDI description", "1 * 1024 * 1024 * 1024", `sm_config = { "allow_caching" = "true" }`),
+				ExpectError: regexp.MustCompile(`"sm_config" doesn't expected to be updated`),
+			},
+			{
+				Config:      providerConfig + testAccVDIResourceConfig("Test VDI 2", "Test VDI description", "1 * 1024 * 1024 * 1024", `allocation = "thick"`),
+				ExpectError: regexp.MustCompile(`"allocation" doesn't expected to be updated`),
+			},
 			// Update and Read testing
 			{
 				Config: providerConfig + testAccVDIResourceConfig("Test VDI 2", "Test VDI description", "1 * 1024 * 1024 * 1024", ""),
@@ -88,3 +105,125 @@ func TestAccVDIResource(t *testing.T) {
 		},
 	})
 }
+
+func TestAccVDIResourceSmConfig(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Create and Read testing
+			{
+				Config: providerConfig + testAccVDIResourceConfig("Test VDI", "", "1 * 1024 * 1024 * 1024", `sm_config = { "allow_caching" = "true" }`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_vdi.test_vdi", "sm_config.%", "1"),
+					resource.TestCheckResourceAttr("xenserver_vdi.test_vdi", "sm_config.allow_caching", "true"),
+				),
+			},
+			// ImportState testing
+			{
+				ResourceName:            "xenserver_vdi.test_vdi",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{},
+			},
+			// Delete testing automatically occurs in TestCase
+		},
+	})
+}
+
+func TestAccVDIResourceAllocation(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      providerConfig + testAccVDIResourceConfig("Test VDI", "", "1 * 1024 * 1024 * 1024", `allocation = "thick"`),
+				ExpectError: regexp.MustCompile(`SR type "nfs" doesn't support "thick" allocation`),
+			},
+		},
+	})
+}
+
+func TestSetVDIAllocation(t *testing.T) {
+	testCases := []struct {
+		srType     string
+		allocation string
+		smConfig   map[string]string
+		expectErr  bool
+	}{
+		{srType: "nfs", allocation: "thin", smConfig: map[string]string{}},
+		{srType: "nfs", allocation: "thick", expectErr: true},
+		{srType: "lvm", allocation: "thick", smConfig: map[string]string{"allocation": "thick"}},
+		{srType: "lvmoiscsi", allocation: "thin", smConfig: map[string]string{"allocation": "thin"}},
+		{srType: "iso", allocation: "thin", expectErr: true},
+	}
+	for _, tc := range testCases {
+		smConfig := map[string]string{}
+		err := setVDIAllocation(tc.srType, tc.allocation, smConfig)
+		if tc.expectErr {
+			if err == nil {
+				t.Errorf("setVDIAllocation(%q, %q) expected an error", tc.srType, tc.allocation)
+			}
+			continue
+		}
+		if err != nil || !maps.Equal(smConfig, tc.smConfig) {
+			t.Errorf("setVDIAllocation(%q, %q) = %v, %v, expected %v", tc.srType, tc.allocation, smConfig, err, tc.smConfig)
+		}
+	}
+}
+
+func TestGetUserVDISmConfig(t *testing.T) {
+	smConfig := map[string]string{
+		"allow_caching":                  "true",
+		"vdi_type":                       "vhd",
+		"vhd-parent":                     "00000000-0000-0000-0000-000000000000",
+		"host_OpaqueRef:00000000":        "RW",
+		"read-caching-enabled-on-000000": "false",
+	}
+	userSmConfig := getUserVDISmConfig(smConfig)
+	if len(userSmConfig) != 1 || userSmConfig["allow_caching"] != "true" {
+		t.Errorf("getUserVDISmConfig() = %v, expected only allow_caching", userSmConfig)
+	}
+}
+
+func TestCheckVDITypeForSR(t *testing.T) {
+	testCases := []struct {
+		vdiType   xenapi.VdiType
+		srRecord  xenapi.SRRecord
+		expectErr bool
+	}{
+		{vdiType: xenapi.VdiTypeUser, srRecord: xenapi.SRRecord{UUID: "local", Shared: false}},
+		{vdiType: xenapi.VdiTypeSuspend, srRecord: xenapi.SRRecord{UUID: "local", Shared: false}},
+		{vdiType: xenapi.VdiTypeHaStatefile, srRecord: xenapi.SRRecord{UUID: "local", Shared: false}, expectErr: true},
+		{vdiType: xenapi.VdiTypeMetadata, srRecord: xenapi.SRRecord{UUID: "local", Shared: false}, expectErr: true},
+		{vdiType: xenapi.VdiTypeRedoLog, srRecord: xenapi.SRRecord{UUID: "local", Shared: false}, expectErr: true},
+		{vdiType: xenapi.VdiTypeHaStatefile, srRecord: xenapi.SRRecord{UUID: "nfs", Shared: true}},
+	}
+	for _, tc := range testCases {
+		err := checkVDITypeForSR(tc.vdiType, tc.srRecord)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("checkVDITypeForSR(%q, %s) returned error %v, expected error %t", tc.vdiType, tc.srRecord.UUID, err, tc.expectErr)
+		}
+	}
+}
+
+func TestGetVDIAllocationMode(t *testing.T) {
+	testCases := []struct {
+		srType      string
+		srSmConfig  map[string]string
+		vdiSmConfig map[string]string
+		expected    string
+	}{
+		{srType: "nfs", expected: "thin"},
+		{srType: "lvm", expected: "thick"},
+		{srType: "lvmohba", srSmConfig: map[string]string{"allocation": "thin"}, expected: "thin"},
+		{srType: "lvmohba", srSmConfig: map[string]string{"allocation": "thin"}, vdiSmConfig: map[string]string{"vdi_type": "aio"}, expected: "thick"},
+		{srType: "lvmoiscsi", srSmConfig: map[string]string{"allocation": "thick"}, vdiSmConfig: map[string]string{"allocation": "thin"}, expected: "thin"},
+		{srType: "gfs2", srSmConfig: map[string]string{"allocation": "thick"}, expected: "thin"},
+		{srType: "iso", expected: ""},
+	}
+	for _, tc := range testCases {
+		allocation := getVDIAllocationMode(tc.srType, tc.srSmConfig, tc.vdiSmConfig)
+		if allocation != tc.expected {
+			t.Errorf("getVDIAllocationMode(%q, %v, %v) = %q, expected %q", tc.srType, tc.srSmConfig, tc.vdiSmConfig, allocation, tc.expected)
+		}
+	}
+}
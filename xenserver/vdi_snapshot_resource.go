@@ -0,0 +1,272 @@
+package xenserver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"xenapi"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var (
+	_ resource.Resource                = &vdiSnapshotResource{}
+	_ resource.ResourceWithConfigure   = &vdiSnapshotResource{}
+	_ resource.ResourceWithImportState = &vdiSnapshotResource{}
+)
+
+func NewVDISnapshotResource() resource.Resource {
+	return &vdiSnapshotResource{}
+}
+
+// vdiSnapshotResource defines the resource implementation.
+type vdiSnapshotResource struct {
+	session          *xenapi.Session
+	operationLimiter operationLimiter
+}
+
+func (r *vdiSnapshotResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_vdi_snapshot"
+}
+
+func (r *vdiSnapshotResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Provides a virtual disk image snapshot resource, which is useful to back up a single disk without snapshotting the whole VM.",
+		Attributes:          vdiSnapshotSchema(),
+	}
+}
+
+// Set the parameter of the resource, pass value from provider
+func (r *vdiSnapshotResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+	providerData, ok := req.ProviderData.(*xsProvider)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *xenserver.xsProvider, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+	r.session = providerData.session
+	r.operationLimiter = providerData.operationLimiter
+}
+
+func (r *vdiSnapshotResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data vdiSnapshotResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	tflog.Debug(ctx, "Creating VDI snapshot...")
+	sourceRef, err := withSessionRetry(r.session, func() (xenapi.VDIRef, error) {
+		return xenapi.VDI.GetByUUID(r.session, data.SourceVDI.ValueString())
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get source VDI ref",
+			err.Error(),
+		)
+		return
+	}
+	snapshotRef, err := xenapi.VDI.Snapshot(r.session, sourceRef, map[string]string{})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to create VDI snapshot",
+			err.Error(),
+		)
+		return
+	}
+	err = vdiSnapshotResourceModelUpdate(r.session, snapshotRef, data)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to update VDI snapshot resource",
+			err.Error(),
+		)
+		err = cleanupVDIResource(r.session, snapshotRef)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Error cleaning up VDI snapshot resource",
+				err.Error(),
+			)
+		}
+		return
+	}
+	snapshotRecord, err := xenapi.VDI.GetRecord(r.session, snapshotRef)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get VDI snapshot record",
+			err.Error(),
+		)
+		err = cleanupVDIResource(r.session, snapshotRef)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Error cleaning up VDI snapshot resource",
+				err.Error(),
+			)
+		}
+		return
+	}
+	updateVDISnapshotResourceModelComputed(snapshotRecord, &data)
+	tflog.Debug(ctx, "VDI snapshot created")
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *vdiSnapshotResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data vdiSnapshotResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	// Overwrite data with refreshed resource state
+	snapshotRef, err := withSessionRetry(r.session, func() (xenapi.VDIRef, error) {
+		return xenapi.VDI.GetByUUID(r.session, data.UUID.ValueString())
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get VDI snapshot ref",
+			err.Error(),
+		)
+		return
+	}
+	snapshotRecord, err := xenapi.VDI.GetRecord(r.session, snapshotRef)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get VDI snapshot record",
+			err.Error(),
+		)
+		return
+	}
+	err = updateVDISnapshotResourceModel(r.session, snapshotRecord, &data)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to update the fields of VDISnapshotResourceModel",
+			err.Error(),
+		)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *vdiSnapshotResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state vdiSnapshotResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Checking if configuration changes are allowed
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	err = vdiSnapshotResourceModelUpdateCheck(plan, state)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error update xenserver_vdi_snapshot configuration",
+			err.Error(),
+		)
+		return
+	}
+
+	// Update the resource with new configuration
+	snapshotRef, err := withSessionRetry(r.session, func() (xenapi.VDIRef, error) {
+		return xenapi.VDI.GetByUUID(r.session, plan.UUID.ValueString())
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get VDI snapshot ref",
+			err.Error(),
+		)
+		return
+	}
+	err = vdiSnapshotResourceModelUpdate(r.session, snapshotRef, plan)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to update VDI snapshot resource",
+			err.Error(),
+		)
+		return
+	}
+	snapshotRecord, err := xenapi.VDI.GetRecord(r.session, snapshotRef)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get VDI snapshot record",
+			err.Error(),
+		)
+		return
+	}
+	updateVDISnapshotResourceModelComputed(snapshotRecord, &plan)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *vdiSnapshotResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data vdiSnapshotResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	snapshotRef, err := withSessionRetry(r.session, func() (xenapi.VDIRef, error) {
+		return xenapi.VDI.GetByUUID(r.session, data.UUID.ValueString())
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get VDI snapshot ref",
+			err.Error(),
+		)
+		return
+	}
+	err = cleanupVDIResource(r.session, snapshotRef)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to delete VDI snapshot resource",
+			err.Error(),
+		)
+		return
+	}
+}
+
+func (r *vdiSnapshotResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("uuid"), req, resp)
+}
@@ -0,0 +1,63 @@
+package xenserver
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func testAccVDISnapshotResourceConfig(extra_config string) string {
+	return fmt.Sprintf(`
+data "xenserver_sr" "sr" {
+	name_label = "Local storage"
+}
+
+resource "xenserver_vdi" "test_vdi" {
+	name_label       = "Test VDI"
+	name_description = "Test VDI description"
+	sr_uuid          = data.xenserver_sr.sr.data_items[0].uuid
+	virtual_size     = 1 * 1024 * 1024 * 1024
+}
+
+resource "xenserver_vdi_snapshot" "test_vdi_snapshot" {
+	source_vdi_uuid = xenserver_vdi.test_vdi.uuid
+	%s
+}
+`, extra_config)
+}
+
+func TestAccVDISnapshotResource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Create and Read testing
+			{
+				Config: providerConfig + testAccVDISnapshotResourceConfig(""),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_vdi_snapshot.test_vdi_snapshot", "name_label", "Test VDI"),
+					resource.TestCheckResourceAttr("xenserver_vdi_snapshot.test_vdi_snapshot", "name_description", "Test VDI description"),
+					resource.TestCheckResourceAttrPair("xenserver_vdi_snapshot.test_vdi_snapshot", "source_vdi_uuid", "xenserver_vdi.test_vdi", "uuid"),
+					// Verify dynamic values have any value set in the state.
+					resource.TestCheckResourceAttrSet("xenserver_vdi_snapshot.test_vdi_snapshot", "uuid"),
+				),
+			},
+			// ImportState testing
+			{
+				ResourceName:            "xenserver_vdi_snapshot.test_vdi_snapshot",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{},
+			},
+			// Update and Read testing
+			{
+				Config: providerConfig + testAccVDISnapshotResourceConfig(`name_label = "Test VDI snapshot"`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_vdi_snapshot.test_vdi_snapshot", "name_label", "Test VDI snapshot"),
+					resource.TestCheckResourceAttr("xenserver_vdi_snapshot.test_vdi_snapshot", "name_description", "Test VDI description"),
+				),
+			},
+			// Delete testing automatically occurs in TestCase
+		},
+	})
+}
@@ -3,7 +3,10 @@ package xenserver
 import (
 	"context"
 	"errors"
+	"slices"
+	"strings"
 
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
@@ -12,6 +15,7 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 
 	"xenapi"
@@ -21,11 +25,14 @@ type vdiResourceModel struct {
 	NameLabel       types.String `tfsdk:"name_label"`
 	NameDescription types.String `tfsdk:"name_description"`
 	SR              types.String `tfsdk:"sr_uuid"`
+	SRType          types.String `tfsdk:"sr_type"`
 	VirtualSize     types.Int64  `tfsdk:"virtual_size"`
 	Type            types.String `tfsdk:"type"`
 	Sharable        types.Bool   `tfsdk:"sharable"`
 	ReadOnly        types.Bool   `tfsdk:"read_only"`
 	OtherConfig     types.Map    `tfsdk:"other_config"`
+	SmConfig        types.Map    `tfsdk:"sm_config"`
+	Allocation      types.String `tfsdk:"allocation"`
 	UUID            types.String `tfsdk:"uuid"`
 	ID              types.String `tfsdk:"id"`
 }
@@ -34,11 +41,14 @@ var vdiResourceModelAttrTypes = map[string]attr.Type{
 	"name_label":       types.StringType,
 	"name_description": types.StringType,
 	"sr_uuid":          types.StringType,
+	"sr_type":          types.StringType,
 	"virtual_size":     types.Int64Type,
 	"type":             types.StringType,
 	"sharable":         types.BoolType,
 	"read_only":        types.BoolType,
 	"other_config":     types.MapType{ElemType: types.StringType},
+	"sm_config":        types.MapType{ElemType: types.StringType},
+	"allocation":       types.StringType,
 	"uuid":             types.StringType,
 	"id":               types.StringType,
 }
@@ -56,9 +66,18 @@ func vdiSchema() map[string]schema.Attribute {
 			Default:             stringdefault.StaticString(""),
 		},
 		"sr_uuid": schema.StringAttribute{
-			MarkdownDescription: "The UUID of the storage repository used." +
+			MarkdownDescription: "The UUID of the storage repository used, it's required if `sr_type` is not set. When both are set, `sr_uuid` is used." +
 				"\n\n-> **Note:** `sr_uuid` is not allowed to be updated.",
-			Required: true,
+			Optional: true,
+			Computed: true,
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.UseStateForUnknown(),
+			},
+		},
+		"sr_type": schema.StringAttribute{
+			MarkdownDescription: "The type of the storage repository to create the virtual disk image on, for example, `\"nfs\"` or `\"lvmoiscsi\"`. The storage repository of this type with the most free space is chosen when `sr_uuid` is not set, which spreads the virtual disk images across the storage repositories." +
+				"\n\n-> **Note:** The storage repository is chosen on create only, `sr_type` is not allowed to be updated.",
+			Optional: true,
 		},
 		"virtual_size": schema.Int64Attribute{
 			MarkdownDescription: "The size of virtual disk image (in bytes)." +
@@ -66,11 +85,14 @@ func vdiSchema() map[string]schema.Attribute {
 			Required: true,
 		},
 		"type": schema.StringAttribute{
-			MarkdownDescription: "The type of the virtual disk image, default to be `\"user\"`." +
-				"\n\n-> **Note:** `type` is not allowed to be updated.",
+			MarkdownDescription: "The type of the virtual disk image, default to be `\"user\"`. Available values are `\"user\"`, `\"system\"`, `\"ephemeral\"`, `\"suspend\"`, `\"crashdump\"`, `\"ha_statefile\"`, `\"metadata\"`, `\"redo_log\"`, `\"rrd\"` and `\"pvs_cache\"`." +
+				"\n\n-> **Note:** `type` is not allowed to be updated. The `\"ha_statefile\"`, `\"metadata\"` and `\"redo_log\"` virtual disk images are used by the whole pool, they must be created on a shared storage repository.",
 			Optional: true,
 			Computed: true,
 			Default:  stringdefault.StaticString("user"),
+			Validators: []validator.String{
+				stringvalidator.OneOf(vdiCreatableTypes...),
+			},
 		},
 		"sharable": schema.BoolAttribute{
 			MarkdownDescription: "True if this disk may be shared, default to be `false`." +
@@ -93,6 +115,27 @@ func vdiSchema() map[string]schema.Attribute {
 			Default:             mapdefault.StaticValue(types.MapValueMust(types.StringType, map[string]attr.Value{})),
 			ElementType:         types.StringType,
 		},
+		"sm_config": schema.MapAttribute{
+			MarkdownDescription: "The storage manager configuration passed to the SR driver when the virtual disk image is created, default to be `{}`." +
+				"\n\n-> **Note:** `sm_config` is not allowed to be updated. The keys which are maintained by the SR driver, like `vdi_type` and `vhd-parent`, are not read back.",
+			Optional:    true,
+			Computed:    true,
+			Default:     mapdefault.StaticValue(types.MapValueMust(types.StringType, map[string]attr.Value{})),
+			ElementType: types.StringType,
+		},
+		"allocation": schema.StringAttribute{
+			MarkdownDescription: "The allocation mode of the virtual disk image, `\"thin\"` or `\"thick\"`. Default to be the allocation mode of the SR, which is read from the SR `sm_config`." +
+				"<br />File based SRs, like `ext`, `nfs`, `smb` and `gfs2`, only support `\"thin\"`. LVM based SRs, like `lvm`, `lvmoiscsi` and `lvmohba`, support both." +
+				"\n\n-> **Note:** `allocation` is not allowed to be updated.",
+			Optional: true,
+			Computed: true,
+			Validators: []validator.String{
+				stringvalidator.OneOf("thin", "thick"),
+			},
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.UseStateForUnknown(),
+			},
+		},
 		"uuid": schema.StringAttribute{
 			MarkdownDescription: "The UUID of the virtual disk image.",
 			Computed:            true,
@@ -110,17 +153,70 @@ func vdiSchema() map[string]schema.Attribute {
 	}
 }
 
+// vdiCreatableTypes are the VDI types which can be set on create, the other ones are only set by XAPI
+var vdiCreatableTypes = []string{
+	string(xenapi.VdiTypeUser),
+	string(xenapi.VdiTypeSystem),
+	string(xenapi.VdiTypeEphemeral),
+	string(xenapi.VdiTypeSuspend),
+	string(xenapi.VdiTypeCrashdump),
+	string(xenapi.VdiTypeHaStatefile),
+	string(xenapi.VdiTypeMetadata),
+	string(xenapi.VdiTypeRedoLog),
+	string(xenapi.VdiTypeRrd),
+	string(xenapi.VdiTypePvsCache),
+}
+
+// vdiSharedSRTypes are the VDI types used by the whole pool, which must be on a shared SR
+var vdiSharedSRTypes = []xenapi.VdiType{
+	xenapi.VdiTypeHaStatefile,
+	xenapi.VdiTypeMetadata,
+	xenapi.VdiTypeRedoLog,
+}
+
+// checkVDITypeForSR returns an error if the VDI of the type can't be created on the SR
+func checkVDITypeForSR(vdiType xenapi.VdiType, srRecord xenapi.SRRecord) error {
+	if slices.Contains(vdiSharedSRTypes, vdiType) && !srRecord.Shared {
+		return errors.New("VDI type \"" + string(vdiType) + "\" is used by the whole pool and must be created on a shared SR, SR " + srRecord.UUID + " is not shared")
+	}
+	return nil
+}
+
+// getVDISRRef returns the SR of sr_uuid if it's set, otherwise the SR of sr_type with the most free space
+func getVDISRRef(session *xenapi.Session, data vdiResourceModel) (xenapi.SRRef, error) {
+	if !data.SR.IsUnknown() && !data.SR.IsNull() {
+		srRef, err := xenapi.SR.GetByUUID(session, data.SR.ValueString())
+		if err != nil {
+			return srRef, errors.New(err.Error())
+		}
+		return srRef, nil
+	}
+	srRecords, err := xenapi.SR.GetAllRecords(session)
+	if err != nil {
+		return "", errors.New(err.Error())
+	}
+	return getSRWithMostFreeSpace(srRecords, []string{data.SRType.ValueString()})
+}
+
 func getVDICreateParams(ctx context.Context, session *xenapi.Session, data vdiResourceModel) (xenapi.VDIRecord, error) {
 	var record xenapi.VDIRecord
 	record.NameLabel = data.NameLabel.ValueString()
 	record.NameDescription = data.NameDescription.ValueString()
-	srRef, err := xenapi.SR.GetByUUID(session, data.SR.ValueString())
+	srRef, err := getVDISRRef(session, data)
 	if err != nil {
-		return record, errors.New(err.Error())
+		return record, err
 	}
 	record.SR = srRef
 	record.VirtualSize = int(data.VirtualSize.ValueInt64())
 	record.Type = xenapi.VdiType(data.Type.ValueString())
+	srRecord, err := xenapi.SR.GetRecord(session, srRef)
+	if err != nil {
+		return record, errors.New(err.Error())
+	}
+	err = checkVDITypeForSR(record.Type, srRecord)
+	if err != nil {
+		return record, err
+	}
 	record.Sharable = data.Sharable.ValueBool()
 	record.ReadOnly = data.ReadOnly.ValueBool()
 
@@ -128,25 +224,38 @@ func getVDICreateParams(ctx context.Context, session *xenapi.Session, data vdiRe
 	if diags.HasError() {
 		return record, errors.New("unable to access VDI other config")
 	}
+	diags = data.SmConfig.ElementsAs(ctx, &record.SmConfig, false)
+	if diags.HasError() {
+		return record, errors.New("unable to access VDI SM config")
+	}
+	if !data.Allocation.IsUnknown() && !data.Allocation.IsNull() {
+		if record.SmConfig == nil {
+			record.SmConfig = make(map[string]string)
+		}
+		err = setVDIAllocation(srRecord.Type, data.Allocation.ValueString(), record.SmConfig)
+		if err != nil {
+			return record, err
+		}
+	}
 
 	return record, nil
 }
 
 func updateVDIResourceModel(ctx context.Context, session *xenapi.Session, record xenapi.VDIRecord, data *vdiResourceModel) error {
 	data.NameLabel = types.StringValue(record.NameLabel)
-	srUUID, err := xenapi.SR.GetUUID(session, record.SR)
-	if err != nil {
-		return errors.New(err.Error())
-	}
-	data.SR = types.StringValue(srUUID)
 	data.VirtualSize = types.Int64Value(int64(record.VirtualSize))
 
-	return updateVDIResourceModelComputed(ctx, record, data)
+	return updateVDIResourceModelComputed(ctx, session, record, data)
 }
 
-func updateVDIResourceModelComputed(ctx context.Context, record xenapi.VDIRecord, data *vdiResourceModel) error {
+func updateVDIResourceModelComputed(ctx context.Context, session *xenapi.Session, record xenapi.VDIRecord, data *vdiResourceModel) error {
 	data.UUID = types.StringValue(record.UUID)
 	data.ID = types.StringValue(record.UUID)
+	srUUID, err := xenapi.SR.GetUUID(session, record.SR)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	data.SR = types.StringValue(srUUID)
 	data.NameDescription = types.StringValue(record.NameDescription)
 	data.Type = types.StringValue(string(record.Type))
 	data.Sharable = types.BoolValue(record.Sharable)
@@ -156,14 +265,109 @@ func updateVDIResourceModelComputed(ctx context.Context, record xenapi.VDIRecord
 	if diags.HasError() {
 		return errors.New("unable to access VDI other config")
 	}
+	data.SmConfig, diags = types.MapValueFrom(ctx, types.StringType, getUserVDISmConfig(record.SmConfig))
+	if diags.HasError() {
+		return errors.New("unable to access VDI SM config")
+	}
+	allocation, err := getVDIAllocation(session, record)
+	if err != nil {
+		return err
+	}
+	data.Allocation = allocation
+
+	return nil
+}
+
+// srTypeAllocationModes lists the VDI allocation modes supported by each SR type, the first one is the default
+// when the SR doesn't report its allocation mode
+var srTypeAllocationModes = map[string][]string{
+	"ext":       {"thin"},
+	"nfs":       {"thin"},
+	"smb":       {"thin"},
+	"file":      {"thin"},
+	"gfs2":      {"thin"},
+	"lvm":       {"thick", "thin"},
+	"lvmoiscsi": {"thick", "thin"},
+	"lvmohba":   {"thick", "thin"},
+}
 
+// setVDIAllocation sets the sm_config key for the allocation mode, file based SRs are always thin provisioned
+// so they don't need the key
+func setVDIAllocation(srType string, allocation string, smConfig map[string]string) error {
+	modes, ok := srTypeAllocationModes[srType]
+	if !ok || !slices.Contains(modes, allocation) {
+		return errors.New("SR type \"" + srType + "\" doesn't support \"" + allocation + "\" allocation")
+	}
+	if len(modes) > 1 {
+		smConfig["allocation"] = allocation
+	}
 	return nil
 }
 
+// vdiRawTypes are the sm_config vdi_type values of the VDIs without a VHD or QCOW2 format, they are fully allocated
+// on the LVM based SRs
+var vdiRawTypes = []string{"aio", "raw"}
+
+// getVDIAllocationMode returns the allocation mode of the VDI, "" if the SR type doesn't support the modes. The VDI
+// sm_config is set by the SR driver when the VDI is created, so it reflects the VDIs created outside of terraform
+// too, otherwise the VDI follows the allocation mode of the SR, for example, a thin provisioned LVMoHBA or GFS2 SR.
+func getVDIAllocationMode(srType string, srSmConfig map[string]string, vdiSmConfig map[string]string) string {
+	modes, ok := srTypeAllocationModes[srType]
+	if !ok {
+		return ""
+	}
+	if allocation, ok := vdiSmConfig["allocation"]; ok && slices.Contains(modes, allocation) {
+		return allocation
+	}
+	if slices.Contains(modes, "thick") && slices.Contains(vdiRawTypes, vdiSmConfig["vdi_type"]) {
+		return "thick"
+	}
+	if allocation, ok := srSmConfig["allocation"]; ok && slices.Contains(modes, allocation) {
+		return allocation
+	}
+	return modes[0]
+}
+
+func getVDIAllocation(session *xenapi.Session, record xenapi.VDIRecord) (types.String, error) {
+	srRecord, err := xenapi.SR.GetRecord(session, record.SR)
+	if err != nil {
+		return types.StringNull(), errors.New(err.Error())
+	}
+	allocation := getVDIAllocationMode(srRecord.Type, srRecord.SmConfig, record.SmConfig)
+	if allocation == "" {
+		return types.StringNull(), nil
+	}
+	return types.StringValue(allocation), nil
+}
+
+// vdiSmConfigInternalKeys are the sm_config keys which are maintained by the SR drivers
+var vdiSmConfigInternalKeys = []string{"allocation", "vdi_type", "vhd-parent", "vhd-blocks", "content_id", "image-format", "paused", "activating", "relinking"}
+
+// vdiSmConfigInternalKeyPrefixes are the prefixes of the per-host sm_config keys which are maintained by the SR drivers
+var vdiSmConfigInternalKeyPrefixes = []string{"host_", "read-caching-"}
+
+// getUserVDISmConfig returns the VDI sm_config without the keys maintained by the SR drivers
+func getUserVDISmConfig(smConfig map[string]string) map[string]string {
+	userSmConfig := make(map[string]string)
+	for key, value := range smConfig {
+		if slices.Contains(vdiSmConfigInternalKeys, key) {
+			continue
+		}
+		if slices.ContainsFunc(vdiSmConfigInternalKeyPrefixes, func(prefix string) bool { return strings.HasPrefix(key, prefix) }) {
+			continue
+		}
+		userSmConfig[key] = value
+	}
+	return userSmConfig
+}
+
 func vdiResourceModelUpdateCheck(data vdiResourceModel, dataState vdiResourceModel) error {
-	if data.SR != dataState.SR {
+	if !data.SR.IsUnknown() && data.SR != dataState.SR {
 		return errors.New(`"sr_uuid" doesn't expected to be updated`)
 	}
+	if data.SRType != dataState.SRType {
+		return errors.New(`"sr_type" doesn't expected to be updated`)
+	}
 	if data.VirtualSize != dataState.VirtualSize {
 		return errors.New(`"virtual_size" doesn't expected to be updated`)
 	}
@@ -176,6 +380,12 @@ func vdiResourceModelUpdateCheck(data vdiResourceModel, dataState vdiResourceMod
 	if data.ReadOnly != dataState.ReadOnly {
 		return errors.New(`"read_only" doesn't expected to be updated`)
 	}
+	if !data.Allocation.IsUnknown() && data.Allocation != dataState.Allocation {
+		return errors.New(`"allocation" doesn't expected to be updated`)
+	}
+	if !data.SmConfig.Equal(dataState.SmConfig) {
+		return errors.New(`"sm_config" doesn't expected to be updated`)
+	}
 	return nil
 }
 
@@ -201,9 +411,162 @@ func vdiResourceModelUpdate(ctx context.Context, session *xenapi.Session, ref xe
 }
 
 func cleanupVDIResource(session *xenapi.Session, ref xenapi.VDIRef) error {
-	err := xenapi.VDI.Destroy(session, ref)
+	uuid, err := xenapi.VDI.GetUUID(session, ref)
+	if err != nil {
+		return cleanupError("VDI", string(ref), err)
+	}
+	err = xenapi.VDI.Destroy(session, ref)
+	if err != nil {
+		return cleanupError("VDI", uuid, err)
+	}
+	return nil
+}
+
+type vdiSnapshotResourceModel struct {
+	NameLabel       types.String `tfsdk:"name_label"`
+	NameDescription types.String `tfsdk:"name_description"`
+	SourceVDI       types.String `tfsdk:"source_vdi_uuid"`
+	UUID            types.String `tfsdk:"uuid"`
+	ID              types.String `tfsdk:"id"`
+}
+
+func vdiSnapshotSchema() map[string]schema.Attribute {
+	return map[string]schema.Attribute{
+		"name_label": schema.StringAttribute{
+			MarkdownDescription: "The name of the VDI snapshot, default to be the name of the source virtual disk image.",
+			Optional:            true,
+			Computed:            true,
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.UseStateForUnknown(),
+			},
+		},
+		"name_description": schema.StringAttribute{
+			MarkdownDescription: "The description of the VDI snapshot, default to be the description of the source virtual disk image.",
+			Optional:            true,
+			Computed:            true,
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.UseStateForUnknown(),
+			},
+		},
+		"source_vdi_uuid": schema.StringAttribute{
+			MarkdownDescription: "The UUID of the virtual disk image to snapshot." +
+				"\n\n-> **Note:** `source_vdi_uuid` is not allowed to be updated.",
+			Required: true,
+		},
+		"uuid": schema.StringAttribute{
+			MarkdownDescription: "The UUID of the VDI snapshot.",
+			Computed:            true,
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.UseStateForUnknown(),
+			},
+		},
+		"id": schema.StringAttribute{
+			MarkdownDescription: "The test ID of the VDI snapshot.",
+			Computed:            true,
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.UseStateForUnknown(),
+			},
+		},
+	}
+}
+
+func updateVDISnapshotResourceModel(session *xenapi.Session, record xenapi.VDIRecord, data *vdiSnapshotResourceModel) error {
+	if !record.IsASnapshot {
+		return errors.New("VDI " + record.UUID + " is not a snapshot")
+	}
+	sourceUUID, err := xenapi.VDI.GetUUID(session, record.SnapshotOf)
 	if err != nil {
 		return errors.New(err.Error())
 	}
+	data.SourceVDI = types.StringValue(sourceUUID)
+
+	updateVDISnapshotResourceModelComputed(record, data)
+	return nil
+}
+
+func updateVDISnapshotResourceModelComputed(record xenapi.VDIRecord, data *vdiSnapshotResourceModel) {
+	data.UUID = types.StringValue(record.UUID)
+	data.ID = types.StringValue(record.UUID)
+	data.NameLabel = types.StringValue(record.NameLabel)
+	data.NameDescription = types.StringValue(record.NameDescription)
+}
+
+func vdiSnapshotResourceModelUpdateCheck(plan vdiSnapshotResourceModel, state vdiSnapshotResourceModel) error {
+	if plan.SourceVDI != state.SourceVDI {
+		return errors.New(`"source_vdi_uuid" doesn't expected to be updated`)
+	}
+	return nil
+}
+
+func vdiSnapshotResourceModelUpdate(session *xenapi.Session, ref xenapi.VDIRef, data vdiSnapshotResourceModel) error {
+	if !data.NameLabel.IsUnknown() {
+		err := xenapi.VDI.SetNameLabel(session, ref, data.NameLabel.ValueString())
+		if err != nil {
+			return errors.New(err.Error())
+		}
+	}
+	if !data.NameDescription.IsUnknown() {
+		err := xenapi.VDI.SetNameDescription(session, ref, data.NameDescription.ValueString())
+		if err != nil {
+			return errors.New(err.Error())
+		}
+	}
+	return nil
+}
+
+type isoDataSourceModel struct {
+	NameLabel types.String `tfsdk:"name_label"`
+	SR        types.String `tfsdk:"sr_uuid"`
+	UUID      types.String `tfsdk:"uuid"`
+	ID        types.String `tfsdk:"id"`
+}
+
+// matchISOVDIs returns the UUIDs of the VDIs with the name, sorted, in the SR if srRef isn't empty
+func matchISOVDIs(vdiRecords map[xenapi.VDIRef]xenapi.VDIRecord, isoName string, srRef xenapi.SRRef) []string {
+	vdiUUIDs := []string{}
+	for _, vdiRecord := range vdiRecords {
+		if vdiRecord.NameLabel != isoName {
+			continue
+		}
+		if srRef != "" && vdiRecord.SR != srRef {
+			continue
+		}
+		vdiUUIDs = append(vdiUUIDs, vdiRecord.UUID)
+	}
+	slices.Sort(vdiUUIDs)
+	return vdiUUIDs
+}
+
+// getISOVDIUUID returns the UUID of the only VDI with the name, in the SR if srRef isn't empty
+func getISOVDIUUID(session *xenapi.Session, isoName string, srRef xenapi.SRRef) (string, error) {
+	vdiRecords, err := xenapi.VDI.GetAllRecords(session)
+	if err != nil {
+		return "", errors.New(err.Error())
+	}
+	vdiUUIDs := matchISOVDIs(vdiRecords, isoName, srRef)
+	if len(vdiUUIDs) == 0 {
+		return "", errors.New("no VDI found with name: " + isoName)
+	}
+	if len(vdiUUIDs) > 1 {
+		return "", errors.New("multiple VDIs found with name: " + isoName + ", the VDI UUIDs are: " + strings.Join(vdiUUIDs, ", "))
+	}
+	return vdiUUIDs[0], nil
+}
+
+func updateISODataSourceModel(session *xenapi.Session, data *isoDataSourceModel) error {
+	var srRef xenapi.SRRef
+	if !data.SR.IsNull() {
+		var err error
+		srRef, err = xenapi.SR.GetByUUID(session, data.SR.ValueString())
+		if err != nil {
+			return errors.New(err.Error())
+		}
+	}
+	vdiUUID, err := getISOVDIUUID(session, data.NameLabel.ValueString(), srRef)
+	if err != nil {
+		return err
+	}
+	data.UUID = types.StringValue(vdiUUID)
+	data.ID = types.StringValue(vdiUUID)
 	return nil
 }
@@ -0,0 +1,109 @@
+package xenserver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+
+	"xenapi"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &vgpuTypeDataSource{}
+	_ datasource.DataSourceWithConfigure = &vgpuTypeDataSource{}
+)
+
+// NewVGPUTypeDataSource is a helper function to simplify the provider implementation.
+func NewVGPUTypeDataSource() datasource.DataSource {
+	return &vgpuTypeDataSource{}
+}
+
+// vgpuTypeDataSource is the data source implementation.
+type vgpuTypeDataSource struct {
+	session          *xenapi.Session
+	operationLimiter operationLimiter
+}
+
+// Metadata returns the data source type name.
+func (d *vgpuTypeDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_vgpu_type"
+}
+
+func (d *vgpuTypeDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Provides the vGPU types available in the pool and the GPU groups they are enabled on." +
+			"\n\n-> **Note:** The capacity is the one of the physical GPUs when the data source is read, the vGPUs started later in the same run are not considered.",
+		Attributes: map[string]schema.Attribute{
+			"uuid": schema.StringAttribute{
+				MarkdownDescription: "The UUID of the vGPU type.",
+				Optional:            true,
+			},
+			"model_name": schema.StringAttribute{
+				MarkdownDescription: "The model name of the vGPU type, for example, `GRID T4-2Q`.",
+				Optional:            true,
+			},
+			"gpu_group_uuid": schema.StringAttribute{
+				MarkdownDescription: "The UUID of the GPU group the vGPU type is enabled on.",
+				Optional:            true,
+			},
+			"data_items": schema.ListNestedAttribute{
+				MarkdownDescription: "The return items of vGPU types, sorted by the vGPU type UUID.",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: vgpuTypeDataSchema(),
+				},
+			},
+		},
+	}
+}
+
+func (d *vgpuTypeDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	providerData, ok := req.ProviderData.(*xsProvider)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *xenserver.xsProvider, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+	d.session = providerData.session
+	d.operationLimiter = providerData.operationLimiter
+}
+
+// Read refreshes the Terraform state with the latest data.
+func (d *vgpuTypeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data vgpuTypeDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := d.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	err = retryOnSessionInvalid(d.session, func() error {
+		return updateVGPUTypeDataSourceModel(ctx, d.session, &data)
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to read vGPU types",
+			err.Error(),
+		)
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,101 @@
+package xenserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+
+	"xenapi"
+)
+
+func testAccVGPUTypeDataSourceConfig() string {
+	return `
+data "xenserver_vgpu_type" "all" {}
+`
+}
+
+func TestAccVGPUTypeDataSource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + testAccVGPUTypeDataSourceConfig(),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("data.xenserver_vgpu_type.all", "data_items.#"),
+				),
+			},
+		},
+	})
+}
+
+func TestGetVGPUTypeRecordData(t *testing.T) {
+	typeRecords := map[xenapi.VGPUTypeRef]xenapi.VGPUTypeRecord{
+		"type-b": {UUID: "uuid-b", ModelName: "GRID T4-2Q", EnabledOnGPUGroups: []xenapi.GPUGroupRef{"group-2", "group-1"}, VGPUs: []xenapi.VGPURef{"vgpu-1", "vgpu-2"}},
+		"type-a": {UUID: "uuid-a", ModelName: "passthrough", EnabledOnGPUGroups: []xenapi.GPUGroupRef{"group-1"}},
+	}
+	groupRecords := map[xenapi.GPUGroupRef]xenapi.GPUGroupRecord{
+		"group-1": {UUID: "group-uuid-1"},
+		"group-2": {UUID: "group-uuid-2"},
+	}
+	pgpuRecords := map[xenapi.PGPURef]xenapi.PGPURecord{
+		"pgpu-1": {EnabledVGPUTypes: []xenapi.VGPUTypeRef{"type-a", "type-b"}, SupportedVGPUMaxCapacities: map[xenapi.VGPUTypeRef]int{"type-a": 1, "type-b": 8}},
+		"pgpu-2": {EnabledVGPUTypes: []xenapi.VGPUTypeRef{"type-a"}, SupportedVGPUMaxCapacities: map[xenapi.VGPUTypeRef]int{"type-a": 1, "type-b": 8}},
+	}
+	// vgpu-2 is of a halted VM
+	vgpuRecords := map[xenapi.VGPURef]xenapi.VGPURecord{
+		"vgpu-1": {Type: "type-b", CurrentlyAttached: true},
+		"vgpu-2": {Type: "type-b", CurrentlyAttached: false},
+	}
+	testCases := []struct {
+		data             vgpuTypeDataSourceModel
+		expectedUUIDs    []string
+		expectedCapacity []int64
+	}{
+		{
+			data:             vgpuTypeDataSourceModel{UUID: types.StringNull(), ModelName: types.StringNull(), GPUGroupUUID: types.StringNull()},
+			expectedUUIDs:    []string{"uuid-a", "uuid-b"},
+			expectedCapacity: []int64{2, 8},
+		},
+		{
+			data:             vgpuTypeDataSourceModel{UUID: types.StringNull(), ModelName: types.StringValue("GRID T4-2Q"), GPUGroupUUID: types.StringNull()},
+			expectedUUIDs:    []string{"uuid-b"},
+			expectedCapacity: []int64{8},
+		},
+		{
+			data:             vgpuTypeDataSourceModel{UUID: types.StringNull(), ModelName: types.StringNull(), GPUGroupUUID: types.StringValue("group-uuid-2")},
+			expectedUUIDs:    []string{"uuid-b"},
+			expectedCapacity: []int64{8},
+		},
+		{
+			data:             vgpuTypeDataSourceModel{UUID: types.StringValue("uuid-a"), ModelName: types.StringNull(), GPUGroupUUID: types.StringValue("group-uuid-2")},
+			expectedUUIDs:    []string{},
+			expectedCapacity: []int64{},
+		},
+	}
+	for _, tc := range testCases {
+		items, err := getVGPUTypeRecordData(context.Background(), typeRecords, groupRecords, pgpuRecords, vgpuRecords, tc.data)
+		if err != nil {
+			t.Fatalf("getVGPUTypeRecordData(%v) returned error: %v", tc.data, err)
+		}
+		if len(items) != len(tc.expectedUUIDs) {
+			t.Fatalf("getVGPUTypeRecordData(%v) returned %d items, expected %d", tc.data, len(items), len(tc.expectedUUIDs))
+		}
+		for i, item := range items {
+			if item.UUID.ValueString() != tc.expectedUUIDs[i] || item.MaxCapacity.ValueInt64() != tc.expectedCapacity[i] {
+				t.Errorf("getVGPUTypeRecordData(%v) item %d = %s with capacity %d, expected %s with capacity %d", tc.data, i, item.UUID, item.MaxCapacity.ValueInt64(), tc.expectedUUIDs[i], tc.expectedCapacity[i])
+			}
+		}
+	}
+
+	items, _ := getVGPUTypeRecordData(context.Background(), typeRecords, groupRecords, pgpuRecords, vgpuRecords, vgpuTypeDataSourceModel{UUID: types.StringValue("uuid-b"), ModelName: types.StringNull(), GPUGroupUUID: types.StringNull()})
+	if len(items) != 1 || items[0].GPUGroupUUIDs.String() != `["group-uuid-1","group-uuid-2"]` || items[0].VGPUCount.ValueInt64() != 1 {
+		t.Errorf("getVGPUTypeRecordData() returned %v, expected the sorted GPU groups and 1 attached vGPU", items)
+	}
+
+	_, err := getVGPUTypeRecordData(context.Background(), map[xenapi.VGPUTypeRef]xenapi.VGPUTypeRecord{"type-c": {UUID: "uuid-c", EnabledOnGPUGroups: []xenapi.GPUGroupRef{"group-3"}}}, groupRecords, pgpuRecords, vgpuRecords, vgpuTypeDataSourceModel{UUID: types.StringNull(), ModelName: types.StringNull(), GPUGroupUUID: types.StringNull()})
+	if err == nil {
+		t.Errorf("getVGPUTypeRecordData() with missing GPU group returned no error")
+	}
+}
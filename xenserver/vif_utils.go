@@ -5,6 +5,8 @@ import (
 	"errors"
 	"regexp"
 	"slices"
+	"sort"
+	"strconv"
 	"strings"
 
 	"xenapi"
@@ -24,6 +26,7 @@ type vifResourceModel struct {
 	VIF         types.String `tfsdk:"vif_ref"`
 	MAC         types.String `tfsdk:"mac"`
 	OtherConfig types.Map    `tfsdk:"other_config"`
+	MTU         types.Int32  `tfsdk:"mtu"`
 }
 
 var vifResourceModelAttrTypes = map[string]attr.Type{
@@ -32,18 +35,21 @@ var vifResourceModelAttrTypes = map[string]attr.Type{
 	"vif_ref":      types.StringType,
 	"mac":          types.StringType,
 	"other_config": types.MapType{ElemType: types.StringType},
+	"mtu":          types.Int32Type,
 }
 
 func vifSchema() map[string]schema.Attribute {
 	return map[string]schema.Attribute{
 		"network_uuid": schema.StringAttribute{
-			MarkdownDescription: "Network UUID to attach to VIF.",
-			Required:            true,
+			MarkdownDescription: "Network UUID to attach to VIF." + "<br />" +
+				"If this value is changed, the VIF is moved to the new network with the same device and MAC address. It is moved live when the virtual machine is running, otherwise it is recreated.",
+			Required: true,
 		},
 		"device": schema.StringAttribute{
-			MarkdownDescription: "Order in which VIF backends are created by [XAPI](https://github.com/xapi-project/xen-api), default to be `\"0\"`." + "<br />" +
+			MarkdownDescription: "Order in which VIF backends are created by [XAPI](https://github.com/xapi-project/xen-api), each network interface should use a different device. If not set, the next free device of the virtual machine is assigned." + "<br />" +
 				"If this value is changed, the VIF will be recreated.",
-			Required: true,
+			Optional: true,
+			Computed: true,
 			Validators: []validator.String{
 				stringvalidator.RegexMatches(
 					regexp.MustCompile(`^[0-9]+$`),
@@ -55,7 +61,7 @@ func vifSchema() map[string]schema.Attribute {
 			Computed: true,
 		},
 		"mac": schema.StringAttribute{
-			MarkdownDescription: "MAC address of the VIF, default to be a random MAC address generated by XenServer." +
+			MarkdownDescription: "MAC address of the VIF, default to be a random MAC address generated by XenServer. Each network interface should use a different MAC address." +
 				"\n\n-> **Note:** `mac` is not allowed to be updated.",
 			Optional: true,
 			Computed: true,
@@ -63,7 +69,7 @@ func vifSchema() map[string]schema.Attribute {
 				// match MAC address regex
 				stringvalidator.RegexMatches(
 					regexp.MustCompile(`^([0-9A-Fa-f]{2}[:-]){5}([0-9A-Fa-f]{2})$`),
-					"Input is not a valid MAC address",
+					"Input is not a valid MAC address, it should be six pairs of hexadecimal digits separated by \":\" or \"-\", for example, \"00:16:3e:00:00:01\"",
 				),
 			},
 		},
@@ -73,9 +79,159 @@ func vifSchema() map[string]schema.Attribute {
 			Optional:            true,
 			Computed:            true,
 		},
+		"mtu": schema.Int32Attribute{
+			MarkdownDescription: "The effective MTU of the network interface, which is the MTU of the network unless it's overridden by the `mtu` key in `other_config`, for example, `other_config = { mtu = \"9000\" }` for jumbo frames." +
+				"\n\n-> **Note:** The MTU is applied when the network interface is plugged, so a change takes effect after the virtual machine is rebooted.",
+			Computed: true,
+		},
 	}
 }
 
+// uniqueVIFDeviceValidator rejects a network_interface set that uses the same device in more than one item,
+// XAPI fails to create the second VIF with "DEVICE_ALREADY_EXISTS".
+type uniqueVIFDeviceValidator struct{}
+
+var _ validator.Set = uniqueVIFDeviceValidator{}
+
+func (v uniqueVIFDeviceValidator) Description(_ context.Context) string {
+	return "each item must use a different device"
+}
+
+func (v uniqueVIFDeviceValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v uniqueVIFDeviceValidator) ValidateSet(_ context.Context, req validator.SetRequest, resp *validator.SetResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, element := range req.ConfigValue.Elements() {
+		object, ok := element.(types.Object)
+		if !ok || object.IsNull() || object.IsUnknown() {
+			continue
+		}
+		device, ok := object.Attributes()["device"].(types.String)
+		if !ok || device.IsNull() || device.IsUnknown() {
+			continue
+		}
+		// "01" and "1" are the same device
+		deviceNum, err := strconv.Atoi(device.ValueString())
+		if err != nil {
+			continue
+		}
+		key := strconv.Itoa(deviceNum)
+		if seen[key] {
+			resp.Diagnostics.AddAttributeError(
+				req.Path,
+				"Duplicate device in "+req.Path.String(),
+				"Device "+device.ValueString()+" is used by more than one item in "+req.Path.String()+", each network interface should use a different device.",
+			)
+			continue
+		}
+		seen[key] = true
+	}
+}
+
+// uniqueVIFMACValidator rejects a network_interface set that uses the same MAC address in more than one item,
+// the VIFs with the same MAC address conflict on the network.
+type uniqueVIFMACValidator struct{}
+
+var _ validator.Set = uniqueVIFMACValidator{}
+
+func (v uniqueVIFMACValidator) Description(_ context.Context) string {
+	return "each item must use a different MAC address"
+}
+
+func (v uniqueVIFMACValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v uniqueVIFMACValidator) ValidateSet(_ context.Context, req validator.SetRequest, resp *validator.SetResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, element := range req.ConfigValue.Elements() {
+		object, ok := element.(types.Object)
+		if !ok || object.IsNull() || object.IsUnknown() {
+			continue
+		}
+		mac, ok := object.Attributes()["mac"].(types.String)
+		if !ok || mac.IsNull() || mac.IsUnknown() {
+			continue
+		}
+		// "00-16-3E-00-00-01" and "00:16:3e:00:00:01" are the same MAC address
+		key := strings.ToLower(strings.ReplaceAll(mac.ValueString(), "-", ":"))
+		if seen[key] {
+			resp.Diagnostics.AddAttributeError(
+				req.Path,
+				"Duplicate MAC address in "+req.Path.String(),
+				"MAC address "+mac.ValueString()+" is used by more than one item in "+req.Path.String()+", each network interface should use a different MAC address.",
+			)
+			continue
+		}
+		seen[key] = true
+	}
+}
+
+// getVIFMTU returns the MTU the VIF is plugged with, "mtu" in the VIF other_config overrides the MTU of the network
+func getVIFMTU(otherConfig map[string]string, networkMTU int) int {
+	mtu, err := strconv.Atoi(otherConfig["mtu"])
+	if err != nil || mtu <= 0 {
+		return networkMTU
+	}
+	return mtu
+}
+
+func isVIFDeviceUnset(vif vifResourceModel) bool {
+	return vif.Device.IsUnknown() || vif.Device.IsNull() || vif.Device.ValueString() == ""
+}
+
+// assignVIFDevices assigns the free devices of the VM in ascending order to the VIFs which don't set the device,
+// the devices set in the plan are skipped even if their VIFs are not created yet
+func assignVIFDevices(session *xenapi.Session, vmRef xenapi.VMRef, vifs []vifResourceModel) error {
+	var reserved []string
+	count := 0
+	for _, vif := range vifs {
+		if isVIFDeviceUnset(vif) {
+			count++
+			continue
+		}
+		reserved = append(reserved, vif.Device.ValueString())
+	}
+	if count == 0 {
+		return nil
+	}
+
+	allowedDevices, err := xenapi.VM.GetAllowedVIFDevices(session, vmRef)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	var freeDevices []int
+	for _, device := range allowedDevices {
+		deviceNum, err := strconv.Atoi(device)
+		if err != nil || slices.Contains(reserved, device) {
+			continue
+		}
+		freeDevices = append(freeDevices, deviceNum)
+	}
+	if len(freeDevices) < count {
+		return errors.New("unable to find available vif devices to attach to vm " + string(vmRef))
+	}
+	sort.Ints(freeDevices)
+
+	for i := range vifs {
+		if isVIFDeviceUnset(vifs[i]) {
+			vifs[i].Device = types.StringValue(strconv.Itoa(freeDevices[0]))
+			freeDevices = freeDevices[1:]
+		}
+	}
+	return nil
+}
+
 func setVIFDefaults(ctx context.Context, vif *vifResourceModel) {
 	// Work around for https://github.com/hashicorp/terraform-plugin-framework/issues/726
 	if vif.MAC.IsUnknown() {
@@ -137,7 +293,7 @@ func createVIF(ctx context.Context, vif vifResourceModel, vmRef xenapi.VMRef, se
 	return nil
 }
 
-func createVIFs(ctx context.Context, session *xenapi.Session, vmRef xenapi.VMRef, data vmResourceModel) error {
+func createVIFs(ctx context.Context, session *xenapi.Session, vmRef xenapi.VMRef, data vmResourceModel, parallel parallelLimit) error {
 	elements := make([]vifResourceModel, 0, len(data.NetworkInterface.Elements()))
 	diags := data.NetworkInterface.ElementsAs(ctx, &elements, false)
 	if diags.HasError() {
@@ -156,12 +312,15 @@ func createVIFs(ctx context.Context, session *xenapi.Session, vmRef xenapi.VMRef
 		}
 	}
 
-	for _, vif := range elements {
-		if err = createVIF(ctx, vif, vmRef, session); err != nil {
-			return errors.New(err.Error())
-		}
+	// assign the devices before creating the VIFs, so they can be created at the same time
+	err = assignVIFDevices(session, vmRef, elements)
+	if err != nil {
+		return err
 	}
-	return nil
+
+	return parallel.run(len(elements), func(i int) error {
+		return createVIF(ctx, elements[i], vmRef, session)
+	})
 }
 
 func vifResourceModelUpdateCheck(plan vifResourceModel, state vifResourceModel) error {
@@ -171,7 +330,87 @@ func vifResourceModelUpdateCheck(plan vifResourceModel, state vifResourceModel)
 	return nil
 }
 
-// updateVIF updates the VIFs in the VM based on the plan and state, the logic is similar to updateVBDs
+// adoptStateVIFDevices sets the device of the plan VIFs which don't set the device to the device of a VIF in state
+// on the same network, if the device isn't used by the other plan VIFs
+func adoptStateVIFDevices(planVIFs []vifResourceModel, stateVIFs []vifResourceModel) {
+	var usedDevices []string
+	for _, vif := range planVIFs {
+		if !isVIFDeviceUnset(vif) {
+			usedDevices = append(usedDevices, vif.Device.ValueString())
+		}
+	}
+	for i := range planVIFs {
+		if !isVIFDeviceUnset(planVIFs[i]) {
+			continue
+		}
+		for _, stateVIF := range stateVIFs {
+			if !stateVIF.Network.Equal(planVIFs[i].Network) || slices.Contains(usedDevices, stateVIF.Device.ValueString()) {
+				continue
+			}
+			if planVIFs[i].MAC.ValueString() != "" && !planVIFs[i].MAC.Equal(stateVIF.MAC) {
+				continue
+			}
+			planVIFs[i].Device = stateVIF.Device
+			usedDevices = append(usedDevices, stateVIF.Device.ValueString())
+			break
+		}
+	}
+}
+
+// destroyVIF unplugs the VIF if the VM is running and destroys it
+func destroyVIF(ctx context.Context, session *xenapi.Session, vifRef xenapi.VIFRef, vmState xenapi.VMPowerState) error {
+	if vmState == xenapi.VMPowerStateRunning {
+		allowedOps, err := xenapi.VIF.GetAllowedOperations(session, vifRef)
+		if err != nil {
+			return errors.New(err.Error())
+		}
+		if slices.Contains(allowedOps, xenapi.VifOperationsUnplug) {
+			tflog.Debug(ctx, "---> Unplug VIF when VM is running.")
+			err = xenapi.VIF.Unplug(session, vifRef)
+			if err != nil {
+				return errors.New(err.Error())
+			}
+		}
+	}
+	tflog.Debug(ctx, "---> Destroy VIF:	"+string(vifRef))
+	err := xenapi.VIF.Destroy(session, vifRef)
+	if err != nil {
+		if !strings.Contains(err.Error(), "HANDLE_INVALID") {
+			return errors.New(err.Error())
+		}
+		tflog.Debug(ctx, "HANDLE_INVALID: VIF already been destroyed.")
+	}
+	return nil
+}
+
+// moveVIF moves the VIF to the network in plan, the VIF is moved live with VIF.move when the VM is running,
+// otherwise it is recreated on the new network with the same device and MAC address
+func moveVIF(ctx context.Context, session *xenapi.Session, vmRef xenapi.VMRef, vmState xenapi.VMPowerState, planVIF vifResourceModel, stateVIF vifResourceModel) error {
+	vifRef := xenapi.VIFRef(stateVIF.VIF.ValueString())
+	if vmState == xenapi.VMPowerStateRunning {
+		networkRef, err := xenapi.Network.GetByUUID(session, planVIF.Network.ValueString())
+		if err != nil {
+			return errors.New(err.Error())
+		}
+		tflog.Debug(ctx, "---> Move VIF "+string(vifRef)+" to network "+planVIF.Network.String())
+		err = xenapi.VIF.Move(session, vifRef, networkRef)
+		if err != nil {
+			return errors.New(err.Error())
+		}
+		return nil
+	}
+
+	err := destroyVIF(ctx, session, vifRef, vmState)
+	if err != nil {
+		return err
+	}
+	planVIF.MAC = stateVIF.MAC
+	tflog.Debug(ctx, "---> Recreate VIF on network "+planVIF.Network.String())
+	return createVIF(ctx, planVIF, vmRef, session)
+}
+
+// updateVIF updates the VIFs in the VM based on the plan and state, the logic is similar to updateVBDs. The VIFs
+// are matched by device, a VIF whose network is changed is moved to the new network.
 func updateVIFs(ctx context.Context, plan vmResourceModel, state vmResourceModel, vmRef xenapi.VMRef, session *xenapi.Session) error {
 	// Get VIFs from plan and state
 	planVIFs := make([]vifResourceModel, 0, len(plan.NetworkInterface.Elements()))
@@ -187,14 +426,20 @@ func updateVIFs(ctx context.Context, plan vmResourceModel, state vmResourceModel
 	}
 
 	var err error
-	planVIFsMap := make(map[string]vifResourceModel)
-	for _, vif := range planVIFs {
-		planVIFsMap[vif.Device.String()+vif.Network.String()] = vif
-	}
-
 	stateVIFsMap := make(map[string]vifResourceModel)
 	for _, vif := range stateVIFs {
-		stateVIFsMap[vif.Device.String()+vif.Network.String()] = vif
+		stateVIFsMap[vif.Device.ValueString()] = vif
+	}
+
+	// keep the device of the existing VIF on the same network when the device isn't set
+	adoptStateVIFDevices(planVIFs, stateVIFs)
+
+	planVIFsMap := make(map[string]vifResourceModel)
+	for _, vif := range planVIFs {
+		if isVIFDeviceUnset(vif) {
+			continue
+		}
+		planVIFsMap[vif.Device.ValueString()] = vif
 	}
 
 	vmState, err := xenapi.VM.GetPowerState(session, vmRef)
@@ -203,36 +448,27 @@ func updateVIFs(ctx context.Context, plan vmResourceModel, state vmResourceModel
 	}
 
 	// Destroy VIFs that are not in plan, destroy VIFs first to avoid error "DEVICE_ALREADY_EXISTS"
-	for networkUUID, stateVIF := range stateVIFsMap {
-		if _, ok := planVIFsMap[networkUUID]; !ok {
-			vifRef := xenapi.VIFRef(stateVIF.VIF.ValueString())
-			if vmState == xenapi.VMPowerStateRunning {
-				allowedOps, err := xenapi.VIF.GetAllowedOperations(session, vifRef)
-				if err != nil {
-					return errors.New(err.Error())
-				}
-				if slices.Contains(allowedOps, xenapi.VifOperationsUnplug) {
-					tflog.Debug(ctx, "---> Unplug VIF when VM is running.")
-					err = xenapi.VIF.Unplug(session, vifRef)
-					if err != nil {
-						return errors.New(err.Error())
-					}
-				}
-			}
-			tflog.Debug(ctx, "---> Destroy VIF:	"+stateVIF.VIF.String())
-			err = xenapi.VIF.Destroy(session, vifRef)
+	for device, stateVIF := range stateVIFsMap {
+		if _, ok := planVIFsMap[device]; !ok {
+			err = destroyVIF(ctx, session, xenapi.VIFRef(stateVIF.VIF.ValueString()), vmState)
 			if err != nil {
-				if !strings.Contains(err.Error(), "HANDLE_INVALID") {
-					return errors.New(err.Error())
-				}
-				tflog.Debug(ctx, "HANDLE_INVALID: VIF already been destroyed.")
+				return err
 			}
 		}
 	}
 
+	// the devices of the destroyed VIFs are free now, assign them to the new VIFs without device
+	err = assignVIFDevices(session, vmRef, planVIFs)
+	if err != nil {
+		return err
+	}
+	for _, vif := range planVIFs {
+		planVIFsMap[vif.Device.ValueString()] = vif
+	}
+
 	// Create VIFs that are in plan but not in state, Update VIFs if already exists and attributes changed
-	for deviceNetwork, planVIF := range planVIFsMap {
-		stateVIF, ok := stateVIFsMap[deviceNetwork]
+	for device, planVIF := range planVIFsMap {
+		stateVIF, ok := stateVIFsMap[device]
 		if !ok {
 			tflog.Debug(ctx, "---> Create VIF with Network: "+planVIF.Network.String()+" <---")
 			err = createVIF(ctx, planVIF, vmRef, session)
@@ -248,6 +484,17 @@ func updateVIFs(ctx context.Context, plan vmResourceModel, state vmResourceModel
 				return err
 			}
 
+			if !planVIF.Network.Equal(stateVIF.Network) {
+				err = moveVIF(ctx, session, vmRef, vmState, planVIF, stateVIF)
+				if err != nil {
+					return err
+				}
+				// the recreated VIF has the other config in plan already
+				if vmState != xenapi.VMPowerStateRunning {
+					continue
+				}
+			}
+
 			if !planVIF.OtherConfig.Equal(stateVIF.OtherConfig) {
 				otherConfig := make(map[string]string)
 				diags := planVIF.OtherConfig.ElementsAs(ctx, &otherConfig, false)
@@ -0,0 +1,282 @@
+package xenserver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"xenapi"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var (
+	_ resource.Resource                = &vmCloneResource{}
+	_ resource.ResourceWithConfigure   = &vmCloneResource{}
+	_ resource.ResourceWithImportState = &vmCloneResource{}
+)
+
+func NewVMCloneResource() resource.Resource {
+	return &vmCloneResource{}
+}
+
+// vmCloneResource defines the resource implementation.
+type vmCloneResource struct {
+	session          *xenapi.Session
+	operationLimiter operationLimiter
+}
+
+func (r *vmCloneResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_vm_clone"
+}
+
+func (r *vmCloneResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Provides a VM clone resource. The new VM is cloned from an existing halted VM, its disks are fast copy-on-write clones on the same SR as the source VM. " +
+			"When `target_sr_uuid` is set, the VM is copied to the target SR with full disk copies instead, which is useful to move a VM's storage to another SR." +
+			"\n\n-> **Note:** The disks of the new VM are destroyed with the resource.",
+		Attributes: map[string]schema.Attribute{
+			"name_label": schema.StringAttribute{
+				MarkdownDescription: "The name of the new VM.",
+				Required:            true,
+			},
+			"source_vm_uuid": schema.StringAttribute{
+				MarkdownDescription: "The UUID of the VM to clone from, the VM should be halted." +
+					"\n\n-> **Note:** `source_vm_uuid` is not allowed to be updated.",
+				Required: true,
+			},
+			"target_sr_uuid": schema.StringAttribute{
+				MarkdownDescription: "The UUID of the SR to copy the VM disks to. If not set, the VM is cloned on the same SR as the source VM." +
+					"\n\n-> **Note:** `target_sr_uuid` is not allowed to be updated.",
+				Optional: true,
+			},
+			"uuid": schema.StringAttribute{
+				MarkdownDescription: "The UUID of the new VM.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"id": schema.StringAttribute{
+				MarkdownDescription: "The test ID of the new VM.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+// Set the parameter of the resource, pass value from provider
+func (r *vmCloneResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+	providerData, ok := req.ProviderData.(*xsProvider)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *xenserver.xsProvider, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+	r.session = providerData.session
+	r.operationLimiter = providerData.operationLimiter
+}
+
+func (r *vmCloneResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data vmCloneResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	tflog.Debug(ctx, "Cloning VM...")
+	vmRef, err := createVMClone(r.session, data)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to clone VM",
+			err.Error(),
+		)
+		if string(vmRef) != "" {
+			err = cleanupVMCloneResource(r.session, vmRef)
+			if err != nil {
+				resp.Diagnostics.AddError(
+					"Error cleaning up VM clone resource",
+					err.Error(),
+				)
+			}
+		}
+		return
+	}
+	vmRecord, err := xenapi.VM.GetRecord(r.session, vmRef)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get VM record",
+			err.Error(),
+		)
+		err = cleanupVMCloneResource(r.session, vmRef)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Error cleaning up VM clone resource",
+				err.Error(),
+			)
+		}
+		return
+	}
+	updateVMCloneResourceModelComputed(vmRecord, &data)
+	tflog.Debug(ctx, "VM cloned")
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *vmCloneResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data vmCloneResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	// Overwrite data with refreshed resource state
+	vmRef, err := withSessionRetry(r.session, func() (xenapi.VMRef, error) {
+		return xenapi.VM.GetByUUID(r.session, data.UUID.ValueString())
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get VM ref",
+			err.Error(),
+		)
+		return
+	}
+	vmRecord, err := xenapi.VM.GetRecord(r.session, vmRef)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get VM record",
+			err.Error(),
+		)
+		return
+	}
+	updateVMCloneResourceModel(vmRecord, &data)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *vmCloneResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state vmCloneResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Checking if configuration changes are allowed
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	err = vmCloneResourceModelUpdateCheck(plan, state)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error update xenserver_vm_clone configuration",
+			err.Error(),
+		)
+		return
+	}
+
+	// Update the resource with new configuration
+	vmRef, err := withSessionRetry(r.session, func() (xenapi.VMRef, error) {
+		return xenapi.VM.GetByUUID(r.session, plan.UUID.ValueString())
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get VM ref",
+			err.Error(),
+		)
+		return
+	}
+	err = xenapi.VM.SetNameLabel(r.session, vmRef, plan.NameLabel.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to update VM clone resource",
+			err.Error(),
+		)
+		return
+	}
+	vmRecord, err := xenapi.VM.GetRecord(r.session, vmRef)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get VM record",
+			err.Error(),
+		)
+		return
+	}
+	updateVMCloneResourceModelComputed(vmRecord, &plan)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *vmCloneResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data vmCloneResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	vmRef, err := withSessionRetry(r.session, func() (xenapi.VMRef, error) {
+		return xenapi.VM.GetByUUID(r.session, data.UUID.ValueString())
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get VM ref",
+			err.Error(),
+		)
+		return
+	}
+	err = cleanupVMCloneResource(r.session, vmRef)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to delete VM clone resource",
+			err.Error(),
+		)
+		return
+	}
+}
+
+func (r *vmCloneResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("uuid"), req, resp)
+}
@@ -0,0 +1,97 @@
+package xenserver
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func testAccVMCloneResourceConfig(name_label string, extra_config string) string {
+	return fmt.Sprintf(`
+data "xenserver_sr" "sr" {
+	name_label = "Local storage"
+}
+
+resource "xenserver_vdi" "vdi1" {
+	name_label   = "A test vdi"
+	sr_uuid      = data.xenserver_sr.sr.data_items[0].uuid
+	virtual_size = 1 * 1024 * 1024 * 1024
+}
+
+resource "xenserver_vm" "vm" {
+	name_label     = "A test virtual-machine"
+	template_name  = "Windows 11"
+	static_mem_max = 4 * 1024 * 1024 * 1024
+	vcpus          = 2
+	hard_drive = [
+		{
+		vdi_uuid = xenserver_vdi.vdi1.uuid,
+		mode     = "RW"
+		},
+	]
+}
+
+resource "xenserver_vm_clone" "test_clone" {
+	name_label     = "%s"
+	source_vm_uuid = xenserver_vm.vm.uuid
+	%s
+}
+`, name_label, extra_config)
+}
+
+func TestAccVMCloneResource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Create and Read testing
+			{
+				Config: providerConfig + testAccVMCloneResourceConfig("Test clone A", ""),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_vm_clone.test_clone", "name_label", "Test clone A"),
+					resource.TestCheckNoResourceAttr("xenserver_vm_clone.test_clone", "target_sr_uuid"),
+					resource.TestCheckResourceAttrPair("xenserver_vm_clone.test_clone", "source_vm_uuid", "xenserver_vm.vm", "uuid"),
+					resource.TestCheckResourceAttrSet("xenserver_vm_clone.test_clone", "uuid"),
+				),
+			},
+			// ImportState testing
+			{
+				ResourceName:            "xenserver_vm_clone.test_clone",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{},
+			},
+			{
+				Config:      providerConfig + testAccVMCloneResourceConfig("Test clone A", "target_sr_uuid = data.xenserver_sr.sr.data_items[0].uuid"),
+				ExpectError: regexp.MustCompile(`"target_sr_uuid" doesn't expected to be updated`),
+			},
+			// Update and Read testing
+			{
+				Config: providerConfig + testAccVMCloneResourceConfig("Test clone B", ""),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_vm_clone.test_clone", "name_label", "Test clone B"),
+				),
+			},
+			// Delete testing automatically occurs in TestCase
+		},
+	})
+}
+
+func TestAccVMCloneResourceCopy(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Create and Read testing
+			{
+				Config: providerConfig + testAccVMCloneResourceConfig("Test copy", "target_sr_uuid = data.xenserver_sr.sr.data_items[0].uuid"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_vm_clone.test_clone", "name_label", "Test copy"),
+					resource.TestCheckResourceAttrPair("xenserver_vm_clone.test_clone", "target_sr_uuid", "data.xenserver_sr.sr", "data_items.0.uuid"),
+					resource.TestCheckResourceAttrSet("xenserver_vm_clone.test_clone", "uuid"),
+				),
+			},
+			// Delete testing automatically occurs in TestCase
+		},
+	})
+}
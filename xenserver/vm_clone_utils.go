@@ -0,0 +1,106 @@
+package xenserver
+
+import (
+	"errors"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"xenapi"
+)
+
+type vmCloneResourceModel struct {
+	NameLabel types.String `tfsdk:"name_label"`
+	SourceVM  types.String `tfsdk:"source_vm_uuid"`
+	TargetSR  types.String `tfsdk:"target_sr_uuid"`
+	UUID      types.String `tfsdk:"uuid"`
+	ID        types.String `tfsdk:"id"`
+}
+
+// createVMClone clones the source VM on the same SR with copy-on-write disks, or copies it with full
+// disks to the target SR when the target SR is set.
+func createVMClone(session *xenapi.Session, data vmCloneResourceModel) (xenapi.VMRef, error) {
+	var vmRef xenapi.VMRef
+	sourceRef, err := withSessionRetry(session, func() (xenapi.VMRef, error) {
+		return xenapi.VM.GetByUUID(session, data.SourceVM.ValueString())
+	})
+	if err != nil {
+		return vmRef, errors.New(err.Error())
+	}
+
+	if data.TargetSR.ValueString() != "" {
+		srRef, err := checkIfSupportFullCopy(session, sourceRef, data.TargetSR.ValueString())
+		if err != nil {
+			return vmRef, err
+		}
+		vmRef, err = xenapi.VM.Copy(session, sourceRef, data.NameLabel.ValueString(), srRef)
+		if err != nil {
+			return vmRef, errors.New(err.Error())
+		}
+	} else {
+		vmRef, err = xenapi.VM.Clone(session, sourceRef, data.NameLabel.ValueString())
+		if err != nil {
+			return vmRef, errors.New(err.Error())
+		}
+	}
+
+	// record where the VM comes from, the new VM is independent from the source VM
+	otherConfig, err := xenapi.VM.GetOtherConfig(session, vmRef)
+	if err != nil {
+		return vmRef, errors.New(err.Error())
+	}
+	otherConfig["tf_source_vm_uuid"] = data.SourceVM.ValueString()
+	otherConfig["tf_target_sr_uuid"] = data.TargetSR.ValueString()
+	err = xenapi.VM.SetOtherConfig(session, vmRef, otherConfig)
+	if err != nil {
+		return vmRef, errors.New(err.Error())
+	}
+
+	return vmRef, nil
+}
+
+func updateVMCloneResourceModel(record xenapi.VMRecord, data *vmCloneResourceModel) {
+	data.NameLabel = types.StringValue(record.NameLabel)
+	if sourceVM, ok := record.OtherConfig["tf_source_vm_uuid"]; ok {
+		data.SourceVM = types.StringValue(sourceVM)
+	}
+	if targetSR, ok := record.OtherConfig["tf_target_sr_uuid"]; ok && targetSR != "" {
+		data.TargetSR = types.StringValue(targetSR)
+	}
+	updateVMCloneResourceModelComputed(record, data)
+}
+
+func updateVMCloneResourceModelComputed(record xenapi.VMRecord, data *vmCloneResourceModel) {
+	data.UUID = types.StringValue(record.UUID)
+	data.ID = types.StringValue(record.UUID)
+}
+
+func vmCloneResourceModelUpdateCheck(plan vmCloneResourceModel, state vmCloneResourceModel) error {
+	if plan.SourceVM != state.SourceVM {
+		return errors.New(`"source_vm_uuid" doesn't expected to be updated`)
+	}
+	if plan.TargetSR != state.TargetSR {
+		return errors.New(`"target_sr_uuid" doesn't expected to be updated`)
+	}
+	return nil
+}
+
+func cleanupVMCloneResource(session *xenapi.Session, ref xenapi.VMRef) error {
+	uuid, err := xenapi.VM.GetUUID(session, ref)
+	if err != nil {
+		return cleanupError("VM", string(ref), err)
+	}
+	// the clone may be started outside of terraform
+	powerState, err := xenapi.VM.GetPowerState(session, ref)
+	if err != nil {
+		return cleanupError("VM", uuid, err)
+	}
+	if powerState != xenapi.VMPowerStateHalted {
+		err = xenapi.VM.HardShutdown(session, ref)
+		if err != nil {
+			return cleanupError("VM", uuid, err)
+		}
+	}
+
+	// all the disks are created for the clone, destroy them with the VM
+	return cleanupSnapshotResource(session, ref)
+}
@@ -25,7 +25,8 @@ func NewVMDataSource() datasource.DataSource {
 
 // vmDataSource is the data source implementation.
 type vmDataSource struct {
-	session *xenapi.Session
+	session          *xenapi.Session
+	operationLimiter operationLimiter
 }
 
 // Metadata returns the data source type name.
@@ -87,7 +88,7 @@ func vmDataSchema() map[string]schema.Attribute {
 			Computed:            true,
 		},
 		"other_config": schema.MapAttribute{
-			MarkdownDescription: "Additional configuration.",
+			MarkdownDescription: "Additional configuration, the state recorded by the provider for `xenserver_vm` is not included.",
 			Computed:            true,
 			ElementType:         types.StringType,
 		},
@@ -461,6 +462,7 @@ func (d *vmDataSource) Configure(_ context.Context, req datasource.ConfigureRequ
 		return
 	}
 	d.session = providerData.session
+	d.operationLimiter = providerData.operationLimiter
 }
 
 // Read refreshes the Terraform state with the latest data.
@@ -473,7 +475,16 @@ func (d *vmDataSource) Read(ctx context.Context, req datasource.ReadRequest, res
 		return
 	}
 
-	vmRecords, err := xenapi.VM.GetAllRecords(d.session)
+	ctx, done, err := d.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	vmRecords, err := withSessionRetry(d.session, func() (map[xenapi.VMRef]xenapi.VMRecord, error) {
+		return xenapi.VM.GetAllRecords(d.session)
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to read VM records",
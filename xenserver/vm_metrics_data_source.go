@@ -0,0 +1,134 @@
+package xenserver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"xenapi"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &vmMetricsDataSource{}
+	_ datasource.DataSourceWithConfigure = &vmMetricsDataSource{}
+)
+
+// NewVMMetricsDataSource is a helper function to simplify the provider implementation.
+func NewVMMetricsDataSource() datasource.DataSource {
+	return &vmMetricsDataSource{}
+}
+
+// vmMetricsDataSource is the data source implementation.
+type vmMetricsDataSource struct {
+	session          *xenapi.Session
+	operationLimiter operationLimiter
+}
+
+// Metadata returns the data source type name.
+func (d *vmMetricsDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_vm_metrics"
+}
+
+func (d *vmMetricsDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Provides the metrics of a virtual machine: its vCPUs, the memory actually allocated to it and its start time, and the utilization reported by the guest agent when available." +
+			"\n\n-> **Note:** The guest metrics are only reported when the guest agent is installed and running in the virtual machine, otherwise `guest_memory` and `guest_disks` are empty. The metrics of a halted virtual machine are the ones of its last run.",
+		Attributes: map[string]schema.Attribute{
+			"uuid": schema.StringAttribute{
+				MarkdownDescription: "The UUID of the virtual machine.",
+				Required:            true,
+			},
+			"power_state": schema.StringAttribute{
+				MarkdownDescription: "The power state of the virtual machine, for example, `\"Running\"` or `\"Halted\"`.",
+				Computed:            true,
+			},
+			"vcpus_number": schema.Int32Attribute{
+				MarkdownDescription: "The current number of vCPUs of the virtual machine.",
+				Computed:            true,
+			},
+			"vcpus_utilisation": schema.MapAttribute{
+				MarkdownDescription: "The utilisation of the vCPUs as a fraction between `0` and `1`, keyed by the vCPU number.",
+				Computed:            true,
+				ElementType:         types.Float64Type,
+			},
+			"memory_actual": schema.Int64Attribute{
+				MarkdownDescription: "The memory in bytes actually allocated to the virtual machine.",
+				Computed:            true,
+			},
+			"start_time": schema.StringAttribute{
+				MarkdownDescription: "The time the virtual machine was last started, in RFC 3339 format, `\"\"` if it has never been started.",
+				Computed:            true,
+			},
+			"last_updated": schema.StringAttribute{
+				MarkdownDescription: "The time the metrics were last updated, in RFC 3339 format, `\"\"` if they have never been updated.",
+				Computed:            true,
+			},
+			"guest_metrics_live": schema.BoolAttribute{
+				MarkdownDescription: "Whether the guest agent is reporting the guest metrics.",
+				Computed:            true,
+			},
+			"guest_memory": schema.MapAttribute{
+				MarkdownDescription: "The memory usage reported by the guest agent, for example, the `\"free\"` and `\"total\"` memory in KiB.",
+				Computed:            true,
+				ElementType:         types.StringType,
+			},
+			"guest_disks": schema.MapAttribute{
+				MarkdownDescription: "The disk usage reported by the guest agent.",
+				Computed:            true,
+				ElementType:         types.StringType,
+			},
+		},
+	}
+}
+
+func (d *vmMetricsDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	providerData, ok := req.ProviderData.(*xsProvider)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *xenserver.xsProvider, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+	d.session = providerData.session
+	d.operationLimiter = providerData.operationLimiter
+}
+
+// Read refreshes the Terraform state with the latest data.
+func (d *vmMetricsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data vmMetricsDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := d.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	err = retryOnSessionInvalid(d.session, func() error {
+		return updateVMMetricsDataSourceModel(ctx, d.session, &data)
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to read VM metrics",
+			err.Error(),
+		)
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
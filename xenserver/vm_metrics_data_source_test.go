@@ -0,0 +1,83 @@
+package xenserver
+
+import (
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func testAccVMMetricsDataSourceConfig() string {
+	return `
+data "xenserver_network" "network" {}
+
+resource "xenserver_vm" "test_vm" {
+  name_label     = "Test metrics VM"
+  template_name  = "Debian Bullseye 11"
+  static_mem_max = 2 * 1024 * 1024 * 1024
+  vcpus          = 2
+  network_interface = [
+    {
+      network_uuid = data.xenserver_network.network.data_items[1].uuid,
+    },
+  ]
+}
+
+data "xenserver_vm_metrics" "test_vm_metrics" {
+  uuid = xenserver_vm.test_vm.uuid
+}
+`
+}
+
+func TestAccVMMetricsDataSource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + testAccVMMetricsDataSourceConfig(),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.xenserver_vm_metrics.test_vm_metrics", "power_state", "Halted"),
+					resource.TestCheckResourceAttrSet("data.xenserver_vm_metrics.test_vm_metrics", "vcpus_number"),
+					resource.TestCheckResourceAttrSet("data.xenserver_vm_metrics.test_vm_metrics", "memory_actual"),
+					resource.TestCheckResourceAttr("data.xenserver_vm_metrics.test_vm_metrics", "guest_metrics_live", "false"),
+					resource.TestCheckResourceAttr("data.xenserver_vm_metrics.test_vm_metrics", "guest_memory.%", "0"),
+				),
+			},
+		},
+	})
+}
+
+func TestFormatXapiTime(t *testing.T) {
+	testCases := []struct {
+		time     time.Time
+		expected string
+	}{
+		{time: time.Time{}, expected: ""},
+		{time: time.Unix(0, 0), expected: ""},
+		{time: time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC), expected: "2024-05-01T10:30:00Z"},
+		{time: time.Date(2024, 5, 1, 12, 30, 0, 0, time.FixedZone("CEST", 2*60*60)), expected: "2024-05-01T10:30:00Z"},
+	}
+	for _, tc := range testCases {
+		formatted := formatXapiTime(tc.time)
+		if formatted != tc.expected {
+			t.Errorf("formatXapiTime(%v) = %q, expected %q", tc.time, formatted, tc.expected)
+		}
+	}
+}
+
+func TestGetVCPUsUtilisation(t *testing.T) {
+	testCases := []struct {
+		utilisation map[int]float64
+		expected    map[string]float64
+	}{
+		{utilisation: nil, expected: map[string]float64{}},
+		{utilisation: map[int]float64{0: 0.25, 1: 0.5}, expected: map[string]float64{"0": 0.25, "1": 0.5}},
+	}
+	for _, tc := range testCases {
+		utilisation := getVCPUsUtilisation(tc.utilisation)
+		if !maps.Equal(utilisation, tc.expected) {
+			t.Errorf("getVCPUsUtilisation(%v) = %v, expected %v", tc.utilisation, utilisation, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package xenserver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"xenapi"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &vmPlacementDataSource{}
+	_ datasource.DataSourceWithConfigure = &vmPlacementDataSource{}
+)
+
+// NewVMPlacementDataSource is a helper function to simplify the provider implementation.
+func NewVMPlacementDataSource() datasource.DataSource {
+	return &vmPlacementDataSource{}
+}
+
+// vmPlacementDataSource is the data source implementation.
+type vmPlacementDataSource struct {
+	session          *xenapi.Session
+	operationLimiter operationLimiter
+}
+
+// Metadata returns the data source type name.
+func (d *vmPlacementDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_vm_placement"
+}
+
+func (d *vmPlacementDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Provides the placement of a virtual machine: the host it's running on and the hosts it can be started on. It's useful to avoid proposing impossible placements, for example, with a `precondition`." +
+			"\n\n-> **Note:** The possible hosts are the ones XAPI checks the virtual machine can boot on, considering the free memory, the storage and the networks of the hosts. They may change once the placement of the other virtual machines changes.",
+		Attributes: map[string]schema.Attribute{
+			"uuid": schema.StringAttribute{
+				MarkdownDescription: "The UUID of the virtual machine.",
+				Required:            true,
+			},
+			"power_state": schema.StringAttribute{
+				MarkdownDescription: "The power state of the virtual machine, for example, `\"Running\"` or `\"Halted\"`.",
+				Computed:            true,
+			},
+			"resident_on": schema.StringAttribute{
+				MarkdownDescription: "The UUID of the host the virtual machine is resident on, `\"\"` when the virtual machine is not running.",
+				Computed:            true,
+			},
+			"possible_hosts": schema.ListAttribute{
+				MarkdownDescription: "The UUIDs of the hosts the virtual machine can be started on, sorted. They are not checked for a live migration of the running virtual machine. The host it's resident on is not included.",
+				Computed:            true,
+				ElementType:         types.StringType,
+			},
+		},
+	}
+}
+
+func (d *vmPlacementDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	providerData, ok := req.ProviderData.(*xsProvider)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *xenserver.xsProvider, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+	d.session = providerData.session
+	d.operationLimiter = providerData.operationLimiter
+}
+
+// Read refreshes the Terraform state with the latest data.
+func (d *vmPlacementDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data vmPlacementDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := d.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	err = retryOnSessionInvalid(d.session, func() error {
+		return updateVMPlacementDataSourceModel(ctx, d.session, &data)
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to read VM placement",
+			err.Error(),
+		)
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
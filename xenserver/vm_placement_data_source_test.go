@@ -0,0 +1,72 @@
+package xenserver
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+
+	"xenapi"
+)
+
+func testAccVMPlacementDataSourceConfig() string {
+	return `
+data "xenserver_network" "network" {}
+
+resource "xenserver_vm" "test_vm" {
+  name_label     = "Test placement VM"
+  template_name  = "Debian Bullseye 11"
+  static_mem_max = 2 * 1024 * 1024 * 1024
+  vcpus          = 2
+  network_interface = [
+    {
+      network_uuid = data.xenserver_network.network.data_items[1].uuid,
+    },
+  ]
+}
+
+data "xenserver_vm_placement" "test_vm_placement" {
+  uuid = xenserver_vm.test_vm.uuid
+}
+`
+}
+
+func TestAccVMPlacementDataSource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + testAccVMPlacementDataSourceConfig(),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.xenserver_vm_placement.test_vm_placement", "power_state", "Halted"),
+					resource.TestCheckResourceAttr("data.xenserver_vm_placement.test_vm_placement", "resident_on", ""),
+					resource.TestCheckResourceAttrSet("data.xenserver_vm_placement.test_vm_placement", "possible_hosts.#"),
+				),
+			},
+		},
+	})
+}
+
+func TestGetPossibleHostUUIDs(t *testing.T) {
+	hostUUIDs := map[xenapi.HostRef]string{
+		"host-a": "uuid-a",
+		"host-b": "uuid-b",
+		"host-c": "uuid-c",
+	}
+	testCases := []struct {
+		possibleHosts []xenapi.HostRef
+		residentOn    xenapi.HostRef
+		expected      []string
+	}{
+		{possibleHosts: []xenapi.HostRef{"host-c", "host-a"}, residentOn: "OpaqueRef:NULL", expected: []string{"uuid-a", "uuid-c"}},
+		{possibleHosts: []xenapi.HostRef{"host-a", "host-b", "host-c"}, residentOn: "host-b", expected: []string{"uuid-a", "uuid-c"}},
+		{possibleHosts: []xenapi.HostRef{"host-b"}, residentOn: "host-b", expected: []string{}},
+		{possibleHosts: []xenapi.HostRef{"host-unknown"}, residentOn: "OpaqueRef:NULL", expected: []string{}},
+	}
+	for _, tc := range testCases {
+		uuids := getPossibleHostUUIDs(hostUUIDs, tc.possibleHosts, tc.residentOn)
+		if !slices.Equal(uuids, tc.expected) {
+			t.Errorf("getPossibleHostUUIDs(%v, %q) = %v, expected %v", tc.possibleHosts, tc.residentOn, uuids, tc.expected)
+		}
+	}
+}
@@ -2,7 +2,9 @@ package xenserver
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
@@ -16,14 +18,20 @@ var (
 	_ resource.Resource                = &vmResource{}
 	_ resource.ResourceWithConfigure   = &vmResource{}
 	_ resource.ResourceWithImportState = &vmResource{}
+	_ resource.ResourceWithModifyPlan  = &vmResource{}
 )
 
+// vmDriftPrivateStateKey is the private state key of the disks and network interfaces drift reported
+// on the last refresh
+const vmDriftPrivateStateKey = "drift"
+
 func NewVMResource() resource.Resource {
 	return &vmResource{}
 }
 
 type vmResource struct {
-	session *xenapi.Session
+	session          *xenapi.Session
+	operationLimiter operationLimiter
 }
 
 func (r *vmResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -50,65 +58,184 @@ func (r *vmResource) Configure(_ context.Context, req resource.ConfigureRequest,
 		return
 	}
 	r.session = providerData.session
+	r.operationLimiter = providerData.operationLimiter
 }
 
-func (r *vmResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
-	tflog.Debug(ctx, "---> Create VM resource")
+// ModifyPlan warns when the memory and vcpus of the VM are out of the range recommended by the template
+func (r *vmResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if r.session == nil || req.Plan.Raw.IsNull() {
+		return
+	}
 	var plan vmResourceModel
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	// create new resource
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	var state vmResourceModel
+	if !req.State.Raw.IsNull() {
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		// the attachment of the disks and network interfaces changes if the VM is started by the apply
+		var keepUnknown []string
+		if isVMAutoStarted(plan) {
+			keepUnknown = []string{"currently_attached"}
+		}
+		hardDrive, diags := fillSetItemsFromState(ctx, plan.HardDrive, state.HardDrive, keepUnknown)
+		resp.Diagnostics.Append(diags...)
+		networkInterface, diags := fillSetItemsFromState(ctx, plan.NetworkInterface, state.NetworkInterface, keepUnknown)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("hard_drive"), hardDrive)...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("network_interface"), networkInterface)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		plan.HardDrive = hardDrive
+		plan.NetworkInterface = networkInterface
+	}
+	if !plan.TemplateName.IsUnknown() {
+		err := checkBootableHardDrive(ctx, r.session, plan)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("boot_order"),
+				"Invalid boot_order",
+				err.Error(),
+			)
+		}
+	}
+	if !plan.SuspendSR.IsUnknown() {
+		_, err := getSuspendSRRef(r.session, plan.SuspendSR.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("suspend_sr_uuid"),
+				"Invalid suspend_sr_uuid",
+				err.Error(),
+			)
+		}
+	}
+	if plan.TemplateName.IsNull() {
+		if !plan.SRForFullDiskCopy.IsUnknown() && plan.SRForFullDiskCopy.ValueString() != "" {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("sr_for_full_disk_copy"),
+				"Invalid sr_for_full_disk_copy",
+				"sr_for_full_disk_copy can only be used with template_name, the VM created from scratch has no template disk to copy.",
+			)
+		}
+		return
+	}
+	if plan.TemplateName.IsUnknown() || plan.StaticMemMax.IsUnknown() || plan.VCPUs.IsUnknown() {
+		return
+	}
+	// only check the sizing when it's set, the existing VMs, e.g. imported, may be sized out of the recommendations on purpose
+	if !req.State.Raw.IsNull() &&
+		plan.TemplateName.Equal(state.TemplateName) &&
+		plan.StaticMemMax.Equal(state.StaticMemMax) &&
+		plan.VCPUs.Equal(state.VCPUs) {
+		return
+	}
+
+	// the template may be removed after the VM is created, leave the template check to Create
 	templateRef, err := getFirstTemplate(r.session, plan.TemplateName.ValueString())
+	if err != nil {
+		return
+	}
+	recommendations, err := xenapi.VM.GetRecommendations(r.session, templateRef)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get template recommendations",
+			err.Error(),
+		)
+		return
+	}
+	ranges, err := getTemplateRecommendations(recommendations)
 	if err != nil {
 		resp.Diagnostics.AddError(
-			"Unable to get template Ref",
+			"Unable to get template recommendations",
 			err.Error(),
 		)
 		return
 	}
+	violations := checkTemplateRecommendations(ranges, plan.StaticMemMax.ValueInt64(), int64(plan.VCPUs.ValueInt32()))
+	for _, attribute := range []string{"static_mem_max", "vcpus"} {
+		if detail, ok := violations[attribute]; ok {
+			resp.Diagnostics.AddAttributeWarning(
+				path.Root(attribute),
+				"Value out of the template recommendations",
+				detail,
+			)
+		}
+	}
+}
 
-	var vmRef xenapi.VMRef
-	if !plan.SRForFullDiskCopy.IsUnknown() && plan.SRForFullDiskCopy.ValueString() != "" {
-		srRef, err := checkIfSupportFullCopy(r.session, templateRef, plan.SRForFullDiskCopy.ValueString())
+func (r *vmResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	tflog.Debug(ctx, "---> Create VM resource")
+	var plan vmResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	if plan.EnforceUniqueName.ValueBool() {
+		err := checkVMNameUnique(r.session, plan.NameLabel.ValueString(), "")
 		if err != nil {
 			resp.Diagnostics.AddError(
-				"Use storage-level full disk copy but get error",
+				"Duplicate VM name",
 				err.Error(),
 			)
 			return
 		}
-		tflog.Debug(ctx, "----> Copy VM from a template")
-		vmRef, err = xenapi.VM.Copy(r.session, templateRef, plan.NameLabel.ValueString(), srRef)
+	}
+
+	// create new resource
+	var vmRef xenapi.VMRef
+	if plan.TemplateName.IsNull() {
+		tflog.Debug(ctx, "----> Create VM from scratch")
+		vmRef, err = createVMFromScratch(ctx, r.session, plan)
 		if err != nil {
 			resp.Diagnostics.AddError(
-				"Unable to copy VM from template",
+				"Unable to create VM",
 				err.Error(),
 			)
 			return
 		}
 	} else {
-		tflog.Debug(ctx, "----> Clone VM from a template")
-		vmRef, err = xenapi.VM.Clone(r.session, templateRef, plan.NameLabel.ValueString())
+		vmRef, err = createVMFromTemplate(ctx, r.session, plan)
 		if err != nil {
 			resp.Diagnostics.AddError(
-				"Unable to clone VM from template",
+				"Unable to create VM from template",
 				err.Error(),
 			)
 			return
 		}
 	}
 
-	err = setVMResourceModel(ctx, r.session, vmRef, plan)
+	err = setVMResourceModel(ctx, r.session, vmRef, plan, r.operationLimiter.newParallelLimit())
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to set VM resource model",
 			err.Error(),
 		)
 
-		err = cleanupVMResource(r.session, vmRef)
+		err = cleanupVMResource(ctx, r.session, vmRef, false, 0)
 		if err != nil {
 			resp.Diagnostics.AddError(
 				"Unable to destroy VM",
@@ -127,7 +254,7 @@ func (r *vmResource) Create(ctx context.Context, req resource.CreateRequest, res
 			err.Error(),
 		)
 
-		err = cleanupVMResource(r.session, vmRef)
+		err = cleanupVMResource(ctx, r.session, vmRef, false, 0)
 		if err != nil {
 			resp.Diagnostics.AddError(
 				"Unable to destroy VM",
@@ -144,7 +271,7 @@ func (r *vmResource) Create(ctx context.Context, req resource.CreateRequest, res
 			err.Error(),
 		)
 
-		err = cleanupVMResource(r.session, vmRef)
+		err = cleanupVMResource(ctx, r.session, vmRef, false, 0)
 		if err != nil {
 			resp.Diagnostics.AddError(
 				"Unable to destroy VM",
@@ -168,8 +295,17 @@ func (r *vmResource) Read(ctx context.Context, req resource.ReadRequest, resp *r
 		return
 	}
 
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
 	// Overwrite state with refreshed resource state
-	vmRef, err := xenapi.VM.GetByUUID(r.session, state.UUID.ValueString())
+	vmRef, err := withSessionRetry(r.session, func() (xenapi.VMRef, error) {
+		return xenapi.VM.GetByUUID(r.session, state.UUID.ValueString())
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to get VM ref",
@@ -187,6 +323,8 @@ func (r *vmResource) Read(ctx context.Context, req resource.ReadRequest, resp *r
 		return
 	}
 
+	priorHardDrive := state.HardDrive
+	priorNetworkInterface := state.NetworkInterface
 	err = updateVMResourceModel(ctx, r.session, vmRecord, &state)
 	if err != nil {
 		resp.Diagnostics.AddError(
@@ -196,6 +334,53 @@ func (r *vmResource) Read(ctx context.Context, req resource.ReadRequest, resp *r
 		return
 	}
 
+	// report the disks and network interfaces added or removed outside of terraform as drift, only when
+	// it's different from the drift reported on the last refresh
+	drift := map[string]setItemsDrift{
+		"hard_drive":        getSetItemsDrift(priorHardDrive, state.HardDrive, "vdi_uuid"),
+		"network_interface": getSetItemsDrift(priorNetworkInterface, state.NetworkInterface, "device"),
+	}
+	reportedData, diags := req.Private.GetKey(ctx, vmDriftPrivateStateKey)
+	resp.Diagnostics.Append(diags...)
+	reported := getReportedDrift(reportedData)
+	if hardDrive := drift["hard_drive"]; !hardDrive.Equal(reported["hard_drive"]) {
+		if len(hardDrive.Added) > 0 {
+			resp.Diagnostics.AddWarning(
+				"Disks attached outside of terraform",
+				"VDIs "+strings.Join(hardDrive.Added, ", ")+" are attached to the VM outside of terraform, they are detached on the next apply if hard_drive is configured without them.",
+			)
+		}
+		if len(hardDrive.Removed) > 0 {
+			resp.Diagnostics.AddWarning(
+				"Disks detached outside of terraform",
+				"VDIs "+strings.Join(hardDrive.Removed, ", ")+" are detached from the VM outside of terraform, they are attached again on the next apply if they are in hard_drive.",
+			)
+		}
+	}
+	if networkInterface := drift["network_interface"]; !networkInterface.Equal(reported["network_interface"]) {
+		if len(networkInterface.Added) > 0 {
+			resp.Diagnostics.AddWarning(
+				"Network interfaces added outside of terraform",
+				"Network interfaces on devices "+strings.Join(networkInterface.Added, ", ")+" are added to the VM outside of terraform, they are destroyed on the next apply if they are not in network_interface.",
+			)
+		}
+		if len(networkInterface.Removed) > 0 {
+			resp.Diagnostics.AddWarning(
+				"Network interfaces removed outside of terraform",
+				"Network interfaces on devices "+strings.Join(networkInterface.Removed, ", ")+" are removed from the VM outside of terraform, they are created again on the next apply if they are in network_interface.",
+			)
+		}
+	}
+	driftData, err := json.Marshal(drift)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to record the VM drift",
+			err.Error(),
+		)
+		return
+	}
+	resp.Diagnostics.Append(resp.Private.SetKey(ctx, vmDriftPrivateStateKey, driftData)...)
+
 	// Save updated state into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
@@ -209,7 +394,14 @@ func (r *vmResource) Update(ctx context.Context, req resource.UpdateRequest, res
 		return
 	}
 
-	err := vmResourceModelUpdateCheck(plan, state)
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	err = vmResourceModelUpdateCheck(plan, state)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Error update xenserver_vm configuration",
@@ -218,8 +410,22 @@ func (r *vmResource) Update(ctx context.Context, req resource.UpdateRequest, res
 		return
 	}
 
+	// only a new name can clash, the VMs which already have the name don't block the other updates
+	if plan.EnforceUniqueName.ValueBool() && !plan.NameLabel.Equal(state.NameLabel) {
+		err = checkVMNameUnique(r.session, plan.NameLabel.ValueString(), state.UUID.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Duplicate VM name",
+				err.Error(),
+			)
+			return
+		}
+	}
+
 	// Get existing vm record
-	vmRef, err := xenapi.VM.GetByUUID(r.session, plan.UUID.ValueString())
+	vmRef, err := withSessionRetry(r.session, func() (xenapi.VMRef, error) {
+		return xenapi.VM.GetByUUID(r.session, plan.UUID.ValueString())
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to get VM ref",
@@ -269,8 +475,17 @@ func (r *vmResource) Delete(ctx context.Context, req resource.DeleteRequest, res
 		return
 	}
 
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
 	// delete resource
-	vmRef, err := xenapi.VM.GetByUUID(r.session, state.UUID.ValueString())
+	vmRef, err := withSessionRetry(r.session, func() (xenapi.VMRef, error) {
+		return xenapi.VM.GetByUUID(r.session, state.UUID.ValueString())
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to get VM ref",
@@ -279,7 +494,7 @@ func (r *vmResource) Delete(ctx context.Context, req resource.DeleteRequest, res
 		return
 	}
 
-	err = cleanupVMResource(r.session, vmRef)
+	err = cleanupVMResource(ctx, r.session, vmRef, state.ForceDestroy.ValueBool(), state.ShutdownTimeout.ValueInt64())
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to destroy VM",
@@ -1,11 +1,22 @@
 package xenserver
 
 import (
+	"context"
 	"fmt"
+	"maps"
+	"os"
 	"regexp"
+	"slices"
+	"strconv"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+
+	"xenapi"
 )
 
 func testAccVMResourceConfig(name_label string, template string, memory int, vcpu int, cores_per_socket int, boot_mode string, boot_order string, bootable string, mode string, mac string, device string) string {
@@ -54,6 +65,44 @@ resource "xenserver_vm" "test_vm" {
 `, name_label, template, memory, vcpu, cores_per_socket, boot_mode, boot_order, bootable, mode, mac, device)
 }
 
+func testAccVMResourceDuplicateVDIConfig() string {
+	return `
+data "xenserver_sr" "sr" {
+  name_label = "Local storage"
+}
+
+resource "xenserver_vdi" "vdi" {
+  name_label       = "local-storage-vdi"
+  sr_uuid          = data.xenserver_sr.sr.data_items[0].uuid
+  virtual_size     = 100 * 1024 * 1024 * 1024
+}
+
+data "xenserver_network" "network" {}
+
+resource "xenserver_vm" "test_vm" {
+  name_label = "duplicate vdi"
+  template_name = "Windows 11"
+  static_mem_max = 4 * 1024 * 1024 * 1024
+  vcpus         = 4
+  hard_drive = [
+    {
+      vdi_uuid = "duplicate-vdi-uuid",
+      mode = "RW"
+    },
+    {
+      vdi_uuid = "duplicate-vdi-uuid",
+      mode = "RO"
+    },
+  ]
+  network_interface = [
+    {
+      network_uuid = data.xenserver_network.network.data_items[1].uuid,
+    },
+  ]
+}
+`
+}
+
 func TestAccVMResource(t *testing.T) {
 	resource.Test(t, resource.TestCase{
 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
@@ -75,6 +124,10 @@ func TestAccVMResource(t *testing.T) {
 				Config:      providerConfig + testAccVMResourceConfig("invalid vm config", "Windows 11", 4, 4, 2, "uefi", "invalid order", "false", "RW", "11:22:33:44:55:66", "1"),
 				ExpectError: regexp.MustCompile(`boot_order the value is combination string of \['c', 'd', 'n'\]`),
 			},
+			{
+				Config:      providerConfig + testAccVMResourceDuplicateVDIConfig(),
+				ExpectError: regexp.MustCompile(`Duplicate VDI in hard_drive`),
+			},
 			// Create and Read testing
 			{
 				Config: providerConfig + testAccVMResourceConfig("test vm 1", "Windows 11", 4, 4, 4, "uefi", "ncd", "true", "RW", "11:22:33:44:55:66", "0"),
@@ -88,13 +141,24 @@ func TestAccVMResource(t *testing.T) {
 					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "vcpus", "4"),
 					resource.TestCheckResourceAttrSet("xenserver_vm.test_vm", "cores_per_socket"),
 					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "check_ip_timeout", "0"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "force_destroy", "false"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "shutdown_timeout", "60"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "auto_reboot_on_config_change", "false"),
+					resource.TestCheckResourceAttrSet("xenserver_vm.test_vm", "requires_reboot"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "wait_for_guest_tools", "false"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "guest_tools_timeout", "300"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "blocked_operations.%", "0"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "platform.%", "0"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "xenstore_data.%", "0"),
 					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "default_ip", ""),
 					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "boot_mode", "uefi"),
 					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "boot_order", "ncd"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "domain_type", "hvm"),
 					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "hard_drive.#", "1"),
-					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "hard_drive.0.%", "4"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "hard_drive.0.%", "5"),
 					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "hard_drive.0.mode", "RW"),
 					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "hard_drive.0.bootable", "true"),
+					resource.TestCheckResourceAttrSet("xenserver_vm.test_vm", "hard_drive.0.currently_attached"),
 					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "network_interface.#", "1"),
 					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "network_interface.0.%", "5"),
 					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "network_interface.0.device", "0"),
@@ -123,10 +187,14 @@ func TestAccVMResource(t *testing.T) {
 				Config:      providerConfig + testAccVMResourceConfig("test vm 1", "Windows 11", 3, 3, 2, "uefi", "ncd", "false", "RO", "11:22:33:44:55:66", "1"),
 				ExpectError: regexp.MustCompile("3 cores could not fit to 2 cores-per-socket topology*"),
 			},
+			{
+				Config:      providerConfig + testAccVMResourceConfig("test vm 1", "Windows 11", 3, 2, 2, "uefi", "cnd", "false", "RO", "11:22:33:44:55:66", "1"),
+				ExpectError: regexp.MustCompile(`boot_order contains "c" but there is no disk to boot from`),
+			},
 			// Update and Read testing
 			// change the network_interface device
 			{
-				Config: providerConfig + testAccVMResourceConfig("test vm 1", "Windows 11", 3, 2, 2, "uefi", "cnd", "false", "RO", "11:22:33:44:55:66", "1"),
+				Config: providerConfig + testAccVMResourceConfig("test vm 1", "Windows 11", 3, 2, 2, "uefi", "dn", "false", "RO", "11:22:33:44:55:66", "1"),
 				Check: resource.ComposeAggregateTestCheckFunc(
 					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "name_label", "test vm 1"),
 					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "template_name", "Windows 11"),
@@ -140,7 +208,7 @@ func TestAccVMResource(t *testing.T) {
 					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "hard_drive.0.mode", "RO"),
 					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "hard_drive.0.bootable", "false"),
 					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "boot_mode", "uefi"),
-					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "boot_order", "cnd"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "boot_order", "dn"),
 					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "network_interface.#", "1"),
 					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "network_interface.0.device", "1"),
 					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "network_interface.0.mac", "11:22:33:44:55:66"),
@@ -179,8 +247,11 @@ func TestAccLinuxVMResource(t *testing.T) {
 					resource.TestCheckResourceAttrSet("xenserver_vm.test_vm", "cores_per_socket"),
 					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "boot_mode", "uefi"),
 					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "boot_order", "ncd"),
+					resource.TestCheckResourceAttrSet("xenserver_vm.test_vm", "actions_after_shutdown"),
+					resource.TestCheckResourceAttrSet("xenserver_vm.test_vm", "actions_after_reboot"),
+					resource.TestCheckResourceAttrSet("xenserver_vm.test_vm", "actions_after_crash"),
 					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "hard_drive.#", "1"),
-					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "hard_drive.0.%", "4"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "hard_drive.0.%", "5"),
 					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "hard_drive.0.mode", "RW"),
 					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "hard_drive.0.bootable", "true"),
 					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "network_interface.#", "1"),
@@ -196,3 +267,1083 @@ func TestAccLinuxVMResource(t *testing.T) {
 		},
 	})
 }
+
+func testAccVMResourceAutoRebootConfig(template string, memory int) string {
+	return fmt.Sprintf(`
+resource "xenserver_vm" "test_vm" {
+  name_label                   = "Test auto reboot VM"
+  template_name                = "%s"
+  static_mem_max               = %d * 1024 * 1024 * 1024
+  vcpus                        = 2
+  wait_for_guest_tools         = true
+  auto_reboot_on_config_change = true
+}
+`, template, memory)
+}
+
+func TestAccVMResourceAutoReboot(t *testing.T) {
+	// the VM is rebooted cleanly, which needs a template with the guest tools installed
+	template := os.Getenv("TEST_GUEST_TOOLS_TEMPLATE")
+	if template == "" {
+		t.Skip("Skipping TestAccVMResourceAutoReboot test due to TEST_GUEST_TOOLS_TEMPLATE not set")
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + testAccVMResourceAutoRebootConfig(template, 2),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "static_mem_max", "2147483648"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "requires_reboot", "false"),
+				),
+			},
+			// change the memory of the running VM
+			{
+				Config: providerConfig + testAccVMResourceAutoRebootConfig(template, 3),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "static_mem_max", "3221225472"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "requires_reboot", "false"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccVMResourcePlatform(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// The provider-managed platform keys are reserved
+			{
+				Config:      providerConfig + testAccVMResourceCDROMConfig(`platform = { "secureboot" = "true" }`),
+				ExpectError: regexp.MustCompile(`value must be none of`),
+			},
+			{
+				Config:      providerConfig + testAccVMResourceCDROMConfig(`hvm_shadow_multiplier = 0.5`),
+				ExpectError: regexp.MustCompile(`value must be at least 1.000000`),
+			},
+			// The suspend SR must exist
+			{
+				Config:      providerConfig + testAccVMResourceCDROMConfig(`suspend_sr_uuid = "00000000-0000-0000-0000-000000000000"`),
+				ExpectError: regexp.MustCompile(`unable to find the suspend SR`),
+			},
+			// The other_config keys with the tf_ prefix are reserved
+			{
+				Config:      providerConfig + testAccVMResourceCDROMConfig(`other_config = { "tf_template_name" = "test" }`),
+				ExpectError: regexp.MustCompile(`Reserved key in other_config`),
+			},
+			{
+				Config: providerConfig + testAccVMResourceCDROMConfig(`
+  platform = { "nx" = "true" }
+  xenstore_data = { "vm-data/hostname" = "test-vm" }
+  hvm_shadow_multiplier = 2
+  user_version = 2`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "user_version", "2"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "hvm_shadow_multiplier", "2"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "platform.%", "1"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "platform.nx", "true"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "xenstore_data.%", "1"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "xenstore_data.vm-data/hostname", "test-vm"),
+					resource.TestCheckResourceAttrSet("xenserver_vm.test_vm", "cores_per_socket"),
+				),
+			},
+			// Update the xenstore data in place
+			{
+				Config: providerConfig + testAccVMResourceCDROMConfig(`
+  platform = { "nx" = "true" }
+  xenstore_data = { "vm-data/hostname" = "test-vm-2", "vm-data/domain" = "example.com" }
+  hvm_shadow_multiplier = 2
+  user_version = 2`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "xenstore_data.%", "2"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "xenstore_data.vm-data/hostname", "test-vm-2"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "xenstore_data.vm-data/domain", "example.com"),
+				),
+			},
+			// Remove the user platform keys, the provider-managed ones are kept
+			{
+				Config: providerConfig + testAccVMResourceCDROMConfig(""),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "platform.%", "0"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "xenstore_data.%", "0"),
+					resource.TestCheckResourceAttrSet("xenserver_vm.test_vm", "cores_per_socket"),
+				),
+			},
+		},
+	})
+}
+
+func testAccVMResourceNetworkConfig(networkIndex int) string {
+	return fmt.Sprintf(`
+data "xenserver_network" "network" {}
+
+resource "xenserver_vm" "test_vm" {
+  name_label = "Test network VM"
+  template_name = "Debian Bullseye 11"
+  static_mem_max = 2 * 1024 * 1024 * 1024
+  vcpus         = 2
+  network_interface = [
+    {
+      mac          = "11:22:33:44:55:66"
+      device       = "0"
+      network_uuid = data.xenserver_network.network.data_items[%d].uuid,
+    },
+  ]
+}
+`, networkIndex)
+}
+
+func TestAccVMResourceMoveVIF(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + testAccVMResourceNetworkConfig(1),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrPair("xenserver_vm.test_vm", "network_interface.0.network_uuid", "data.xenserver_network.network", "data_items.1.uuid"),
+					resource.TestCheckResourceAttrPair("xenserver_vm.test_vm", "network_interface.0.mtu", "data.xenserver_network.network", "data_items.1.mtu"),
+				),
+			},
+			// Move the VIF to another network, the device and MAC address are kept
+			{
+				Config: providerConfig + testAccVMResourceNetworkConfig(0),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "network_interface.#", "1"),
+					resource.TestCheckResourceAttrPair("xenserver_vm.test_vm", "network_interface.0.network_uuid", "data.xenserver_network.network", "data_items.0.uuid"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "network_interface.0.device", "0"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "network_interface.0.mac", "11:22:33:44:55:66"),
+				),
+			},
+		},
+	})
+}
+
+func testAccVMResourceScratchConfig(vcpus int) string {
+	return fmt.Sprintf(`
+data "xenserver_network" "network" {}
+
+resource "xenserver_vm" "test_vm" {
+  name_label = "Test scratch VM"
+  static_mem_max = 2 * 1024 * 1024 * 1024
+  vcpus         = %d
+  boot_mode     = "uefi"
+  network_interface = [
+    {
+      network_uuid = data.xenserver_network.network.data_items[1].uuid,
+    },
+  ]
+}
+`, vcpus)
+}
+
+func TestAccVMResourceScratch(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + testAccVMResourceScratchConfig(2),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckNoResourceAttr("xenserver_vm.test_vm", "template_name"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "vcpus", "2"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "cores_per_socket", "2"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "boot_mode", "uefi"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "boot_order", "cdn"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "domain_type", "hvm"),
+				),
+			},
+			// ImportState testing
+			{
+				ResourceName:      "xenserver_vm.test_vm",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+			{
+				Config: providerConfig + testAccVMResourceScratchConfig(4),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "vcpus", "4"),
+				),
+			},
+		},
+	})
+}
+
+func testAccVMResourceBootFromDiskConfig() string {
+	return `
+data "xenserver_sr" "sr" {
+  name_label = "Local storage"
+}
+
+resource "xenserver_vdi" "os_disk" {
+  name_label   = "os-disk"
+  sr_uuid      = data.xenserver_sr.sr.data_items[0].uuid
+  virtual_size = 1 * 1024 * 1024 * 1024
+}
+
+resource "xenserver_vm" "test_vm" {
+  name_label     = "Test boot from disk VM"
+  static_mem_max = 2 * 1024 * 1024 * 1024
+  vcpus          = 2
+  hard_drive = [
+    {
+      vdi_uuid = xenserver_vdi.os_disk.uuid,
+      bootable = true,
+      mode     = "RW"
+    },
+  ]
+}
+`
+}
+
+func TestAccVMResourceBootFromDisk(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + testAccVMResourceBootFromDiskConfig(),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckNoResourceAttr("xenserver_vm.test_vm", "template_name"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "boot_order", "c"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "hard_drive.#", "1"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "hard_drive.0.bootable", "true"),
+					resource.TestCheckResourceAttrPair("xenserver_vm.test_vm", "hard_drive.0.vdi_uuid", "xenserver_vdi.os_disk", "uuid"),
+				),
+			},
+		},
+	})
+}
+
+func testAccVMResourceSharedVDIConfig(secondVMDrive string) string {
+	return fmt.Sprintf(`
+data "xenserver_sr" "sr" {
+  name_label = "Local storage"
+}
+
+resource "xenserver_vdi" "shared_vdi" {
+  name_label   = "shared-vdi"
+  sr_uuid      = data.xenserver_sr.sr.data_items[0].uuid
+  virtual_size = 1 * 1024 * 1024 * 1024
+  sharable     = true
+}
+
+data "xenserver_network" "network" {}
+
+resource "xenserver_vm" "test_vm_1" {
+  name_label     = "Test shared VDI VM 1"
+  template_name  = "Debian Bullseye 11"
+  static_mem_max = 2 * 1024 * 1024 * 1024
+  vcpus          = 2
+  hard_drive = [
+    {
+      vdi_uuid = xenserver_vdi.shared_vdi.uuid,
+      mode     = "RW"
+    },
+  ]
+  network_interface = [
+    {
+      network_uuid = data.xenserver_network.network.data_items[1].uuid,
+    },
+  ]
+}
+
+resource "xenserver_vm" "test_vm_2" {
+  name_label     = "Test shared VDI VM 2"
+  template_name  = "Debian Bullseye 11"
+  static_mem_max = 2 * 1024 * 1024 * 1024
+  vcpus          = 2
+  hard_drive = [
+    %s
+  ]
+  network_interface = [
+    {
+      network_uuid = data.xenserver_network.network.data_items[1].uuid,
+    },
+  ]
+}
+`, secondVMDrive)
+}
+
+func TestAccVMResourceSharedVDI(t *testing.T) {
+	sharedDrive := `{
+      vdi_uuid = xenserver_vdi.shared_vdi.uuid,
+      mode     = "RW"
+    },`
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + testAccVMResourceSharedVDIConfig(sharedDrive),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm_1", "hard_drive.#", "1"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm_2", "hard_drive.#", "1"),
+					resource.TestCheckResourceAttrPair("xenserver_vm.test_vm_1", "hard_drive.0.vdi_uuid", "xenserver_vm.test_vm_2", "hard_drive.0.vdi_uuid"),
+				),
+			},
+			// detaching the VDI from one VM keeps it attached to the other one
+			{
+				Config: providerConfig + testAccVMResourceSharedVDIConfig(""),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm_1", "hard_drive.#", "1"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm_2", "hard_drive.#", "0"),
+				),
+			},
+		},
+	})
+}
+
+func testAccVMResourceCDROMConfig(cdromAttr string) string {
+	return fmt.Sprintf(`
+data "xenserver_network" "network" {}
+
+resource "xenserver_vm" "test_vm" {
+  name_label = "Test CD-ROM VM"
+  template_name = "Debian Bullseye 11"
+  static_mem_max = 2 * 1024 * 1024 * 1024
+  vcpus         = 2
+  %s
+  network_interface = [
+    {
+      network_uuid = data.xenserver_network.network.data_items[1].uuid,
+    },
+  ]
+}
+`, cdromAttr)
+}
+
+func TestAccVMResourceCDROM(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      providerConfig + testAccVMResourceCDROMConfig(`cdrom = ""`+"\n"+`cdroms = [""]`),
+				ExpectError: regexp.MustCompile(`Invalid Attribute Combination`),
+			},
+			// Create with an empty CD-ROM drive
+			{
+				Config: providerConfig + testAccVMResourceCDROMConfig(`cdrom = ""`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "cdrom", ""),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "cdroms.#", "1"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "cdrom_drives.#", "1"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "cdrom_drives.0.empty", "true"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "cdrom_drives.0.currently_attached", "false"),
+				),
+			},
+			// Manage the CD-ROM drive by VDI UUID
+			{
+				Config: providerConfig + testAccVMResourceCDROMConfig(`cdrom_vdi_uuid = ""`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "cdrom_vdi_uuid", ""),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "cdroms.#", "1"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "cdrom_drives.0.empty", "true"),
+				),
+			},
+			// Stop managing the CD-ROM drive, the empty drive is kept
+			{
+				Config: providerConfig + testAccVMResourceCDROMConfig(""),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "cdrom", ""),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "cdroms.#", "1"),
+				),
+			},
+			// Attach multiple CD-ROM drives
+			{
+				Config: providerConfig + testAccVMResourceCDROMConfig(`cdroms = ["", ""]`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "cdroms.#", "2"),
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "cdrom", ""),
+				),
+			},
+			// Remove the extra CD-ROM drive
+			{
+				Config: providerConfig + testAccVMResourceCDROMConfig(`cdroms = [""]`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_vm.test_vm", "cdroms.#", "1"),
+				),
+			},
+		},
+	})
+}
+
+func TestMergeTrackedKeys(t *testing.T) {
+	testCases := []struct {
+		values      map[string]string
+		trackedKeys string
+		planValues  map[string]string
+		expected    map[string]string
+		expectedKey string
+	}{
+		{
+			values:      map[string]string{"secureboot": "true", "nx": "false"},
+			planValues:  map[string]string{},
+			expected:    map[string]string{"secureboot": "true", "nx": "false"},
+			expectedKey: "",
+		},
+		{
+			values:      map[string]string{"secureboot": "true", "nx": "false"},
+			planValues:  map[string]string{"nx": "true", "acpi": "1"},
+			expected:    map[string]string{"secureboot": "true", "nx": "true", "acpi": "1"},
+			expectedKey: "acpi,nx",
+		},
+		{
+			values:      map[string]string{"secureboot": "true", "nx": "true", "acpi": "1"},
+			trackedKeys: "acpi,nx",
+			planValues:  map[string]string{"acpi": "0"},
+			expected:    map[string]string{"secureboot": "true", "acpi": "0"},
+			expectedKey: "acpi",
+		},
+		{
+			values:      map[string]string{"secureboot": "true"},
+			planValues:  map[string]string{"secureboot": "false"},
+			expected:    map[string]string{"secureboot": "true"},
+			expectedKey: "",
+		},
+	}
+	for _, tc := range testCases {
+		values := maps.Clone(tc.values)
+		keys := mergeTrackedKeys(values, tc.trackedKeys, tc.planValues, vmManagedPlatformKeys)
+		if keys != tc.expectedKey || !maps.Equal(values, tc.expected) {
+			t.Errorf("mergeTrackedKeys(%v, %q, %v) = %v, %q, expected %v, %q", tc.values, tc.trackedKeys, tc.planValues, values, keys, tc.expected, tc.expectedKey)
+		}
+	}
+}
+
+func TestGetXenstoreRebootKeys(t *testing.T) {
+	testCases := []struct {
+		oldData  map[string]string
+		newData  map[string]string
+		expected []string
+	}{
+		{
+			oldData:  map[string]string{"vm-data/hostname": "vm1"},
+			newData:  map[string]string{"vm-data/hostname": "vm2", "vm-data/domain": "example.com"},
+			expected: nil,
+		},
+		{
+			oldData:  map[string]string{"vm-data/hostname": "vm1", "attr/eth0": "1", "data/keep": "1"},
+			newData:  map[string]string{"vm-data/hostname": "vm1", "attr/eth0": "2", "data/keep": "1", "data/new": "1"},
+			expected: []string{"attr/eth0", "data/new"},
+		},
+		{
+			oldData:  map[string]string{"data/removed": "1", "vm-data/removed": "1"},
+			newData:  map[string]string{},
+			expected: []string{"data/removed"},
+		},
+	}
+	for _, tc := range testCases {
+		keys := getXenstoreRebootKeys(tc.oldData, tc.newData)
+		if !slices.Equal(keys, tc.expected) {
+			t.Errorf("getXenstoreRebootKeys(%v, %v) = %v, expected %v", tc.oldData, tc.newData, keys, tc.expected)
+		}
+	}
+}
+
+func TestGetVMState(t *testing.T) {
+	testCases := []struct {
+		otherConfig map[string]string
+		expected    map[string]string
+	}{
+		{
+			otherConfig: map[string]string{"foo": "bar"},
+			expected:    map[string]string{},
+		},
+		// the state written by the older versions of the provider
+		{
+			otherConfig: map[string]string{"foo": "bar", "tf_template_name": "Debian Bullseye 11", "tf_other_config_keys": "foo"},
+			expected:    map[string]string{"template_name": "Debian Bullseye 11", "other_config_keys": "foo"},
+		},
+		{
+			otherConfig: map[string]string{"tf_template_name": "old", "tf_state": `{"template_name":"new","check_ip_timeout":"0"}`},
+			expected:    map[string]string{"template_name": "new", "check_ip_timeout": "0"},
+		},
+		{
+			otherConfig: map[string]string{"tf_template_name": "old", "tf_state": "broken"},
+			expected:    map[string]string{"template_name": "old"},
+		},
+	}
+	for _, tc := range testCases {
+		vmState := getVMState(tc.otherConfig)
+		if !maps.Equal(vmState, tc.expected) {
+			t.Errorf("getVMState(%v) = %v, expected %v", tc.otherConfig, vmState, tc.expected)
+		}
+
+		otherConfig := maps.Clone(tc.otherConfig)
+		err := setVMState(otherConfig, vmState)
+		if err != nil {
+			t.Fatalf("setVMState(%v) failed: %v", tc.otherConfig, err)
+		}
+		if !maps.Equal(getVMOtherConfigWithoutState(otherConfig), getVMOtherConfigWithoutState(tc.otherConfig)) || !maps.Equal(getVMState(otherConfig), vmState) {
+			t.Errorf("setVMState(%v) = %v, expected the legacy keys to be moved to tf_state", tc.otherConfig, otherConfig)
+		}
+	}
+}
+
+func TestAdoptStateVIFDevices(t *testing.T) {
+	stateVIFs := []vifResourceModel{
+		{Network: types.StringValue("network-a"), Device: types.StringValue("0"), MAC: types.StringValue("11:22:33:44:55:66")},
+		{Network: types.StringValue("network-b"), Device: types.StringValue("1"), MAC: types.StringValue("11:22:33:44:55:77")},
+	}
+	testCases := []struct {
+		planVIFs []vifResourceModel
+		expected []string
+	}{
+		{
+			planVIFs: []vifResourceModel{
+				{Network: types.StringValue("network-a"), Device: types.StringUnknown(), MAC: types.StringUnknown()},
+				{Network: types.StringValue("network-b"), Device: types.StringUnknown(), MAC: types.StringUnknown()},
+			},
+			expected: []string{"0", "1"},
+		},
+		{
+			// device 0 is taken by the plan VIF on network-b, the VIF on network-a gets a new device later
+			planVIFs: []vifResourceModel{
+				{Network: types.StringValue("network-a"), Device: types.StringUnknown(), MAC: types.StringUnknown()},
+				{Network: types.StringValue("network-b"), Device: types.StringValue("0"), MAC: types.StringUnknown()},
+			},
+			expected: []string{"", "0"},
+		},
+		{
+			// the MAC doesn't match the VIF in state
+			planVIFs: []vifResourceModel{
+				{Network: types.StringValue("network-a"), Device: types.StringUnknown(), MAC: types.StringValue("11:22:33:44:55:88")},
+			},
+			expected: []string{""},
+		},
+	}
+	for _, tc := range testCases {
+		adoptStateVIFDevices(tc.planVIFs, stateVIFs)
+		for i, vif := range tc.planVIFs {
+			if vif.Device.ValueString() != tc.expected[i] {
+				t.Errorf("adoptStateVIFDevices() device of VIF %d = %q, expected %q", i, vif.Device.ValueString(), tc.expected[i])
+			}
+		}
+	}
+}
+
+func TestGetSetItemsDrift(t *testing.T) {
+	testCases := []struct {
+		prior    []string
+		current  []string
+		expected setItemsDrift
+	}{
+		{
+			prior:    []string{"vdi-a"},
+			current:  []string{"vdi-a"},
+			expected: setItemsDrift{Added: []string{}, Removed: []string{}},
+		},
+		{
+			prior:    []string{"vdi-a"},
+			current:  []string{"vdi-b", "vdi-a"},
+			expected: setItemsDrift{Added: []string{"vdi-b"}, Removed: []string{}},
+		},
+		{
+			prior:    []string{"vdi-b", "vdi-a"},
+			current:  []string{"vdi-c"},
+			expected: setItemsDrift{Added: []string{"vdi-c"}, Removed: []string{"vdi-a", "vdi-b"}},
+		},
+		// nothing to compare with on import
+		{
+			prior:    nil,
+			current:  []string{"vdi-a"},
+			expected: setItemsDrift{Added: []string{}, Removed: []string{}},
+		},
+	}
+	for _, tc := range testCases {
+		sets := []types.Set{}
+		for _, vdiUUIDs := range [][]string{tc.prior, tc.current} {
+			if vdiUUIDs == nil {
+				sets = append(sets, types.SetNull(types.ObjectType{AttrTypes: vbdResourceModelAttrTypes}))
+				continue
+			}
+			values := []attr.Value{}
+			for _, vdiUUID := range vdiUUIDs {
+				value, diags := types.ObjectValueFrom(context.Background(), vbdResourceModelAttrTypes, vbdResourceModel{
+					VDI:               types.StringValue(vdiUUID),
+					VBD:               types.StringValue("OpaqueRef:" + vdiUUID),
+					Mode:              types.StringValue("RW"),
+					Bootable:          types.BoolValue(false),
+					CurrentlyAttached: types.BoolValue(false),
+				})
+				if diags.HasError() {
+					t.Fatalf("unable to build the hard_drive item of %s", vdiUUID)
+				}
+				values = append(values, value)
+			}
+			sets = append(sets, types.SetValueMust(types.ObjectType{AttrTypes: vbdResourceModelAttrTypes}, values))
+		}
+		drift := getSetItemsDrift(sets[0], sets[1], "vdi_uuid")
+		if !drift.Equal(tc.expected) {
+			t.Errorf("getSetItemsDrift(%v, %v) = %v, expected %v", tc.prior, tc.current, drift, tc.expected)
+		}
+	}
+}
+
+func TestGetReportedDrift(t *testing.T) {
+	testCases := []struct {
+		data     string
+		expected map[string]setItemsDrift
+	}{
+		{
+			data:     "",
+			expected: map[string]setItemsDrift{},
+		},
+		{
+			data: `{"hard_drive":{"added":["vdi-b"],"removed":[]}}`,
+			expected: map[string]setItemsDrift{
+				"hard_drive": {Added: []string{"vdi-b"}, Removed: []string{}},
+			},
+		},
+		// the drift is reported again if the private state can't be parsed
+		{
+			data:     `not json`,
+			expected: map[string]setItemsDrift{},
+		},
+	}
+	for _, tc := range testCases {
+		reported := getReportedDrift([]byte(tc.data))
+		if !maps.EqualFunc(reported, tc.expected, setItemsDrift.Equal) {
+			t.Errorf("getReportedDrift(%q) = %v, expected %v", tc.data, reported, tc.expected)
+		}
+	}
+}
+
+// fakeVMClient fakes the platform of a VM, see xapi_client.go
+type fakeVMClient struct {
+	platform map[string]string
+}
+
+func (f *fakeVMClient) GetPlatform(_ *xenapi.Session, _ xenapi.VMRef) (map[string]string, error) {
+	return maps.Clone(f.platform), nil
+}
+
+func (f *fakeVMClient) SetPlatform(_ *xenapi.Session, _ xenapi.VMRef, value map[string]string) error {
+	f.platform = maps.Clone(value)
+	return nil
+}
+
+func TestUpdateCorePerSocket(t *testing.T) {
+	defer func(vm vmClient) { vmAPI = vm }(vmAPI)
+
+	testCases := []struct {
+		platform       map[string]string
+		vcpus          int32
+		coresPerSocket types.Int32
+		expected       string
+		expectedErr    bool
+	}{
+		// not set by the user, keep the one from the template
+		{platform: map[string]string{"cores-per-socket": "2"}, vcpus: 4, coresPerSocket: types.Int32Unknown(), expected: "2"},
+		// not set by the user and not in the template, default to the VCPUs
+		{platform: map[string]string{}, vcpus: 4, coresPerSocket: types.Int32Unknown(), expected: "4"},
+		{platform: map[string]string{"cores-per-socket": "2"}, vcpus: 4, coresPerSocket: types.Int32Value(4), expected: "4"},
+		{platform: map[string]string{"cores-per-socket": "2"}, vcpus: 3, coresPerSocket: types.Int32Value(2), expected: "2", expectedErr: true},
+	}
+	for _, tc := range testCases {
+		fake := &fakeVMClient{platform: tc.platform}
+		vmAPI = fake
+		plan := vmResourceModel{VCPUs: types.Int32Value(tc.vcpus), CorePerSocket: tc.coresPerSocket}
+		err := updateCorePerSocket(nil, "vm", plan)
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("updateCorePerSocket(%d, %v) returned error %v, expected error %t", tc.vcpus, tc.coresPerSocket, err, tc.expectedErr)
+		}
+		if fake.platform["cores-per-socket"] != tc.expected {
+			t.Errorf("updateCorePerSocket(%d, %v) set cores-per-socket %q, expected %q", tc.vcpus, tc.coresPerSocket, fake.platform["cores-per-socket"], tc.expected)
+		}
+	}
+}
+
+func TestGetVMMemory(t *testing.T) {
+	const gib = 1024 * 1024 * 1024
+	testCases := []struct {
+		data     vmResourceModel
+		expected vmMemorySetting
+	}{
+		// only static_mem_max is set, all the others default to it
+		{
+			data: vmResourceModel{
+				StaticMemMax:  types.Int64Value(4 * gib),
+				StaticMemMin:  types.Int64Unknown(),
+				DynamicMemMin: types.Int64Unknown(),
+				DynamicMemMax: types.Int64Unknown(),
+			},
+			expected: vmMemorySetting{4 * gib, 4 * gib, 4 * gib, 4 * gib},
+		},
+		{
+			data: vmResourceModel{
+				StaticMemMax:  types.Int64Value(4 * gib),
+				StaticMemMin:  types.Int64Value(1 * gib),
+				DynamicMemMin: types.Int64Value(2 * gib),
+				DynamicMemMax: types.Int64Value(3 * gib),
+			},
+			expected: vmMemorySetting{1 * gib, 4 * gib, 2 * gib, 3 * gib},
+		},
+	}
+	for _, tc := range testCases {
+		memory := getVMMemory(tc.data)
+		if memory != tc.expected {
+			t.Errorf("getVMMemory() = %v, expected %v", memory, tc.expected)
+		}
+	}
+}
+
+func TestGetBootModeFromVMRecord(t *testing.T) {
+	testCases := []struct {
+		firmware    string
+		domainType  xenapi.DomainType
+		secureBoot  map[string]string
+		expected    string
+		expectedErr bool
+	}{
+		{firmware: "bios", secureBoot: map[string]string{"secureboot": "false"}, expected: "bios"},
+		{firmware: "bios", secureBoot: map[string]string{}, expected: "bios"},
+		{firmware: "uefi", secureBoot: map[string]string{"secureboot": "true"}, expected: "uefi_security"},
+		{firmware: "uefi", secureBoot: map[string]string{"secureboot": "false"}, expected: "uefi"},
+		{firmware: "uefi", secureBoot: map[string]string{"secureboot": "auto"}, expected: "uefi"},
+		{firmware: "uefi", secureBoot: map[string]string{"secureboot": ""}, expected: "uefi"},
+		{firmware: "uefi", secureBoot: map[string]string{}, expected: "uefi"},
+		{firmware: "", secureBoot: map[string]string{"secureboot": "true"}, expectedErr: true},
+		{firmware: "", domainType: xenapi.DomainTypePv, secureBoot: map[string]string{}, expected: ""},
+		{firmware: "", domainType: xenapi.DomainTypePvh, secureBoot: map[string]string{}, expected: ""},
+		{firmware: "", domainType: xenapi.DomainTypeUnspecified, secureBoot: map[string]string{}, expected: ""},
+		{firmware: "uefi", domainType: xenapi.DomainTypePvh, secureBoot: map[string]string{}, expected: "uefi"},
+	}
+	for _, tc := range testCases {
+		if tc.domainType == "" {
+			tc.domainType = xenapi.DomainTypeHvm
+		}
+		vmRecord := xenapi.VMRecord{
+			DomainType:    tc.domainType,
+			HVMBootParams: map[string]string{},
+			Platform:      tc.secureBoot,
+		}
+		if tc.firmware != "" {
+			vmRecord.HVMBootParams["firmware"] = tc.firmware
+		}
+		bootMode, err := getBootModeFromVMRecord(vmRecord)
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("getBootModeFromVMRecord(%q, %v) returned error %v, expected error %t", tc.firmware, tc.secureBoot, err, tc.expectedErr)
+		}
+		if bootMode != tc.expected {
+			t.Errorf("getBootModeFromVMRecord(%q, %v) = %q, expected %q", tc.firmware, tc.secureBoot, bootMode, tc.expected)
+		}
+	}
+}
+
+func TestGetCorePerSocket(t *testing.T) {
+	testCases := []struct {
+		platform    map[string]string
+		vcpusMax    int
+		expected    int32
+		expectedErr bool
+	}{
+		{platform: map[string]string{"cores-per-socket": "2"}, vcpusMax: 4, expected: 2},
+		{platform: map[string]string{}, vcpusMax: 4, expected: 4},
+		{platform: map[string]string{"cores-per-socket": "two"}, vcpusMax: 4, expectedErr: true},
+	}
+	for _, tc := range testCases {
+		vmRecord := xenapi.VMRecord{Platform: tc.platform, VCPUsMax: tc.vcpusMax}
+		socket, err := getCorePerSocket(vmRecord)
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("getCorePerSocket(%v) returned error %v, expected error %t", tc.platform, err, tc.expectedErr)
+		}
+		if socket != tc.expected {
+			t.Errorf("getCorePerSocket(%v) = %d, expected %d", tc.platform, socket, tc.expected)
+		}
+	}
+}
+
+func TestGetBootOrderFromVMRecord(t *testing.T) {
+	testCases := []struct {
+		domainType    xenapi.DomainType
+		bootPolicy    string
+		hvmBootParams map[string]string
+		expected      string
+	}{
+		{domainType: xenapi.DomainTypeHvm, bootPolicy: "BIOS order", hvmBootParams: map[string]string{"order": "dcn"}, expected: "dcn"},
+		{domainType: xenapi.DomainTypeHvm, bootPolicy: "BIOS order", hvmBootParams: map[string]string{}, expected: "cd"},
+		{domainType: xenapi.DomainTypeHvm, bootPolicy: "BIOS order", hvmBootParams: map[string]string{"order": ""}, expected: "cd"},
+		{domainType: xenapi.DomainTypeUnspecified, bootPolicy: "BIOS order", hvmBootParams: map[string]string{}, expected: "cd"},
+		{domainType: xenapi.DomainTypeUnspecified, bootPolicy: "", hvmBootParams: map[string]string{}, expected: ""},
+		{domainType: xenapi.DomainTypePv, bootPolicy: "", hvmBootParams: map[string]string{}, expected: ""},
+		{domainType: xenapi.DomainTypePvh, bootPolicy: "", hvmBootParams: map[string]string{"order": "c"}, expected: "c"},
+	}
+	for _, tc := range testCases {
+		vmRecord := xenapi.VMRecord{DomainType: tc.domainType, HVMBootPolicy: tc.bootPolicy, HVMBootParams: tc.hvmBootParams}
+		bootOrder := getBootOrderFromVMRecord(vmRecord)
+		if bootOrder != tc.expected {
+			t.Errorf("getBootOrderFromVMRecord(%s, %q, %v) = %q, expected %q", tc.domainType, tc.bootPolicy, tc.hvmBootParams, bootOrder, tc.expected)
+		}
+	}
+}
+
+func TestGetCDROMItems(t *testing.T) {
+	testCases := []struct {
+		plan         vmResourceModel
+		expected     []cdromItem
+		manageDrives bool
+	}{
+		{
+			plan:     vmResourceModel{CDROM: types.StringUnknown(), CDROMs: types.ListUnknown(types.StringType), CDROMVDI: types.StringUnknown()},
+			expected: nil,
+		},
+		{
+			plan:     vmResourceModel{CDROM: types.StringValue("debian.iso"), CDROMs: types.ListUnknown(types.StringType), CDROMVDI: types.StringUnknown()},
+			expected: []cdromItem{{isoName: "debian.iso"}},
+		},
+		{
+			plan:     vmResourceModel{CDROM: types.StringUnknown(), CDROMs: types.ListUnknown(types.StringType), CDROMVDI: types.StringValue("vdi-uuid")},
+			expected: []cdromItem{{vdiUUID: "vdi-uuid", byUUID: true}},
+		},
+		{
+			plan: vmResourceModel{
+				CDROM:    types.StringUnknown(),
+				CDROMs:   types.ListValueMust(types.StringType, []attr.Value{types.StringValue("debian.iso"), types.StringValue("")}),
+				CDROMVDI: types.StringUnknown(),
+			},
+			expected:     []cdromItem{{isoName: "debian.iso"}, {isoName: ""}},
+			manageDrives: true,
+		},
+	}
+	for _, tc := range testCases {
+		items, manageDrives, err := getCDROMItems(context.Background(), tc.plan)
+		if err != nil {
+			t.Errorf("getCDROMItems() returned error %v", err)
+		}
+		if !slices.Equal(items, tc.expected) || manageDrives != tc.manageDrives {
+			t.Errorf("getCDROMItems() = %v, %t, expected %v, %t", items, manageDrives, tc.expected, tc.manageDrives)
+		}
+	}
+
+	// an item by VDI UUID matches the drive holding the VDI whatever the ISO name is
+	cd := cdVBD{isoName: "debian.iso", vdiUUID: "vdi-uuid"}
+	if !(cdromItem{vdiUUID: "vdi-uuid", byUUID: true}).matches(cd) {
+		t.Errorf("cdromItem by VDI UUID doesn't match the drive holding the VDI")
+	}
+	if (cdromItem{isoName: "debian.iso", byUUID: false}).matches(cdVBD{isoName: "other.iso", vdiUUID: "vdi-uuid"}) {
+		t.Errorf("cdromItem by ISO name matches the drive holding another ISO")
+	}
+}
+
+func TestSelectConsoleLocation(t *testing.T) {
+	vt100 := xenapi.ConsoleRecord{Protocol: xenapi.ConsoleProtocolVt100, Location: "https://10.0.0.1/console?ref=OpaqueRef:vt100"}
+	rfb := xenapi.ConsoleRecord{Protocol: xenapi.ConsoleProtocolRfb, Location: "https://10.0.0.1/console?ref=OpaqueRef:rfb"}
+	testCases := []struct {
+		consoleRecords []xenapi.ConsoleRecord
+		expected       string
+	}{
+		{consoleRecords: []xenapi.ConsoleRecord{}, expected: ""},
+		{consoleRecords: []xenapi.ConsoleRecord{vt100}, expected: vt100.Location},
+		{consoleRecords: []xenapi.ConsoleRecord{vt100, rfb}, expected: rfb.Location},
+		{consoleRecords: []xenapi.ConsoleRecord{rfb, vt100}, expected: rfb.Location},
+	}
+	for _, tc := range testCases {
+		location := selectConsoleLocation(tc.consoleRecords)
+		if location != tc.expected {
+			t.Errorf("selectConsoleLocation(%v) = %q, expected %q", tc.consoleRecords, location, tc.expected)
+		}
+	}
+}
+
+func TestGetVMsWithName(t *testing.T) {
+	vmRecords := map[xenapi.VMRef]xenapi.VMRecord{
+		"vm1":      {UUID: "uuid-1", NameLabel: "web"},
+		"vm2":      {UUID: "uuid-2", NameLabel: "web"},
+		"vm3":      {UUID: "uuid-3", NameLabel: "db"},
+		"template": {UUID: "uuid-4", NameLabel: "db", IsATemplate: true},
+		"snapshot": {UUID: "uuid-5", NameLabel: "db", IsASnapshot: true},
+		"dom0":     {UUID: "uuid-6", NameLabel: "Control domain on host: xs", IsControlDomain: true},
+	}
+	testCases := []struct {
+		nameLabel   string
+		excludeUUID string
+		expected    []string
+	}{
+		{nameLabel: "web", excludeUUID: "", expected: []string{"uuid-1", "uuid-2"}},
+		{nameLabel: "web", excludeUUID: "uuid-1", expected: []string{"uuid-2"}},
+		{nameLabel: "db", excludeUUID: "uuid-3", expected: []string{}},
+		{nameLabel: "db", excludeUUID: "", expected: []string{"uuid-3"}},
+		{nameLabel: "Control domain on host: xs", excludeUUID: "", expected: []string{}},
+		{nameLabel: "new", excludeUUID: "", expected: []string{}},
+	}
+	for _, tc := range testCases {
+		vmUUIDs := getVMsWithName(vmRecords, tc.nameLabel, tc.excludeUUID)
+		if !slices.Equal(vmUUIDs, tc.expected) {
+			t.Errorf("getVMsWithName(%q, %q) = %v, expected %v", tc.nameLabel, tc.excludeUUID, vmUUIDs, tc.expected)
+		}
+	}
+}
+
+func TestFillSetItemsFromState(t *testing.T) {
+	ctx := context.Background()
+	vifSet := func(vifs ...vifResourceModel) types.Set {
+		values := []attr.Value{}
+		for _, vif := range vifs {
+			value, _ := types.ObjectValueFrom(ctx, vifResourceModelAttrTypes, vif)
+			values = append(values, value)
+		}
+		return types.SetValueMust(types.ObjectType{AttrTypes: vifResourceModelAttrTypes}, values)
+	}
+	stateVIF := func(network string, device string) vifResourceModel {
+		return vifResourceModel{
+			Network:     types.StringValue(network),
+			Device:      types.StringValue(device),
+			VIF:         types.StringValue("OpaqueRef:vif-" + device),
+			MAC:         types.StringValue("00:16:3e:00:00:0" + device),
+			OtherConfig: types.MapValueMust(types.StringType, map[string]attr.Value{}),
+		}
+	}
+	planVIF := func(network string, device types.String) vifResourceModel {
+		return vifResourceModel{
+			Network:     types.StringValue(network),
+			Device:      device,
+			VIF:         types.StringUnknown(),
+			MAC:         types.StringUnknown(),
+			OtherConfig: types.MapUnknown(types.StringType),
+		}
+	}
+	state := vifSet(stateVIF("net-a", "0"), stateVIF("net-b", "1"))
+	testCases := []struct {
+		plan     types.Set
+		expected types.Set
+	}{
+		// unchanged items take the computed values from the state
+		{
+			plan:     vifSet(planVIF("net-a", types.StringUnknown()), planVIF("net-b", types.StringValue("1"))),
+			expected: state,
+		},
+		// the moved and the new items are left unknown
+		{
+			plan:     vifSet(planVIF("net-a", types.StringUnknown()), planVIF("net-c", types.StringValue("1")), planVIF("net-a", types.StringUnknown())),
+			expected: vifSet(stateVIF("net-a", "0"), planVIF("net-c", types.StringValue("1")), planVIF("net-a", types.StringUnknown())),
+		},
+		{
+			plan:     types.SetNull(types.ObjectType{AttrTypes: vifResourceModelAttrTypes}),
+			expected: types.SetNull(types.ObjectType{AttrTypes: vifResourceModelAttrTypes}),
+		},
+	}
+	for _, tc := range testCases {
+		filled, diags := fillSetItemsFromState(ctx, tc.plan, state, nil)
+		if diags.HasError() {
+			t.Errorf("fillSetItemsFromState() returned error %v", diags)
+		}
+		if !filled.Equal(tc.expected) {
+			t.Errorf("fillSetItemsFromState(%v) = %v, expected %v", tc.plan, filled, tc.expected)
+		}
+	}
+
+	// the attributes to keep unknown are not filled
+	filled, _ := fillSetItemsFromState(ctx, vifSet(planVIF("net-a", types.StringValue("0"))), state, []string{"vif_ref"})
+	expected := stateVIF("net-a", "0")
+	expected.VIF = types.StringUnknown()
+	if !filled.Equal(vifSet(expected)) {
+		t.Errorf("fillSetItemsFromState() = %v, expected vif_ref to be unknown", filled)
+	}
+}
+
+func TestUniqueVIFMACValidator(t *testing.T) {
+	ctx := context.Background()
+	vifSet := func(macs ...types.String) types.Set {
+		values := []attr.Value{}
+		for i, mac := range macs {
+			value, _ := types.ObjectValueFrom(ctx, vifResourceModelAttrTypes, vifResourceModel{
+				Network:     types.StringValue("net-a"),
+				Device:      types.StringValue(strconv.Itoa(i)),
+				VIF:         types.StringUnknown(),
+				MAC:         mac,
+				OtherConfig: types.MapNull(types.StringType),
+			})
+			values = append(values, value)
+		}
+		return types.SetValueMust(types.ObjectType{AttrTypes: vifResourceModelAttrTypes}, values)
+	}
+	testCases := []struct {
+		config      types.Set
+		expectedErr bool
+	}{
+		{config: vifSet(types.StringValue("00:16:3e:00:00:01"), types.StringValue("00:16:3e:00:00:02"))},
+		{config: vifSet(types.StringValue("00:16:3e:00:00:01"), types.StringNull(), types.StringUnknown())},
+		{config: vifSet(types.StringValue("00:16:3e:00:00:01"), types.StringValue("00:16:3e:00:00:01")), expectedErr: true},
+		{config: vifSet(types.StringValue("00:16:3e:00:00:0a"), types.StringValue("00-16-3E-00-00-0A")), expectedErr: true},
+	}
+	for _, tc := range testCases {
+		req := validator.SetRequest{Path: path.Root("network_interface"), ConfigValue: tc.config}
+		resp := &validator.SetResponse{}
+		uniqueVIFMACValidator{}.ValidateSet(ctx, req, resp)
+		if resp.Diagnostics.HasError() != tc.expectedErr {
+			t.Errorf("uniqueVIFMACValidator(%v) returned diagnostics %v, expected error %t", tc.config, resp.Diagnostics, tc.expectedErr)
+		}
+	}
+}
+
+func TestGetVIFMTU(t *testing.T) {
+	testCases := []struct {
+		otherConfig map[string]string
+		networkMTU  int
+		expected    int
+	}{
+		{otherConfig: map[string]string{}, networkMTU: 1500, expected: 1500},
+		{otherConfig: map[string]string{"mtu": "9000"}, networkMTU: 1500, expected: 9000},
+		{otherConfig: map[string]string{"mtu": "jumbo"}, networkMTU: 1500, expected: 1500},
+		{otherConfig: map[string]string{"mtu": "0"}, networkMTU: 9000, expected: 9000},
+	}
+	for _, tc := range testCases {
+		mtu := getVIFMTU(tc.otherConfig, tc.networkMTU)
+		if mtu != tc.expected {
+			t.Errorf("getVIFMTU(%v, %d) = %d, expected %d", tc.otherConfig, tc.networkMTU, mtu, tc.expected)
+		}
+	}
+}
+
+func TestIsVMStable(t *testing.T) {
+	testCases := []struct {
+		record   xenapi.VMRecord
+		expected bool
+	}{
+		{record: xenapi.VMRecord{PowerState: xenapi.VMPowerStateRunning}, expected: true},
+		{record: xenapi.VMRecord{PowerState: xenapi.VMPowerStateHalted}, expected: false},
+		{record: xenapi.VMRecord{PowerState: xenapi.VMPowerStatePaused}, expected: false},
+		{record: xenapi.VMRecord{PowerState: xenapi.VMPowerStateRunning, CurrentOperations: map[string]xenapi.VMOperations{"OpaqueRef:task": xenapi.VMOperationsPoolMigrate}}, expected: false},
+	}
+	for _, tc := range testCases {
+		stable := isVMStable(tc.record)
+		if stable != tc.expected {
+			t.Errorf("isVMStable(%v) = %t, expected %t", tc.record, stable, tc.expected)
+		}
+	}
+}
+
+func TestIsVMRebootRequired(t *testing.T) {
+	testCases := []struct {
+		record   xenapi.VMRecord
+		expected bool
+	}{
+		{record: xenapi.VMRecord{PowerState: xenapi.VMPowerStateRunning, RequiresReboot: true}, expected: true},
+		{record: xenapi.VMRecord{PowerState: xenapi.VMPowerStateRunning, RequiresReboot: false}, expected: false},
+		{record: xenapi.VMRecord{PowerState: xenapi.VMPowerStateHalted, RequiresReboot: true}, expected: false},
+	}
+	for _, tc := range testCases {
+		required := isVMRebootRequired(tc.record)
+		if required != tc.expected {
+			t.Errorf("isVMRebootRequired(%v) = %t, expected %t", tc.record, required, tc.expected)
+		}
+	}
+}
+
+func TestGetOtherAttachedVMs(t *testing.T) {
+	testCases := []struct {
+		vbdRecords []xenapi.VBDRecord
+		expected   []xenapi.VMRef
+	}{
+		{vbdRecords: nil, expected: nil},
+		{vbdRecords: []xenapi.VBDRecord{{VM: "vm-1", CurrentlyAttached: true}}, expected: nil},
+		{vbdRecords: []xenapi.VBDRecord{{VM: "vm-2", CurrentlyAttached: false}}, expected: nil},
+		{vbdRecords: []xenapi.VBDRecord{{VM: "vm-1", CurrentlyAttached: true}, {VM: "vm-2", CurrentlyAttached: true}, {VM: "vm-2", CurrentlyAttached: true}, {VM: "vm-3", CurrentlyAttached: true}}, expected: []xenapi.VMRef{"vm-2", "vm-3"}},
+	}
+	for _, tc := range testCases {
+		vmRefs := getOtherAttachedVMs(tc.vbdRecords, "vm-1")
+		if !slices.Equal(vmRefs, tc.expected) {
+			t.Errorf("getOtherAttachedVMs(%v) = %v, expected %v", tc.vbdRecords, vmRefs, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package xenserver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"xenapi"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var (
+	_ resource.Resource                = &vmTemplateResource{}
+	_ resource.ResourceWithConfigure   = &vmTemplateResource{}
+	_ resource.ResourceWithImportState = &vmTemplateResource{}
+)
+
+func NewVMTemplateResource() resource.Resource {
+	return &vmTemplateResource{}
+}
+
+// vmTemplateResource defines the resource implementation.
+type vmTemplateResource struct {
+	session          *xenapi.Session
+	operationLimiter operationLimiter
+}
+
+func (r *vmTemplateResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_vm_template"
+}
+
+func (r *vmTemplateResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Provides a VM template resource, which is useful for golden image pipelines. The template is cloned from a configured halted VM and marked as a template, the source VM is kept as it is. " +
+			"`name_label`, `name_description` and `user_version` changed outside of terraform are detected as drift." +
+			"\n\n-> **Note:** The disks of the template are destroyed with the resource.",
+		Attributes: vmTemplateSchema(),
+	}
+}
+
+// Set the parameter of the resource, pass value from provider
+func (r *vmTemplateResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+	providerData, ok := req.ProviderData.(*xsProvider)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *xenserver.xsProvider, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+	r.session = providerData.session
+	r.operationLimiter = providerData.operationLimiter
+}
+
+func (r *vmTemplateResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data vmTemplateResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	tflog.Debug(ctx, "Creating VM template...")
+	templateRef, err := createVMTemplate(r.session, data)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to create VM template",
+			err.Error(),
+		)
+		if string(templateRef) != "" {
+			err = cleanupSnapshotResource(r.session, templateRef)
+			if err != nil {
+				resp.Diagnostics.AddError(
+					"Error cleaning up VM template resource",
+					err.Error(),
+				)
+			}
+		}
+		return
+	}
+	templateRecord, err := xenapi.VM.GetRecord(r.session, templateRef)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get VM template record",
+			err.Error(),
+		)
+		err = cleanupSnapshotResource(r.session, templateRef)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Error cleaning up VM template resource",
+				err.Error(),
+			)
+		}
+		return
+	}
+	updateVMTemplateResourceModelComputed(templateRecord, &data)
+	tflog.Debug(ctx, "VM template created")
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *vmTemplateResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data vmTemplateResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	// Overwrite data with refreshed resource state
+	templateRef, err := withSessionRetry(r.session, func() (xenapi.VMRef, error) {
+		return xenapi.VM.GetByUUID(r.session, data.UUID.ValueString())
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get VM template ref",
+			err.Error(),
+		)
+		return
+	}
+	templateRecord, err := xenapi.VM.GetRecord(r.session, templateRef)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get VM template record",
+			err.Error(),
+		)
+		return
+	}
+	updateVMTemplateResourceModel(templateRecord, &data)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *vmTemplateResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state vmTemplateResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Checking if configuration changes are allowed
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	err = vmTemplateResourceModelUpdateCheck(plan, state)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error update xenserver_vm_template configuration",
+			err.Error(),
+		)
+		return
+	}
+
+	// Update the resource with new configuration
+	templateRef, err := withSessionRetry(r.session, func() (xenapi.VMRef, error) {
+		return xenapi.VM.GetByUUID(r.session, plan.UUID.ValueString())
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get VM template ref",
+			err.Error(),
+		)
+		return
+	}
+	err = vmTemplateResourceModelUpdate(r.session, templateRef, plan)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to update VM template resource",
+			err.Error(),
+		)
+		return
+	}
+	templateRecord, err := xenapi.VM.GetRecord(r.session, templateRef)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get VM template record",
+			err.Error(),
+		)
+		return
+	}
+	updateVMTemplateResourceModelComputed(templateRecord, &plan)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *vmTemplateResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data vmTemplateResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := r.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	templateRef, err := withSessionRetry(r.session, func() (xenapi.VMRef, error) {
+		return xenapi.VM.GetByUUID(r.session, data.UUID.ValueString())
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get VM template ref",
+			err.Error(),
+		)
+		return
+	}
+	// all the disks are created for the template, destroy them with the template
+	err = cleanupSnapshotResource(r.session, templateRef)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to delete VM template resource",
+			err.Error(),
+		)
+		return
+	}
+}
+
+func (r *vmTemplateResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("uuid"), req, resp)
+}
@@ -0,0 +1,77 @@
+package xenserver
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func testAccVMTemplateResourceConfig(name_label string, user_version int) string {
+	return fmt.Sprintf(`
+data "xenserver_sr" "sr" {
+	name_label = "Local storage"
+}
+
+resource "xenserver_vdi" "vdi1" {
+	name_label   = "A test vdi"
+	sr_uuid      = data.xenserver_sr.sr.data_items[0].uuid
+	virtual_size = 1 * 1024 * 1024 * 1024
+}
+
+resource "xenserver_vm" "vm" {
+	name_label     = "A test virtual-machine"
+	template_name  = "Windows 11"
+	static_mem_max = 4 * 1024 * 1024 * 1024
+	vcpus          = 2
+	hard_drive = [
+		{
+		vdi_uuid = xenserver_vdi.vdi1.uuid,
+		mode     = "RW"
+		},
+	]
+}
+
+resource "xenserver_vm_template" "test_template" {
+	name_label       = "%s"
+	name_description = "A golden image template"
+	source_vm_uuid   = xenserver_vm.vm.uuid
+	user_version     = %d
+}
+`, name_label, user_version)
+}
+
+func TestAccVMTemplateResource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Create and Read testing
+			{
+				Config: providerConfig + testAccVMTemplateResourceConfig("Test template A", 1),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_vm_template.test_template", "name_label", "Test template A"),
+					resource.TestCheckResourceAttr("xenserver_vm_template.test_template", "name_description", "A golden image template"),
+					resource.TestCheckResourceAttr("xenserver_vm_template.test_template", "user_version", "1"),
+					resource.TestCheckResourceAttrPair("xenserver_vm_template.test_template", "source_vm_uuid", "xenserver_vm.vm", "uuid"),
+					resource.TestCheckResourceAttrSet("xenserver_vm_template.test_template", "uuid"),
+				),
+			},
+			// ImportState testing
+			{
+				ResourceName:            "xenserver_vm_template.test_template",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{},
+			},
+			// Update and Read testing
+			{
+				Config: providerConfig + testAccVMTemplateResourceConfig("Test template B", 2),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("xenserver_vm_template.test_template", "name_label", "Test template B"),
+					resource.TestCheckResourceAttr("xenserver_vm_template.test_template", "user_version", "2"),
+				),
+			},
+			// Delete testing automatically occurs in TestCase
+		},
+	})
+}
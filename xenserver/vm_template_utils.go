@@ -0,0 +1,285 @@
+package xenserver
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64default"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"xenapi"
+)
+
+type vmTemplateResourceModel struct {
+	NameLabel       types.String `tfsdk:"name_label"`
+	NameDescription types.String `tfsdk:"name_description"`
+	SourceVM        types.String `tfsdk:"source_vm_uuid"`
+	UserVersion     types.Int64  `tfsdk:"user_version"`
+	ReferenceLabel  types.String `tfsdk:"reference_label"`
+	UUID            types.String `tfsdk:"uuid"`
+	ID              types.String `tfsdk:"id"`
+}
+
+func vmTemplateSchema() map[string]schema.Attribute {
+	return map[string]schema.Attribute{
+		"name_label": schema.StringAttribute{
+			MarkdownDescription: "The name of the template.",
+			Required:            true,
+		},
+		"name_description": schema.StringAttribute{
+			MarkdownDescription: "The description of the template, default to be `\"\"`.",
+			Optional:            true,
+			Computed:            true,
+			Default:             stringdefault.StaticString(""),
+		},
+		"source_vm_uuid": schema.StringAttribute{
+			MarkdownDescription: "The UUID of the configured VM to create the template from, the VM should be halted." +
+				"\n\n-> **Note:** `source_vm_uuid` is not allowed to be updated.",
+			Required: true,
+		},
+		"user_version": schema.Int64Attribute{
+			MarkdownDescription: "The user-defined version of the template, default to be `1`. Increase it when the template is rebuilt to track the image versions.",
+			Optional:            true,
+			Computed:            true,
+			Default:             int64default.StaticInt64(1),
+		},
+		"reference_label": schema.StringAttribute{
+			MarkdownDescription: "The reference label of the template, which is inherited from the template the source VM was created from.",
+			Computed:            true,
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.UseStateForUnknown(),
+			},
+		},
+		"uuid": schema.StringAttribute{
+			MarkdownDescription: "The UUID of the template.",
+			Computed:            true,
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.UseStateForUnknown(),
+			},
+		},
+		"id": schema.StringAttribute{
+			MarkdownDescription: "The test ID of the template.",
+			Computed:            true,
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.UseStateForUnknown(),
+			},
+		},
+	}
+}
+
+// createVMTemplate clones the halted source VM and marks the clone as a template, the source VM is kept as it is
+func createVMTemplate(session *xenapi.Session, data vmTemplateResourceModel) (xenapi.VMRef, error) {
+	var templateRef xenapi.VMRef
+	sourceRef, err := withSessionRetry(session, func() (xenapi.VMRef, error) {
+		return xenapi.VM.GetByUUID(session, data.SourceVM.ValueString())
+	})
+	if err != nil {
+		return templateRef, errors.New(err.Error())
+	}
+	powerState, err := xenapi.VM.GetPowerState(session, sourceRef)
+	if err != nil {
+		return templateRef, errors.New(err.Error())
+	}
+	if powerState != xenapi.VMPowerStateHalted {
+		return templateRef, errors.New("the source VM " + data.SourceVM.ValueString() + " is " + string(powerState) + ", shut it down before creating the template")
+	}
+
+	templateRef, err = xenapi.VM.Clone(session, sourceRef, data.NameLabel.ValueString())
+	if err != nil {
+		return templateRef, errors.New(err.Error())
+	}
+	err = xenapi.VM.SetIsATemplate(session, templateRef, true)
+	if err != nil {
+		return templateRef, errors.New(err.Error())
+	}
+
+	// record where the template comes from for import
+	otherConfig, err := xenapi.VM.GetOtherConfig(session, templateRef)
+	if err != nil {
+		return templateRef, errors.New(err.Error())
+	}
+	otherConfig["tf_source_vm_uuid"] = data.SourceVM.ValueString()
+	err = xenapi.VM.SetOtherConfig(session, templateRef, otherConfig)
+	if err != nil {
+		return templateRef, errors.New(err.Error())
+	}
+
+	return templateRef, vmTemplateResourceModelUpdate(session, templateRef, data)
+}
+
+func vmTemplateResourceModelUpdate(session *xenapi.Session, ref xenapi.VMRef, data vmTemplateResourceModel) error {
+	err := xenapi.VM.SetNameLabel(session, ref, data.NameLabel.ValueString())
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	err = xenapi.VM.SetNameDescription(session, ref, data.NameDescription.ValueString())
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	err = xenapi.VM.SetUserVersion(session, ref, int(data.UserVersion.ValueInt64()))
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	return nil
+}
+
+func updateVMTemplateResourceModel(record xenapi.VMRecord, data *vmTemplateResourceModel) {
+	data.NameLabel = types.StringValue(record.NameLabel)
+	data.NameDescription = types.StringValue(record.NameDescription)
+	data.UserVersion = types.Int64Value(int64(record.UserVersion))
+	if sourceVM, ok := record.OtherConfig["tf_source_vm_uuid"]; ok {
+		data.SourceVM = types.StringValue(sourceVM)
+	}
+	updateVMTemplateResourceModelComputed(record, data)
+}
+
+func updateVMTemplateResourceModelComputed(record xenapi.VMRecord, data *vmTemplateResourceModel) {
+	data.ReferenceLabel = types.StringValue(record.ReferenceLabel)
+	data.UUID = types.StringValue(record.UUID)
+	data.ID = types.StringValue(record.UUID)
+}
+
+func vmTemplateResourceModelUpdateCheck(plan vmTemplateResourceModel, state vmTemplateResourceModel) error {
+	if plan.SourceVM != state.SourceVM {
+		return errors.New(`"source_vm_uuid" doesn't expected to be updated`)
+	}
+	return nil
+}
+
+type vmTemplatesDataSourceModel struct {
+	NameLabel types.String           `tfsdk:"name_label"`
+	UUID      types.String           `tfsdk:"uuid"`
+	DataItems []vmTemplateRecordData `tfsdk:"data_items"`
+}
+
+type vmTemplateRecordData struct {
+	UUID                 types.String `tfsdk:"uuid"`
+	NameLabel            types.String `tfsdk:"name_label"`
+	NameDescription      types.String `tfsdk:"name_description"`
+	ReferenceLabel       types.String `tfsdk:"reference_label"`
+	IsDefaultTemplate    types.Bool   `tfsdk:"is_default_template"`
+	StaticMemMax         types.Int64  `tfsdk:"static_mem_max"`
+	VCPUs                types.Int64  `tfsdk:"vcpus"`
+	RecommendedMemoryMin types.Int64  `tfsdk:"recommended_memory_min"`
+	RecommendedMemoryMax types.Int64  `tfsdk:"recommended_memory_max"`
+	RecommendedVCPUsMin  types.Int64  `tfsdk:"recommended_vcpus_min"`
+	RecommendedVCPUsMax  types.Int64  `tfsdk:"recommended_vcpus_max"`
+}
+
+// templateRecommendations is the format of the VM recommendations, for example:
+// <restrictions><restriction field="memory-static-max" max="137438953472" /><restriction field="vcpus-max" max="32" /></restrictions>
+type templateRecommendations struct {
+	Restrictions []struct {
+		Field string `xml:"field,attr"`
+		Min   string `xml:"min,attr"`
+		Max   string `xml:"max,attr"`
+	} `xml:"restriction"`
+}
+
+// templateRecommendationRanges is the memory and vcpus ranges recommended by the template, a bound is -1 when
+// it isn't recommended
+type templateRecommendationRanges struct {
+	MemoryMin int64
+	MemoryMax int64
+	VCPUsMin  int64
+	VCPUsMax  int64
+}
+
+func parseRecommendationBound(field string, value string) (int64, error) {
+	if value == "" {
+		return -1, nil
+	}
+	bound, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return -1, errors.New("unable to parse the recommended " + field + ": " + err.Error())
+	}
+	return bound, nil
+}
+
+func getTemplateRecommendations(recommendations string) (templateRecommendationRanges, error) {
+	ranges := templateRecommendationRanges{MemoryMin: -1, MemoryMax: -1, VCPUsMin: -1, VCPUsMax: -1}
+	if recommendations == "" {
+		return ranges, nil
+	}
+	var parsed templateRecommendations
+	err := xml.Unmarshal([]byte(recommendations), &parsed)
+	if err != nil {
+		return ranges, errors.New("unable to parse the recommendations: " + err.Error())
+	}
+	for _, restriction := range parsed.Restrictions {
+		var minBound, maxBound *int64
+		switch restriction.Field {
+		case "memory-static-max":
+			minBound, maxBound = &ranges.MemoryMin, &ranges.MemoryMax
+		case "vcpus-max":
+			minBound, maxBound = &ranges.VCPUsMin, &ranges.VCPUsMax
+		default:
+			continue
+		}
+		*minBound, err = parseRecommendationBound(restriction.Field, restriction.Min)
+		if err != nil {
+			return ranges, err
+		}
+		*maxBound, err = parseRecommendationBound(restriction.Field, restriction.Max)
+		if err != nil {
+			return ranges, err
+		}
+	}
+	return ranges, nil
+}
+
+func recommendationBoundValue(bound int64) types.Int64 {
+	if bound < 0 {
+		return types.Int64Null()
+	}
+	return types.Int64Value(bound)
+}
+
+func updateVMTemplateRecordData(record xenapi.VMRecord, data *vmTemplateRecordData) error {
+	data.UUID = types.StringValue(record.UUID)
+	data.NameLabel = types.StringValue(record.NameLabel)
+	data.NameDescription = types.StringValue(record.NameDescription)
+	data.ReferenceLabel = types.StringValue(record.ReferenceLabel)
+	data.IsDefaultTemplate = types.BoolValue(record.IsDefaultTemplate)
+	data.StaticMemMax = types.Int64Value(int64(record.MemoryStaticMax))
+	data.VCPUs = types.Int64Value(int64(record.VCPUsAtStartup))
+	ranges, err := getTemplateRecommendations(record.Recommendations)
+	if err != nil {
+		return err
+	}
+	data.RecommendedMemoryMin = recommendationBoundValue(ranges.MemoryMin)
+	data.RecommendedMemoryMax = recommendationBoundValue(ranges.MemoryMax)
+	data.RecommendedVCPUsMin = recommendationBoundValue(ranges.VCPUsMin)
+	data.RecommendedVCPUsMax = recommendationBoundValue(ranges.VCPUsMax)
+	return nil
+}
+
+// checkTemplateRecommendations returns the messages keyed by the VM attributes which are out of the range
+// recommended by the template
+func checkTemplateRecommendations(ranges templateRecommendationRanges, staticMemMax int64, vcpus int64) map[string]string {
+	violations := map[string]string{}
+	if (ranges.MemoryMin >= 0 && staticMemMax < ranges.MemoryMin) || (ranges.MemoryMax >= 0 && staticMemMax > ranges.MemoryMax) {
+		violations["static_mem_max"] = fmt.Sprintf("%d is out of the range recommended by the template, %s", staticMemMax, formatRecommendationRange(ranges.MemoryMin, ranges.MemoryMax))
+	}
+	if (ranges.VCPUsMin >= 0 && vcpus < ranges.VCPUsMin) || (ranges.VCPUsMax >= 0 && vcpus > ranges.VCPUsMax) {
+		violations["vcpus"] = fmt.Sprintf("%d is out of the range recommended by the template, %s", vcpus, formatRecommendationRange(ranges.VCPUsMin, ranges.VCPUsMax))
+	}
+	return violations
+}
+
+func formatRecommendationRange(minBound int64, maxBound int64) string {
+	switch {
+	case minBound >= 0 && maxBound >= 0:
+		return fmt.Sprintf("expected between %d and %d", minBound, maxBound)
+	case minBound >= 0:
+		return fmt.Sprintf("expected at least %d", minBound)
+	default:
+		return fmt.Sprintf("expected at most %d", maxBound)
+	}
+}
@@ -0,0 +1,184 @@
+package xenserver
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+
+	"xenapi"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &vmTemplatesDataSource{}
+	_ datasource.DataSourceWithConfigure = &vmTemplatesDataSource{}
+)
+
+// NewVMTemplatesDataSource is a helper function to simplify the provider implementation.
+func NewVMTemplatesDataSource() datasource.DataSource {
+	return &vmTemplatesDataSource{}
+}
+
+// vmTemplatesDataSource is the data source implementation.
+type vmTemplatesDataSource struct {
+	session          *xenapi.Session
+	operationLimiter operationLimiter
+}
+
+// Metadata returns the data source type name.
+func (d *vmTemplatesDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_vm_templates"
+}
+
+// Schema defines the schema for the data source.
+func (d *vmTemplatesDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Provides information about the VM templates, which is useful to check a template exists and read its recommended sizing before creating a VM. Snapshots are not included.",
+
+		Attributes: map[string]schema.Attribute{
+			"name_label": schema.StringAttribute{
+				MarkdownDescription: "The name of the template.",
+				Optional:            true,
+			},
+			"uuid": schema.StringAttribute{
+				MarkdownDescription: "The UUID of the template.",
+				Optional:            true,
+			},
+			"data_items": schema.ListNestedAttribute{
+				MarkdownDescription: "The return items of templates.",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"uuid": schema.StringAttribute{
+							MarkdownDescription: "The UUID of the template.",
+							Computed:            true,
+						},
+						"name_label": schema.StringAttribute{
+							MarkdownDescription: "The name of the template.",
+							Computed:            true,
+						},
+						"name_description": schema.StringAttribute{
+							MarkdownDescription: "The human-readable description of the template.",
+							Computed:            true,
+						},
+						"reference_label": schema.StringAttribute{
+							MarkdownDescription: "The reference label of the template, which is stable across XenServer versions.",
+							Computed:            true,
+						},
+						"is_default_template": schema.BoolAttribute{
+							MarkdownDescription: "True if the template is a default template shipped with XenServer.",
+							Computed:            true,
+						},
+						"static_mem_max": schema.Int64Attribute{
+							MarkdownDescription: "The default maximum static memory of the template in bytes.",
+							Computed:            true,
+						},
+						"vcpus": schema.Int64Attribute{
+							MarkdownDescription: "The default number of VCPUs of the template.",
+							Computed:            true,
+						},
+						"recommended_memory_min": schema.Int64Attribute{
+							MarkdownDescription: "The minimum memory in bytes recommended by the template, null if the template doesn't recommend it.",
+							Computed:            true,
+						},
+						"recommended_memory_max": schema.Int64Attribute{
+							MarkdownDescription: "The maximum memory in bytes recommended by the template, null if the template doesn't recommend it.",
+							Computed:            true,
+						},
+						"recommended_vcpus_min": schema.Int64Attribute{
+							MarkdownDescription: "The minimum number of VCPUs recommended by the template, null if the template doesn't recommend it.",
+							Computed:            true,
+						},
+						"recommended_vcpus_max": schema.Int64Attribute{
+							MarkdownDescription: "The maximum number of VCPUs recommended by the template, null if the template doesn't recommend it.",
+							Computed:            true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *vmTemplatesDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	providerData, ok := req.ProviderData.(*xsProvider)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *xenserver.xsProvider, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+	d.session = providerData.session
+	d.operationLimiter = providerData.operationLimiter
+}
+
+func (d *vmTemplatesDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data vmTemplatesDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, done, err := d.operationLimiter.start(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start the operation", err.Error())
+		return
+	}
+	defer done()
+
+	vmRecords, err := withSessionRetry(d.session, func() (map[xenapi.VMRef]xenapi.VMRecord, error) {
+		return xenapi.VM.GetAllRecords(d.session)
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to get VM records",
+			err.Error(),
+		)
+		return
+	}
+
+	var templateItems []vmTemplateRecordData
+
+	for _, vmRecord := range vmRecords {
+		if !vmRecord.IsATemplate || vmRecord.IsASnapshot {
+			continue
+		}
+		if !data.NameLabel.IsNull() && vmRecord.NameLabel != data.NameLabel.ValueString() {
+			continue
+		}
+		if !data.UUID.IsNull() && vmRecord.UUID != data.UUID.ValueString() {
+			continue
+		}
+
+		var templateData vmTemplateRecordData
+		err = updateVMTemplateRecordData(vmRecord, &templateData)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Unable to update VM template record data",
+				"Template "+vmRecord.UUID+": "+err.Error(),
+			)
+			return
+		}
+		templateItems = append(templateItems, templateData)
+	}
+
+	// sort templateItems by NameLabel
+	sort.Slice(templateItems, func(i, j int) bool {
+		return templateItems[i].NameLabel.ValueString() < templateItems[j].NameLabel.ValueString()
+	})
+
+	data.DataItems = templateItems
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+}
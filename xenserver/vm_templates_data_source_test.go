@@ -0,0 +1,91 @@
+package xenserver
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func testAccVMTemplatesDataSourceConfig(name_label string) string {
+	return fmt.Sprintf(`
+data "xenserver_vm_templates" "test_templates_data" {
+	name_label = "%s"
+}
+`, name_label)
+}
+
+func TestAccVMTemplatesDataSource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + testAccVMTemplatesDataSourceConfig("Windows 11"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.xenserver_vm_templates.test_templates_data", "name_label", "Windows 11"),
+					resource.TestCheckResourceAttr("data.xenserver_vm_templates.test_templates_data", "data_items.#", "1"),
+					resource.TestCheckResourceAttr("data.xenserver_vm_templates.test_templates_data", "data_items.0.is_default_template", "true"),
+					resource.TestCheckResourceAttrSet("data.xenserver_vm_templates.test_templates_data", "data_items.0.recommended_memory_max"),
+				),
+			},
+		},
+	})
+}
+
+func TestGetTemplateRecommendations(t *testing.T) {
+	testCases := []struct {
+		recommendations string
+		ranges          templateRecommendationRanges
+		expectErr       bool
+	}{
+		{recommendations: "", ranges: templateRecommendationRanges{MemoryMin: -1, MemoryMax: -1, VCPUsMin: -1, VCPUsMax: -1}},
+		{
+			recommendations: `<restrictions><restriction field="memory-static-max" max="137438953472" /><restriction field="vcpus-max" max="32" /><restriction property="number-of-vbds" max="255" /><restriction field="has-vendor-device" value="false" /></restrictions>`,
+			ranges:          templateRecommendationRanges{MemoryMin: -1, MemoryMax: 137438953472, VCPUsMin: -1, VCPUsMax: 32},
+		},
+		{
+			recommendations: `<restrictions><restriction field="memory-static-max" min="1073741824" /><restriction field="vcpus-max" min="2" max="16" /></restrictions>`,
+			ranges:          templateRecommendationRanges{MemoryMin: 1073741824, MemoryMax: -1, VCPUsMin: 2, VCPUsMax: 16},
+		},
+		{recommendations: `<restrictions><restriction field="vcpus-max" max="many" /></restrictions>`, expectErr: true},
+		{recommendations: `<restrictions>`, expectErr: true},
+	}
+	for _, tc := range testCases {
+		ranges, err := getTemplateRecommendations(tc.recommendations)
+		if tc.expectErr {
+			if err == nil {
+				t.Errorf("getTemplateRecommendations(%q) expected an error", tc.recommendations)
+			}
+			continue
+		}
+		if err != nil || ranges != tc.ranges {
+			t.Errorf("getTemplateRecommendations(%q) = %+v, %v, expected %+v", tc.recommendations, ranges, err, tc.ranges)
+		}
+	}
+}
+
+func TestCheckTemplateRecommendations(t *testing.T) {
+	ranges := templateRecommendationRanges{MemoryMin: 1024, MemoryMax: 4096, VCPUsMin: -1, VCPUsMax: 8}
+	testCases := []struct {
+		staticMemMax int64
+		vcpus        int64
+		attributes   []string
+	}{
+		{staticMemMax: 2048, vcpus: 4},
+		{staticMemMax: 512, vcpus: 4, attributes: []string{"static_mem_max"}},
+		{staticMemMax: 8192, vcpus: 16, attributes: []string{"static_mem_max", "vcpus"}},
+		{staticMemMax: 4096, vcpus: 1},
+	}
+	for _, tc := range testCases {
+		violations := checkTemplateRecommendations(ranges, tc.staticMemMax, tc.vcpus)
+		if len(violations) != len(tc.attributes) {
+			t.Errorf("checkTemplateRecommendations(%d, %d) = %v, expected violations of %v", tc.staticMemMax, tc.vcpus, violations, tc.attributes)
+			continue
+		}
+		for _, attribute := range tc.attributes {
+			if _, ok := violations[attribute]; !ok {
+				t.Errorf("checkTemplateRecommendations(%d, %d) = %v, expected violations of %v", tc.staticMemMax, tc.vcpus, violations, tc.attributes)
+			}
+		}
+	}
+}
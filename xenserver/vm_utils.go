@@ -2,21 +2,31 @@ package xenserver
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"net"
 	"regexp"
 	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/cenkalti/backoff/v4"
+	"github.com/hashicorp/terraform-plugin-framework-validators/float64validator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/mapvalidator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/setvalidator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64default"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapdefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
@@ -36,6 +46,26 @@ type vmDataSourceModel struct {
 	DataItems []vmRecordData `tfsdk:"data_items"`
 }
 
+type vmPlacementDataSourceModel struct {
+	UUID          types.String `tfsdk:"uuid"`
+	PowerState    types.String `tfsdk:"power_state"`
+	ResidentOn    types.String `tfsdk:"resident_on"`
+	PossibleHosts types.List   `tfsdk:"possible_hosts"`
+}
+
+type vmMetricsDataSourceModel struct {
+	UUID             types.String `tfsdk:"uuid"`
+	PowerState       types.String `tfsdk:"power_state"`
+	VCPUsNumber      types.Int32  `tfsdk:"vcpus_number"`
+	VCPUsUtilisation types.Map    `tfsdk:"vcpus_utilisation"`
+	MemoryActual     types.Int64  `tfsdk:"memory_actual"`
+	StartTime        types.String `tfsdk:"start_time"`
+	LastUpdated      types.String `tfsdk:"last_updated"`
+	GuestMetricsLive types.Bool   `tfsdk:"guest_metrics_live"`
+	GuestMemory      types.Map    `tfsdk:"guest_memory"`
+	GuestDisks       types.Map    `tfsdk:"guest_disks"`
+}
+
 type vmRecordData struct {
 	UUID                        types.String  `tfsdk:"uuid"`
 	AllowedOperations           types.List    `tfsdk:"allowed_operations"`
@@ -131,26 +161,62 @@ type vmRecordData struct {
 
 // vmResourceModel describes the resource data model.
 type vmResourceModel struct {
-	NameLabel         types.String `tfsdk:"name_label"`
-	NameDescription   types.String `tfsdk:"name_description"`
-	TemplateName      types.String `tfsdk:"template_name"`
-	StaticMemMin      types.Int64  `tfsdk:"static_mem_min"`
-	StaticMemMax      types.Int64  `tfsdk:"static_mem_max"`
-	DynamicMemMin     types.Int64  `tfsdk:"dynamic_mem_min"`
-	DynamicMemMax     types.Int64  `tfsdk:"dynamic_mem_max"`
-	VCPUs             types.Int32  `tfsdk:"vcpus"`
-	BootMode          types.String `tfsdk:"boot_mode"`
-	BootOrder         types.String `tfsdk:"boot_order"`
-	CorePerSocket     types.Int32  `tfsdk:"cores_per_socket"`
-	OtherConfig       types.Map    `tfsdk:"other_config"`
-	HardDrive         types.Set    `tfsdk:"hard_drive"`
-	SRForFullDiskCopy types.String `tfsdk:"sr_for_full_disk_copy"`
-	NetworkInterface  types.Set    `tfsdk:"network_interface"`
-	CDROM             types.String `tfsdk:"cdrom"`
-	UUID              types.String `tfsdk:"uuid"`
-	ID                types.String `tfsdk:"id"`
-	DefaultIP         types.String `tfsdk:"default_ip"`
-	CheckIPTimeout    types.Int64  `tfsdk:"check_ip_timeout"`
+	NameLabel            types.String  `tfsdk:"name_label"`
+	NameDescription      types.String  `tfsdk:"name_description"`
+	TemplateName         types.String  `tfsdk:"template_name"`
+	StaticMemMin         types.Int64   `tfsdk:"static_mem_min"`
+	StaticMemMax         types.Int64   `tfsdk:"static_mem_max"`
+	DynamicMemMin        types.Int64   `tfsdk:"dynamic_mem_min"`
+	DynamicMemMax        types.Int64   `tfsdk:"dynamic_mem_max"`
+	VCPUs                types.Int32   `tfsdk:"vcpus"`
+	BootMode             types.String  `tfsdk:"boot_mode"`
+	BootOrder            types.String  `tfsdk:"boot_order"`
+	CorePerSocket        types.Int32   `tfsdk:"cores_per_socket"`
+	DomainType           types.String  `tfsdk:"domain_type"`
+	ActionsAfterShutdown types.String  `tfsdk:"actions_after_shutdown"`
+	ActionsAfterReboot   types.String  `tfsdk:"actions_after_reboot"`
+	ActionsAfterCrash    types.String  `tfsdk:"actions_after_crash"`
+	SuspendSR            types.String  `tfsdk:"suspend_sr_uuid"`
+	HVMShadowMultiplier  types.Float64 `tfsdk:"hvm_shadow_multiplier"`
+	UserVersion          types.Int64   `tfsdk:"user_version"`
+	GenerationID         types.String  `tfsdk:"generation_id"`
+	ConsoleURL           types.String  `tfsdk:"console_url"`
+	OtherConfig          types.Map     `tfsdk:"other_config"`
+	Platform             types.Map     `tfsdk:"platform"`
+	XenstoreData         types.Map     `tfsdk:"xenstore_data"`
+	BlockedOperations    types.Map     `tfsdk:"blocked_operations"`
+	HardDrive            types.Set     `tfsdk:"hard_drive"`
+	SRForFullDiskCopy    types.String  `tfsdk:"sr_for_full_disk_copy"`
+	NetworkInterface     types.Set     `tfsdk:"network_interface"`
+	CDROM                types.String  `tfsdk:"cdrom"`
+	CDROMVDI             types.String  `tfsdk:"cdrom_vdi_uuid"`
+	CDROMs               types.List    `tfsdk:"cdroms"`
+	CDROMDrives          types.List    `tfsdk:"cdrom_drives"`
+	UUID                 types.String  `tfsdk:"uuid"`
+	ID                   types.String  `tfsdk:"id"`
+	DefaultIP            types.String  `tfsdk:"default_ip"`
+	CheckIPTimeout       types.Int64   `tfsdk:"check_ip_timeout"`
+	ForceDestroy         types.Bool    `tfsdk:"force_destroy"`
+	EnforceUniqueName    types.Bool    `tfsdk:"enforce_unique_name"`
+	WaitForGuestTools    types.Bool    `tfsdk:"wait_for_guest_tools"`
+	GuestToolsTimeout    types.Int64   `tfsdk:"guest_tools_timeout"`
+	WaitForRunning       types.Bool    `tfsdk:"wait_for_running"`
+	RunningTimeout       types.Int64   `tfsdk:"running_timeout"`
+	ShutdownTimeout      types.Int64   `tfsdk:"shutdown_timeout"`
+	AutoReboot           types.Bool    `tfsdk:"auto_reboot_on_config_change"`
+	RequiresReboot       types.Bool    `tfsdk:"requires_reboot"`
+}
+
+// vmOperations are the VM operations which can be blocked
+var vmOperations = []string{
+	"snapshot", "clone", "copy", "create_template", "revert", "checkpoint", "snapshot_with_quiesce", "provision",
+	"start", "start_on", "pause", "unpause", "clean_shutdown", "clean_reboot", "hard_shutdown", "power_state_reset",
+	"hard_reboot", "suspend", "csvm", "resume", "resume_on", "pool_migrate", "migrate_send", "get_boot_record",
+	"send_sysrq", "send_trigger", "query_services", "shutdown", "call_plugin", "changing_memory_live",
+	"awaiting_memory_live", "changing_dynamic_range", "changing_static_range", "changing_memory_limits",
+	"changing_shadow_memory", "changing_shadow_memory_live", "changing_VCPUs", "changing_VCPUs_live",
+	"changing_NVRAM", "assert_operation_valid", "data_source_op", "update_allowed_operations", "make_into_template",
+	"import", "export", "metadata_export", "reverting", "destroy", "create_vtpm",
 }
 
 func vmSchema() map[string]schema.Attribute {
@@ -166,9 +232,11 @@ func vmSchema() map[string]schema.Attribute {
 			Default:             stringdefault.StaticString(""),
 		},
 		"template_name": schema.StringAttribute{
-			MarkdownDescription: "The template name of the virtual machine which cloned from." +
+			MarkdownDescription: "The template name of the virtual machine which cloned from." + "<br />" +
+				"If not set, the virtual machine is created from scratch with the memory, VCPUs, boot and domain type settings in the configuration, the settings not configured use the defaults, for example, `bios` boot mode and `cdn` boot order. When `domain_type` is not `\"hvm\"`, the guest boots with the `pygrub` bootloader instead of the HVM firmware. `sr_for_full_disk_copy` can't be used in this case." + "<br />" +
+				"To boot from an existing disk, for example, imported from a disk image, set `bootable = true` on its item in `hard_drive`, the boot order is `c` by default then." +
 				"\n\n-> **Note:** `template_name` is not allowed to be updated.",
-			Required: true,
+			Optional: true,
 		},
 		"static_mem_min": schema.Int64Attribute{
 			MarkdownDescription: "Statically-set (absolute) minimum memory (bytes), default same with `static_mem_max`. The least amount of memory this VM can boot with without crashing.",
@@ -176,7 +244,7 @@ func vmSchema() map[string]schema.Attribute {
 			Computed:            true,
 		},
 		"static_mem_max": schema.Int64Attribute{
-			MarkdownDescription: "Statically-set (absolute) maximum memory (bytes). This value acts as a hard limit of the amount of memory a guest can use at VM start time. New values only take effect on reboot.",
+			MarkdownDescription: "Statically-set (absolute) maximum memory (bytes). This value acts as a hard limit of the amount of memory a guest can use at VM start time. New values only take effect on reboot. A warning is reported at plan time when the value is set out of the range recommended by the template.",
 			Required:            true,
 		},
 		"dynamic_mem_min": schema.Int64Attribute{
@@ -190,7 +258,7 @@ func vmSchema() map[string]schema.Attribute {
 			Computed:            true,
 		},
 		"vcpus": schema.Int32Attribute{
-			MarkdownDescription: "The number of VCPUs for the virtual machine.",
+			MarkdownDescription: "The number of VCPUs for the virtual machine. A warning is reported at plan time when the value is set out of the range recommended by the template.",
 			Required:            true,
 		},
 		"cores_per_socket": schema.Int32Attribute{
@@ -200,7 +268,7 @@ func vmSchema() map[string]schema.Attribute {
 		},
 		"boot_mode": schema.StringAttribute{
 			MarkdownDescription: "The boot mode of the virtual machine, default inherited from the template." + "<br />" +
-				"This value can be one of [`\"bios\", \"uefi\", \"uefi_security\"`]." +
+				"This value can be one of [`\"bios\", \"uefi\", \"uefi_security\"`], it's empty for the non-HVM virtual machines without a firmware." +
 				"\n\n-> **Note:** `boot_mode` is not allowed to be updated.",
 			Optional: true,
 			Computed: true,
@@ -210,25 +278,138 @@ func vmSchema() map[string]schema.Attribute {
 		},
 		"boot_order": schema.StringAttribute{
 			MarkdownDescription: "The boot order of the virtual machine, default inherited from the template." + "<br />" +
-				"This value is a combination string of [`\"c\", \"d\", \"n\"`]. Find more details in [Setting boot order for domUs](https://wiki.xenproject.org/wiki/Setting_boot_order_for_domUs).",
+				"This value is a combination string of [`\"c\", \"d\", \"n\"`]. Find more details in [Setting boot order for domUs](https://wiki.xenproject.org/wiki/Setting_boot_order_for_domUs)." + "<br />" +
+				"When the value contains `\"c\"` and `hard_drive` is configured, one of its items should be `bootable` unless the template provides the disks." + "<br />" +
+				"It's `\"cd\"` for the HVM virtual machines without a boot order, and empty for the non-HVM ones booted by the PV bootloader.",
 			Optional: true,
 			Computed: true,
 			Validators: []validator.String{
 				stringvalidator.RegexMatches(regexp.MustCompile(`^[cdn]{1,3}$`), "the value is combination string of ['c', 'd', 'n']"),
 			},
 		},
-		"cdrom": schema.StringAttribute{
-			MarkdownDescription: "The VDI name in ISO library to attach to the virtual machine, default inherited from the template.",
+		"domain_type": schema.StringAttribute{
+			MarkdownDescription: "The domain type of the virtual machine, default inherited from the template." + "<br />" +
+				"This value can be one of [`\"hvm\", \"pv\", \"pv_in_pvh\", \"pvh\"`]." +
+				"\n\n-> **Note:** `domain_type` is only allowed to be updated when the virtual machine is halted.",
+			Optional: true,
+			Computed: true,
+			Validators: []validator.String{
+				stringvalidator.OneOf("hvm", "pv", "pv_in_pvh", "pvh"),
+			},
+		},
+		"actions_after_shutdown": schema.StringAttribute{
+			MarkdownDescription: "The action to take after the guest has shutdown itself, default inherited from the template." + "<br />" +
+				"This value can be one of [`\"destroy\", \"restart\"`].",
+			Optional: true,
+			Computed: true,
+			Validators: []validator.String{
+				stringvalidator.OneOf("destroy", "restart"),
+			},
+		},
+		"actions_after_reboot": schema.StringAttribute{
+			MarkdownDescription: "The action to take after the guest has rebooted itself, default inherited from the template." + "<br />" +
+				"This value can be one of [`\"destroy\", \"restart\"`].",
+			Optional: true,
+			Computed: true,
+			Validators: []validator.String{
+				stringvalidator.OneOf("destroy", "restart"),
+			},
+		},
+		"actions_after_crash": schema.StringAttribute{
+			MarkdownDescription: "The action to take if the guest crashes, default inherited from the template." + "<br />" +
+				"This value can be one of [`\"destroy\", \"coredump_and_destroy\", \"restart\", \"coredump_and_restart\", \"preserve\", \"rename_restart\"`].",
+			Optional: true,
+			Computed: true,
+			Validators: []validator.String{
+				stringvalidator.OneOf("destroy", "coredump_and_destroy", "restart", "coredump_and_restart", "preserve", "rename_restart"),
+			},
+		},
+		"hvm_shadow_multiplier": schema.Float64Attribute{
+			MarkdownDescription: "The multiplier applied to the amount of shadow memory of the virtual machine, default inherited from the template, which is usually `1.0`. It must be at least `1.0`." + "<br />" +
+				"A larger value may help the Windows guests with heavy page table updates. It's updated live when the virtual machine is running." +
+				"\n\n-> **Note:** It only applies to the virtual machines with `domain_type` `\"hvm\"`.",
+			Optional: true,
+			Computed: true,
+			Validators: []validator.Float64{
+				float64validator.AtLeast(1.0),
+			},
+		},
+		"user_version": schema.Int64Attribute{
+			MarkdownDescription: "The user defined version of the virtual machine, default inherited from the template.",
 			Optional:            true,
 			Computed:            true,
+			Validators: []validator.Int64{
+				int64validator.AtLeast(0),
+			},
+		},
+		"generation_id": schema.StringAttribute{
+			MarkdownDescription: "The VM Generation ID presented to the guest, which tells the guest, for example, an Active Directory domain controller, that the virtual machine is a clone or is reverted to a snapshot. It's `\"\"` when the template doesn't present a VM Generation ID." +
+				"\n\n-> **Note:** XAPI generates a new VM Generation ID every time the virtual machine is cloned or copied, including when it's created from the template and by `xenserver_vm_clone`, so a cloned virtual machine never has the same VM Generation ID as its source. It can't be set otherwise.",
+			Computed: true,
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.UseStateForUnknown(),
+			},
+		},
+		"console_url": schema.StringAttribute{
+			MarkdownDescription: "The URL of the console of the virtual machine, the graphical (VNC) console is preferred over the text one. It's `\"\"` when the virtual machine is not running." + "<br />" +
+				"The URL doesn't carry a session, append `&session_id=<session reference>` to connect to it.",
+			Computed: true,
+		},
+		"suspend_sr_uuid": schema.StringAttribute{
+			MarkdownDescription: "The UUID of the storage repository to store the suspend image of the virtual machine on, which is used when the virtual machine is suspended or checkpointed, default inherited from the template." + "<br />" +
+				"Set as `\"\"` to use the default storage repository of the pool.",
+			Optional: true,
+			Computed: true,
+		},
+		"cdrom": schema.StringAttribute{
+			MarkdownDescription: "The VDI name in ISO library to attach to the virtual machine, default inherited from the template." + "<br />" +
+				"Set as `\"\"` to attach an empty CD-ROM drive, or leave it unset to not manage the CD-ROM drive." + "<br />" +
+				"When the virtual machine has more than one CD-ROM drive, only the first one is managed by this attribute.",
+			Optional: true,
+			Computed: true,
+			Validators: []validator.String{
+				stringvalidator.ConflictsWith(path.MatchRoot("cdroms")),
+				stringvalidator.ConflictsWith(path.MatchRoot("cdrom_vdi_uuid")),
+			},
+		},
+		"cdrom_vdi_uuid": schema.StringAttribute{
+			MarkdownDescription: "The UUID of the VDI to insert in the first CD-ROM drive of the virtual machine, default inherited from the template." + "<br />" +
+				"Use it instead of `cdrom` when the ISO name is not unique, or the VDI is not in an ISO library, `xenserver_iso` resolves the VDI UUID of an ISO in a given storage repository. Set as `\"\"` to attach an empty CD-ROM drive.",
+			Optional: true,
+			Computed: true,
+			Validators: []validator.String{
+				stringvalidator.ConflictsWith(path.MatchRoot("cdroms")),
+			},
+		},
+		"cdroms": schema.ListAttribute{
+			MarkdownDescription: "A list of VDI names in ISO library to attach to the virtual machine as CD-ROM drives in device order, default inherited from the template." + "<br />" +
+				"Set an item as `\"\"` to attach an empty CD-ROM drive. Use it instead of `cdrom` when more than one CD-ROM drive is needed, the drives not in the list will be removed.",
+			ElementType: types.StringType,
+			Optional:    true,
+			Computed:    true,
+			Validators: []validator.List{
+				listvalidator.ConflictsWith(path.MatchRoot("cdrom")),
+				listvalidator.ConflictsWith(path.MatchRoot("cdrom_vdi_uuid")),
+			},
+		},
+		"cdrom_drives": schema.ListNestedAttribute{
+			MarkdownDescription: "The state of the CD-ROM drives of the virtual machine in device order, the same order as `cdroms`.",
+			NestedObject: schema.NestedAttributeObject{
+				Attributes: cdromDriveSchema(),
+			},
+			Computed: true,
 		},
 		"hard_drive": schema.SetNestedAttribute{
-			MarkdownDescription: "A set of hard drive attributes to attach to the virtual machine, default inherited from the template.",
+			MarkdownDescription: "A set of hard drive attributes to attach to the virtual machine, default inherited from the template." + "<br />" +
+				"The disks attached or detached outside of terraform are reported as drift once, the attached ones are detached on the next apply when `hard_drive` is configured without them. When `hard_drive` is not configured, the disks of the virtual machine are kept as they are.",
 			NestedObject: schema.NestedAttributeObject{
 				Attributes: vbdSchema(),
 			},
 			Optional: true,
 			Computed: true,
+			Validators: []validator.Set{
+				uniqueVDIValidator{},
+			},
 		},
 		"sr_for_full_disk_copy": schema.StringAttribute{
 			MarkdownDescription: "Use storage-level full disk copy. Give a SR uuid or set as `\"origin\"` to keep use the origin SR of template disks. Only support custom template." +
@@ -246,14 +427,53 @@ func vmSchema() map[string]schema.Attribute {
 			Required: true,
 			Validators: []validator.Set{
 				setvalidator.SizeAtLeast(1),
+				uniqueVIFDeviceValidator{},
+				uniqueVIFMACValidator{},
 			},
 		},
 		"other_config": schema.MapAttribute{
-			MarkdownDescription: "The additional configuration of the virtual machine, default to be `{}`.",
-			Optional:            true,
-			Computed:            true,
-			ElementType:         types.StringType,
-			Default:             mapdefault.StaticValue(types.MapValueMust(types.StringType, map[string]attr.Value{})),
+			MarkdownDescription: "The additional configuration of the virtual machine, default to be `{}`." +
+				"\n\n-> **Note:** The keys with the `tf_` prefix are reserved for the provider to record its own state, they are not allowed in `other_config`.",
+			Optional:    true,
+			Computed:    true,
+			ElementType: types.StringType,
+			Default:     mapdefault.StaticValue(types.MapValueMust(types.StringType, map[string]attr.Value{})),
+			Validators: []validator.Map{
+				mapvalidator.KeysAre(reservedKeyPrefixValidator{prefix: vmReservedOtherConfigPrefix}),
+			},
+		},
+		"platform": schema.MapAttribute{
+			MarkdownDescription: "The platform settings of the virtual machine, for example, `{ \"nx\" = \"true\" }`, default to be `{}`." + "<br />" +
+				"The settings are merged with the ones inherited from the template, only the keys set by terraform are tracked. New values only take effect on reboot." +
+				"\n\n-> **Note:** The keys managed by the provider are reserved and not allowed in `platform`: `secureboot` is set by `boot_mode` and `cores-per-socket` is set by `cores_per_socket`.",
+			Optional:    true,
+			Computed:    true,
+			ElementType: types.StringType,
+			Default:     mapdefault.StaticValue(types.MapValueMust(types.StringType, map[string]attr.Value{})),
+			Validators: []validator.Map{
+				mapvalidator.KeysAre(stringvalidator.NoneOf(vmManagedPlatformKeys...)),
+			},
+		},
+		"xenstore_data": schema.MapAttribute{
+			MarkdownDescription: "The data to be inserted into the xenstore tree of the virtual machine, for example, `{ \"vm-data/hostname\" = \"vm1\" }`, default to be `{}`." + "<br />" +
+				"The data is merged with the one inherited from the template, only the keys set by terraform are tracked." +
+				"\n\n-> **Note:** The keys under `vm-data/` can be updated on a running virtual machine, XAPI writes the new values to the xenstore tree of the guest straight away. The other keys only take effect after the virtual machine is rebooted.",
+			Optional:    true,
+			Computed:    true,
+			ElementType: types.StringType,
+			Default:     mapdefault.StaticValue(types.MapValueMust(types.StringType, map[string]attr.Value{})),
+		},
+		"blocked_operations": schema.MapAttribute{
+			MarkdownDescription: "The operations which are blocked on the virtual machine and the reasons, for example, `{ \"clean_shutdown\" = \"production VM\" }`, default to be `{}`." + "<br />" +
+				"Only the operations blocked by terraform are managed, the operations blocked outside of terraform, for example, by the template or XenCenter, are kept and not shown. " +
+				"The blocked operations are removed before the virtual machine is destroyed by terraform, so blocking `destroy` only protects it from being destroyed outside of terraform.",
+			Optional:    true,
+			Computed:    true,
+			ElementType: types.StringType,
+			Default:     mapdefault.StaticValue(types.MapValueMust(types.StringType, map[string]attr.Value{})),
+			Validators: []validator.Map{
+				mapvalidator.KeysAre(stringvalidator.OneOf(vmOperations...)),
+			},
 		},
 		"check_ip_timeout": schema.Int64Attribute{
 			MarkdownDescription: "The duration for checking the IP address of the virtual machine. default is 0 seconds, once the value greater than 0, the provider will check the IP address of the virtual machine in the specified duration.",
@@ -264,6 +484,76 @@ func vmSchema() map[string]schema.Attribute {
 				int64validator.AtLeast(0),
 			},
 		},
+		"wait_for_guest_tools": schema.BoolAttribute{
+			MarkdownDescription: "Whether to wait for the guest agent of the virtual machine to be live with the PV drivers detected after the virtual machine is started, default to be `false`." + "<br />" +
+				"When set to `true`, the virtual machine is started automatically. It's more reliable than `check_ip_timeout` for the virtual machines on isolated networks.",
+			Optional: true,
+			Computed: true,
+			Default:  booldefault.StaticBool(false),
+		},
+		"guest_tools_timeout": schema.Int64Attribute{
+			MarkdownDescription: "The duration in seconds to wait for the guest agent when `wait_for_guest_tools` is `true`, default to be `300`.",
+			Optional:            true,
+			Computed:            true,
+			Default:             int64default.StaticInt64(300),
+			Validators: []validator.Int64{
+				int64validator.AtLeast(1),
+			},
+		},
+		"wait_for_running": schema.BoolAttribute{
+			MarkdownDescription: "Whether to wait for the virtual machine to be running with no operation in progress after it's created or updated, default to be `false`." + "<br />" +
+				"When set to `true`, the virtual machine is started automatically, so the dependent resources, for example, the provisioners, don't run against a virtual machine which is still starting.",
+			Optional: true,
+			Computed: true,
+			Default:  booldefault.StaticBool(false),
+		},
+		"running_timeout": schema.Int64Attribute{
+			MarkdownDescription: "The duration in seconds to wait for the virtual machine to be running when `wait_for_running` is `true`, default to be `300`.",
+			Optional:            true,
+			Computed:            true,
+			Default:             int64default.StaticInt64(300),
+			Validators: []validator.Int64{
+				int64validator.AtLeast(1),
+			},
+		},
+		"shutdown_timeout": schema.Int64Attribute{
+			MarkdownDescription: "The duration in seconds to wait for the virtual machine to shut down cleanly before it's destroyed, default to be `60`." + "<br />" +
+				"If the guest doesn't shut down in time, the virtual machine is forcibly shut down. Set to `0` to forcibly shut down the virtual machine immediately." + "<br />" +
+				"It also bounds the clean shutdown of the memory change with `auto_reboot_on_config_change`.",
+			Optional: true,
+			Computed: true,
+			Default:  int64default.StaticInt64(60),
+			Validators: []validator.Int64{
+				int64validator.AtLeast(0),
+			},
+		},
+		"force_destroy": schema.BoolAttribute{
+			MarkdownDescription: "Whether to destroy the virtual machine whatever its state when the resource is destroyed, default to be `false`." + "<br />" +
+				"When set to `true`, the virtual machine is hard shut down whatever its power state, for example, paused or suspended, then all its network interfaces and disk attachments are destroyed, including the ones attached outside of terraform. " +
+				"The snapshots of the virtual machine are destroyed too, otherwise the destroy fails if the virtual machine has snapshots." +
+				"\n\n-> **Note:** The disks attached outside of terraform are detached but not destroyed.",
+			Optional: true,
+			Computed: true,
+			Default:  booldefault.StaticBool(false),
+		},
+		"enforce_unique_name": schema.BoolAttribute{
+			MarkdownDescription: "Whether to check that no other virtual machine in the pool has the same `name_label` when the virtual machine is created or its `name_label` is updated, default to be `false`." + "<br />" +
+				"XenServer allows duplicate names, set it to `true` to keep the name-based lookups, for example, imports by name, unambiguous. The templates and snapshots are not checked.",
+			Optional: true,
+			Computed: true,
+			Default:  booldefault.StaticBool(false),
+		},
+		"auto_reboot_on_config_change": schema.BoolAttribute{
+			MarkdownDescription: "Whether to reboot the running virtual machine cleanly after it's updated when XAPI reports it requires a reboot, default to be `false`." + "<br />" +
+				"Some changes, for example, the vendor device, only take effect after a reboot, set to `true` to apply them without manual intervention. The memory can only be changed on a halted virtual machine, with `true`, the running virtual machine is shut down cleanly within `shutdown_timeout` and started again with the new memory, it's never forcibly shut down, so the memory change fails when `shutdown_timeout` is `0`.",
+			Optional: true,
+			Computed: true,
+			Default:  booldefault.StaticBool(false),
+		},
+		"requires_reboot": schema.BoolAttribute{
+			MarkdownDescription: "True if the virtual machine needs to be rebooted to apply its configuration changes.",
+			Computed:            true,
+		},
 		"default_ip": schema.StringAttribute{
 			MarkdownDescription: "The default IP address of the virtual machine.",
 			Computed:            true,
@@ -366,7 +656,7 @@ func updateVMRecordData(ctx context.Context, record xenapi.VMRecord, data *vmRec
 		return errors.New("unable to read VM platform")
 	}
 	data.PCIBus = types.StringValue(record.PCIBus)
-	data.OtherConfig, diags = types.MapValueFrom(ctx, types.StringType, record.OtherConfig)
+	data.OtherConfig, diags = types.MapValueFrom(ctx, types.StringType, getVMOtherConfigWithoutState(record.OtherConfig))
 	if diags.HasError() {
 		return errors.New("unable to read VM other config")
 	}
@@ -465,9 +755,128 @@ func updateVMRecordData(ctx context.Context, record xenapi.VMRecord, data *vmRec
 	return nil
 }
 
+// createVMFromTemplate clones the VM from the template, or copies it with full disks to the SR when
+// sr_for_full_disk_copy is set
+func createVMFromTemplate(ctx context.Context, session *xenapi.Session, plan vmResourceModel) (xenapi.VMRef, error) {
+	var vmRef xenapi.VMRef
+	templateRef, err := getFirstTemplate(session, plan.TemplateName.ValueString())
+	if err != nil {
+		return vmRef, err
+	}
+
+	if !plan.SRForFullDiskCopy.IsUnknown() && plan.SRForFullDiskCopy.ValueString() != "" {
+		srRef, err := checkIfSupportFullCopy(session, templateRef, plan.SRForFullDiskCopy.ValueString())
+		if err != nil {
+			return vmRef, errors.New("use storage-level full disk copy but get error: " + err.Error())
+		}
+		tflog.Debug(ctx, "----> Copy VM from a template")
+		vmRef, err = xenapi.VM.Copy(session, templateRef, plan.NameLabel.ValueString(), srRef)
+		if err != nil {
+			return vmRef, errors.New(err.Error())
+		}
+		return vmRef, nil
+	}
+
+	tflog.Debug(ctx, "----> Clone VM from a template")
+	vmRef, err = xenapi.VM.Clone(session, templateRef, plan.NameLabel.ValueString())
+	if err != nil {
+		return vmRef, errors.New(err.Error())
+	}
+	return vmRef, nil
+}
+
+// createVMFromScratch creates an empty guest, HVM unless domain_type is set, with the defaults of the settings which
+// are inherited from the template otherwise, the plan is applied by setVMResourceModel as the VM cloned from a template.
+// With a bootable item in hard_drive, e.g. a disk imported from an image, the VM boots from the disk only by default.
+func createVMFromScratch(ctx context.Context, session *xenapi.Session, plan vmResourceModel) (xenapi.VMRef, error) {
+	memory := getVMMemory(plan)
+	vcpus := int(plan.VCPUs.ValueInt32())
+
+	firmware := "bios"
+	secureBoot := "false"
+	if !plan.BootMode.IsUnknown() && plan.BootMode.ValueString() != "bios" {
+		firmware = "uefi"
+		if plan.BootMode.ValueString() == "uefi_security" {
+			secureBoot = "true"
+		}
+	}
+	bootOrder := "cdn"
+	if !plan.BootOrder.IsUnknown() {
+		bootOrder = plan.BootOrder.ValueString()
+	} else {
+		bootable, err := hasBootableHardDrive(ctx, plan)
+		if err != nil {
+			return "", err
+		}
+		if bootable {
+			bootOrder = "c"
+		}
+	}
+	domainType := xenapi.DomainTypeHvm
+	if !plan.DomainType.IsUnknown() {
+		domainType = xenapi.DomainType(plan.DomainType.ValueString())
+	}
+
+	vmRecord := xenapi.VMRecord{
+		NameLabel:            plan.NameLabel.ValueString(),
+		NameDescription:      plan.NameDescription.ValueString(),
+		UserVersion:          1,
+		MemoryStaticMin:      memory.staticMemMin,
+		MemoryStaticMax:      memory.staticMemMax,
+		MemoryDynamicMin:     memory.dynamicMemMin,
+		MemoryDynamicMax:     memory.dynamicMemMax,
+		VCPUsMax:             vcpus,
+		VCPUsAtStartup:       vcpus,
+		VCPUsParams:          map[string]string{},
+		ActionsAfterShutdown: xenapi.OnNormalExitDestroy,
+		ActionsAfterReboot:   xenapi.OnNormalExitRestart,
+		ActionsAfterCrash:    xenapi.OnCrashBehaviourRestart,
+		HVMBootPolicy:        "BIOS order",
+		HVMBootParams:        map[string]string{"order": bootOrder, "firmware": firmware},
+		HVMShadowMultiplier:  1.0,
+		Platform: map[string]string{
+			"acpi":             "1",
+			"apic":             "true",
+			"pae":              "true",
+			"nx":               "true",
+			"viridian":         "false",
+			"device-model":     "qemu-upstream-compat",
+			"secureboot":       secureBoot,
+			"cores-per-socket": strconv.Itoa(vcpus),
+		},
+		DomainType:        domainType,
+		OtherConfig:       map[string]string{},
+		XenstoreData:      map[string]string{},
+		BlockedOperations: map[xenapi.VMOperations]string{},
+		Affinity:          xenapi.HostRef("OpaqueRef:NULL"),
+		SuspendSR:         xenapi.SRRef("OpaqueRef:NULL"),
+		Appliance:         xenapi.VMApplianceRef("OpaqueRef:NULL"),
+		ProtectionPolicy:  xenapi.VMPPRef("OpaqueRef:NULL"),
+		SnapshotSchedule:  xenapi.VMSSRef("OpaqueRef:NULL"),
+	}
+	// the PV guests boot their kernel with the bootloader in dom0 instead of the HVM firmware
+	if domainType != xenapi.DomainTypeHvm {
+		vmRecord.HVMBootPolicy = ""
+		vmRecord.HVMBootParams = map[string]string{}
+		vmRecord.PVBootloader = "pygrub"
+		for _, key := range []string{"device-model", "viridian", "secureboot"} {
+			delete(vmRecord.Platform, key)
+		}
+	}
+	vmRef, err := withSessionRetry(session, func() (xenapi.VMRef, error) {
+		return xenapi.VM.Create(session, vmRecord)
+	})
+	if err != nil {
+		return vmRef, errors.New(err.Error())
+	}
+	return vmRef, nil
+}
+
 func getFirstTemplate(session *xenapi.Session, templateName string) (xenapi.VMRef, error) {
 	var vmRef xenapi.VMRef
-	records, err := xenapi.VM.GetAllRecords(session)
+	records, err := withSessionRetry(session, func() (map[xenapi.VMRef]xenapi.VMRecord, error) {
+		return xenapi.VM.GetAllRecords(session)
+	})
 	if err != nil {
 		return vmRef, errors.New(err.Error())
 	}
@@ -492,6 +901,12 @@ func checkIfSupportFullCopy(session *xenapi.Session, templateRef xenapi.VMRef, s
 		return srRef, errors.New("don't support default template")
 	}
 
+	// the source of xenserver_vm_clone may be a VM instead of a template
+	isTemplate, err := xenapi.VM.GetIsATemplate(session, templateRef)
+	if err != nil {
+		return srRef, errors.New("can't get is_a_template. " + err.Error())
+	}
+
 	// check if VM template disk allow copy
 	templateHardDrives, err := getAllDiskTypeVBDs(session, templateRef)
 	if err != nil {
@@ -507,6 +922,9 @@ func checkIfSupportFullCopy(session *xenapi.Session, templateRef xenapi.VMRef, s
 			return srRef, errors.New("can't get VDI allowed_operations. " + err.Error())
 		}
 		if !slices.Contains(allowedOps, xenapi.VdiOperationsCopy) {
+			if !isTemplate {
+				return srRef, errors.New("source VM disk doesn't allow copy, it may be in use, for example, by the running VM")
+			}
 			return srRef, errors.New("template disk doesn't allow copy")
 		}
 	}
@@ -539,8 +957,13 @@ func setOtherConfigWhenCreate(session *xenapi.Session, vmRef xenapi.VMRef) error
 	}
 	templateVBDs := strings.Join(templateHardDrives, ",")
 	// Set the template VBD refs only once after the VM is cloned from a template
+	vmState := getVMState(vmOtherConfig)
 	if templateVBDs != "" {
-		vmOtherConfig["tf_template_vbds"] = templateVBDs
+		vmState["template_vbds"] = templateVBDs
+	}
+	err = setVMState(vmOtherConfig, vmState)
+	if err != nil {
+		return err
 	}
 
 	err = xenapi.VM.SetOtherConfig(session, vmRef, vmOtherConfig)
@@ -551,6 +974,97 @@ func setOtherConfigWhenCreate(session *xenapi.Session, vmRef xenapi.VMRef) error
 	return nil
 }
 
+// vmStateOtherConfigKey is the other_config key which holds the provider state of the VM, encoded as JSON
+const vmStateOtherConfigKey = "tf_state"
+
+// vmLegacyStateKeys are the other_config keys which held the provider state before it was moved into
+// vmStateOtherConfigKey, they are read until the state is saved again
+var vmLegacyStateKeys = []string{
+	"tf_other_config_keys",
+	"tf_check_ip_timeout",
+	"tf_template_name",
+	"tf_template_vbds",
+	"tf_sr_for_full_disk_copy",
+}
+
+// getVMState returns the provider state of the VM from its other_config, the values in the legacy keys
+// are overridden by the ones in vmStateOtherConfigKey
+func getVMState(otherConfig map[string]string) map[string]string {
+	vmState := make(map[string]string)
+	for _, key := range vmLegacyStateKeys {
+		if value, ok := otherConfig[key]; ok {
+			vmState[strings.TrimPrefix(key, vmReservedOtherConfigPrefix)] = value
+		}
+	}
+
+	// the state may be broken outside of terraform, keep the legacy values and rewrite it on the next apply
+	savedState := make(map[string]string)
+	if err := json.Unmarshal([]byte(otherConfig[vmStateOtherConfigKey]), &savedState); err == nil {
+		for key, value := range savedState {
+			vmState[key] = value
+		}
+	}
+
+	return vmState
+}
+
+// setVMState saves the provider state of the VM to the other_config and removes the legacy keys
+func setVMState(otherConfig map[string]string, vmState map[string]string) error {
+	stateJSON, err := json.Marshal(vmState)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	otherConfig[vmStateOtherConfigKey] = string(stateJSON)
+	for _, key := range vmLegacyStateKeys {
+		delete(otherConfig, key)
+	}
+	return nil
+}
+
+// getVMOtherConfigWithoutState returns a copy of the other_config without the provider state of the VM
+func getVMOtherConfigWithoutState(otherConfig map[string]string) map[string]string {
+	result := make(map[string]string)
+	for key, value := range otherConfig {
+		if key == vmStateOtherConfigKey || slices.Contains(vmLegacyStateKeys, key) {
+			continue
+		}
+		result[key] = value
+	}
+	return result
+}
+
+// vmReservedOtherConfigPrefix is the prefix of the other_config keys used by the provider for bookkeeping
+const vmReservedOtherConfigPrefix = "tf_"
+
+// reservedKeyPrefixValidator rejects the map keys which start with a prefix reserved by the provider.
+type reservedKeyPrefixValidator struct {
+	prefix string
+}
+
+var _ validator.String = reservedKeyPrefixValidator{}
+
+func (v reservedKeyPrefixValidator) Description(_ context.Context) string {
+	return "key must not start with " + v.prefix
+}
+
+func (v reservedKeyPrefixValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v reservedKeyPrefixValidator) ValidateString(_ context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	if strings.HasPrefix(req.ConfigValue.ValueString(), v.prefix) {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Reserved key in "+req.Path.String(),
+			"Key "+req.ConfigValue.ValueString()+" is not allowed, the keys with the \""+v.prefix+"\" prefix are reserved by the provider.",
+		)
+	}
+}
+
 func updateOtherConfigFromPlan(ctx context.Context, session *xenapi.Session, vmRef xenapi.VMRef, plan vmResourceModel) error {
 	planOtherConfig := make(map[string]string)
 	if !plan.OtherConfig.IsUnknown() {
@@ -565,9 +1079,9 @@ func updateOtherConfigFromPlan(ctx context.Context, session *xenapi.Session, vmR
 		return errors.New(err.Error())
 	}
 
-	originalTFOtherConfigKeys := vmOtherConfig["tf_other_config_keys"]
-	// Remove all originalTFOtherConfigKeys
-	originalKeys := strings.Split(originalTFOtherConfigKeys, ",")
+	vmState := getVMState(vmOtherConfig)
+	// Remove all the other config keys set by terraform before
+	originalKeys := strings.Split(vmState["other_config_keys"], ",")
 	for _, key := range originalKeys {
 		delete(vmOtherConfig, key)
 	}
@@ -579,10 +1093,22 @@ func updateOtherConfigFromPlan(ctx context.Context, session *xenapi.Session, vmR
 		tflog.Debug(ctx, "-----> setOtherConfig key: "+key+" value: "+value)
 	}
 
-	vmOtherConfig["tf_other_config_keys"] = strings.Join(tfOtherConfigKeys, ",")
-	vmOtherConfig["tf_check_ip_timeout"] = plan.CheckIPTimeout.String()
-	vmOtherConfig["tf_template_name"] = plan.TemplateName.ValueString()
-	vmOtherConfig["tf_sr_for_full_disk_copy"] = plan.SRForFullDiskCopy.ValueString()
+	vmState["other_config_keys"] = strings.Join(tfOtherConfigKeys, ",")
+	vmState["check_ip_timeout"] = plan.CheckIPTimeout.String()
+	vmState["template_name"] = plan.TemplateName.ValueString()
+	vmState["sr_for_full_disk_copy"] = plan.SRForFullDiskCopy.ValueString()
+	vmState["force_destroy"] = plan.ForceDestroy.String()
+	vmState["enforce_unique_name"] = plan.EnforceUniqueName.String()
+	vmState["wait_for_guest_tools"] = plan.WaitForGuestTools.String()
+	vmState["guest_tools_timeout"] = plan.GuestToolsTimeout.String()
+	vmState["wait_for_running"] = plan.WaitForRunning.String()
+	vmState["running_timeout"] = plan.RunningTimeout.String()
+	vmState["shutdown_timeout"] = plan.ShutdownTimeout.String()
+	vmState["auto_reboot_on_config_change"] = plan.AutoReboot.String()
+	err = setVMState(vmOtherConfig, vmState)
+	if err != nil {
+		return err
+	}
 
 	err = xenapi.VM.SetOtherConfig(session, vmRef, vmOtherConfig)
 	if err != nil {
@@ -592,93 +1118,350 @@ func updateOtherConfigFromPlan(ctx context.Context, session *xenapi.Session, vmR
 	return nil
 }
 
+// isHVMDomain returns true if the VM boots through the HVM firmware, older VMs with an "unspecified"
+// domain type are HVM when they have a boot policy
+func isHVMDomain(vmRecord xenapi.VMRecord) bool {
+	if vmRecord.DomainType == xenapi.DomainTypeUnspecified {
+		return vmRecord.HVMBootPolicy != ""
+	}
+	return vmRecord.DomainType == xenapi.DomainTypeHvm
+}
+
 func getBootModeFromVMRecord(vmRecord xenapi.VMRecord) (string, error) {
 	bootMode, ok := vmRecord.HVMBootParams["firmware"]
 	if !ok {
+		// PV guests boot their kernel directly and have no firmware
+		if !isHVMDomain(vmRecord) {
+			return "", nil
+		}
 		return "", errors.New("unable to read VM HVM boot firmware")
 	}
-	secureBoot, ok := vmRecord.Platform["secureboot"]
-	if !ok {
-		return "", errors.New("unable to read VM platform secureboot")
-	}
-
-	// keep tf state consistent with the boot mode, especially user didn't provide the boot mode attribute
-	if bootMode == "uefi" && secureBoot != "false" {
+	// keep tf state consistent with the boot mode, especially user didn't provide the boot mode attribute.
+	// Only "true" enforces secure boot, updateBootMode writes "true" or "false", while templates may
+	// also carry "auto" or nothing, which boot in UEFI mode without enforcing it.
+	if bootMode == "uefi" && vmRecord.Platform["secureboot"] == "true" {
 		bootMode = "uefi_security"
 	}
 
 	return bootMode, nil
 }
 
-func getCorePerSocket(vmRecord xenapi.VMRecord) (int32, error) {
-	socket, ok := vmRecord.Platform["cores-per-socket"]
-	if !ok {
-		return 0, errors.New("unable to read VM platform cores-per-socket")
-	}
-	socketInt, err := strconv.Atoi(socket)
-	if err != nil {
-		return 0, errors.New("unable to convert cores-per-socket to an int value")
+// getVMConsoleURL returns the location of the VNC console of the VM, or of its text console if it
+// has no VNC one
+func getVMConsoleURL(session *xenapi.Session, consoleRefs []xenapi.ConsoleRef) (string, error) {
+	consoleRecords := make([]xenapi.ConsoleRecord, 0, len(consoleRefs))
+	for _, consoleRef := range consoleRefs {
+		consoleRecord, err := xenapi.Console.GetRecord(session, consoleRef)
+		if err != nil {
+			return "", errors.New(err.Error())
+		}
+		consoleRecords = append(consoleRecords, consoleRecord)
 	}
+	return selectConsoleLocation(consoleRecords), nil
+}
 
-	return int32(socketInt), nil // #nosec G109
+func selectConsoleLocation(consoleRecords []xenapi.ConsoleRecord) string {
+	location := ""
+	for _, consoleRecord := range consoleRecords {
+		if consoleRecord.Protocol == xenapi.ConsoleProtocolRfb {
+			return consoleRecord.Location
+		}
+		if location == "" {
+			location = consoleRecord.Location
+		}
+	}
+	return location
 }
 
-func updateVMResourceModelComputed(ctx context.Context, session *xenapi.Session, vmRecord xenapi.VMRecord, data *vmResourceModel) error {
-	var err error
-	data.NameDescription = types.StringValue(vmRecord.NameDescription)
-	data.UUID = types.StringValue(vmRecord.UUID)
-	data.ID = types.StringValue(vmRecord.UUID)
-	data.StaticMemMin = types.Int64Value(int64(vmRecord.MemoryStaticMin))
-	data.DynamicMemMin = types.Int64Value(int64(vmRecord.MemoryDynamicMin))
-	data.DynamicMemMax = types.Int64Value(int64(vmRecord.MemoryDynamicMax))
+// getVMsWithName returns the UUIDs of the VMs with the name except the one with excludeUUID, sorted.
+// The templates, snapshots and control domains are not VMs managed by the resource and are skipped.
+func getVMsWithName(vmRecords map[xenapi.VMRef]xenapi.VMRecord, nameLabel string, excludeUUID string) []string {
+	vmUUIDs := []string{}
+	for _, vmRecord := range vmRecords {
+		if vmRecord.IsATemplate || vmRecord.IsASnapshot || vmRecord.IsControlDomain {
+			continue
+		}
+		if vmRecord.NameLabel == nameLabel && vmRecord.UUID != excludeUUID {
+			vmUUIDs = append(vmUUIDs, vmRecord.UUID)
+		}
+	}
+	slices.Sort(vmUUIDs)
+	return vmUUIDs
+}
 
-	socketInt, err := getCorePerSocket(vmRecord)
+// checkVMNameUnique returns an error if a VM other than the one with selfUUID has the name
+func checkVMNameUnique(session *xenapi.Session, nameLabel string, selfUUID string) error {
+	vmRecords, err := withSessionRetry(session, func() (map[xenapi.VMRef]xenapi.VMRecord, error) {
+		return xenapi.VM.GetAllRecords(session)
+	})
 	if err != nil {
-		return err
+		return errors.New(err.Error())
 	}
-	data.CorePerSocket = types.Int32Value(socketInt)
+	vmUUIDs := getVMsWithName(vmRecords, nameLabel, selfUUID)
+	if len(vmUUIDs) > 0 {
+		return errors.New(`the name_label "` + nameLabel + `" is already used by VMs: ` + strings.Join(vmUUIDs, ", "))
+	}
+	return nil
+}
 
-	data.NetworkInterface, err = getVIFsFromVMRecord(ctx, session, vmRecord)
-	if err != nil {
-		return err
+// getPossibleHostUUIDs returns the UUIDs of the possible hosts of the VM except the one it's resident on, sorted
+func getPossibleHostUUIDs(hostUUIDs map[xenapi.HostRef]string, possibleHosts []xenapi.HostRef, residentOn xenapi.HostRef) []string {
+	uuids := []string{}
+	for _, hostRef := range possibleHosts {
+		uuid, ok := hostUUIDs[hostRef]
+		if !ok || hostRef == residentOn {
+			continue
+		}
+		uuids = append(uuids, uuid)
 	}
+	slices.Sort(uuids)
+	return uuids
+}
 
-	data.HardDrive, _, err = getVBDsFromVMRecord(ctx, session, vmRecord, xenapi.VbdTypeDisk)
+func updateVMPlacementDataSourceModel(ctx context.Context, session *xenapi.Session, data *vmPlacementDataSourceModel) error {
+	vmRef, err := xenapi.VM.GetByUUID(session, data.UUID.ValueString())
 	if err != nil {
-		return err
+		return errors.New(err.Error())
 	}
-
-	cd, err := getCDFromVMRecord(ctx, session, vmRecord)
+	vmRecord, err := xenapi.VM.GetRecord(session, vmRef)
 	if err != nil {
-		return err
+		return errors.New(err.Error())
 	}
-	data.CDROM = types.StringValue(cd.isoName)
-
-	bootMode, err := getBootModeFromVMRecord(vmRecord)
+	hostRecords, err := xenapi.Host.GetAllRecords(session)
 	if err != nil {
-		return err
+		return errors.New(err.Error())
 	}
-	data.BootMode = types.StringValue(bootMode)
-
-	bootOrder, ok := vmRecord.HVMBootParams["order"]
-	if !ok {
-		return errors.New("unable to read VM HVM boot order")
+	hostUUIDs := make(map[xenapi.HostRef]string, len(hostRecords))
+	for hostRef, hostRecord := range hostRecords {
+		hostUUIDs[hostRef] = hostRecord.UUID
 	}
-	data.BootOrder = types.StringValue(bootOrder)
-
-	// only keep the key which configured by user
-	data.OtherConfig, err = getOtherConfigFromVMRecord(ctx, vmRecord)
+	possibleHosts, err := xenapi.VM.GetPossibleHosts(session, vmRef)
 	if err != nil {
-		return err
+		return errors.New(err.Error())
 	}
 
-	if _, ok := vmRecord.OtherConfig["tf_check_ip_timeout"]; ok {
-		checkIPDuration, err := strconv.Atoi(vmRecord.OtherConfig["tf_check_ip_timeout"])
-		if err != nil {
-			return errors.New("unable to convert check_ip_timeout to an int value")
-		}
-		data.CheckIPTimeout = types.Int64Value(int64(checkIPDuration))
-
+	data.PowerState = types.StringValue(string(vmRecord.PowerState))
+	data.ResidentOn = types.StringValue(hostUUIDs[vmRecord.ResidentOn])
+	var diags diag.Diagnostics
+	data.PossibleHosts, diags = types.ListValueFrom(ctx, types.StringType, getPossibleHostUUIDs(hostUUIDs, possibleHosts, vmRecord.ResidentOn))
+	if diags.HasError() {
+		return errors.New("unable to get possible hosts list value")
+	}
+	return nil
+}
+
+// formatXapiTime returns the time in RFC 3339 format, or "" when XAPI doesn't set it and returns the epoch
+func formatXapiTime(t time.Time) string {
+	if t.IsZero() || t.Unix() == 0 {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// getVCPUsUtilisation returns the utilisation of the vCPUs keyed by their number as string
+func getVCPUsUtilisation(utilisation map[int]float64) map[string]float64 {
+	result := make(map[string]float64, len(utilisation))
+	for vcpu, value := range utilisation {
+		result[strconv.Itoa(vcpu)] = value
+	}
+	return result
+}
+
+func updateVMMetricsDataSourceModel(ctx context.Context, session *xenapi.Session, data *vmMetricsDataSourceModel) error {
+	vmRef, err := xenapi.VM.GetByUUID(session, data.UUID.ValueString())
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	vmRecord, err := xenapi.VM.GetRecord(session, vmRef)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	metrics, err := xenapi.VMMetrics.GetRecord(session, vmRecord.Metrics)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+
+	data.PowerState = types.StringValue(string(vmRecord.PowerState))
+	data.VCPUsNumber = types.Int32Value(int32(metrics.VCPUsNumber))
+	data.MemoryActual = types.Int64Value(int64(metrics.MemoryActual))
+	data.StartTime = types.StringValue(formatXapiTime(metrics.StartTime))
+	data.LastUpdated = types.StringValue(formatXapiTime(metrics.LastUpdated))
+	var diags diag.Diagnostics
+	data.VCPUsUtilisation, diags = types.MapValueFrom(ctx, types.Float64Type, getVCPUsUtilisation(metrics.VCPUsUtilisation))
+	if diags.HasError() {
+		return errors.New("unable to get vCPUs utilisation map value")
+	}
+
+	// the guest metrics are only reported by the guest agent
+	guestMetrics := xenapi.VMGuestMetricsRecord{Memory: map[string]string{}, Disks: map[string]string{}}
+	if vmRecord.GuestMetrics != "OpaqueRef:NULL" {
+		guestMetrics, err = xenapi.VMGuestMetrics.GetRecord(session, vmRecord.GuestMetrics)
+		if err != nil {
+			return errors.New(err.Error())
+		}
+	}
+	data.GuestMetricsLive = types.BoolValue(guestMetrics.Live)
+	data.GuestMemory, diags = types.MapValueFrom(ctx, types.StringType, guestMetrics.Memory)
+	if diags.HasError() {
+		return errors.New("unable to get guest memory map value")
+	}
+	data.GuestDisks, diags = types.MapValueFrom(ctx, types.StringType, guestMetrics.Disks)
+	if diags.HasError() {
+		return errors.New("unable to get guest disks map value")
+	}
+	return nil
+}
+
+// defaultHVMBootOrder is the order the HVM firmware boots with when HVM-boot-params has none
+const defaultHVMBootOrder = "cd"
+
+func getBootOrderFromVMRecord(vmRecord xenapi.VMRecord) string {
+	bootOrder, ok := vmRecord.HVMBootParams["order"]
+	if ok && bootOrder != "" {
+		return bootOrder
+	}
+	// "BIOS order" is the only HVM boot policy, PV guests are started by the PV bootloader which
+	// has no boot order
+	if isHVMDomain(vmRecord) && vmRecord.HVMBootPolicy != "" {
+		return defaultHVMBootOrder
+	}
+	return ""
+}
+
+func getCorePerSocket(vmRecord xenapi.VMRecord) (int32, error) {
+	socket, ok := vmRecord.Platform["cores-per-socket"]
+	if !ok {
+		// VMs imported without the key use one socket, the same default as updateCorePerSocket
+		return int32(vmRecord.VCPUsMax), nil // #nosec G109
+	}
+	socketInt, err := strconv.Atoi(socket)
+	if err != nil {
+		return 0, errors.New("unable to convert cores-per-socket to an int value")
+	}
+
+	return int32(socketInt), nil // #nosec G109
+}
+
+func updateVMResourceModelComputed(ctx context.Context, session *xenapi.Session, vmRecord xenapi.VMRecord, data *vmResourceModel) error {
+	var err error
+	data.NameDescription = types.StringValue(vmRecord.NameDescription)
+	data.UUID = types.StringValue(vmRecord.UUID)
+	data.ID = types.StringValue(vmRecord.UUID)
+	data.StaticMemMin = types.Int64Value(int64(vmRecord.MemoryStaticMin))
+	data.DynamicMemMin = types.Int64Value(int64(vmRecord.MemoryDynamicMin))
+	data.DynamicMemMax = types.Int64Value(int64(vmRecord.MemoryDynamicMax))
+	data.RequiresReboot = types.BoolValue(vmRecord.RequiresReboot)
+
+	socketInt, err := getCorePerSocket(vmRecord)
+	if err != nil {
+		return err
+	}
+	data.CorePerSocket = types.Int32Value(socketInt)
+
+	data.NetworkInterface, err = getVIFsFromVMRecord(ctx, session, vmRecord)
+	if err != nil {
+		return err
+	}
+
+	data.HardDrive, _, err = getVBDsFromVMRecord(ctx, session, vmRecord, xenapi.VbdTypeDisk)
+	if err != nil {
+		return err
+	}
+
+	cds, err := getCDsFromVMRecord(session, vmRecord)
+	if err != nil {
+		return err
+	}
+	isoNames := make([]string, 0, len(cds))
+	cdromDrives := make([]cdromDriveModel, 0, len(cds))
+	for _, cd := range cds {
+		isoNames = append(isoNames, cd.isoName)
+		cdromDrives = append(cdromDrives, cdromDriveModel{
+			VBD:               types.StringValue(string(cd.vbdRef)),
+			Empty:             types.BoolValue(cd.empty),
+			CurrentlyAttached: types.BoolValue(cd.currentlyAttached),
+		})
+	}
+	var diags diag.Diagnostics
+	data.CDROMs, diags = types.ListValueFrom(ctx, types.StringType, isoNames)
+	if diags.HasError() {
+		return errors.New("unable to get CD-ROMs list value")
+	}
+	data.CDROMDrives, diags = types.ListValueFrom(ctx, types.ObjectType{AttrTypes: cdromDriveModelAttrTypes}, cdromDrives)
+	if diags.HasError() {
+		return errors.New("unable to get CD-ROM drives list value")
+	}
+	// keep null when VM has no CD-ROM drive, "" means an empty drive
+	data.CDROM = types.StringNull()
+	data.CDROMVDI = types.StringNull()
+	if len(cds) > 0 {
+		data.CDROM = types.StringValue(cds[0].isoName)
+		data.CDROMVDI = types.StringValue(cds[0].vdiUUID)
+	}
+
+	bootMode, err := getBootModeFromVMRecord(vmRecord)
+	if err != nil {
+		return err
+	}
+	data.BootMode = types.StringValue(bootMode)
+
+	data.BootOrder = types.StringValue(getBootOrderFromVMRecord(vmRecord))
+	data.DomainType = types.StringValue(string(vmRecord.DomainType))
+	data.ActionsAfterShutdown = types.StringValue(string(vmRecord.ActionsAfterShutdown))
+	data.ActionsAfterReboot = types.StringValue(string(vmRecord.ActionsAfterReboot))
+	data.ActionsAfterCrash = types.StringValue(string(vmRecord.ActionsAfterCrash))
+	data.HVMShadowMultiplier = types.Float64Value(vmRecord.HVMShadowMultiplier)
+	data.UserVersion = types.Int64Value(int64(vmRecord.UserVersion))
+	data.GenerationID = types.StringValue(vmRecord.GenerationID)
+	consoleURL, err := getVMConsoleURL(session, vmRecord.Consoles)
+	if err != nil {
+		return err
+	}
+	data.ConsoleURL = types.StringValue(consoleURL)
+	data.SuspendSR = types.StringValue("")
+	if string(vmRecord.SuspendSR) != "OpaqueRef:NULL" && vmRecord.SuspendSR != "" {
+		// the suspend SR may be forgotten after it's set, the VM has no usable suspend SR then
+		suspendSRUUID, err := xenapi.SR.GetUUID(session, vmRecord.SuspendSR)
+		if err == nil {
+			data.SuspendSR = types.StringValue(suspendSRUUID)
+		} else if !strings.Contains(err.Error(), "HANDLE_INVALID") {
+			return errors.New(err.Error())
+		}
+	}
+
+	vmState := getVMState(vmRecord.OtherConfig)
+
+	// only keep the key which configured by user
+	data.OtherConfig, err = getOtherConfigFromVMRecord(ctx, vmRecord)
+	if err != nil {
+		return err
+	}
+
+	data.Platform, err = getTrackedMapValue(ctx, vmRecord.Platform, vmState["platform_keys"])
+	if err != nil {
+		return errors.New("unable to get VM platform")
+	}
+	data.XenstoreData, err = getTrackedMapValue(ctx, vmRecord.XenstoreData, vmState["xenstore_data_keys"])
+	if err != nil {
+		return errors.New("unable to get VM xenstore data")
+	}
+
+	blockedOperations := make(map[string]string)
+	for operation, reason := range vmRecord.BlockedOperations {
+		blockedOperations[string(operation)] = reason
+	}
+	data.BlockedOperations, err = getTrackedMapValue(ctx, blockedOperations, vmState["blocked_operations_keys"])
+	if err != nil {
+		return errors.New("unable to get VM blocked operations")
+	}
+
+	if _, ok := vmState["check_ip_timeout"]; ok {
+		checkIPDuration, err := strconv.Atoi(vmState["check_ip_timeout"])
+		if err != nil {
+			return errors.New("unable to convert check_ip_timeout to an int value")
+		}
+		data.CheckIPTimeout = types.Int64Value(int64(checkIPDuration))
+
 		ip, err := checkIP(ctx, session, vmRecord)
 		if err != nil {
 			return err
@@ -686,17 +1469,48 @@ func updateVMResourceModelComputed(ctx context.Context, session *xenapi.Session,
 		data.DefaultIP = types.StringValue(ip)
 	}
 
-	if _, ok := vmRecord.OtherConfig["tf_sr_for_full_disk_copy"]; ok {
-		data.SRForFullDiskCopy = types.StringValue(vmRecord.OtherConfig["tf_sr_for_full_disk_copy"])
+	if _, ok := vmState["sr_for_full_disk_copy"]; ok {
+		data.SRForFullDiskCopy = types.StringValue(vmState["sr_for_full_disk_copy"])
 	}
 
+	data.ForceDestroy = types.BoolValue(vmState["force_destroy"] == "true")
+	data.EnforceUniqueName = types.BoolValue(vmState["enforce_unique_name"] == "true")
+	data.WaitForGuestTools = types.BoolValue(vmState["wait_for_guest_tools"] == "true")
+	if _, ok := vmState["guest_tools_timeout"]; ok {
+		guestToolsTimeout, err := strconv.Atoi(vmState["guest_tools_timeout"])
+		if err != nil {
+			return errors.New("unable to convert guest_tools_timeout to an int value")
+		}
+		data.GuestToolsTimeout = types.Int64Value(int64(guestToolsTimeout))
+	}
+	data.WaitForRunning = types.BoolValue(vmState["wait_for_running"] == "true")
+	if _, ok := vmState["running_timeout"]; ok {
+		runningTimeout, err := strconv.Atoi(vmState["running_timeout"])
+		if err != nil {
+			return errors.New("unable to convert running_timeout to an int value")
+		}
+		data.RunningTimeout = types.Int64Value(int64(runningTimeout))
+	}
+	if _, ok := vmState["shutdown_timeout"]; ok {
+		shutdownTimeout, err := strconv.Atoi(vmState["shutdown_timeout"])
+		if err != nil {
+			return errors.New("unable to convert shutdown_timeout to an int value")
+		}
+		data.ShutdownTimeout = types.Int64Value(int64(shutdownTimeout))
+	}
+	data.AutoReboot = types.BoolValue(vmState["auto_reboot_on_config_change"] == "true")
+
 	return nil
 }
 
 // Update vmResourceModel base on new vmRecord, except uuid
 func updateVMResourceModel(ctx context.Context, session *xenapi.Session, vmRecord xenapi.VMRecord, data *vmResourceModel) error {
 	data.NameLabel = types.StringValue(vmRecord.NameLabel)
-	data.TemplateName = types.StringValue(vmRecord.OtherConfig["tf_template_name"])
+	// the VM created from scratch doesn't have a template
+	data.TemplateName = types.StringNull()
+	if templateName := getVMState(vmRecord.OtherConfig)["template_name"]; templateName != "" {
+		data.TemplateName = types.StringValue(templateName)
+	}
 	data.StaticMemMax = types.Int64Value(int64(vmRecord.MemoryStaticMax))
 	data.VCPUs = types.Int32Value(int32(vmRecord.VCPUsMax))
 	return updateVMResourceModelComputed(ctx, session, vmRecord, data)
@@ -726,10 +1540,11 @@ func getVBDsFromVMRecord(ctx context.Context, session *xenapi.Session, vmRecord
 			vdiUUID = vdiRecord.UUID
 		}
 		vbd := vbdResourceModel{
-			VDI:      types.StringValue(vdiUUID),
-			VBD:      types.StringValue(string(vbdRef)),
-			Bootable: types.BoolValue(vbdRecord.Bootable),
-			Mode:     types.StringValue(string(vbdRecord.Mode)),
+			VDI:               types.StringValue(vdiUUID),
+			VBD:               types.StringValue(string(vbdRef)),
+			Bootable:          types.BoolValue(vbdRecord.Bootable),
+			Mode:              types.StringValue(string(vbdRecord.Mode)),
+			CurrentlyAttached: types.BoolValue(vbdRecord.CurrentlyAttached),
 		}
 		vbdSet = append(vbdSet, vbd)
 	}
@@ -743,10 +1558,146 @@ func getVBDsFromVMRecord(ctx context.Context, session *xenapi.Session, vmRecord
 	return setValue, vbdSet, nil
 }
 
+// setItemsDrift is the values of an attribute in the set items which are added or removed outside of
+// terraform, it's recorded in the private state so the same drift is reported only once
+type setItemsDrift struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+func (d setItemsDrift) Equal(other setItemsDrift) bool {
+	return slices.Equal(d.Added, other.Added) && slices.Equal(d.Removed, other.Removed)
+}
+
+// getSetItemsDrift compares the values of the attribute in the items of the prior and the current set,
+// it's used to find the disks and network interfaces added or removed outside of terraform
+func getSetItemsDrift(prior basetypes.SetValue, current basetypes.SetValue, attribute string) setItemsDrift {
+	drift := setItemsDrift{Added: []string{}, Removed: []string{}}
+	// nothing to compare with on import
+	if prior.IsNull() || prior.IsUnknown() {
+		return drift
+	}
+
+	priorValues := getSetItemValues(prior, attribute)
+	currentValues := getSetItemValues(current, attribute)
+	for _, value := range currentValues {
+		if !slices.Contains(priorValues, value) {
+			drift.Added = append(drift.Added, value)
+		}
+	}
+	for _, value := range priorValues {
+		if !slices.Contains(currentValues, value) {
+			drift.Removed = append(drift.Removed, value)
+		}
+	}
+	slices.Sort(drift.Added)
+	slices.Sort(drift.Removed)
+	return drift
+}
+
+// getReportedDrift parses the drift recorded in the private state on the last refresh
+func getReportedDrift(data []byte) map[string]setItemsDrift {
+	reported := make(map[string]setItemsDrift)
+	if len(data) == 0 {
+		return reported
+	}
+	// the drift is reported again if the private state can't be parsed
+	_ = json.Unmarshal(data, &reported)
+	return reported
+}
+
+// fillSetItemsFromState fills the unknown attributes of the planned set items with the values of the
+// matching items in the state, so the computed attributes, for example, vbd_ref and vif_ref, don't
+// show as changed for the items which are not. A state item matches if it has the same values for
+// all the known attributes of the planned item, each state item is used once. The attributes in
+// keepUnknown are left unknown as they may change in the apply.
+func fillSetItemsFromState(ctx context.Context, plan basetypes.SetValue, state basetypes.SetValue, keepUnknown []string) (basetypes.SetValue, diag.Diagnostics) {
+	if plan.IsNull() || plan.IsUnknown() || state.IsNull() || state.IsUnknown() {
+		return plan, nil
+	}
+
+	stateElements := state.Elements()
+	used := make([]bool, len(stateElements))
+	elements := make([]attr.Value, 0, len(plan.Elements()))
+	for _, element := range plan.Elements() {
+		object, ok := element.(types.Object)
+		if !ok || object.IsNull() || object.IsUnknown() || !hasUnknownAttribute(object) {
+			elements = append(elements, element)
+			continue
+		}
+		for i, stateElement := range stateElements {
+			stateObject, ok := stateElement.(types.Object)
+			if used[i] || !ok || !matchKnownAttributes(object, stateObject) {
+				continue
+			}
+			used[i] = true
+			attributes := maps.Clone(stateObject.Attributes())
+			for _, name := range keepUnknown {
+				if value, ok := object.Attributes()[name]; ok && value.IsUnknown() {
+					attributes[name] = value
+				}
+			}
+			filled, diags := types.ObjectValue(object.AttributeTypes(ctx), attributes)
+			if diags.HasError() {
+				return plan, diags
+			}
+			element = filled
+			break
+		}
+		elements = append(elements, element)
+	}
+	return types.SetValue(plan.ElementType(ctx), elements)
+}
+
+func hasUnknownAttribute(object types.Object) bool {
+	for _, value := range object.Attributes() {
+		if value.IsUnknown() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchKnownAttributes returns true if the state object has the same values for all the known attributes of
+// the planned object
+func matchKnownAttributes(plan types.Object, state types.Object) bool {
+	if state.IsNull() || state.IsUnknown() {
+		return false
+	}
+	stateAttributes := state.Attributes()
+	for name, value := range plan.Attributes() {
+		if value.IsUnknown() {
+			continue
+		}
+		stateValue, ok := stateAttributes[name]
+		if !ok || !value.Equal(stateValue) {
+			return false
+		}
+	}
+	return true
+}
+
+func getSetItemValues(set basetypes.SetValue, attribute string) []string {
+	values := []string{}
+	for _, element := range set.Elements() {
+		object, ok := element.(types.Object)
+		if !ok || object.IsNull() || object.IsUnknown() {
+			continue
+		}
+		value, ok := object.Attributes()[attribute].(types.String)
+		if !ok || value.IsNull() || value.IsUnknown() {
+			continue
+		}
+		values = append(values, value.ValueString())
+	}
+	return values
+}
+
 func getOtherConfigFromVMRecord(ctx context.Context, vmRecord xenapi.VMRecord) (basetypes.MapValue, error) {
 	otherConfig := make(map[string]string)
+	otherConfigKeys := strings.Split(getVMState(vmRecord.OtherConfig)["other_config_keys"], ",")
 	for key := range vmRecord.OtherConfig {
-		if slices.Contains(strings.Split(vmRecord.OtherConfig["tf_other_config_keys"], ","), key) {
+		if slices.Contains(otherConfigKeys, key) {
 			otherConfig[key] = vmRecord.OtherConfig[key]
 		}
 	}
@@ -759,6 +1710,52 @@ func getOtherConfigFromVMRecord(ctx context.Context, vmRecord xenapi.VMRecord) (
 	return otherConfigMap, nil
 }
 
+// getTrackedKeys returns the keys recorded in the comma separated tracked keys
+func getTrackedKeys(trackedKeys string) []string {
+	var keys []string
+	for _, key := range strings.Split(trackedKeys, ",") {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// getTrackedMapValue returns the map value which only keeps the keys set by terraform
+func getTrackedMapValue(ctx context.Context, values map[string]string, trackedKeys string) (basetypes.MapValue, error) {
+	trackedValues := make(map[string]string)
+	for _, key := range getTrackedKeys(trackedKeys) {
+		if value, ok := values[key]; ok {
+			trackedValues[key] = value
+		}
+	}
+
+	mapValue, diags := types.MapValueFrom(ctx, types.StringType, trackedValues)
+	if diags.HasError() {
+		return mapValue, errors.New("unable to get map value")
+	}
+	return mapValue, nil
+}
+
+// mergeTrackedKeys removes the keys set by terraform before from values and sets the planned ones, the other
+// keys are kept. The keys managed by the provider are left as they are. It returns the new tracked keys.
+func mergeTrackedKeys(values map[string]string, trackedKeys string, planValues map[string]string, managedKeys []string) string {
+	for _, key := range getTrackedKeys(trackedKeys) {
+		delete(values, key)
+	}
+
+	var keys []string
+	for key, value := range planValues {
+		if slices.Contains(managedKeys, key) {
+			continue
+		}
+		values[key] = value
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
 func getVIFsFromVMRecord(ctx context.Context, session *xenapi.Session, vmRecord xenapi.VMRecord) (basetypes.SetValue, error) {
 	vifSet := []vifResourceModel{}
 	var setValue basetypes.SetValue
@@ -780,6 +1777,7 @@ func getVIFsFromVMRecord(ctx context.Context, session *xenapi.Session, vmRecord
 			VIF:     types.StringValue(string(vifRef)),
 			MAC:     types.StringValue(vifRecord.MAC),
 			Device:  types.StringValue(vifRecord.Device),
+			MTU:     types.Int32Value(int32(getVIFMTU(vifRecord.OtherConfig, networkRecord.MTU))), // #nosec G109
 		}
 
 		vif.OtherConfig, diags = types.MapValueFrom(ctx, types.StringType, vifRecord.OtherConfig)
@@ -846,14 +1844,31 @@ func updateVMMemory(ctx context.Context, session *xenapi.Session, vmRef xenapi.V
 		return errors.New(err.Error())
 	}
 	if vmState == xenapi.VMPowerStateRunning {
-		return errors.New("unable to change memory for a running VM")
+		if !plan.AutoReboot.ValueBool() {
+			return errors.New("unable to change memory for a running VM, set auto_reboot_on_config_change to restart it with the new memory")
+		}
+		// the memory limits can only be set on a halted VM, restart it cleanly around the change
+		tflog.Debug(ctx, "-----> Shut down VM to change memory")
+		if plan.ShutdownTimeout.ValueInt64() <= 0 {
+			return errors.New("unable to shut down the VM cleanly to change memory, the clean shutdown is disabled by shutdown_timeout = 0")
+		}
+		if !cleanShutdownVM(ctx, session, vmRef, plan.ShutdownTimeout.ValueInt64()) {
+			return errors.New("unable to shut down the VM cleanly to change memory, the guest tools may not be running")
+		}
 	}
 	err = xenapi.VM.SetMemoryLimits(session, vmRef, planMemorySetting.staticMemMin, planMemorySetting.staticMemMax, planMemorySetting.dynamicMemMin, planMemorySetting.dynamicMemMax)
 	if err != nil {
-		return errors.New(err.Error())
+		err = errors.New(err.Error())
+	}
+	if vmState == xenapi.VMPowerStateRunning {
+		// start the VM again even if the memory is not changed
+		startErr := xenapi.VM.Start(session, vmRef, false, true)
+		if startErr != nil {
+			err = errors.Join(err, errors.New(startErr.Error()))
+		}
 	}
 
-	return nil
+	return err
 }
 
 func changeVCPUSettings(session *xenapi.Session, vmRef xenapi.VMRef, plan vmResourceModel) error {
@@ -904,8 +1919,116 @@ func updateVMCPUs(ctx context.Context, session *xenapi.Session, vmRef xenapi.VMR
 	return changeVCPUSettings(session, vmRef, plan)
 }
 
+// vmManagedPlatformKeys are the platform keys set by the provider from the other attributes
+var vmManagedPlatformKeys = []string{"secureboot", "cores-per-socket"}
+
+// updatePlatformFromPlan merges the platform in plan with the VM platform, it should be called before the
+// provider-managed keys are set
+func updatePlatformFromPlan(ctx context.Context, session *xenapi.Session, vmRef xenapi.VMRef, plan vmResourceModel) error {
+	if plan.Platform.IsUnknown() {
+		return nil
+	}
+	planPlatform := make(map[string]string)
+	diags := plan.Platform.ElementsAs(ctx, &planPlatform, false)
+	if diags.HasError() {
+		return errors.New("unable to access VM platform in plan data")
+	}
+
+	vmRecord, err := xenapi.VM.GetRecord(session, vmRef)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	vmState := getVMState(vmRecord.OtherConfig)
+	platform := vmRecord.Platform
+	platformKeys := mergeTrackedKeys(platform, vmState["platform_keys"], planPlatform, vmManagedPlatformKeys)
+	err = xenapi.VM.SetPlatform(session, vmRef, platform)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+
+	otherConfig := vmRecord.OtherConfig
+	vmState["platform_keys"] = platformKeys
+	err = setVMState(otherConfig, vmState)
+	if err != nil {
+		return err
+	}
+	err = xenapi.VM.SetOtherConfig(session, vmRef, otherConfig)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+
+	return nil
+}
+
+// xenstoreLivePrefix is the part of the xenstore tree which XAPI also writes to the domain of a running VM,
+// the other keys are only written when the VM starts
+const xenstoreLivePrefix = "vm-data/"
+
+// getXenstoreRebootKeys returns the keys added, changed or removed in the xenstore data which only take
+// effect after the VM is rebooted
+func getXenstoreRebootKeys(oldData map[string]string, newData map[string]string) []string {
+	var keys []string
+	for key, value := range newData {
+		if oldValue, ok := oldData[key]; (!ok || oldValue != value) && !strings.HasPrefix(key, xenstoreLivePrefix) {
+			keys = append(keys, key)
+		}
+	}
+	for key := range oldData {
+		if _, ok := newData[key]; !ok && !strings.HasPrefix(key, xenstoreLivePrefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// updateXenstoreDataFromPlan merges the xenstore data in plan with the VM xenstore data.
+// For a running VM, XAPI writes the vm-data keys to the live domain as well, so the guest sees the
+// new values without a reboot.
+func updateXenstoreDataFromPlan(ctx context.Context, session *xenapi.Session, vmRef xenapi.VMRef, plan vmResourceModel) error {
+	if plan.XenstoreData.IsUnknown() {
+		return nil
+	}
+	planXenstoreData := make(map[string]string)
+	diags := plan.XenstoreData.ElementsAs(ctx, &planXenstoreData, false)
+	if diags.HasError() {
+		return errors.New("unable to access VM xenstore data in plan data")
+	}
+
+	vmRecord, err := xenapi.VM.GetRecord(session, vmRef)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	vmState := getVMState(vmRecord.OtherConfig)
+	xenstoreData := maps.Clone(vmRecord.XenstoreData)
+	xenstoreDataKeys := mergeTrackedKeys(xenstoreData, vmState["xenstore_data_keys"], planXenstoreData, []string{})
+	if !maps.Equal(xenstoreData, vmRecord.XenstoreData) {
+		err = xenapi.VM.SetXenstoreData(session, vmRef, xenstoreData)
+		if err != nil {
+			return errors.New(err.Error())
+		}
+		rebootKeys := getXenstoreRebootKeys(vmRecord.XenstoreData, xenstoreData)
+		if vmRecord.PowerState == xenapi.VMPowerStateRunning && len(rebootKeys) > 0 {
+			tflog.Warn(ctx, "The xenstore data keys "+strings.Join(rebootKeys, ", ")+" only take effect after the VM is rebooted")
+		}
+	}
+
+	otherConfig := vmRecord.OtherConfig
+	vmState["xenstore_data_keys"] = xenstoreDataKeys
+	err = setVMState(otherConfig, vmState)
+	if err != nil {
+		return err
+	}
+	err = xenapi.VM.SetOtherConfig(session, vmRef, otherConfig)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+
+	return nil
+}
+
 func updateCorePerSocket(session *xenapi.Session, vmRef xenapi.VMRef, plan vmResourceModel) error {
-	platform, err := xenapi.VM.GetPlatform(session, vmRef)
+	platform, err := vmAPI.GetPlatform(session, vmRef)
 	if err != nil {
 		return errors.New(err.Error())
 	}
@@ -913,7 +2036,7 @@ func updateCorePerSocket(session *xenapi.Session, vmRef xenapi.VMRef, plan vmRes
 		// if user doesn't set cores-per-socket and it is not found in template, set it to VCPUs num as the default value
 		if _, ok := platform["cores-per-socket"]; !ok {
 			platform["cores-per-socket"] = plan.VCPUs.String()
-			err := xenapi.VM.SetPlatform(session, vmRef, platform)
+			err := vmAPI.SetPlatform(session, vmRef, platform)
 			if err != nil {
 				return errors.New(err.Error())
 			}
@@ -925,7 +2048,7 @@ func updateCorePerSocket(session *xenapi.Session, vmRef xenapi.VMRef, plan vmRes
 			return fmt.Errorf("%d cores could not fit to %d cores-per-socket topology", vcpus, coresPerSocket)
 		}
 		platform["cores-per-socket"] = strconv.Itoa(coresPerSocket)
-		err := xenapi.VM.SetPlatform(session, vmRef, platform)
+		err := vmAPI.SetPlatform(session, vmRef, platform)
 		if err != nil {
 			return errors.New(err.Error())
 		}
@@ -988,9 +2111,136 @@ func updateBootMode(session *xenapi.Session, vmRef xenapi.VMRef, plan vmResource
 	return nil
 }
 
-func vmResourceModelUpdate(ctx context.Context, session *xenapi.Session, vmRef xenapi.VMRef, plan vmResourceModel, state vmResourceModel) error {
+func updateDomainType(session *xenapi.Session, vmRef xenapi.VMRef, plan vmResourceModel) error {
+	// don't set domain type if it is unknown, using the default value from the template
+	if plan.DomainType.IsUnknown() {
+		return nil
+	}
+
+	vmRecord, err := xenapi.VM.GetRecord(session, vmRef)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+
+	domainType := xenapi.DomainType(plan.DomainType.ValueString())
+	if vmRecord.DomainType == domainType {
+		return nil
+	}
+
+	if vmRecord.PowerState != xenapi.VMPowerStateHalted {
+		return errors.New("unable to update the domain_type for a VM which is not halted")
+	}
+
+	err = xenapi.VM.SetDomainType(session, vmRef, domainType)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+
+	return nil
+}
+
+func updateVMActions(session *xenapi.Session, vmRef xenapi.VMRef, plan vmResourceModel) error {
+	// don't set the actions if they are unknown, using the default value from the template
+	if !plan.ActionsAfterShutdown.IsUnknown() {
+		err := xenapi.VM.SetActionsAfterShutdown(session, vmRef, xenapi.OnNormalExit(plan.ActionsAfterShutdown.ValueString()))
+		if err != nil {
+			return errors.New(err.Error())
+		}
+	}
+
+	if !plan.ActionsAfterReboot.IsUnknown() {
+		err := xenapi.VM.SetActionsAfterReboot(session, vmRef, xenapi.OnNormalExit(plan.ActionsAfterReboot.ValueString()))
+		if err != nil {
+			return errors.New(err.Error())
+		}
+	}
+
+	if !plan.ActionsAfterCrash.IsUnknown() {
+		err := xenapi.VM.SetActionsAfterCrash(session, vmRef, xenapi.OnCrashBehaviour(plan.ActionsAfterCrash.ValueString()))
+		if err != nil {
+			return errors.New(err.Error())
+		}
+	}
+
+	return nil
+}
+
+func updateUserVersion(session *xenapi.Session, vmRef xenapi.VMRef, plan vmResourceModel) error {
+	// don't set the user version if it is unknown, using the default value from the template
+	if plan.UserVersion.IsUnknown() {
+		return nil
+	}
+	err := xenapi.VM.SetUserVersion(session, vmRef, int(plan.UserVersion.ValueInt64()))
+	if err != nil {
+		return errors.New(err.Error())
+	}
+
+	return nil
+}
+
+func updateHVMShadowMultiplier(session *xenapi.Session, vmRef xenapi.VMRef, plan vmResourceModel) error {
+	// don't set the shadow multiplier if it is unknown, using the default value from the template
+	if plan.HVMShadowMultiplier.IsUnknown() {
+		return nil
+	}
+	vmRecord, err := xenapi.VM.GetRecord(session, vmRef)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	multiplier := plan.HVMShadowMultiplier.ValueFloat64()
+	if vmRecord.HVMShadowMultiplier == multiplier {
+		return nil
+	}
+	if vmRecord.PowerState == xenapi.VMPowerStateRunning {
+		err = xenapi.VM.SetShadowMultiplierLive(session, vmRef, multiplier)
+	} else {
+		err = xenapi.VM.SetHVMShadowMultiplier(session, vmRef, multiplier)
+	}
+	if err != nil {
+		return errors.New(err.Error())
+	}
+
+	return nil
+}
+
+// getSuspendSRRef returns the ref of the suspend SR in plan, the SR must exist
+func getSuspendSRRef(session *xenapi.Session, suspendSRUUID string) (xenapi.SRRef, error) {
+	if suspendSRUUID == "" {
+		return xenapi.SRRef("OpaqueRef:NULL"), nil
+	}
+	srRef, err := xenapi.SR.GetByUUID(session, suspendSRUUID)
+	if err != nil {
+		return srRef, errors.New("unable to find the suspend SR " + suspendSRUUID + "\n" + err.Error())
+	}
+	return srRef, nil
+}
+
+func updateSuspendSR(session *xenapi.Session, vmRef xenapi.VMRef, plan vmResourceModel) error {
+	// don't set the suspend SR if it is unknown, using the default value from the template
+	if plan.SuspendSR.IsUnknown() {
+		return nil
+	}
+	srRef, err := getSuspendSRRef(session, plan.SuspendSR.ValueString())
+	if err != nil {
+		return err
+	}
+	err = xenapi.VM.SetSuspendSR(session, vmRef, srRef)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+
+	return nil
+}
+
+func vmResourceModelUpdate(ctx context.Context, session *xenapi.Session, vmRef xenapi.VMRef, plan vmResourceModel, state vmResourceModel) error {
+	// unblock the operations first, the changes below may need them
+	err := unblockOperations(ctx, session, vmRef, plan)
+	if err != nil {
+		return err
+	}
+
 	// set other config before getting the VM record for tf_ fields update
-	err := updateOtherConfigFromPlan(ctx, session, vmRef, plan)
+	err = updateOtherConfigFromPlan(ctx, session, vmRef, plan)
 	if err != nil {
 		return err
 	}
@@ -1030,6 +2280,16 @@ func vmResourceModelUpdate(ctx context.Context, session *xenapi.Session, vmRef x
 		return err
 	}
 
+	err = updatePlatformFromPlan(ctx, session, vmRef, plan)
+	if err != nil {
+		return err
+	}
+
+	err = updateXenstoreDataFromPlan(ctx, session, vmRef, plan)
+	if err != nil {
+		return err
+	}
+
 	err = updateCorePerSocket(session, vmRef, plan)
 	if err != nil {
 		return err
@@ -1045,20 +2305,162 @@ func vmResourceModelUpdate(ctx context.Context, session *xenapi.Session, vmRef x
 		return err
 	}
 
+	err = updateDomainType(session, vmRef, plan)
+	if err != nil {
+		return err
+	}
+
+	err = updateVMActions(session, vmRef, plan)
+	if err != nil {
+		return err
+	}
+
+	err = updateSuspendSR(session, vmRef, plan)
+	if err != nil {
+		return err
+	}
+
+	err = updateHVMShadowMultiplier(session, vmRef, plan)
+	if err != nil {
+		return err
+	}
+
+	err = updateUserVersion(session, vmRef, plan)
+	if err != nil {
+		return err
+	}
+
+	// reboot after all the changes, before waiting for the VM to be running again
+	err = rebootVMIfRequired(ctx, session, vmRef, plan)
+	if err != nil {
+		return err
+	}
+
 	err = startVM(session, vmRef, plan)
 	if err != nil {
 		return err
 	}
 
+	err = waitForRunning(ctx, session, vmRef, plan)
+	if err != nil {
+		return err
+	}
+
+	err = waitForGuestTools(ctx, session, vmRef, plan)
+	if err != nil {
+		return err
+	}
+
+	// block the operations at last, they may be needed by the update
+	err = blockOperations(ctx, session, vmRef, plan)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// getPlanBlockedOperations returns the blocked operations in plan, nil if they are unknown
+func getPlanBlockedOperations(ctx context.Context, plan vmResourceModel) (map[string]string, error) {
+	if plan.BlockedOperations.IsUnknown() {
+		return nil, nil
+	}
+	planBlockedOperations := make(map[string]string)
+	diags := plan.BlockedOperations.ElementsAs(ctx, &planBlockedOperations, false)
+	if diags.HasError() {
+		return nil, errors.New("unable to access VM blocked operations in plan data")
+	}
+	return planBlockedOperations, nil
+}
+
+// unblockOperations unblocks the operations blocked by terraform before which are not in the plan, it runs
+// before the other changes of the update as they may need the operations. The operations blocked outside of
+// terraform, e.g. by the template or XenCenter, are kept.
+func unblockOperations(ctx context.Context, session *xenapi.Session, vmRef xenapi.VMRef, plan vmResourceModel) error {
+	planBlockedOperations, err := getPlanBlockedOperations(ctx, plan)
+	if err != nil || planBlockedOperations == nil {
+		return err
+	}
+	vmRecord, err := xenapi.VM.GetRecord(session, vmRef)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+
+	for _, operation := range getTrackedKeys(getVMState(vmRecord.OtherConfig)["blocked_operations_keys"]) {
+		if _, ok := vmRecord.BlockedOperations[xenapi.VMOperations(operation)]; !ok {
+			continue
+		}
+		if _, ok := planBlockedOperations[operation]; !ok {
+			tflog.Debug(ctx, "---> Unblock VM operation: "+operation)
+			err = xenapi.VM.RemoveFromBlockedOperations(session, vmRef, xenapi.VMOperations(operation))
+			if err != nil {
+				return errors.New(err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+// blockOperations blocks the operations in the plan which are not blocked yet or blocked for another reason,
+// and records them as the operations blocked by terraform. It runs after the other changes of the update so
+// it doesn't block an operation the update needs.
+func blockOperations(ctx context.Context, session *xenapi.Session, vmRef xenapi.VMRef, plan vmResourceModel) error {
+	planBlockedOperations, err := getPlanBlockedOperations(ctx, plan)
+	if err != nil || planBlockedOperations == nil {
+		return err
+	}
+	vmRecord, err := xenapi.VM.GetRecord(session, vmRef)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+
+	var keys []string
+	for operation, reason := range planBlockedOperations {
+		keys = append(keys, operation)
+		currentReason, ok := vmRecord.BlockedOperations[xenapi.VMOperations(operation)]
+		if ok && currentReason == reason {
+			continue
+		}
+		if ok {
+			err = xenapi.VM.RemoveFromBlockedOperations(session, vmRef, xenapi.VMOperations(operation))
+			if err != nil {
+				return errors.New(err.Error())
+			}
+		}
+		tflog.Debug(ctx, "---> Block VM operation: "+operation)
+		err = xenapi.VM.AddToBlockedOperations(session, vmRef, xenapi.VMOperations(operation), reason)
+		if err != nil {
+			return errors.New(err.Error())
+		}
+	}
+	sort.Strings(keys)
+
+	otherConfig := vmRecord.OtherConfig
+	vmState := getVMState(otherConfig)
+	vmState["blocked_operations_keys"] = strings.Join(keys, ",")
+	err = setVMState(otherConfig, vmState)
+	if err != nil {
+		return err
+	}
+	err = xenapi.VM.SetOtherConfig(session, vmRef, otherConfig)
+	if err != nil {
+		return errors.New(err.Error())
+	}
 	return nil
 }
 
-func setVMResourceModel(ctx context.Context, session *xenapi.Session, vmRef xenapi.VMRef, plan vmResourceModel) error {
+func setVMResourceModel(ctx context.Context, session *xenapi.Session, vmRef xenapi.VMRef, plan vmResourceModel, parallel parallelLimit) error {
 	err := setOtherConfigWhenCreate(session, vmRef)
 	if err != nil {
 		return err
 	}
 
+	// the blocked operations may be copied from the template, unblock the ones not in the plan first
+	err = unblockOperations(ctx, session, vmRef, plan)
+	if err != nil {
+		return err
+	}
+
 	// set other config before getting the VM record for tf_ fields update
 	err = updateOtherConfigFromPlan(ctx, session, vmRef, plan)
 	if err != nil {
@@ -1088,6 +2490,17 @@ func setVMResourceModel(ctx context.Context, session *xenapi.Session, vmRef xena
 		return err
 	}
 
+	// set platform and xenstore data before the provider-managed platform keys
+	err = updatePlatformFromPlan(ctx, session, vmRef, plan)
+	if err != nil {
+		return err
+	}
+
+	err = updateXenstoreDataFromPlan(ctx, session, vmRef, plan)
+	if err != nil {
+		return err
+	}
+
 	err = updateCorePerSocket(session, vmRef, plan)
 	if err != nil {
 		return err
@@ -1105,20 +2518,55 @@ func setVMResourceModel(ctx context.Context, session *xenapi.Session, vmRef xena
 		return err
 	}
 
-	// add hard_drive
-	err = createVBDs(ctx, session, vmRef, plan, xenapi.VbdTypeDisk)
+	// set domain type
+	err = updateDomainType(session, vmRef, plan)
 	if err != nil {
 		return err
 	}
 
-	// set CDROM and it should be set after hard_drive to keep device order
-	err = setCDROM(ctx, session, vmRef, plan)
+	// set actions after shutdown, reboot and crash
+	err = updateVMActions(session, vmRef, plan)
+	if err != nil {
+		return err
+	}
+
+	err = updateSuspendSR(session, vmRef, plan)
+	if err != nil {
+		return err
+	}
+
+	err = updateHVMShadowMultiplier(session, vmRef, plan)
+	if err != nil {
+		return err
+	}
+
+	err = updateUserVersion(session, vmRef, plan)
 	if err != nil {
 		return err
 	}
 
-	// add network_interface
-	err = createVIFs(ctx, session, vmRef, plan)
+	// the disks and the network interfaces don't depend on each other, create them at the same time, each disk and
+	// network interface is created by its own goroutine within the limit of the concurrent operations
+	var wg sync.WaitGroup
+	var vbdErr, vifErr error
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		// add hard_drive
+		vbdErr = createVBDs(ctx, session, vmRef, plan, xenapi.VbdTypeDisk, parallel)
+		if vbdErr != nil {
+			return
+		}
+		// set CDROM and it should be set after hard_drive to keep device order
+		vbdErr = setCDROM(ctx, session, vmRef, plan)
+	}()
+	go func() {
+		defer wg.Done()
+		// add network_interface
+		vifErr = createVIFs(ctx, session, vmRef, plan, parallel)
+	}()
+	wg.Wait()
+	err = errors.Join(vbdErr, vifErr)
 	if err != nil {
 		return err
 	}
@@ -1138,6 +2586,22 @@ func setVMResourceModel(ctx context.Context, session *xenapi.Session, vmRef xena
 	if err != nil {
 		return err
 	}
+
+	err = waitForRunning(ctx, session, vmRef, plan)
+	if err != nil {
+		return err
+	}
+
+	err = waitForGuestTools(ctx, session, vmRef, plan)
+	if err != nil {
+		return err
+	}
+
+	// block the operations at last, they may be needed to set up the VM
+	err = blockOperations(ctx, session, vmRef, plan)
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -1148,9 +2612,14 @@ func isValidIpAddress(ip net.IP) bool {
 	return !(ip.IsLinkLocalMulticast() || ip.IsLinkLocalUnicast() || ip.IsLoopback() || ip.IsMulticast())
 }
 
+// isVMAutoStarted returns true if the VM is started automatically, which happens when the check_ip_timeout
+// is set and not equal to 0, or when waiting for the guest tools or for the VM to be running
+func isVMAutoStarted(plan vmResourceModel) bool {
+	return (!plan.CheckIPTimeout.IsUnknown() && plan.CheckIPTimeout.ValueInt64() != 0) || plan.WaitForGuestTools.ValueBool() || plan.WaitForRunning.ValueBool()
+}
+
 func startVM(session *xenapi.Session, vmRef xenapi.VMRef, plan vmResourceModel) error {
-	// start a VM automatically if the check_ip_timeout is set and not equal to 0
-	if plan.CheckIPTimeout.IsUnknown() || plan.CheckIPTimeout.ValueInt64() == 0 {
+	if !isVMAutoStarted(plan) {
 		return nil
 	}
 	vmPowerState, err := xenapi.VM.GetPowerState(session, vmRef)
@@ -1168,8 +2637,105 @@ func startVM(session *xenapi.Session, vmRef xenapi.VMRef, plan vmResourceModel)
 	return nil
 }
 
+// rebootVMIfRequired cleanly reboots the running VM when auto_reboot_on_config_change is set and XAPI reports
+// the VM requires a reboot to apply the changes
+func rebootVMIfRequired(ctx context.Context, session *xenapi.Session, vmRef xenapi.VMRef, plan vmResourceModel) error {
+	if !plan.AutoReboot.ValueBool() {
+		return nil
+	}
+	vmRecord, err := xenapi.VM.GetRecord(session, vmRef)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	if !isVMRebootRequired(vmRecord) {
+		return nil
+	}
+	tflog.Debug(ctx, "-----> Reboot VM to apply the changes")
+	err = xenapi.VM.CleanReboot(session, vmRef)
+	if err != nil {
+		return errors.New("unable to reboot the VM cleanly to apply the changes, the guest tools may not be running: " + err.Error())
+	}
+	return nil
+}
+
+// isVMRebootRequired returns true if the VM is running with the configuration changes which are not applied yet
+func isVMRebootRequired(vmRecord xenapi.VMRecord) bool {
+	return vmRecord.PowerState == xenapi.VMPowerStateRunning && vmRecord.RequiresReboot
+}
+
+// isVMStable returns true if the VM is running and no operation is in progress on it
+func isVMStable(vmRecord xenapi.VMRecord) bool {
+	return vmRecord.PowerState == xenapi.VMPowerStateRunning && len(vmRecord.CurrentOperations) == 0
+}
+
+// waitForRunning waits until the VM is running with no operation in progress, for example, a start or a
+// migration which is not finished yet
+func waitForRunning(ctx context.Context, session *xenapi.Session, vmRef xenapi.VMRef, plan vmResourceModel) error {
+	if !plan.WaitForRunning.ValueBool() {
+		return nil
+	}
+
+	operation := func() error {
+		vmRecord, err := withSessionRetry(session, func() (xenapi.VMRecord, error) {
+			return xenapi.VM.GetRecord(session, vmRef)
+		})
+		if err != nil {
+			if kind := classifyXapiError(err); kind == xapiErrorFatal || kind == xapiErrorSessionInvalid {
+				return backoff.Permanent(err)
+			}
+			return err
+		}
+		if !isVMStable(vmRecord) {
+			tflog.Debug(ctx, "-----> Retry checking VM is running, power state: "+string(vmRecord.PowerState))
+			return errors.New("VM is " + string(vmRecord.PowerState) + " with " + strconv.Itoa(len(vmRecord.CurrentOperations)) + " operations in progress")
+		}
+		return nil
+	}
+
+	timeout := plan.RunningTimeout.ValueInt64()
+	b := backoff.NewExponentialBackOff()
+	b.MaxInterval = 5 * time.Second
+	b.MaxElapsedTime = time.Duration(timeout) * time.Second
+	err := backoff.Retry(operation, backoff.WithContext(b, ctx))
+	if err != nil {
+		return errors.New("VM is not running in " + strconv.FormatInt(timeout, 10) + " seconds: " + err.Error())
+	}
+	return nil
+}
+
+// waitForGuestTools waits until the guest agent of the running VM is live and the PV drivers are detected
+func waitForGuestTools(ctx context.Context, session *xenapi.Session, vmRef xenapi.VMRef, plan vmResourceModel) error {
+	if !plan.WaitForGuestTools.ValueBool() {
+		return nil
+	}
+
+	timeout := plan.GuestToolsTimeout.ValueInt64()
+	timeoutChan := time.After(time.Duration(timeout) * time.Second)
+	for {
+		guestMetricsRef, err := xenapi.VM.GetGuestMetrics(session, vmRef)
+		if err != nil {
+			return errors.New(err.Error())
+		}
+		if guestMetricsRef != "OpaqueRef:NULL" {
+			guestMetrics, err := xenapi.VMGuestMetrics.GetRecord(session, guestMetricsRef)
+			if err == nil && guestMetrics.Live && guestMetrics.PVDriversDetected {
+				return nil
+			}
+		}
+		tflog.Debug(ctx, "-----> Retry checking VM guest tools")
+		select {
+		case <-timeoutChan:
+			return errors.New("guest tools are not live in " + strconv.FormatInt(timeout, 10) + " seconds")
+		case <-ctx.Done():
+			return errors.New("waiting for guest tools stopped: " + ctx.Err().Error())
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
 func checkIP(ctx context.Context, session *xenapi.Session, vmRecord xenapi.VMRecord) (string, error) {
-	checkIPTimeout, err := strconv.Atoi(vmRecord.OtherConfig["tf_check_ip_timeout"])
+	checkIPTimeoutValue := getVMState(vmRecord.OtherConfig)["check_ip_timeout"]
+	checkIPTimeout, err := strconv.Atoi(checkIPTimeoutValue)
 	if err != nil {
 		return "", errors.New(err.Error())
 	}
@@ -1182,16 +2748,17 @@ func checkIP(ctx context.Context, session *xenapi.Session, vmRecord xenapi.VMRec
 	// set timeout channel to check if IP address is available
 	timeoutChan := time.After(time.Duration(checkIPTimeout) * time.Second)
 	for {
+		ip, _ := getIPAddressFromMetrics(session, vmRecord)
+		if ip != "" {
+			return ip, nil
+		}
+		tflog.Debug(ctx, "-----> Retry getIPAddressFromMetrics")
 		select {
 		case <-timeoutChan:
-			return "", errors.New("get IP timeout in " + vmRecord.OtherConfig["tf_check_ip_timeout"] + " seconds")
-		default:
-			ip, _ := getIPAddressFromMetrics(session, vmRecord)
-			if ip != "" {
-				return ip, nil
-			}
-			tflog.Debug(ctx, "-----> Retry getIPAddressFromMetrics")
-			time.Sleep(5 * time.Second)
+			return "", errors.New("get IP timeout in " + checkIPTimeoutValue + " seconds")
+		case <-ctx.Done():
+			return "", errors.New("get IP stopped: " + ctx.Err().Error())
+		case <-time.After(5 * time.Second):
 		}
 	}
 }
@@ -1213,25 +2780,144 @@ func getIPAddressFromMetrics(session *xenapi.Session, vmRecord xenapi.VMRecord)
 	return "", errors.New("unable to get IP address from metrics")
 }
 
-func cleanupVMResource(session *xenapi.Session, vmRef xenapi.VMRef) error {
+// cleanupVMSnapshots destroys the snapshots of the VM with their disks when force is set,
+// otherwise it refuses to destroy the VM and lists the snapshots
+func cleanupVMSnapshots(ctx context.Context, session *xenapi.Session, snapshotRefs []xenapi.VMRef, force bool) error {
+	snapshotUUIDs := make([]string, 0, len(snapshotRefs))
+	for _, snapshotRef := range snapshotRefs {
+		snapshotUUID, err := xenapi.VM.GetUUID(session, snapshotRef)
+		if err != nil {
+			return errors.New(err.Error())
+		}
+		snapshotUUIDs = append(snapshotUUIDs, snapshotUUID)
+	}
+	if !force {
+		return errors.New("the VM has snapshots " + strings.Join(snapshotUUIDs, ", ") + ", destroy them first or set \"force_destroy\" to destroy them with the VM")
+	}
+
+	for i, snapshotRef := range snapshotRefs {
+		tflog.Debug(ctx, "---> Destroy VM snapshot: "+snapshotUUIDs[i])
+		err := cleanupSnapshotResource(session, snapshotRef)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cleanShutdownVM asks the guest to shut down and waits for it up to the timeout in seconds, it returns
+// false if the VM is not shut down cleanly in time, the pending shutdown is cancelled then
+func cleanShutdownVM(ctx context.Context, session *xenapi.Session, vmRef xenapi.VMRef, timeout int64) bool {
+	taskRef, err := xenapi.VM.AsyncCleanShutdown(session, vmRef)
+	if err != nil {
+		tflog.Debug(ctx, "-----> Unable to shut down the VM cleanly: "+err.Error())
+		return false
+	}
+	defer func() {
+		_ = xenapi.Task.Destroy(session, taskRef)
+	}()
+
+	operation := func() error {
+		status, err := xenapi.Task.GetStatus(session, taskRef)
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+		switch status {
+		case xenapi.TaskStatusTypeSuccess:
+			return nil
+		case xenapi.TaskStatusTypePending:
+			return errors.New("clean shutdown is pending")
+		default:
+			return backoff.Permanent(errors.New("clean shutdown is " + string(status)))
+		}
+	}
+
+	b := backoff.NewExponentialBackOff()
+	b.MaxInterval = 5 * time.Second
+	b.MaxElapsedTime = time.Duration(timeout) * time.Second
+	err = backoff.Retry(operation, backoff.WithContext(b, ctx))
+	if err != nil {
+		tflog.Debug(ctx, "-----> VM is not shut down cleanly in "+strconv.FormatInt(timeout, 10)+" seconds: "+err.Error())
+		_ = xenapi.Task.Cancel(session, taskRef)
+		return false
+	}
+	return true
+}
+
+// shutdownVM shuts down the running VM cleanly within the timeout in seconds, and falls back to a hard
+// shutdown if the guest doesn't stop in time. With a timeout of 0, the VM is hard shut down immediately.
+func shutdownVM(ctx context.Context, session *xenapi.Session, vmRef xenapi.VMRef, timeout int64) error {
+	if timeout > 0 && cleanShutdownVM(ctx, session, vmRef, timeout) {
+		return nil
+	}
+
+	// the VM may be halted while the clean shutdown is cancelled
+	powerState, err := xenapi.VM.GetPowerState(session, vmRef)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	if powerState == xenapi.VMPowerStateHalted {
+		return nil
+	}
+	err = xenapi.VM.HardShutdown(session, vmRef)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	return nil
+}
+
+// cleanupVMResource destroys the VM with its VIFs and VBDs, and the VDIs cloned from the template.
+// With force, the VM is shut down whatever its power state, e.g. paused or suspended, and its snapshots are destroyed.
+// A running VM is shut down cleanly within shutdownTimeout seconds before it's forcibly shut down.
+func cleanupVMResource(ctx context.Context, session *xenapi.Session, vmRef xenapi.VMRef, force bool, shutdownTimeout int64) error {
 	// delete VIFs and VBDs, then destroy VM
 	vmRecord, err := xenapi.VM.GetRecord(session, vmRef)
 	if err != nil {
-		return errors.New(err.Error())
+		return cleanupError("VM", string(vmRef), err)
+	}
+	uuid := vmRecord.UUID
+
+	// the snapshots would be orphaned after the VM is destroyed
+	if len(vmRecord.Snapshots) > 0 {
+		err = cleanupVMSnapshots(ctx, session, vmRecord.Snapshots, force)
+		if err != nil {
+			return cleanupError("VM", uuid, err)
+		}
+	}
+
+	// the operations blocked on the VM would prevent the cleanup
+	if len(vmRecord.BlockedOperations) > 0 {
+		err = xenapi.VM.SetBlockedOperations(session, vmRef, map[xenapi.VMOperations]string{})
+		if err != nil {
+			return cleanupError("VM", uuid, err)
+		}
 	}
 
 	// if VM is runing, stop it first
 	if vmRecord.PowerState == xenapi.VMPowerStateRunning {
+		err := shutdownVM(ctx, session, vmRef, shutdownTimeout)
+		if err != nil {
+			return cleanupError("VM", uuid, err)
+		}
+	} else if force && vmRecord.PowerState != xenapi.VMPowerStateHalted {
 		err := xenapi.VM.HardShutdown(session, vmRef)
 		if err != nil {
-			return errors.New(err.Error())
+			return cleanupError("VM", uuid, err)
+		}
+	}
+
+	if force {
+		// get the devices again, they may be attached outside of terraform during the shutdown
+		vmRecord, err = xenapi.VM.GetRecord(session, vmRef)
+		if err != nil {
+			return cleanupError("VM", uuid, err)
 		}
 	}
 
 	for _, vifRef := range vmRecord.VIFs {
 		err := xenapi.VIF.Destroy(session, vifRef)
 		if err != nil {
-			return errors.New(err.Error())
+			return cleanupError("VM", uuid, err)
 		}
 	}
 
@@ -1240,26 +2926,39 @@ func cleanupVMResource(session *xenapi.Session, vmRef xenapi.VMRef) error {
 		if slices.Contains(getTemplateVBDRefListFromVMRecord(vmRecord), vbdRef) {
 			vdiRef, err := xenapi.VBD.GetVDI(session, vbdRef)
 			if err != nil {
-				return errors.New(err.Error())
+				return cleanupError("VM", uuid, err)
 			}
 			vdiRefs = append(vdiRefs, vdiRef)
 		}
 		err := xenapi.VBD.Destroy(session, vbdRef)
 		if err != nil {
-			return errors.New(err.Error())
+			return cleanupError("VM", uuid, err)
 		}
 	}
 
 	for _, vdiRef := range vdiRefs {
-		err := xenapi.VDI.Destroy(session, vdiRef)
+		// the VBDs of the VM are destroyed, the VDI is shared with other VMs if it still has VBDs
+		vbdRefs, err := xenapi.VDI.GetVBDs(session, vdiRef)
 		if err != nil {
-			return errors.New(err.Error())
+			return cleanupError("VM", uuid, err)
+		}
+		if len(vbdRefs) > 0 {
+			vdiUUID, err := xenapi.VDI.GetUUID(session, vdiRef)
+			if err != nil {
+				return cleanupError("VM", uuid, err)
+			}
+			tflog.Warn(ctx, "Skip destroying VDI "+vdiUUID+", it's still attached to other VMs")
+			continue
+		}
+		err = cleanupVDIResource(session, vdiRef)
+		if err != nil {
+			return cleanupError("VM", uuid, err)
 		}
 	}
 
 	err = xenapi.VM.Destroy(session, vmRef)
 	if err != nil {
-		return errors.New(err.Error())
+		return cleanupError("VM", uuid, err)
 	}
 
 	return nil
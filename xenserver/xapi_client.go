@@ -0,0 +1,37 @@
+package xenserver
+
+import (
+	"xenapi"
+)
+
+// The interfaces below cover the XAPI calls made by the helpers which are unit tested, the xenapi
+// classes satisfy them as they are. The helpers call XAPI through the variables, so the unit tests
+// can replace them with fakes and don't need a live pool.
+
+// vmClient is the part of the XAPI VM class used by the helpers
+type vmClient interface {
+	GetPlatform(session *xenapi.Session, self xenapi.VMRef) (map[string]string, error)
+	SetPlatform(session *xenapi.Session, self xenapi.VMRef, value map[string]string) error
+}
+
+// pifClient is the part of the XAPI PIF class used by the helpers
+type pifClient interface {
+	GetRecord(session *xenapi.Session, self xenapi.PIFRef) (xenapi.PIFRecord, error)
+}
+
+// vlanClient is the part of the XAPI VLAN class used by the helpers
+type vlanClient interface {
+	GetRecord(session *xenapi.Session, self xenapi.VLANRef) (xenapi.VLANRecord, error)
+}
+
+// bondClient is the part of the XAPI Bond class used by the helpers
+type bondClient interface {
+	GetRecord(session *xenapi.Session, self xenapi.BondRef) (xenapi.BondRecord, error)
+}
+
+var (
+	vmAPI   vmClient   = xenapi.VM
+	pifAPI  pifClient  = xenapi.PIF
+	vlanAPI vlanClient = xenapi.VLAN
+	bondAPI bondClient = xenapi.Bond
+)